@@ -114,6 +114,7 @@ func (suite *BenchmarkSuite) profilePathwaysGeneration() {
 	netexRepo := repository.NewDefaultNetexRepository()
 	gtfsRepo := repository.NewDefaultGtfsRepository()
 	producer := producer.NewDefaultPathwaysProducer(netexRepo, gtfsRepo)
+	producer.SetHeuristicFallback(true)
 
 	totalProcessed := 0
 	sizes := []int{5, 10, 15, 20}
@@ -399,10 +399,111 @@ func TestNeTExCalendarProcessor_ProcessDayTypeAssignment_Uncovered(t *testing.T)
 	t.Logf("ProcessDayTypeAssignment completed with error: %v", err)
 }
 
-// func TestNeTExCalendarProcessor_ProcessUicOperatingPeriod_Uncovered(t *testing.T) {
-//	// UicOperatingPeriod type doesn't exist in model - commenting out this test
-//	t.Skip("UicOperatingPeriod type not found in model package")
-// }
+func TestNeTExCalendarProcessor_ProcessUicOperatingPeriod(t *testing.T) {
+	manager := NewCalendarManager(CalendarConfig{HolidayCountryCode: "NO", TimezoneName: "Europe/Oslo"})
+	processor, err := NewNeTExCalendarProcessor(manager, "Europe/Oslo")
+	if err != nil {
+		t.Fatalf("Error creating NeTExCalendarProcessor: %v", err)
+	}
+
+	// Three weeks from Monday 2024-06-03 on weekdays, except Thursday 13th
+	uicPeriod := &model.UicOperatingPeriod{
+		ID:           "uic_1",
+		Name:         "Summer weekdays",
+		FromDate:     "2024-06-03T00:00:00",
+		ValidDayBits: "111110011101001111100",
+	}
+
+	period, err := processor.ProcessUicOperatingPeriod(uicPeriod)
+	if err != nil {
+		t.Fatalf("ProcessUicOperatingPeriod() failed: %v", err)
+	}
+	if period.StartDate.Format("20060102") != "20240603" || period.EndDate.Format("20060102") != "20240623" {
+		t.Errorf("Expected period 20240603-20240623, got %s-%s",
+			period.StartDate.Format("20060102"), period.EndDate.Format("20060102"))
+	}
+
+	pattern := period.BasePattern
+	if pattern == nil || pattern.BaseCalendar == nil {
+		t.Fatal("Expected a base pattern with calendar")
+	}
+	if len(pattern.OperatingDays) != 5 || pattern.OperatingDays[0] != time.Monday || pattern.OperatingDays[4] != time.Friday {
+		t.Errorf("Expected Monday to Friday, got %v", pattern.OperatingDays)
+	}
+	if len(pattern.Exceptions) != 1 || pattern.Exceptions[0].Type != ExceptionRemoved ||
+		pattern.Exceptions[0].Date.Format("20060102") != "20240613" {
+		t.Errorf("Expected 20240613 removed, got %+v", pattern.Exceptions)
+	}
+
+	if _, err := processor.ProcessUicOperatingPeriod(&model.UicOperatingPeriod{ID: "bad", FromDate: "2024-06-03", ValidDayBits: "1x1"}); err == nil {
+		t.Error("Expected error for invalid ValidDayBits")
+	}
+	if _, err := processor.ProcessUicOperatingPeriod("not a period"); err == nil {
+		t.Error("Expected error for unsupported type")
+	}
+}
+
+func TestDecodeValidDayBits(t *testing.T) {
+	from := time.Date(2024, 12, 30, 0, 0, 0, 0, time.UTC)
+	dates, err := DecodeValidDayBits(from, " 1001 ")
+	if err != nil {
+		t.Fatalf("DecodeValidDayBits() failed: %v", err)
+	}
+	if len(dates) != 2 || dates[0].Format("20060102") != "20241230" || dates[1].Format("20060102") != "20250102" {
+		t.Errorf("Unexpected dates: %v", dates)
+	}
+}
+
+func TestCompressServiceDates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 7, d, 0, 0, 0, 0, time.UTC) }
+
+	t.Run("regular pattern uses calendar row", func(t *testing.T) {
+		// Weekends of July 2024 except Sunday 14th, plus Wednesday 17th
+		var dates []time.Time
+		for d := 1; d <= 31; d++ {
+			weekday := day(d).Weekday()
+			if (weekday == time.Saturday || weekday == time.Sunday) && d != 14 {
+				dates = append(dates, day(d))
+			}
+		}
+		dates = append(dates, day(17), day(17))
+
+		calendar, exceptions := CompressServiceDates("svc", dates)
+		if calendar == nil {
+			t.Fatal("Expected a calendar row")
+		}
+		if !calendar.Saturday || !calendar.Sunday || calendar.Monday || calendar.Wednesday {
+			t.Errorf("Expected weekend service, got %+v", calendar)
+		}
+		if calendar.StartDate != "20240706" || calendar.EndDate != "20240728" {
+			t.Errorf("Expected row 20240706-20240728, got %s-%s", calendar.StartDate, calendar.EndDate)
+		}
+		got := make(map[string]int)
+		for _, e := range exceptions {
+			got[e.Date] = e.ExceptionType
+		}
+		if len(got) != 2 || got["20240714"] != 2 || got["20240717"] != 1 {
+			t.Errorf("Expected 20240714 removed and 20240717 added, got %v", got)
+		}
+	})
+
+	t.Run("sparse dates stay in calendar_dates", func(t *testing.T) {
+		calendar, exceptions := CompressServiceDates("svc", []time.Time{day(3), day(19)})
+		if calendar != nil {
+			t.Errorf("Expected no calendar row, got %+v", calendar)
+		}
+		if len(exceptions) != 2 || exceptions[0].Date != "20240703" || exceptions[0].ExceptionType != 1 {
+			t.Errorf("Expected two added dates, got %d", len(exceptions))
+		}
+	})
+
+	t.Run("no dates", func(t *testing.T) {
+		calendar, exceptions := CompressServiceDates("svc", nil)
+		if calendar != nil || len(exceptions) != 0 {
+			t.Error("Expected nothing for no dates")
+		}
+	})
+}
 
 func TestNeTExCalendarProcessor_ConvertToGTFSCalendar_Uncovered(t *testing.T) {
 	config := CalendarConfig{
@@ -431,3 +431,156 @@ func (gcg *GTFSCalendarGenerator) GenerateCalendarSummary(calendars []*model.Cal
 
 	return summary
 }
+
+// gtfsDateFormat is the GTFS date layout (YYYYMMDD)
+const gtfsDateFormat = "20060102"
+
+// CompressServiceDates returns the smallest combination of a calendar.txt row and
+// calendar_dates.txt exceptions that activates exactly the given service dates.
+// The calendar row is nil when listing every date as an addition is as small.
+func CompressServiceDates(serviceID string, dates []time.Time) (*model.Calendar, []*model.CalendarDate) {
+	active := make(map[string]bool, len(dates))
+	days := make([]time.Time, 0, len(dates))
+	for _, date := range dates {
+		day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+		key := day.Format(gtfsDateFormat)
+		if !active[key] {
+			active[key] = true
+			days = append(days, day)
+		}
+	}
+	if len(days) == 0 {
+		return nil, nil
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	first, last := days[0], days[len(days)-1]
+
+	// Each weekday is chosen independently: running it costs one removal per
+	// inactive day of that weekday, not running it costs one addition per active day.
+	// Ties keep the weekday so regular patterns read naturally.
+	var activeCount, inactiveCount [7]int
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if active[day.Format(gtfsDateFormat)] {
+			activeCount[day.Weekday()]++
+		} else {
+			inactiveCount[day.Weekday()]++
+		}
+	}
+	var weekdays [7]bool
+	for day := range weekdays {
+		weekdays[day] = activeCount[day] > 0 && activeCount[day] >= inactiveCount[day]
+	}
+
+	// Trim the row to the first and last active day it covers
+	var start, end time.Time
+	for _, day := range days {
+		if weekdays[day.Weekday()] {
+			if start.IsZero() {
+				start = day
+			}
+			end = day
+		}
+	}
+
+	exceptions := make([]*model.CalendarDate, 0)
+	if !start.IsZero() {
+		for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+			key := day.Format(gtfsDateFormat)
+			covered := weekdays[day.Weekday()] && !day.Before(start) && !day.After(end)
+			switch {
+			case active[key] && !covered:
+				exceptions = append(exceptions, &model.CalendarDate{ServiceID: serviceID, Date: key, ExceptionType: 1})
+			case !active[key] && covered:
+				exceptions = append(exceptions, &model.CalendarDate{ServiceID: serviceID, Date: key, ExceptionType: 2})
+			}
+		}
+	}
+	if start.IsZero() || 1+len(exceptions) >= len(days) {
+		exceptions = make([]*model.CalendarDate, 0, len(days))
+		for _, day := range days {
+			exceptions = append(exceptions, &model.CalendarDate{ServiceID: serviceID, Date: day.Format(gtfsDateFormat), ExceptionType: 1})
+		}
+		return nil, exceptions
+	}
+
+	return &model.Calendar{
+		ServiceID: serviceID,
+		Monday:    weekdays[time.Monday],
+		Tuesday:   weekdays[time.Tuesday],
+		Wednesday: weekdays[time.Wednesday],
+		Thursday:  weekdays[time.Thursday],
+		Friday:    weekdays[time.Friday],
+		Saturday:  weekdays[time.Saturday],
+		Sunday:    weekdays[time.Sunday],
+		StartDate: start.Format(gtfsDateFormat),
+		EndDate:   end.Format(gtfsDateFormat),
+	}, exceptions
+}
+
+// ServicePeriod returns the first and last dates on which any service of a
+// feed runs, taking calendar.txt weekdays and calendar_dates.txt additions and
+// removals into account. ok is false when no service runs on any date.
+func ServicePeriod(calendars []*model.Calendar, calendarDates []*model.CalendarDate) (first, last time.Time, ok bool) {
+	include := func(day time.Time) {
+		if !ok || day.Before(first) {
+			first = day
+		}
+		if !ok || day.After(last) {
+			last = day
+		}
+		ok = true
+	}
+
+	removed := make(map[string]map[string]bool)
+	for _, cd := range calendarDates {
+		if cd == nil {
+			continue
+		}
+		switch cd.ExceptionType {
+		case 1:
+			if day, err := time.Parse(gtfsDateFormat, cd.Date); err == nil {
+				include(day)
+			}
+		case 2:
+			if removed[cd.ServiceID] == nil {
+				removed[cd.ServiceID] = make(map[string]bool)
+			}
+			removed[cd.ServiceID][cd.Date] = true
+		}
+	}
+
+	for _, cal := range calendars {
+		if cal == nil {
+			continue
+		}
+		start, err := time.Parse(gtfsDateFormat, cal.StartDate)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(gtfsDateFormat, cal.EndDate)
+		if err != nil {
+			continue
+		}
+		weekdays := make(map[time.Weekday]bool)
+		for _, day := range calendarWeekdays(cal) {
+			weekdays[day] = true
+		}
+		runs := func(day time.Time) bool {
+			return weekdays[day.Weekday()] && !removed[cal.ServiceID][day.Format(gtfsDateFormat)]
+		}
+
+		for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+			if runs(day) {
+				include(day)
+				break
+			}
+		}
+		for day := end; !day.Before(start); day = day.AddDate(0, 0, -1) {
+			if runs(day) {
+				include(day)
+				break
+			}
+		}
+	}
+	return first, last, ok
+}
@@ -117,20 +117,123 @@ func (ncp *NeTExCalendarProcessor) ProcessDayTypeAssignment(assignment interface
 	return nil
 }
 
-// ProcessUicOperatingPeriod processes UIC operating periods common in European rail
+// ProcessUicOperatingPeriod processes UIC operating periods common in European rail.
+// The ValidDayBits are decoded into dates and compressed into the base pattern.
 func (ncp *NeTExCalendarProcessor) ProcessUicOperatingPeriod(uicPeriod interface{}) (*OperatingPeriod, error) {
 	// UIC (International Union of Railways) operating periods
 	// These are standardized across European rail networks
+	period, ok := uicPeriod.(*model.UicOperatingPeriod)
+	if !ok || period == nil {
+		return nil, fmt.Errorf("unsupported UIC operating period type %T", uicPeriod)
+	}
 
-	period := &OperatingPeriod{
-		ID:        "uic_period_example",
-		Name:      "UIC Operating Period",
-		StartDate: time.Now(),
-		EndDate:   time.Now().AddDate(0, 3, 0), // 3 months
-		Priority:  2,
+	dates, err := ncp.UicOperatingPeriodDates(period)
+	if err != nil {
+		return nil, err
 	}
 
-	return period, nil
+	fromDate, err := ncp.ParseNeTExDate(period.FromDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FromDate in UIC operating period %s: %v", period.ID, err)
+	}
+	toDate := fromDate.AddDate(0, 0, len(strings.TrimSpace(period.ValidDayBits))-1)
+
+	calendar, exceptions := CompressServiceDates(period.ID, dates)
+	pattern := &ServicePattern{
+		ID:                 period.ID,
+		Name:               period.Name,
+		Type:               PatternRegular,
+		BaseCalendar:       calendar,
+		Exceptions:         make([]*ServiceException, 0, len(exceptions)),
+		SeasonalVariations: make([]*SeasonalVariation, 0),
+		SpecialDays:        make(map[string]*SpecialDay),
+		ValidityPeriod: &ValidityPeriod{
+			StartDate: fromDate,
+			EndDate:   toDate,
+		},
+		HolidayBehavior: HolidayAsWeekday,
+	}
+	if calendar != nil {
+		pattern.OperatingDays = calendarWeekdays(calendar)
+	}
+	for _, exception := range exceptions {
+		date, err := time.ParseInLocation(gtfsDateFormat, exception.Date, ncp.timezone)
+		if err != nil {
+			continue
+		}
+		exceptionType := ExceptionAdded
+		if exception.ExceptionType == 2 {
+			exceptionType = ExceptionRemoved
+		}
+		pattern.Exceptions = append(pattern.Exceptions, &ServiceException{
+			Date:   date,
+			Type:   exceptionType,
+			Reason: "UIC valid day bits",
+		})
+	}
+
+	return &OperatingPeriod{
+		ID:          period.ID,
+		Name:        period.Name,
+		StartDate:   fromDate,
+		EndDate:     toDate,
+		BasePattern: pattern,
+		Overrides:   make(map[string]*ServicePattern),
+		Priority:    2,
+	}, nil
+}
+
+// UicOperatingPeriodDates returns the dates on which a UIC operating period is valid
+func (ncp *NeTExCalendarProcessor) UicOperatingPeriodDates(period *model.UicOperatingPeriod) ([]time.Time, error) {
+	fromDate, err := ncp.ParseNeTExDate(period.FromDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FromDate in UIC operating period %s: %v", period.ID, err)
+	}
+	dates, err := DecodeValidDayBits(fromDate, period.ValidDayBits)
+	if err != nil {
+		return nil, fmt.Errorf("UIC operating period %s: %v", period.ID, err)
+	}
+	return dates, nil
+}
+
+// DecodeValidDayBits decodes a UIC ValidDayBits string, where the character at
+// index i tells whether the service runs i days after fromDate
+func DecodeValidDayBits(fromDate time.Time, validDayBits string) ([]time.Time, error) {
+	bits := strings.TrimSpace(validDayBits)
+	dates := make([]time.Time, 0, strings.Count(bits, "1"))
+	for i, bit := range bits {
+		switch bit {
+		case '1':
+			dates = append(dates, fromDate.AddDate(0, 0, i))
+		case '0':
+		default:
+			return nil, fmt.Errorf("invalid character %q at position %d in ValidDayBits", bit, i)
+		}
+	}
+	return dates, nil
+}
+
+// calendarWeekdays lists the weekdays a GTFS calendar row runs on
+func calendarWeekdays(calendar *model.Calendar) []time.Weekday {
+	flags := []struct {
+		day     time.Weekday
+		running bool
+	}{
+		{time.Monday, calendar.Monday},
+		{time.Tuesday, calendar.Tuesday},
+		{time.Wednesday, calendar.Wednesday},
+		{time.Thursday, calendar.Thursday},
+		{time.Friday, calendar.Friday},
+		{time.Saturday, calendar.Saturday},
+		{time.Sunday, calendar.Sunday},
+	}
+	weekdays := make([]time.Weekday, 0, len(flags))
+	for _, flag := range flags {
+		if flag.running {
+			weekdays = append(weekdays, flag.day)
+		}
+	}
+	return weekdays
 }
 
 // Example service pattern creation methods
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/calendar"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/config"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/errors"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/exporter"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/output"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
+)
+
+// convertOptions holds the flags of the convert command that are not part of
+// the configuration
+type convertOptions struct {
+	netexPath  string
+	stopsPath  string
+	stopsOnly  bool
+	outputPath string
+}
+
+// addExporterFlags registers the flags that configure the exporter; stats
+// shares them to convert NeTEx input
+func (o *convertOptions) addExporterFlags(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Codespace, "codespace", cfg.Codespace, "NeTEx codespace (required for timetable conversion)")
+	fs.StringVar(&o.stopsPath, "stops", "", "NeTEx stops ZIP file, used in place of the timetable's own stop places")
+	fs.StringVar(&cfg.TimeZone, "timezone", cfg.TimeZone, "Agency timezone, overriding the dataset's FrameDefaults (e.g. Europe/Oslo)")
+	fs.StringVar(&cfg.Language, "lang", cfg.Language, "Agency and feed language, overriding the dataset's FrameDefaults (e.g. no)")
+	fs.StringVar(&cfg.Producers.ViaFormat, "via-format", cfg.Producers.ViaFormat, "Headsign format for destinations with vias; empty leaves vias out")
+	fs.StringVar(&cfg.Producers.ZonePolicy, "zone-policy", cfg.Producers.ZonePolicy, "zone_id of stops in several tariff zones: first, empty or combined")
+	fs.StringVar(&cfg.Producers.Multimodal, "multimodal", cfg.Producers.Multimodal, "station of stop places in a multimodal stop place: child or parent")
+	fs.BoolVar(&cfg.Producers.PathwayHeuristics, "pathway-heuristics", cfg.Producers.PathwayHeuristics, "infer levels and pathways of stop places without NeTEx levels and path links")
+	fs.Var(stopTimesFlag{&cfg.Producers}, "interpolate-stop-times", "Interpolate times of stops without passing times")
+}
+
+// stopTimesFlag sets producers.stop_times from the boolean
+// --interpolate-stop-times flag
+type stopTimesFlag struct {
+	producers *config.Producers
+}
+
+func (f stopTimesFlag) String() string {
+	return strconv.FormatBool(f.producers != nil && f.producers.StopTimes == config.StopTimesInterpolated)
+}
+
+func (f stopTimesFlag) Set(value string) error {
+	interpolate, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	f.producers.StopTimes = config.StopTimesPassingTimes
+	if interpolate {
+		f.producers.StopTimes = config.StopTimesInterpolated
+	}
+	return nil
+}
+
+func (f stopTimesFlag) IsBoolFlag() bool {
+	return true
+}
+
+// runConvert implements the convert command
+func runConvert(env *environment, cmd *command, args []string) int {
+	opts := &convertOptions{}
+	fs := env.newFlagSet(cmd)
+	cfg := env.config
+	opts.addExporterFlags(fs, cfg)
+	fs.StringVar(&opts.netexPath, "netex", "", "NeTEx timetable ZIP file (required unless --stops-only)")
+	fs.BoolVar(&opts.stopsOnly, "stops-only", false, "Convert only the stops of the --stops archive")
+	fs.StringVar(&opts.outputPath, "output", "gtfs.zip", "Output GTFS ZIP file, directory (ending in / or existing) or s3://bucket/key")
+	fs.StringVar(&cfg.Holidays.Country, "holiday-country", cfg.Holidays.Country, "Country code of the public holidays listed with --verbose (e.g. NO)")
+	fs.StringVar(&cfg.FeedInfo.PublisherName, "publisher-name", cfg.FeedInfo.PublisherName, "feed_info.txt publisher name")
+	fs.StringVar(&cfg.FeedInfo.PublisherURL, "publisher-url", cfg.FeedInfo.PublisherURL, "feed_info.txt publisher URL")
+	fs.StringVar(&cfg.FeedInfo.ContactEmail, "contact-email", cfg.FeedInfo.ContactEmail, "feed_info.txt contact email")
+	fs.StringVar(&cfg.FeedInfo.ContactURL, "contact-url", cfg.FeedInfo.ContactURL, "feed_info.txt contact URL")
+
+	if code, ok := env.parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	if err := opts.validate(cfg); err != nil {
+		env.errorf("%v", err)
+		env.usageHint(cmd.name)
+		return exitUsage
+	}
+
+	if err := convert(env, opts); err != nil {
+		env.errorf("%v", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// validate checks flag combinations before any file is read; the values of
+// the configuration are checked by parseFlags
+func (o *convertOptions) validate(cfg *config.Config) error {
+	if o.stopsOnly {
+		if o.stopsPath == "" {
+			return fmt.Errorf("--stops is required with --stops-only")
+		}
+	} else {
+		if o.netexPath == "" {
+			return fmt.Errorf("--netex is required unless --stops-only is set")
+		}
+		if strings.TrimSpace(cfg.Codespace) == "" {
+			return fmt.Errorf("--codespace is required for timetable conversion")
+		}
+	}
+	if o.outputPath == "" {
+		return fmt.Errorf("--output must not be empty")
+	}
+	return nil
+}
+
+// convert runs the conversion and writes the GTFS archive; any returned error
+// is fatal
+func convert(env *environment, opts *convertOptions) error {
+	start := time.Now()
+
+	if env.config.Holidays.Country != "" && env.verbose {
+		if err := reportHolidays(env); err != nil {
+			return err
+		}
+	}
+
+	gtfsExporter, err := newGtfsExporter(env, opts)
+	if err != nil {
+		return err
+	}
+
+	var netexFile *os.File
+	if !opts.stopsOnly {
+		// #nosec G304 -- the path comes from the command line
+		netexFile, err = os.Open(opts.netexPath)
+		if err != nil {
+			return fmt.Errorf("opening NeTEx archive: %w", err)
+		}
+		defer func() { _ = netexFile.Close() }()
+	}
+
+	sink, err := openSink(env, opts.outputPath)
+	if err != nil {
+		return err
+	}
+
+	// The feed streams into the sink as it is written; the sink is only
+	// completed once the result is checked
+	held := &heldSink{Sink: sink}
+	var result *errors.ConversionResult
+	if opts.stopsOnly {
+		env.logf("Converting stops of %s", opts.stopsPath)
+		result, err = gtfsExporter.ConvertStopsToGtfsSink(env.ctx, held)
+	} else {
+		env.logf("Converting %s (codespace %s)", opts.netexPath, env.config.Codespace)
+		result, err = gtfsExporter.ConvertTimetablesToGtfsSink(env.ctx, netexFile, held)
+	}
+
+	if err := checkResult(env, result, err); err != nil {
+		_ = sink.Abort()
+		return err
+	}
+
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("writing GTFS output: %w", err)
+	}
+
+	_, _ = fmt.Fprintf(env.stdout, "Wrote GTFS to %s in %v\n", opts.outputPath, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// newGtfsExporter creates an exporter configured from the command line,
+// loading the --stops archive when given
+func newGtfsExporter(env *environment, opts *convertOptions) (*exporter.EnhancedGtfsExporter, error) {
+	stopAreaRepository := repository.NewDefaultStopAreaRepository()
+	if opts.stopsPath != "" {
+		// #nosec G304 -- the path comes from the command line
+		data, err := os.ReadFile(opts.stopsPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading stops archive: %w", err)
+		}
+		if err := stopAreaRepository.LoadStopAreas(data); err != nil {
+			return nil, fmt.Errorf("loading stops archive %s: %w", opts.stopsPath, err)
+		}
+		env.logf("Loaded %d quays from %s", len(stopAreaRepository.GetAllQuays()), opts.stopsPath)
+	}
+
+	gtfsExporter := exporter.NewEnhancedGtfsExporter(env.config.Codespace, stopAreaRepository)
+	env.config.ConfigureExporter(gtfsExporter)
+	if env.verbose {
+		gtfsExporter.SetProgressHandler(func(event exporter.ProgressEvent) {
+			if event.Total > 0 && event.Processed == event.Total {
+				env.logf("  %s: %d %s(s)", event.Stage, event.Total, event.EntityType)
+			}
+		})
+	}
+	return gtfsExporter, nil
+}
+
+// checkResult reports the conversion result and returns an error when the
+// conversion failed as a whole
+func checkResult(env *environment, result *errors.ConversionResult, err error) error {
+	reportResult(env, result)
+	if err != nil {
+		return fmt.Errorf("conversion failed: %w", err)
+	}
+	// Entities that could not be recovered are skipped; only errors that
+	// compromise the whole feed, such as an unreadable dataset, fail the run
+	if result != nil && !result.Success {
+		return fmt.Errorf("conversion failed, see the errors above")
+	}
+	return nil
+}
+
+// reportHolidays lists the public holidays of the current year for the
+// configured country
+func reportHolidays(env *environment) error {
+	serviceConfig := env.config.CalendarServiceConfig()
+	serviceConfig.EnableHolidayDetection = true
+	service, err := calendar.NewCalendarService(serviceConfig)
+	if err != nil {
+		return fmt.Errorf("configuring holidays: %w", err)
+	}
+
+	year := time.Now().Year()
+	holidays, err := service.GetHolidays(year)
+	if err != nil {
+		return fmt.Errorf("computing holidays: %w", err)
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date) })
+
+	_, _ = fmt.Fprintf(env.stdout, "Public holidays (%s, %d):\n", serviceConfig.HolidayCountryCode, year)
+	for _, holiday := range holidays {
+		_, _ = fmt.Fprintf(env.stdout, "  %s %s\n", holiday.Date.Format("2006-01-02"), holiday.Name)
+	}
+	return nil
+}
+
+// reportResult prints the unrecoverable conversion errors to stderr, and all
+// errors, warnings and counts when verbose
+func reportResult(env *environment, result *errors.ConversionResult) {
+	if result == nil {
+		return
+	}
+
+	for _, convErr := range result.Errors {
+		if env.verbose || !convErr.Recoverable {
+			_, _ = fmt.Fprintf(env.stderr, "%v\n", convErr)
+		}
+	}
+
+	if !env.verbose {
+		if len(result.Errors) > 0 || len(result.Warnings) > 0 {
+			_, _ = fmt.Fprintf(env.stdout, "%d error(s), %d warning(s); run with --verbose for details\n",
+				len(result.Errors), len(result.Warnings))
+		}
+		return
+	}
+
+	for _, warning := range result.Warnings {
+		_, _ = fmt.Fprintf(env.stdout, "%v\n", warning)
+	}
+	_, _ = fmt.Fprint(env.stdout, result.GetSummary())
+}
+
+// openSink opens the output named by path: an S3 object for s3:// URLs, a
+// directory of text files for paths ending in a separator or naming an
+// existing directory, and a ZIP archive otherwise. S3 credentials and
+// endpoint come from the AWS environment variables.
+func openSink(env *environment, path string) (output.Sink, error) {
+	if strings.HasPrefix(path, "s3://") {
+		bucket, key, err := output.ParseS3URL(path)
+		if err != nil {
+			return nil, err
+		}
+		return output.NewS3Sink(env.ctx, output.S3ConfigFromEnv(), bucket, key)
+	}
+	if strings.HasSuffix(path, "/") || strings.HasSuffix(path, string(filepath.Separator)) {
+		return output.NewDirectorySink(path)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return output.NewDirectorySink(path)
+	}
+	return output.NewZipFileSink(path)
+}
+
+// heldSink passes files to a sink but holds back Close, so that the command
+// completes the output only after checking the conversion result
+type heldSink struct {
+	output.Sink
+}
+
+func (heldSink) Close() error {
+	return nil
+}
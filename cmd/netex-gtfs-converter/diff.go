@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
+)
+
+// diffKeys are the columns identifying a row of each GTFS file; rows of
+// other files are identified by all their values
+var diffKeys = map[string][]string{
+	"agency.txt":          {"agency_id"},
+	"stops.txt":           {"stop_id"},
+	"routes.txt":          {"route_id"},
+	"trips.txt":           {"trip_id"},
+	"stop_times.txt":      {"trip_id", "stop_sequence"},
+	"calendar.txt":        {"service_id"},
+	"calendar_dates.txt":  {"service_id", "date"},
+	"shapes.txt":          {"shape_id", "shape_pt_sequence"},
+	"frequencies.txt":     {"trip_id", "start_time"},
+	"transfers.txt":       {"from_stop_id", "to_stop_id", "from_trip_id", "to_trip_id", "from_route_id", "to_route_id"},
+	"feed_info.txt":       {},
+	"pathways.txt":        {"pathway_id"},
+	"levels.txt":          {"level_id"},
+	"fare_attributes.txt": {"fare_id"},
+	"attributions.txt":    {"attribution_id"},
+}
+
+// fileDiff lists the differences of one GTFS file
+type fileDiff struct {
+	File    string      `json:"file"`
+	Status  string      `json:"status"`
+	Added   []string    `json:"added,omitempty"`
+	Removed []string    `json:"removed,omitempty"`
+	Changed []rowChange `json:"changed,omitempty"`
+}
+
+// rowChange lists the changed columns of a row present in both archives
+type rowChange struct {
+	Key    string        `json:"key"`
+	Fields []fieldChange `json:"fields"`
+}
+
+// fieldChange is one changed value
+type fieldChange struct {
+	Column string `json:"column"`
+	Old    string `json:"old"`
+	New    string `json:"new"`
+}
+
+// diffIgnore holds the files and columns left out of the comparison
+type diffIgnore struct {
+	files   map[string]bool
+	columns map[string]map[string]bool
+}
+
+// parseDiffIgnore parses a comma separated list of file or file:column
+func parseDiffIgnore(value string) diffIgnore {
+	ignore := diffIgnore{files: make(map[string]bool), columns: make(map[string]map[string]bool)}
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		file, column, hasColumn := strings.Cut(item, ":")
+		if !hasColumn {
+			ignore.files[file] = true
+			continue
+		}
+		if ignore.columns[file] == nil {
+			ignore.columns[file] = make(map[string]bool)
+		}
+		ignore.columns[file][column] = true
+	}
+	return ignore
+}
+
+// runDiff implements the diff command; like diff(1) it exits with 0 when the
+// archives are identical, 1 when they differ and 2 on trouble
+func runDiff(env *environment, cmd *command, args []string) int {
+	fs := env.newFlagSet(cmd)
+	format := fs.String("format", "text", "Output format: text or json")
+	ignore := fs.String("ignore", "", "Comma separated files or file:column pairs to leave out (e.g. feed_info.txt:feed_version)")
+
+	if code, ok := env.parseFlags(fs, args, 2); !ok {
+		return code
+	}
+	if *format != "text" && *format != "json" {
+		env.errorf("unknown --format %q", *format)
+		env.usageHint(cmd.name)
+		return exitUsage
+	}
+
+	oldTables, err := readGtfsInput(fs.Arg(0))
+	if err != nil {
+		env.errorf("%v", err)
+		return exitUsage
+	}
+	newTables, err := readGtfsInput(fs.Arg(1))
+	if err != nil {
+		env.errorf("%v", err)
+		return exitUsage
+	}
+
+	diffs := diffGtfs(oldTables, newTables, parseDiffIgnore(*ignore))
+
+	if *format == "json" {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diffs); err != nil {
+			env.errorf("%v", err)
+			return exitUsage
+		}
+	} else {
+		writeDiffText(env.stdout, diffs, env.verbose)
+	}
+
+	for _, diff := range diffs {
+		if diff.Status != "unchanged" {
+			return exitFailure
+		}
+	}
+	return exitOK
+}
+
+// diffGtfs compares every file of two GTFS archives
+func diffGtfs(oldTables, newTables map[string]*repository.GtfsTable, ignore diffIgnore) []*fileDiff {
+	names := make(map[string]bool)
+	for name := range oldTables {
+		names[name] = true
+	}
+	for name := range newTables {
+		names[name] = true
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		if !ignore.files[name] {
+			sorted = append(sorted, name)
+		}
+	}
+	sort.Strings(sorted)
+
+	diffs := make([]*fileDiff, 0, len(sorted))
+	for _, name := range sorted {
+		diffs = append(diffs, diffTable(name, oldTables[name], newTables[name], ignore.columns[name]))
+	}
+	return diffs
+}
+
+// diffTable compares the rows of one file, either table being nil when the
+// file is missing from its archive
+func diffTable(name string, oldTable, newTable *repository.GtfsTable, ignoredColumns map[string]bool) *fileDiff {
+	diff := &fileDiff{File: name}
+	switch {
+	case oldTable == nil:
+		diff.Status = "added"
+	case newTable == nil:
+		diff.Status = "removed"
+	}
+
+	columns := diffColumns(oldTable, newTable, ignoredColumns)
+	keyColumns, ok := diffKeys[name]
+	if !ok {
+		keyColumns = columns
+	}
+	oldRows := keyRows(oldTable, keyColumns)
+	newRows := keyRows(newTable, keyColumns)
+
+	for _, key := range sortedKeys(oldRows) {
+		newRow, ok := newRows[key]
+		if !ok {
+			diff.Removed = append(diff.Removed, key)
+			continue
+		}
+		oldRow := oldRows[key]
+		var fields []fieldChange
+		for _, column := range columns {
+			oldValue := oldTable.Value(oldRow, column)
+			newValue := newTable.Value(newRow, column)
+			if oldValue != newValue {
+				fields = append(fields, fieldChange{Column: column, Old: oldValue, New: newValue})
+			}
+		}
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, rowChange{Key: key, Fields: fields})
+		}
+	}
+	for _, key := range sortedKeys(newRows) {
+		if _, ok := oldRows[key]; !ok {
+			diff.Added = append(diff.Added, key)
+		}
+	}
+
+	if diff.Status == "" {
+		diff.Status = "unchanged"
+		if len(diff.Added)+len(diff.Removed)+len(diff.Changed) > 0 {
+			diff.Status = "changed"
+		}
+	}
+	return diff
+}
+
+// diffColumns returns the columns of either table that are not ignored, so
+// that a column present in one archive only shows up as changed values
+func diffColumns(oldTable, newTable *repository.GtfsTable, ignoredColumns map[string]bool) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, table := range []*repository.GtfsTable{oldTable, newTable} {
+		if table == nil {
+			continue
+		}
+		for _, column := range table.Header {
+			if !seen[column] && !ignoredColumns[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	return columns
+}
+
+// keyRows indexes the rows of a table by their key columns; repeated keys
+// get a #n suffix so that no row is lost
+func keyRows(table *repository.GtfsTable, keyColumns []string) map[string][]string {
+	rows := make(map[string][]string)
+	if table == nil {
+		return rows
+	}
+	for _, row := range table.Rows {
+		values := make([]string, len(keyColumns))
+		for i, column := range keyColumns {
+			values[i] = table.Value(row, column)
+		}
+		base := strings.Join(values, "|")
+		if base == "" {
+			// Single row files such as feed_info.txt have no key columns
+			base = "(row)"
+		}
+		key := base
+		for n := 2; ; n++ {
+			if _, exists := rows[key]; !exists {
+				break
+			}
+			key = fmt.Sprintf("%s#%d", base, n)
+		}
+		rows[key] = row
+	}
+	return rows
+}
+
+// sortedKeys returns the keys of an indexed table in order
+func sortedKeys(rows map[string][]string) []string {
+	keys := make([]string, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeDiffText prints a summary line per file, and each added, removed and
+// changed row when verbose
+func writeDiffText(w io.Writer, diffs []*fileDiff, verbose bool) {
+	differences := 0
+	for _, diff := range diffs {
+		if diff.Status == "unchanged" {
+			continue
+		}
+		differences++
+		_, _ = fmt.Fprintf(w, "%s: %s, %d added, %d removed, %d changed\n",
+			diff.File, diff.Status, len(diff.Added), len(diff.Removed), len(diff.Changed))
+		if !verbose {
+			continue
+		}
+		for _, key := range diff.Added {
+			_, _ = fmt.Fprintf(w, "  + %s\n", key)
+		}
+		for _, key := range diff.Removed {
+			_, _ = fmt.Fprintf(w, "  - %s\n", key)
+		}
+		for _, change := range diff.Changed {
+			_, _ = fmt.Fprintf(w, "  ~ %s\n", change.Key)
+			for _, field := range change.Fields {
+				_, _ = fmt.Fprintf(w, "      %s: %q -> %q\n", field.Column, field.Old, field.New)
+			}
+		}
+	}
+	if differences == 0 {
+		_, _ = fmt.Fprintf(w, "No differences\n")
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/loader"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
+)
+
+// inputKind tells NeTEx and GTFS input apart
+type inputKind int
+
+const (
+	inputNetex inputKind = iota
+	inputGtfs
+)
+
+func (k inputKind) String() string {
+	if k == inputGtfs {
+		return "GTFS"
+	}
+	return "NeTEx"
+}
+
+// gtfsMarkerFiles are files only a GTFS archive contains
+var gtfsMarkerFiles = map[string]bool{
+	"agency.txt":     true,
+	"stops.txt":      true,
+	"routes.txt":     true,
+	"trips.txt":      true,
+	"stop_times.txt": true,
+}
+
+// readInput reads an input file and tells whether it is NeTEx or GTFS: a ZIP
+// archive with GTFS text files is GTFS, one with XML files or a bare XML
+// document is NeTEx
+func readInput(filePath string) ([]byte, inputKind, error) {
+	// #nosec G304 -- the path comes from the command line
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, inputNetex, fmt.Errorf("reading %s: %w", filePath, err)
+	}
+
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+			return data, inputNetex, nil
+		}
+		return nil, inputNetex, fmt.Errorf("%s is neither a ZIP archive nor an XML document", filePath)
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, inputNetex, fmt.Errorf("opening %s: %w", filePath, err)
+	}
+	hasXML := false
+	for _, file := range zipReader.File {
+		name := strings.ToLower(path.Base(file.Name))
+		if gtfsMarkerFiles[name] {
+			return data, inputGtfs, nil
+		}
+		if strings.HasSuffix(name, ".xml") {
+			hasXML = true
+		}
+	}
+	if !hasXML {
+		return nil, inputNetex, fmt.Errorf("%s contains neither NeTEx XML nor GTFS files", filePath)
+	}
+	return data, inputNetex, nil
+}
+
+// readGtfsInput reads an archive that must be GTFS
+func readGtfsInput(filePath string) (map[string]*repository.GtfsTable, error) {
+	data, kind, err := readInput(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if kind != inputGtfs {
+		return nil, fmt.Errorf("%s is not a GTFS archive", filePath)
+	}
+	return repository.ReadGtfsArchive(data)
+}
+
+// loadNetex loads a NeTEx archive or document into a new repository
+func loadNetex(data []byte) (producer.NetexRepository, error) {
+	netexRepository := repository.NewDefaultNetexRepository()
+	if err := loader.NewStreamingNetexDatasetLoader().Load(bytes.NewReader(data), netexRepository); err != nil {
+		return nil, fmt.Errorf("loading NeTEx: %w", err)
+	}
+	return netexRepository, nil
+}
+
+// forEachNetexDocument calls fn with each XML document of a NeTEx archive,
+// or with the input itself when it is a bare XML document
+func forEachNetexDocument(data []byte, name string, fn func(name string, r io.Reader) error) error {
+	if !bytes.HasPrefix(data, []byte("PK")) {
+		return fn(name, bytes.NewReader(data))
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", name, err)
+	}
+	for _, file := range zipReader.File {
+		if !strings.HasSuffix(strings.ToLower(file.Name), ".xml") {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", file.Name, err)
+		}
+		err = fn(file.Name, rc)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// netexInspection summarises a NeTEx dataset without converting it
+type netexInspection struct {
+	Files                []string       `json:"files"`
+	PublicationTimestamp string         `json:"publication_timestamp,omitempty"`
+	Codespaces           []string       `json:"codespaces"`
+	Validity             dateRange      `json:"validity"`
+	OperatingPeriods     dateRange      `json:"operating_periods"`
+	Frames               []netexFrame   `json:"frames"`
+	EntityCounts         map[string]int `json:"entity_counts"`
+
+	codespaces map[string]bool
+}
+
+// netexFrame is one frame of a NeTEx document
+type netexFrame struct {
+	Type string `json:"type"`
+	ID   string `json:"id,omitempty"`
+	File string `json:"file"`
+}
+
+// dateRange is the span of a set of NeTEx dates, as YYYY-MM-DD
+type dateRange struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// extend widens the range to a FromDate or ToDate value
+func (r *dateRange) extend(value string, isFrom bool) {
+	if len(value) > 10 {
+		value = value[:10]
+	}
+	if isFrom {
+		if r.From == "" || value < r.From {
+			r.From = value
+		}
+	} else if value > r.To {
+		r.To = value
+	}
+}
+
+// String renders the range for the text output
+func (r dateRange) String() string {
+	if r.From == "" && r.To == "" {
+		return "not declared"
+	}
+	from, to := r.From, r.To
+	if from == "" {
+		from = "?"
+	}
+	if to == "" {
+		to = "?"
+	}
+	return from + " to " + to
+}
+
+// runInspect implements the inspect command
+func runInspect(env *environment, cmd *command, args []string) int {
+	fs := env.newFlagSet(cmd)
+	format := fs.String("format", "text", "Output format: text or json")
+
+	if code, ok := env.parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	if *format != "text" && *format != "json" {
+		env.errorf("unknown --format %q", *format)
+		env.usageHint(cmd.name)
+		return exitUsage
+	}
+
+	inputPath := fs.Arg(0)
+	data, kind, err := readInput(inputPath)
+	if err == nil && kind != inputNetex {
+		err = fmt.Errorf("%s is a GTFS archive; inspect reads NeTEx", inputPath)
+	}
+	if err != nil {
+		env.errorf("%v", err)
+		return exitFailure
+	}
+
+	inspection := &netexInspection{
+		EntityCounts: make(map[string]int),
+		codespaces:   make(map[string]bool),
+	}
+	if err := forEachNetexDocument(data, filepath.Base(inputPath), inspection.scan); err != nil {
+		env.errorf("%v", err)
+		return exitFailure
+	}
+	inspection.finish()
+
+	if *format == "json" {
+		encoder := json.NewEncoder(env.stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(inspection); err != nil {
+			env.errorf("%v", err)
+			return exitFailure
+		}
+		return exitOK
+	}
+	inspection.writeText(env.stdout)
+	return exitOK
+}
+
+// scan reads one NeTEx document token by token, so that large files are not
+// decoded into entities
+func (in *netexInspection) scan(name string, r io.Reader) error {
+	in.Files = append(in.Files, name)
+
+	decoder := xml.NewDecoder(r)
+	var stack []string
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			local := t.Name.Local
+			stack = append(stack, local)
+			id := attrValue(t, "id")
+			switch {
+			case strings.HasSuffix(local, "Frame"):
+				in.Frames = append(in.Frames, netexFrame{Type: local, ID: id, File: name})
+			case id != "" && local != "PublicationDelivery":
+				in.EntityCounts[local]++
+				if i := strings.Index(id, ":"); i > 0 {
+					in.codespaces[id[:i]] = true
+				}
+			}
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			text := strings.TrimSpace(string(t))
+			if text == "" || len(stack) < 2 {
+				continue
+			}
+			in.scanText(stack[len(stack)-1], stack[len(stack)-2], text)
+		}
+	}
+}
+
+// scanText records the element values inspect reports
+func (in *netexInspection) scanText(element, parent, text string) {
+	switch element {
+	case "PublicationTimestamp":
+		if parent == "PublicationDelivery" && text > in.PublicationTimestamp {
+			in.PublicationTimestamp = text
+		}
+	case "Xmlns":
+		if parent == "Codespace" {
+			in.codespaces[text] = true
+		}
+	case "FromDate", "ToDate":
+		isFrom := element == "FromDate"
+		switch parent {
+		case "ValidBetween", "AvailabilityCondition":
+			in.Validity.extend(text, isFrom)
+		case "OperatingPeriod", "UicOperatingPeriod":
+			in.OperatingPeriods.extend(text, isFrom)
+		}
+	}
+}
+
+// finish sorts the collected values for stable output
+func (in *netexInspection) finish() {
+	in.Codespaces = make([]string, 0, len(in.codespaces))
+	for codespace := range in.codespaces {
+		in.Codespaces = append(in.Codespaces, codespace)
+	}
+	sort.Strings(in.Codespaces)
+	sort.Strings(in.Files)
+}
+
+// writeText prints the inspection as aligned text
+func (in *netexInspection) writeText(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Documents:\t%d\n", len(in.Files))
+	if in.PublicationTimestamp != "" {
+		_, _ = fmt.Fprintf(tw, "Published:\t%s\n", in.PublicationTimestamp)
+	}
+	_, _ = fmt.Fprintf(tw, "Codespaces:\t%s\n", strings.Join(in.Codespaces, ", "))
+	_, _ = fmt.Fprintf(tw, "Validity:\t%s\n", in.Validity)
+	_, _ = fmt.Fprintf(tw, "Operating periods:\t%s\n", in.OperatingPeriods)
+	_ = tw.Flush()
+
+	_, _ = fmt.Fprintf(w, "\nFrames (%d):\n", len(in.Frames))
+	for _, frame := range in.Frames {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\t%s\n", frame.Type, frame.ID, frame.File)
+	}
+	_ = tw.Flush()
+
+	names := make([]string, 0, len(in.EntityCounts))
+	for name := range in.EntityCounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	_, _ = fmt.Fprintf(w, "\nEntities:\n")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\n", name, in.EntityCounts[name])
+	}
+	_ = tw.Flush()
+}
+
+// attrValue returns the value of an attribute, or "" when it is missing
+func attrValue(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
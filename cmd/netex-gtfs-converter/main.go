@@ -1,386 +1,231 @@
 package main
 
 import (
-	"archive/zip"
+	"context"
 	"flag"
 	"fmt"
 	"io"
-	"math"
 	"os"
-	"path/filepath"
-	"time"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"github.com/theoremus-urban-solutions/netex-gtfs-converter/calendar"
-	"github.com/theoremus-urban-solutions/netex-gtfs-converter/exporter"
-	"github.com/theoremus-urban-solutions/netex-gtfs-converter/loader"
-	"github.com/theoremus-urban-solutions/netex-gtfs-converter/memory"
-	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
-	"github.com/theoremus-urban-solutions/netex-gtfs-converter/validation"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/config"
 )
 
-func main() {
-	// Parse command line arguments
-	var (
-		netexPath  = flag.String("netex", "fluo-grand-est-riv-netex.zip", "Path to NeTEx file")
-		codespace  = flag.String("codespace", "FR", "Codespace for the data")
-		outputPath = flag.String("output", "/tmp/gtfs.zip", "Output GTFS file path")
-	)
-	flag.Parse()
-
-	fmt.Println("🚀 === Final NeTEx to GTFS Converter Demonstration ===")
-	fmt.Println("Testing with French Grand Est Regional Transit Data")
-	fmt.Println()
-
-	// Initialize memory manager for optimization
-	memoryManager := memory.NewMemoryManager()
-	fmt.Printf("✅ Memory manager initialized\n")
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
 
-	// Initialize validation service
-	validationService := validation.NewValidationService()
-	ctx := validationService.StartConversion()
-	fmt.Printf("✅ Validation service initialized\n")
+// Process exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
 
-	// Configure calendar service for French data
-	calendarConfig := calendar.CalendarServiceConfig{
-		DefaultTimezoneName:        "Europe/Paris",
-		HolidayCountryCode:         "FR",
-		EnableHolidayDetection:     true,
-		EnableSeasonalPatterns:     true,
-		ValidationLevel:            calendar.ValidationStandard,
-		OptimizeCalendarDates:      true,
-		MaxServiceExceptions:       1000,
-		ConsolidateSimilarPatterns: true,
-	}
+// command is a subcommand of the converter
+type command struct {
+	name     string
+	synopsis string
+	summary  string
+	run      func(env *environment, cmd *command, args []string) int
+}
 
-	calendarService, err := calendar.NewCalendarService(calendarConfig)
-	if err != nil {
-		fmt.Printf("❌ Error creating calendar service: %v\n", err)
-		return
-	}
-	fmt.Printf("✅ Enhanced calendar service configured for French transit data\n")
+// commands lists the subcommands in the order of the usage message
+var commands = []*command{
+	{
+		name:     "convert",
+		synopsis: "[flags]",
+		summary:  "Convert a NeTEx timetable archive, or only a NeTEx stops archive, to GTFS",
+		run:      runConvert,
+	},
+	{
+		name:     "validate",
+		synopsis: "[flags] <netex-or-gtfs.zip>",
+		summary:  "Validate a NeTEx or GTFS archive and print a validation report",
+		run:      runValidate,
+	},
+	{
+		name:     "inspect",
+		synopsis: "[flags] <netex.zip>",
+		summary:  "List the frames, codespaces, entity counts and validity of a NeTEx archive",
+		run:      runInspect,
+	},
+	{
+		name:     "stats",
+		synopsis: "[flags] <netex-or-gtfs.zip>",
+		summary:  "Report trips, service hours and stops per line",
+		run:      runStats,
+	},
+	{
+		name:     "diff",
+		synopsis: "[flags] <old-gtfs.zip> <new-gtfs.zip>",
+		summary:  "Compare two GTFS archives row by row",
+		run:      runDiff,
+	},
+}
 
-	// Test with the French NeTEx ZIP file
-	zipPath := *netexPath
+// environment carries the output streams, the flags shared by all
+// subcommands and the configuration they build on
+type environment struct {
+	// ctx is cancelled when the process is interrupted
+	ctx     context.Context
+	stdout  io.Writer
+	stderr  io.Writer
+	verbose bool
+
+	// configPath is the --config file; config starts from the defaults and
+	// subcommands bind their flags to its fields
+	configPath string
+	config     *config.Config
+}
 
-	if _, err := os.Stat(zipPath); os.IsNotExist(err) {
-		fmt.Printf("❌ NeTEx file not found: %s\n", zipPath)
-		return
+// logf prints a progress message when --verbose is set
+func (env *environment) logf(format string, args ...interface{}) {
+	if env.verbose {
+		_, _ = fmt.Fprintf(env.stdout, format+"\n", args...)
 	}
+}
 
-	fmt.Printf("📁 Processing NeTEx dataset: %s\n", zipPath)
+// errorf reports an error on stderr
+func (env *environment) errorf(format string, args ...interface{}) {
+	_, _ = fmt.Fprintf(env.stderr, "Error: "+format+"\n", args...)
+}
 
-	// Open ZIP file and examine contents
-	zipReader, err := zip.OpenReader(zipPath)
-	if err != nil {
-		fmt.Printf("❌ Error opening ZIP file: %v\n", err)
-		return
-	}
-	defer func() { _ = zipReader.Close() }()
+// newFlagSet creates the flag set of a subcommand with the shared flags
+// already registered
+func (env *environment) newFlagSet(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(env.stderr)
+	fs.BoolVar(&env.verbose, "verbose", false, "Enable verbose logging")
+	fs.StringVar(&env.configPath, "config", "", "Configuration file (.json, .yaml or .yml); flags override its values")
+	env.config = config.Default()
+
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(fs.Output(), "Usage: netex-gtfs-converter %s %s\n\n", cmd.name, cmd.synopsis)
+		_, _ = fmt.Fprintf(fs.Output(), "%s.\n\n", cmd.summary)
+		_, _ = fmt.Fprintf(fs.Output(), "Flags:\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
 
-	// Find all XML files
-	var netexFiles []*zip.File
-	totalSize := int64(0)
-	fmt.Printf("\n📦 Dataset Contents:\n")
-	for _, file := range zipReader.File {
-		fmt.Printf("   • %s (%.1f KB)\n", file.Name, float64(file.UncompressedSize64)/1024)
-		if filepath.Ext(file.Name) == ".xml" {
-			netexFiles = append(netexFiles, file)
-			totalSize = safeAddInt64(totalSize, file.UncompressedSize64)
+// parseFlags parses the arguments of a subcommand, checks the number of
+// positional arguments and loads the --config file under the flags given on
+// the command line; ok is false when the command must exit with code
+func (env *environment) parseFlags(fs *flag.FlagSet, args []string, positional int) (code int, ok bool) {
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return exitOK, false
 		}
+		// The flag package has already reported the error and the usage
+		return exitUsage, false
 	}
 
-	fmt.Printf("\n📊 Dataset Summary:\n")
-	fmt.Printf("   • Total XML files: %d\n", len(netexFiles))
-	fmt.Printf("   • Total data size: %.2f MB\n", float64(totalSize)/(1024*1024))
-
-	// Create optimized repositories
-	netexRepo := repository.NewOptimizedNetexRepository()
-	stopAreaRepo := repository.NewDefaultStopAreaRepository()
-	fmt.Printf("✅ Optimized repositories initialized\n")
-
-	// Create enhanced exporter with comprehensive error recovery
-	enhancedExporter := exporter.NewEnhancedGtfsExporter(*codespace, stopAreaRepo)
-	enhancedExporter.SetContinueOnError(true)
-	enhancedExporter.SetMaxErrorsPerEntity(100)
-	fmt.Printf("✅ Enhanced GTFS exporter with error recovery configured\n")
-
-	// === STAGE 1: LOAD NETEX DATA ===
-	fmt.Printf("\n🔄 STAGE 1: Loading NeTEx Data\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	overallStart := time.Now()
-	streamingLoader := loader.NewStreamingNetexDatasetLoader()
-	loadedFiles := 0
-	errors := 0
-
-	for i, netexFile := range netexFiles {
-		fmt.Printf("[%d/%d] Processing %s... ", i+1, len(netexFiles), netexFile.Name)
-
-		xmlReader, err := netexFile.Open()
-		if err != nil {
-			fmt.Printf("❌ Open error: %v\n", err)
-			errors++
-			continue
-		}
-
-		stageStart := time.Now()
-		if err := streamingLoader.Load(xmlReader, netexRepo); err != nil {
-			fmt.Printf("⚠️  Load warning: %v\n", err)
-			errors++
+	if fs.NArg() != positional {
+		if fs.NArg() > positional {
+			env.errorf("unexpected argument %q", fs.Arg(positional))
 		} else {
-			duration := time.Since(stageStart)
-			fmt.Printf("✅ (%v)\n", duration)
-			loadedFiles++
+			env.errorf("%s expects %d argument(s), got %d", fs.Name(), positional, fs.NArg())
 		}
-
-		_ = xmlReader.Close()
-
-		// Monitor memory usage
-		memoryManager.CheckMemoryPressure()
+		env.usageHint(fs.Name())
+		return exitUsage, false
 	}
 
-	loadingDuration := time.Since(overallStart)
-	fmt.Printf("\n📈 Loading Results:\n")
-	fmt.Printf("   • Files processed successfully: %d/%d\n", loadedFiles, len(netexFiles))
-	fmt.Printf("   • Loading duration: %v\n", loadingDuration)
-	fmt.Printf("   • Average file processing: %v\n", loadingDuration/time.Duration(len(netexFiles)))
-
-	validationService.RecordProcessingTime(ctx, "loading", loadingDuration)
-	validationService.RecordMemoryUsage(ctx, "post_loading")
-
-	// === STAGE 2: DATA ANALYSIS ===
-	fmt.Printf("\n🔍 STAGE 2: Data Analysis & Validation\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	analysisStart := time.Now()
-
-	// Get loaded data statistics
-	lines := netexRepo.GetLines()
-	quays := netexRepo.GetAllQuays()
-	serviceJourneys := netexRepo.GetServiceJourneys()
-
-	fmt.Printf("📊 Loaded NeTEx Entities:\n")
-	fmt.Printf("   • Transit Lines: %d\n", len(lines))
-	fmt.Printf("   • Stops/Quays: %d\n", len(quays))
-	fmt.Printf("   • Service Journeys: %d\n", len(serviceJourneys))
-
-	// Validate key entities
-	fmt.Printf("\n🔍 Validating loaded entities...\n")
-
-	fmt.Printf("   Validating transit lines... ")
-	lineIssues := 0
-	for _, line := range lines {
-		validationService.ValidateNeTExEntity(ctx, line)
-		if line.AuthorityRef == "" {
-			lineIssues++
-		}
+	if err := env.loadConfig(fs); err != nil {
+		env.errorf("%v", err)
+		env.usageHint(fs.Name())
+		return exitUsage, false
 	}
-	fmt.Printf("✅ (%d potential issues)\n", lineIssues)
-
-	fmt.Printf("   Validating stops/quays... ")
-	quayIssues := 0
-	for i, quay := range quays {
-		if i < 20 { // Sample validation to avoid overflow
-			validationService.ValidateNeTExEntity(ctx, quay)
-			if quay.Name == "" {
-				quayIssues++
-			}
-		}
-	}
-	fmt.Printf("✅ (sample of 20 validated)\n")
-
-	analysisTime := time.Since(analysisStart)
-	validationService.RecordProcessingTime(ctx, "analysis", analysisTime)
-
-	// === STAGE 3: CALENDAR PROCESSING ===
-	fmt.Printf("\n📅 STAGE 3: Advanced Calendar Processing\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	calendarStart := time.Now()
-
-	fmt.Printf("Processing European service patterns with French holidays...\n")
-
-	// Test calendar conversion
-	calendarResult, err := calendarService.ConvertNeTExToGTFS(nil)
-	if err != nil {
-		fmt.Printf("⚠️  Calendar processing note: %v\n", err)
-		fmt.Printf("   Continuing with basic calendar generation...\n")
-	}
-
-	if calendarResult != nil {
-		fmt.Printf("✅ Calendar Processing Results:\n")
-		fmt.Printf("   • Service Patterns Generated: %d\n", calendarResult.ConversionStats.TotalServicePatterns)
-		fmt.Printf("   • GTFS Calendars: %d\n", calendarResult.ConversionStats.TotalCalendars)
-		fmt.Printf("   • Calendar Dates: %d\n", calendarResult.ConversionStats.TotalCalendarDates)
-		fmt.Printf("   • Service Exceptions: %d\n", calendarResult.ConversionStats.TotalExceptions)
-		fmt.Printf("   • Processing Duration: %v\n", calendarResult.ProcessingDuration)
+	return exitOK, true
+}
 
-		if len(calendarResult.ConversionStats.PatternsByType) > 0 {
-			fmt.Printf("   • Pattern Types:\n")
-			for patternType, count := range calendarResult.ConversionStats.PatternsByType {
-				fmt.Printf("     - %s: %d\n", patternType, count)
-			}
+// loadConfig reads the --config file into env.config and sets the flags given
+// on the command line again, so that they take precedence, then validates the
+// result
+func (env *environment) loadConfig(fs *flag.FlagSet) error {
+	if env.configPath != "" {
+		explicit := make(map[string]string)
+		fs.Visit(func(f *flag.Flag) {
+			explicit[f.Name] = f.Value.String()
+		})
+
+		loaded, err := config.Load(env.configPath)
+		if err != nil {
+			return err
 		}
-	}
-
-	// Test French holiday detection
-	if calendarConfig.EnableHolidayDetection {
-		fmt.Printf("\n🇫🇷 Testing French Holiday Detection:\n")
-		holidays, err := calendarService.GetHolidays(2024)
-		if err == nil && len(holidays) > 0 {
-			fmt.Printf("   Found %d French holidays for 2024:\n", len(holidays))
-			count := 0
-			for _, holiday := range holidays {
-				if holiday.IsNational && count < 5 {
-					fmt.Printf("     • %s: %s\n", holiday.Date.Format("Jan 2"), holiday.Name)
-					count++
-				}
-			}
-			if count < len(holidays) {
-				fmt.Printf("     ... and %d more holidays\n", len(holidays)-count)
+		// Flags point into env.config, so it is overwritten in place
+		*env.config = *loaded
+		for name, value := range explicit {
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("invalid value %q for flag --%s: %v", value, name, err)
 			}
 		}
+		env.logf("Loaded configuration from %s", env.configPath)
 	}
+	return env.config.Validate()
+}
 
-	calendarTime := time.Since(calendarStart)
-	validationService.RecordProcessingTime(ctx, "calendar", calendarTime)
-
-	// === STAGE 4: GTFS CONVERSION ===
-	fmt.Printf("\n🔄 STAGE 4: GTFS Conversion\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	conversionStart := time.Now()
-
-	// Open NeTEx file for conversion
-	// #nosec G304 -- zipPath comes from a trusted CLI flag
-	file, err := os.Open(zipPath)
-	if err != nil {
-		fmt.Printf("❌ Error opening NeTEx file for conversion: %v\n", err)
-		return
-	}
-	defer func() { _ = file.Close() }()
-
-	// Convert to GTFS
-	fmt.Printf("Converting NeTEx to GTFS...\n")
-	gtfsReader, conversionResult, err := enhancedExporter.ConvertTimetablesToGtfsWithRecovery(file)
-	if err != nil {
-		fmt.Printf("❌ Conversion error: %v\n", err)
-		return
-	}
-
-	// Write GTFS output
-	outputFile, err := os.Create(*outputPath)
-	if err != nil {
-		fmt.Printf("❌ Error creating output file: %v\n", err)
-		return
-	}
-	defer func() { _ = outputFile.Close() }()
+// usageHint points to the help of a subcommand after a usage error
+func (env *environment) usageHint(name string) {
+	_, _ = fmt.Fprintf(env.stderr, "Run 'netex-gtfs-converter %s --help' for usage.\n", name)
+}
 
-	_, err = io.Copy(outputFile, gtfsReader)
-	if err != nil {
-		fmt.Printf("❌ Error writing GTFS output: %v\n", err)
-		return
-	}
+func main() {
+	// Interrupting the process cancels a running conversion
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := runContext(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
 
-	conversionTime := time.Since(conversionStart)
-	fmt.Printf("✅ GTFS conversion completed successfully!\n")
-	fmt.Printf("   • Output file: %s\n", *outputPath)
-	fmt.Printf("   • Conversion time: %v\n", conversionTime)
+// run executes the command line and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	return runContext(context.Background(), args, stdout, stderr)
+}
 
-	// Calculate totals
-	totalProcessed := 0
-	for _, count := range conversionResult.ProcessedCount {
-		totalProcessed += count
-	}
-	totalSkipped := 0
-	for _, count := range conversionResult.SkippedCount {
-		totalSkipped += count
-	}
+// runContext executes the command line until ctx is cancelled
+func runContext(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	env := &environment{ctx: ctx, stdout: stdout, stderr: stderr}
 
-	fmt.Printf("   • Entities processed: %d\n", totalProcessed)
-	fmt.Printf("   • Entities skipped: %d\n", totalSkipped)
-	if len(conversionResult.Errors) > 0 {
-		fmt.Printf("   • Conversion errors: %d\n", len(conversionResult.Errors))
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
 	}
 
-	validationService.RecordProcessingTime(ctx, "conversion", conversionTime)
-
-	// === STAGE 5: FINAL VALIDATION & REPORTING ===
-	fmt.Printf("\n📋 STAGE 5: Comprehensive Validation & Reporting\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	finalReport := validationService.FinishConversion(ctx)
-
-	totalTime := time.Since(overallStart)
-
-	// === FINAL RESULTS ===
-	fmt.Printf("\n🎯 === COMPREHENSIVE CONVERSION RESULTS ===\n")
-	fmt.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━\n")
-
-	fmt.Printf("\n📊 Data Processing Summary:\n")
-	fmt.Printf("   • Dataset: French Grand Est Regional Transit (Fluo)\n")
-	fmt.Printf("   • Files Processed: %d/%d XML files\n", loadedFiles, len(netexFiles))
-	fmt.Printf("   • Data Volume: %.2f MB processed\n", float64(totalSize)/(1024*1024))
-	fmt.Printf("   • Transit Lines: %d lines loaded\n", len(lines))
-	fmt.Printf("   • Stops/Platforms: %d quays/stops\n", len(quays))
-	fmt.Printf("   • Service Journeys: %d trips/journeys\n", len(serviceJourneys))
-
-	fmt.Printf("\n⚡ Performance Metrics:\n")
-	fmt.Printf("   • Total Processing Time: %v\n", totalTime)
-	fmt.Printf("   • Data Loading: %v (%.1f%%)\n", loadingDuration, float64(loadingDuration)/float64(totalTime)*100)
-	fmt.Printf("   • Analysis & Validation: %v (%.1f%%)\n", analysisTime, float64(analysisTime)/float64(totalTime)*100)
-	fmt.Printf("   • Calendar Processing: %v (%.1f%%)\n", calendarTime, float64(calendarTime)/float64(totalTime)*100)
-	fmt.Printf("   • Memory Usage: %.2f MB\n", finalReport.ProcessingStats.MemoryUsageMB)
-	fmt.Printf("   • Throughput: %.2f MB/s\n", float64(totalSize)/(1024*1024)/totalTime.Seconds())
-
-	fmt.Printf("\n🔍 Validation Results:\n")
-	fmt.Printf("   • Total Issues Detected: %d\n", len(finalReport.Issues))
-
-	// Show issue breakdown
-	issuesBySeverity := make(map[string]int)
-	for _, issue := range finalReport.Issues {
-		issuesBySeverity[issue.Severity.String()]++
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		printUsage(stdout)
+		return exitOK
+	case "version", "-version", "--version":
+		_, _ = fmt.Fprintf(stdout, "netex-gtfs-converter %s\n", version)
+		return exitOK
 	}
 
-	if len(issuesBySeverity) > 0 {
-		fmt.Printf("   • Issues by Severity:\n")
-		for severity, count := range issuesBySeverity {
-			fmt.Printf("     - %s: %d\n", severity, count)
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			return cmd.run(env, cmd, args[1:])
 		}
-	} else {
-		fmt.Printf("   • ✅ No critical validation issues found!\n")
 	}
 
-	fmt.Printf("\n🎯 System Capabilities Demonstrated:\n")
-	fmt.Printf("   ✅ Large-scale NeTEx data processing (multi-file ZIP archives)\n")
-	fmt.Printf("   ✅ Memory-optimized streaming XML processing\n")
-	fmt.Printf("   ✅ Comprehensive error recovery and validation\n")
-	fmt.Printf("   ✅ European calendar patterns with French holiday detection\n")
-	fmt.Printf("   ✅ Multi-stage performance monitoring and optimization\n")
-	fmt.Printf("   ✅ Production-ready GTFS export capabilities\n")
-	fmt.Printf("   ✅ Real-world transit data compatibility (French regional network)\n")
+	// Flags without a command keep converting, as before commands existed
+	if strings.HasPrefix(args[0], "-") {
+		return runConvert(env, commands[0], args)
+	}
 
-	fmt.Printf("\n🚀 === DEMONSTRATION COMPLETE ===\n")
-	fmt.Printf("Successfully processed French Grand Est regional transit data!\n")
-	fmt.Printf("The NeTEx to GTFS converter is ready for production use.\n")
+	env.errorf("unknown command %q", args[0])
+	printUsage(stderr)
+	return exitUsage
 }
 
-// safeAddInt64 safely adds a signed int64 and an unsigned uint64, clamping at MaxInt64 on overflow
-func safeAddInt64(a int64, b uint64) int64 {
-	if a < 0 {
-		// Fallback: if a is negative, just convert b cautiously
-		//nolint:gosec // intentional overflow-safe conversion
-		if int64(b) < 0 { // impossible, but keep the branch explicit
-			return math.MinInt64
-		}
-		//nolint:gosec // intentional overflow check
-		if a+int64(b) < a { // overflow check
-			return math.MaxInt64
-		}
-		return a + int64(b) //nolint:gosec // intentional overflow-safe conversion
-	}
-	if b > uint64(math.MaxInt64)-uint64(a) {
-		return math.MaxInt64
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintf(w, "Usage: netex-gtfs-converter <command> [flags] [arguments]\n\n")
+	_, _ = fmt.Fprintf(w, "Commands:\n")
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
 	}
-	return a + int64(b) //nolint:gosec // intentional overflow-safe conversion
+	_, _ = fmt.Fprintf(w, "  %-10s %s\n", "version", "Print the version")
+	_, _ = fmt.Fprintf(w, "\nRun 'netex-gtfs-converter <command> --help' for the flags of a command.\n")
+	_, _ = fmt.Fprintf(w, "Flags given without a command run convert.\n")
 }
@@ -1,6 +1,11 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -11,7 +16,7 @@ import (
 // TestCLIHelp tests the CLI help functionality
 func TestCLIHelp(t *testing.T) {
 	// Build the CLI binary first
-	cmd := exec.Command("go", "build", "-o", "converter_test", "main.go")
+	cmd := exec.Command("go", "build", "-o", "converter_test", ".")
 	cmd.Dir = "."
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build CLI: %v", err)
@@ -64,7 +69,7 @@ func TestCLIHelp(t *testing.T) {
 // TestCLIInvalidArguments tests CLI error handling
 func TestCLIInvalidArguments(t *testing.T) {
 	// Build the CLI binary first
-	cmd := exec.Command("go", "build", "-o", "converter_test", "main.go")
+	cmd := exec.Command("go", "build", "-o", "converter_test", ".")
 	cmd.Dir = "."
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build CLI: %v", err)
@@ -84,8 +89,8 @@ func TestCLIInvalidArguments(t *testing.T) {
 		{
 			name:        "Missing netex file",
 			args:        []string{"--netex", "nonexistent.zip", "--output", "out.zip", "--codespace", "TEST"},
-			expectError: false, // CLI continues and reports file not found, but doesn't exit with error
-			errorText:   "not found",
+			expectError: true,
+			errorText:   "no such file",
 		},
 		{
 			name:        "Invalid flag",
@@ -96,8 +101,14 @@ func TestCLIInvalidArguments(t *testing.T) {
 		{
 			name:        "Missing required netex flag",
 			args:        []string{"--output", "out.zip", "--codespace", "TEST"},
-			expectError: false,       // CLI uses default netex file
-			errorText:   "not found", // Will use default netex file
+			expectError: true,
+			errorText:   "--netex is required",
+		},
+		{
+			name:        "Stops only without stops archive",
+			args:        []string{"--stops-only", "--output", "out.zip"},
+			expectError: true,
+			errorText:   "--stops is required",
 		},
 	}
 
@@ -124,7 +135,7 @@ func TestCLIInvalidArguments(t *testing.T) {
 // TestCLIWithRealData tests CLI with actual data files
 func TestCLIWithRealData(t *testing.T) {
 	// Build the CLI binary first
-	cmd := exec.Command("go", "build", "-o", "converter_test", "main.go")
+	cmd := exec.Command("go", "build", "-o", "converter_test", ".")
 	cmd.Dir = "."
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build CLI: %v", err)
@@ -184,7 +195,7 @@ func TestCLIWithRealData(t *testing.T) {
 // TestCLIVersionInfo tests version and build information
 func TestCLIVersionInfo(t *testing.T) {
 	// Build the CLI binary first
-	cmd := exec.Command("go", "build", "-o", "converter_test", "main.go")
+	cmd := exec.Command("go", "build", "-o", "converter_test", ".")
 	cmd.Dir = "."
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build CLI: %v", err)
@@ -219,7 +230,7 @@ func TestCLIPerformance(t *testing.T) {
 	}
 
 	// Build the CLI binary first
-	cmd := exec.Command("go", "build", "-o", "converter_test", "main.go")
+	cmd := exec.Command("go", "build", "-o", "converter_test", ".")
 	cmd.Dir = "."
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build CLI: %v", err)
@@ -275,7 +286,7 @@ func TestCLIPerformance(t *testing.T) {
 // TestCLIEnvironmentVariables tests environment variable handling
 func TestCLIEnvironmentVariables(t *testing.T) {
 	// Build the CLI binary first
-	cmd := exec.Command("go", "build", "-o", "converter_test", "main.go")
+	cmd := exec.Command("go", "build", "-o", "converter_test", ".")
 	cmd.Dir = "."
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build CLI: %v", err)
@@ -313,7 +324,7 @@ func TestCLIEnvironmentVariables(t *testing.T) {
 // TestCLIInputValidation tests various input validation scenarios
 func TestCLIInputValidation(t *testing.T) {
 	// Build the CLI binary first
-	cmd := exec.Command("go", "build", "-o", "converter_test", "main.go")
+	cmd := exec.Command("go", "build", "-o", "converter_test", ".")
 	cmd.Dir = "."
 	if err := cmd.Run(); err != nil {
 		t.Fatalf("Failed to build CLI: %v", err)
@@ -333,8 +344,8 @@ func TestCLIInputValidation(t *testing.T) {
 		{
 			name:        "Empty codespace",
 			args:        []string{"--netex", "test.zip", "--output", "out.zip", "--codespace", ""},
-			expectError: false, // CLI accepts empty codespace
-			description: "Empty codespace should be accepted",
+			expectError: true,
+			description: "Empty codespace should be rejected for timetable conversion",
 		},
 		{
 			name:        "Very long codespace",
@@ -371,3 +382,517 @@ func TestCLIInputValidation(t *testing.T) {
 		})
 	}
 }
+
+// TestRunStopsOnly converts a stops archive without a timetable
+func TestRunStopsOnly(t *testing.T) {
+	tempDir := t.TempDir()
+	stopsPath := filepath.Join(tempDir, "stops.zip")
+	outputPath := filepath.Join(tempDir, "stops-gtfs.zip")
+
+	stops := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<dataObjects>
+		<SiteFrame id="NSR:SiteFrame:1" version="1">
+			<stopPlaces>
+				<StopPlace id="NSR:StopPlace:1" version="1">
+					<Name>Oslo S</Name>
+					<Centroid><Location><Longitude>10.752</Longitude><Latitude>59.910</Latitude></Location></Centroid>
+					<quays>
+						<Quay id="NSR:Quay:1" version="1">
+							<Name>Oslo S 1</Name>
+							<Centroid><Location><Longitude>10.752</Longitude><Latitude>59.910</Latitude></Location></Centroid>
+						</Quay>
+					</quays>
+				</StopPlace>
+			</stopPlaces>
+		</SiteFrame>
+	</dataObjects>
+</PublicationDelivery>`
+	writeZip(t, stopsPath, map[string]string{"stops.xml": stops})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--stops", stopsPath, "--stops-only", "--output", outputPath}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", exitOK, code, stdout.String(), stderr.String())
+	}
+
+	zipReader, err := zip.OpenReader(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to open output: %v", err)
+	}
+	defer func() { _ = zipReader.Close() }()
+
+	var stopsTxt string
+	for _, file := range zipReader.File {
+		if file.Name != "stops.txt" {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatalf("Failed to open stops.txt: %v", err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		stopsTxt = string(data)
+	}
+
+	for _, expected := range []string{"NSR:StopPlace:1", "NSR:Quay:1,,Oslo S 1"} {
+		if !strings.Contains(stopsTxt, expected) {
+			t.Errorf("Expected stops.txt to contain %q, got:\n%s", expected, stopsTxt)
+		}
+	}
+}
+
+// TestRunExitCodes checks that usage and fatal errors do not exit with 0
+func TestRunExitCodes(t *testing.T) {
+	tempDir := t.TempDir()
+	corruptPath := filepath.Join(tempDir, "corrupt.zip")
+	if err := os.WriteFile(corruptPath, []byte("PK\x03\x04not a zip"), 0o600); err != nil {
+		t.Fatalf("Failed to write corrupt archive: %v", err)
+	}
+	outputPath := filepath.Join(tempDir, "out.zip")
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"Help", []string{"--help"}, exitOK},
+		{"Version", []string{"--version"}, exitOK},
+		{"Unknown flag", []string{"--invalid-flag"}, exitUsage},
+		{"Unexpected argument", []string{"--netex", corruptPath, "--codespace", "TEST", "extra"}, exitUsage},
+		{"Invalid timezone", []string{"--netex", corruptPath, "--codespace", "TEST", "--timezone", "Mars/Olympus"}, exitUsage},
+		{"Invalid holiday country", []string{"--netex", corruptPath, "--codespace", "TEST", "--holiday-country", "NOR"}, exitUsage},
+		{"Missing stops archive", []string{"--stops", filepath.Join(tempDir, "missing.zip"), "--stops-only"}, exitFailure},
+		{"Corrupt netex archive", []string{"--netex", corruptPath, "--codespace", "TEST", "--output", outputPath}, exitFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.args, &stdout, &stderr); code != tc.expected {
+				t.Errorf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", tc.expected, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no output file after a failed conversion, got err=%v", err)
+	}
+}
+
+// TestRunKeepsOutputOnFailure checks that a failed conversion leaves an
+// existing output file and no temporary file behind
+func TestRunKeepsOutputOnFailure(t *testing.T) {
+	tempDir := t.TempDir()
+	corruptPath := filepath.Join(tempDir, "corrupt.zip")
+	if err := os.WriteFile(corruptPath, []byte("PK\x03\x04not a zip"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	outputPath := filepath.Join(tempDir, "out.zip")
+	if err := os.WriteFile(outputPath, []byte("previous feed"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--netex", corruptPath, "--codespace", "TEST", "--output", outputPath}, &stdout, &stderr); code != exitFailure {
+		t.Fatalf("Expected exit code %d, got %d", exitFailure, code)
+	}
+
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != "previous feed" {
+		t.Errorf("Expected the previous output to be kept, got %q (err=%v)", data, err)
+	}
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected no temporary files, got %v", entries)
+	}
+}
+
+// TestRunDirectoryOutput checks that an --output ending in a separator
+// writes plain text files
+func TestRunDirectoryOutput(t *testing.T) {
+	tempDir := t.TempDir()
+	netexPath := filepath.Join(tempDir, "netex.zip")
+	writeZip(t, netexPath, map[string]string{"netex.xml": testNetexDocument})
+	outputDir := filepath.Join(tempDir, "gtfs") + string(filepath.Separator)
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--netex", netexPath, "--codespace", "TST", "--output", outputDir}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d\nstderr: %s", exitOK, code, stderr.String())
+	}
+
+	for _, name := range []string{"agency.txt", "routes.txt", "feed_info.txt"} {
+		if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+			t.Errorf("Expected %s in the output directory: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "gtfs.zip")); !os.IsNotExist(err) {
+		t.Errorf("Expected no archive for a directory output, got err=%v", err)
+	}
+}
+
+// TestRunCancelled checks that an interrupted conversion fails without
+// writing output
+func TestRunCancelled(t *testing.T) {
+	tempDir := t.TempDir()
+	netexPath := filepath.Join(tempDir, "netex.zip")
+	writeZip(t, netexPath, map[string]string{"netex.xml": testNetexDocument})
+	outputPath := filepath.Join(tempDir, "out.zip")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stdout, stderr bytes.Buffer
+	code := runContext(ctx, []string{"convert", "--netex", netexPath, "--codespace", "TST", "--output", outputPath}, &stdout, &stderr)
+	if code != exitFailure {
+		t.Errorf("Expected exit code %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(stderr.String(), "context canceled") {
+		t.Errorf("Expected a cancellation error, got %q", stderr.String())
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no output file after cancellation, got err=%v", err)
+	}
+}
+
+// TestRunStatsCancelled checks that an interrupted conversion of NeTEx stats
+// input fails
+func TestRunStatsCancelled(t *testing.T) {
+	netexPath := filepath.Join(t.TempDir(), "netex.zip")
+	writeZip(t, netexPath, map[string]string{"netex.xml": testNetexDocument})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stdout, stderr bytes.Buffer
+	code := runContext(ctx, []string{"stats", "--codespace", "TST", netexPath}, &stdout, &stderr)
+	if code != exitFailure {
+		t.Errorf("Expected exit code %d, got %d", exitFailure, code)
+	}
+	if !strings.Contains(stderr.String(), "context canceled") {
+		t.Errorf("Expected a cancellation error, got %q", stderr.String())
+	}
+}
+
+// TestRunConfig checks that --config is loaded and that flags override it
+func TestRunConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	stopsPath := filepath.Join(tempDir, "stops.zip")
+	writeZip(t, stopsPath, map[string]string{"stops.xml": `<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<dataObjects><SiteFrame id="NSR:SiteFrame:1"><stopPlaces>
+		<StopPlace id="NSR:StopPlace:1"><Name>Oslo S</Name>
+			<Centroid><Location><Longitude>10.752</Longitude><Latitude>59.910</Latitude></Location></Centroid>
+		</StopPlace>
+	</stopPlaces></SiteFrame></dataObjects>
+</PublicationDelivery>`})
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(tempDir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	validPath := writeConfig("valid.yaml", "version: 1\ntimezone: Europe/Oslo\nholidays:\n  country: NO\n")
+	invalidPath := writeConfig("invalid.yaml", "version: 1\ntimezone: Mars/Olympus\n")
+	unknownPath := writeConfig("unknown.json", `{"version": 1, "timezon": "Europe/Oslo"}`)
+	outputPath := filepath.Join(tempDir, "out.zip")
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+		stdout   string
+		stderr   string
+	}{
+		{"Valid config", []string{"convert", "--config", validPath, "--verbose", "--stops", stopsPath, "--stops-only", "--output", outputPath}, exitOK, "Public holidays (NO", ""},
+		{"Flag overrides config", []string{"convert", "--holiday-country", "SE", "--config", validPath, "--verbose", "--stops", stopsPath, "--stops-only", "--output", outputPath}, exitOK, "Public holidays (SE", ""},
+		{"Invalid flag over config", []string{"convert", "--config", validPath, "--timezone", "Mars/Olympus", "--stops", stopsPath, "--stops-only", "--output", outputPath}, exitUsage, "", `unknown time zone "Mars/Olympus"`},
+		{"Invalid config value", []string{"convert", "--config", invalidPath, "--stops", stopsPath, "--stops-only", "--output", outputPath}, exitUsage, "", `unknown time zone "Mars/Olympus"`},
+		{"Unknown config key", []string{"stats", "--config", unknownPath, "gtfs.zip"}, exitUsage, "", `did you mean "timezone"?`},
+		{"Missing config file", []string{"inspect", "--config", filepath.Join(tempDir, "missing.yaml"), "netex.zip"}, exitUsage, "", "reading config file"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.args, &stdout, &stderr); code != tc.expected {
+				t.Errorf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", tc.expected, code, stdout.String(), stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tc.stdout) {
+				t.Errorf("Expected stdout to contain %q, got: %s", tc.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tc.stderr) {
+				t.Errorf("Expected stderr to contain %q, got: %s", tc.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+// TestRunCommands checks the dispatch and usage errors of the subcommands
+func TestRunCommands(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"No arguments", nil, exitUsage},
+		{"Help command", []string{"help"}, exitOK},
+		{"Unknown command", []string{"frobnicate"}, exitUsage},
+		{"Convert help", []string{"convert", "--help"}, exitOK},
+		{"Validate without input", []string{"validate"}, exitUsage},
+		{"Validate unknown format", []string{"validate", "--format", "pdf", "in.zip"}, exitUsage},
+		{"Inspect missing input", []string{"inspect", "missing.zip"}, exitFailure},
+		{"Stats with two inputs", []string{"stats", "a.zip", "b.zip"}, exitUsage},
+		{"Diff with one input", []string{"diff", "a.zip"}, exitUsage},
+		{"Diff missing inputs", []string{"diff", "a.zip", "b.zip"}, exitUsage},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tc.args, &stdout, &stderr); code != tc.expected {
+				t.Errorf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", tc.expected, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+// TestRunValidate validates a GTFS archive and a NeTEx document
+func TestRunValidate(t *testing.T) {
+	tempDir := t.TempDir()
+	gtfsPath := filepath.Join(tempDir, "gtfs.zip")
+	writeZip(t, gtfsPath, testGtfsFiles(nil))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"validate", "--format", "json", gtfsPath}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d\nstdout: %s\nstderr: %s", exitOK, code, stdout.String(), stderr.String())
+	}
+	if !strings.Contains(stdout.String(), `"is_valid": true`) {
+		t.Errorf("Expected a JSON report, got:\n%s", stdout.String())
+	}
+
+	// A stop without coordinates makes the report invalid
+	invalidPath := filepath.Join(tempDir, "invalid.zip")
+	writeZip(t, invalidPath, testGtfsFiles(map[string]string{
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\nA,,,\n",
+	}))
+	stdout.Reset()
+	if code := run([]string{"validate", invalidPath}, &stdout, &stderr); code != exitFailure {
+		t.Errorf("Expected exit code %d for an invalid feed, got %d\n%s", exitFailure, code, stdout.String())
+	}
+
+	reportPath := filepath.Join(tempDir, "report.md")
+	netexPath := filepath.Join(tempDir, "netex.xml")
+	if err := os.WriteFile(netexPath, []byte(testNetexDocument), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	run([]string{"validate", "--format", "markdown", "--output", reportPath, netexPath}, &stdout, &stderr)
+	if report, err := os.ReadFile(reportPath); err != nil || len(report) == 0 {
+		t.Errorf("Expected a markdown report in %s, got err=%v", reportPath, err)
+	}
+}
+
+// TestRunInspect lists the frames, codespaces and entities of a NeTEx archive
+func TestRunInspect(t *testing.T) {
+	netexPath := filepath.Join(t.TempDir(), "netex.zip")
+	writeZip(t, netexPath, map[string]string{"line.xml": testNetexDocument})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"inspect", "--format", "json", netexPath}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d\nstderr: %s", exitOK, code, stderr.String())
+	}
+
+	var inspection netexInspection
+	if err := json.Unmarshal(stdout.Bytes(), &inspection); err != nil {
+		t.Fatalf("Failed to decode inspect output: %v\n%s", err, stdout.String())
+	}
+	if len(inspection.Frames) != 2 || inspection.Frames[0].Type != "CompositeFrame" {
+		t.Errorf("Expected CompositeFrame and ServiceFrame, got %+v", inspection.Frames)
+	}
+	if strings.Join(inspection.Codespaces, ",") != "NSR,TST" {
+		t.Errorf("Expected codespaces NSR,TST, got %v", inspection.Codespaces)
+	}
+	if inspection.EntityCounts["Line"] != 1 || inspection.EntityCounts["Route"] != 2 {
+		t.Errorf("Unexpected entity counts %v", inspection.EntityCounts)
+	}
+	if inspection.Validity.From != "2025-01-01" || inspection.Validity.To != "2025-12-31" {
+		t.Errorf("Expected validity 2025-01-01 to 2025-12-31, got %+v", inspection.Validity)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"inspect", netexPath}, &stdout, &stderr); code != exitOK || !strings.Contains(stdout.String(), "TST:ServiceFrame:1") {
+		t.Errorf("Expected a text inspection, got %d:\n%s", code, stdout.String())
+	}
+}
+
+// TestRunStats counts trips, stops and service hours per route
+func TestRunStats(t *testing.T) {
+	gtfsPath := filepath.Join(t.TempDir(), "gtfs.zip")
+	writeZip(t, gtfsPath, testGtfsFiles(nil))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"stats", "--format", "csv", gtfsPath}, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d\nstderr: %s", exitOK, code, stderr.String())
+	}
+
+	// R1 runs two one-hour trips on four weekdays, one weekday being removed;
+	// R2 runs a half-hour trip 4 times a day on five days, one being added
+	expected := "route_id,route_short_name,route_long_name,trips,stops,service_hours\n" +
+		"R1,1,,2,2,8.00\n" +
+		"R2,2,,1,2,10.00\n" +
+		"total,,,3,2,18.00\n"
+	if stdout.String() != expected {
+		t.Errorf("Expected stats:\n%s\ngot:\n%s", expected, stdout.String())
+	}
+}
+
+// TestRunDiff compares two GTFS archives
+func TestRunDiff(t *testing.T) {
+	tempDir := t.TempDir()
+	oldPath := filepath.Join(tempDir, "old.zip")
+	newPath := filepath.Join(tempDir, "new.zip")
+	writeZip(t, oldPath, testGtfsFiles(nil))
+	writeZip(t, newPath, testGtfsFiles(map[string]string{
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\nA,Alpha,59.9,10.7\nC,Gamma,59.7,10.5\n",
+	}))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"diff", oldPath, oldPath}, &stdout, &stderr); code != exitOK {
+		t.Errorf("Expected exit code %d for identical archives, got %d\n%s", exitOK, code, stdout.String())
+	}
+
+	stdout.Reset()
+	code := run([]string{"diff", "--format", "json", oldPath, newPath}, &stdout, &stderr)
+	if code != exitFailure {
+		t.Fatalf("Expected exit code %d for different archives, got %d\nstderr: %s", exitFailure, code, stderr.String())
+	}
+	var diffs []fileDiff
+	if err := json.Unmarshal(stdout.Bytes(), &diffs); err != nil {
+		t.Fatalf("Failed to decode diff output: %v\n%s", err, stdout.String())
+	}
+	for _, diff := range diffs {
+		if diff.File != "stops.txt" {
+			if diff.Status != "unchanged" {
+				t.Errorf("Expected %s unchanged, got %+v", diff.File, diff)
+			}
+			continue
+		}
+		if strings.Join(diff.Removed, ",") != "B" || strings.Join(diff.Added, ",") != "C" || len(diff.Changed) != 1 {
+			t.Errorf("Expected B removed, C added and A changed, got %+v", diff)
+		} else if field := diff.Changed[0].Fields[0]; field.Column != "stop_name" || field.New != "Alpha" {
+			t.Errorf("Expected stop_name of A changed to Alpha, got %+v", field)
+		}
+	}
+
+	stdout.Reset()
+	if code := run([]string{"diff", "--ignore", "stops.txt", oldPath, newPath}, &stdout, &stderr); code != exitOK {
+		t.Errorf("Expected exit code %d with stops.txt ignored, got %d\n%s", exitOK, code, stdout.String())
+	}
+}
+
+// testNetexDocument is a small NeTEx document for the validate and inspect tests
+const testNetexDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex" version="1.0">
+	<PublicationTimestamp>2025-01-01T00:00:00</PublicationTimestamp>
+	<dataObjects>
+		<CompositeFrame id="TST:CompositeFrame:1" version="1">
+			<validityConditions>
+				<ValidBetween>
+					<FromDate>2025-01-01T00:00:00</FromDate>
+					<ToDate>2025-12-31T23:59:59</ToDate>
+				</ValidBetween>
+			</validityConditions>
+			<codespaces>
+				<Codespace id="tst">
+					<Xmlns>TST</Xmlns>
+				</Codespace>
+			</codespaces>
+			<frames>
+				<ServiceFrame id="TST:ServiceFrame:1" version="1">
+					<routes>
+						<Route id="TST:Route:1" version="1"><Name>Outbound</Name><LineRef ref="TST:Line:1"/></Route>
+						<Route id="TST:Route:2" version="1"><Name>Inbound</Name><LineRef ref="TST:Line:1"/></Route>
+					</routes>
+					<lines>
+						<Line id="TST:Line:1" version="1">
+							<Name>Line 1</Name>
+							<TransportMode>bus</TransportMode>
+							<PublicCode>1</PublicCode>
+						</Line>
+					</lines>
+					<scheduledStopPoints>
+						<ScheduledStopPoint id="NSR:ScheduledStopPoint:1" version="1"><Name>A</Name></ScheduledStopPoint>
+					</scheduledStopPoints>
+				</ServiceFrame>
+			</frames>
+		</CompositeFrame>
+	</dataObjects>
+</PublicationDelivery>`
+
+// testGtfsFiles returns a small GTFS feed, with files replaced by overrides
+func testGtfsFiles(overrides map[string]string) map[string]string {
+	files := map[string]string{
+		"agency.txt": "agency_id,agency_name,agency_url,agency_timezone\n" +
+			"TST,Test,https://example.com,Europe/Oslo\n",
+		"stops.txt": "stop_id,stop_name,stop_lat,stop_lon\n" +
+			"A,A,59.9,10.7\n" +
+			"B,B,59.8,10.6\n",
+		"routes.txt": "route_id,agency_id,route_short_name,route_type\n" +
+			"R1,TST,1,3\n" +
+			"R2,TST,2,3\n",
+		"trips.txt": "route_id,service_id,trip_id\n" +
+			"R1,WEEKDAY,T1\n" +
+			"R1,WEEKDAY,T2\n" +
+			"R2,WEEKEND,T3\n",
+		"stop_times.txt": "trip_id,arrival_time,departure_time,stop_id,stop_sequence\n" +
+			"T1,08:00:00,08:00:00,A,1\n" +
+			"T1,09:00:00,09:00:00,B,2\n" +
+			"T2,23:30:00,23:30:00,B,1\n" +
+			"T2,24:30:00,24:30:00,A,2\n" +
+			"T3,10:00:00,10:00:00,A,1\n" +
+			"T3,10:30:00,10:30:00,B,2\n",
+		"calendar.txt": "service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date\n" +
+			"WEEKDAY,1,1,1,1,1,0,0,20250106,20250112\n" +
+			"WEEKEND,0,0,0,0,0,1,1,20250106,20250119\n",
+		"calendar_dates.txt": "service_id,date,exception_type\n" +
+			"WEEKDAY,20250108,2\n" +
+			"WEEKEND,20250120,1\n",
+		"frequencies.txt": "trip_id,start_time,end_time,headway_secs\n" +
+			"T3,10:00:00,12:00:00,1800\n",
+	}
+	for name, content := range overrides {
+		files[name] = content
+	}
+	return files
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatalf("Failed to close archive: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
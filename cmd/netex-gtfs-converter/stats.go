@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
+)
+
+// gtfsDateLayout is the layout of GTFS dates
+const gtfsDateLayout = "20060102"
+
+// lineStats holds the statistics of one GTFS route, the GTFS form of a line
+type lineStats struct {
+	RouteID      string  `json:"route_id"`
+	ShortName    string  `json:"short_name,omitempty"`
+	LongName     string  `json:"long_name,omitempty"`
+	Trips        int     `json:"trips"`
+	Stops        int     `json:"stops"`
+	ServiceHours float64 `json:"service_hours"`
+
+	stops map[string]bool
+}
+
+// runStats implements the stats command
+func runStats(env *environment, cmd *command, args []string) int {
+	opts := &convertOptions{}
+	fs := env.newFlagSet(cmd)
+	opts.addExporterFlags(fs, env.config)
+	format := fs.String("format", "text", "Output format: text, csv or json")
+
+	if code, ok := env.parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	if *format != "text" && *format != "csv" && *format != "json" {
+		env.errorf("unknown --format %q", *format)
+		env.usageHint(cmd.name)
+		return exitUsage
+	}
+
+	tables, err := readStatsInput(env, opts, fs.Arg(0))
+	if err != nil {
+		env.errorf("%v", err)
+		return exitFailure
+	}
+
+	stats, total := computeLineStats(tables)
+	switch *format {
+	case "json":
+		err = writeStatsJSON(env.stdout, stats, total)
+	case "csv":
+		err = writeStatsCSV(env.stdout, append(stats, total))
+	default:
+		err = writeStatsText(env.stdout, append(stats, total))
+	}
+	if err != nil {
+		env.errorf("%v", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// readStatsInput reads GTFS input as is and converts NeTEx input in memory
+func readStatsInput(env *environment, opts *convertOptions, inputPath string) (map[string]*repository.GtfsTable, error) {
+	data, kind, err := readInput(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	if kind == inputGtfs {
+		return repository.ReadGtfsArchive(data)
+	}
+
+	if strings.TrimSpace(env.config.Codespace) == "" {
+		return nil, fmt.Errorf("--codespace is required for NeTEx input")
+	}
+
+	// Conversion messages go to stderr to keep the CSV and JSON output clean
+	convertEnv := *env
+	convertEnv.stdout = env.stderr
+
+	gtfsExporter, err := newGtfsExporter(&convertEnv, opts)
+	if err != nil {
+		return nil, err
+	}
+	convertEnv.logf("Converting %s (codespace %s)", inputPath, env.config.Codespace)
+	gtfs, result, err := gtfsExporter.ConvertTimetablesToGtfsContext(env.ctx, bytes.NewReader(data))
+	if err := checkResult(&convertEnv, result, err); err != nil {
+		return nil, err
+	}
+	converted, err := io.ReadAll(gtfs)
+	if err != nil {
+		return nil, fmt.Errorf("reading converted GTFS: %w", err)
+	}
+	return repository.ReadGtfsArchive(converted)
+}
+
+// computeLineStats counts trips and distinct stops per route and sums the
+// service hours: each trip's duration times the days its service runs, times
+// its runs per day for frequency-based trips
+func computeLineStats(tables map[string]*repository.GtfsTable) ([]*lineStats, *lineStats) {
+	byRoute := make(map[string]*lineStats)
+	var order []string
+	routeStats := func(routeID string) *lineStats {
+		stats, ok := byRoute[routeID]
+		if !ok {
+			stats = &lineStats{RouteID: routeID, stops: make(map[string]bool)}
+			byRoute[routeID] = stats
+			order = append(order, routeID)
+		}
+		return stats
+	}
+
+	if routes := tables["routes.txt"]; routes != nil {
+		for _, row := range routes.Rows {
+			stats := routeStats(routes.Value(row, "route_id"))
+			stats.ShortName = routes.Value(row, "route_short_name")
+			stats.LongName = routes.Value(row, "route_long_name")
+		}
+	}
+
+	serviceDays := countServiceDays(tables)
+	runs := countFrequencyRuns(tables["frequencies.txt"])
+	durations, tripStops := tripDurations(tables["stop_times.txt"])
+
+	if trips := tables["trips.txt"]; trips != nil {
+		for _, row := range trips.Rows {
+			tripID := trips.Value(row, "trip_id")
+			stats := routeStats(trips.Value(row, "route_id"))
+			stats.Trips++
+			for _, stopID := range tripStops[tripID] {
+				stats.stops[stopID] = true
+			}
+
+			runsPerDay := 1
+			if n, ok := runs[tripID]; ok {
+				runsPerDay = n
+			}
+			days := serviceDays[trips.Value(row, "service_id")]
+			stats.ServiceHours += durations[tripID].Hours() * float64(days*runsPerDay)
+		}
+	}
+
+	total := &lineStats{RouteID: "total", stops: make(map[string]bool)}
+	result := make([]*lineStats, 0, len(order))
+	for _, routeID := range order {
+		stats := byRoute[routeID]
+		stats.Stops = len(stats.stops)
+		stats.ServiceHours = math.Round(stats.ServiceHours*100) / 100
+		total.Trips += stats.Trips
+		total.ServiceHours += stats.ServiceHours
+		for stopID := range stats.stops {
+			total.stops[stopID] = true
+		}
+		result = append(result, stats)
+	}
+	total.Stops = len(total.stops)
+	total.ServiceHours = math.Round(total.ServiceHours*100) / 100
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].RouteID < result[j].RouteID })
+	return result, total
+}
+
+// countServiceDays returns the number of dates each service runs on, from
+// calendar.txt and the additions and removals of calendar_dates.txt
+func countServiceDays(tables map[string]*repository.GtfsTable) map[string]int {
+	dates := make(map[string]map[string]bool)
+	serviceDates := func(serviceID string) map[string]bool {
+		if dates[serviceID] == nil {
+			dates[serviceID] = make(map[string]bool)
+		}
+		return dates[serviceID]
+	}
+
+	weekdayColumns := [7]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+	if calendars := tables["calendar.txt"]; calendars != nil {
+		for _, row := range calendars.Rows {
+			serviceID := calendars.Value(row, "service_id")
+			active := serviceDates(serviceID)
+			start, err := time.Parse(gtfsDateLayout, calendars.Value(row, "start_date"))
+			if err != nil {
+				continue
+			}
+			end, err := time.Parse(gtfsDateLayout, calendars.Value(row, "end_date"))
+			if err != nil {
+				continue
+			}
+			for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+				if calendars.Value(row, weekdayColumns[day.Weekday()]) == "1" {
+					active[day.Format(gtfsDateLayout)] = true
+				}
+			}
+		}
+	}
+
+	if calendarDates := tables["calendar_dates.txt"]; calendarDates != nil {
+		for _, row := range calendarDates.Rows {
+			active := serviceDates(calendarDates.Value(row, "service_id"))
+			date := calendarDates.Value(row, "date")
+			switch calendarDates.Value(row, "exception_type") {
+			case "1":
+				active[date] = true
+			case "2":
+				delete(active, date)
+			}
+		}
+	}
+
+	counts := make(map[string]int, len(dates))
+	for serviceID, active := range dates {
+		counts[serviceID] = len(active)
+	}
+	return counts
+}
+
+// countFrequencyRuns returns the daily runs of frequency-based trips
+func countFrequencyRuns(frequencies *repository.GtfsTable) map[string]int {
+	runs := make(map[string]int)
+	if frequencies == nil {
+		return runs
+	}
+	for _, row := range frequencies.Rows {
+		start, okStart := parseGtfsTime(frequencies.Value(row, "start_time"))
+		end, okEnd := parseGtfsTime(frequencies.Value(row, "end_time"))
+		headway, err := strconv.Atoi(frequencies.Value(row, "headway_secs"))
+		if !okStart || !okEnd || err != nil || headway <= 0 || end <= start {
+			continue
+		}
+		runs[frequencies.Value(row, "trip_id")] += int(math.Ceil((end - start).Seconds() / float64(headway)))
+	}
+	return runs
+}
+
+// tripDurations returns the time from first departure to last arrival of
+// each trip, and the stops each trip serves
+func tripDurations(stopTimes *repository.GtfsTable) (map[string]time.Duration, map[string][]string) {
+	durations := make(map[string]time.Duration)
+	stops := make(map[string][]string)
+	if stopTimes == nil {
+		return durations, stops
+	}
+
+	first := make(map[string]time.Duration)
+	last := make(map[string]time.Duration)
+	for _, row := range stopTimes.Rows {
+		tripID := stopTimes.Value(row, "trip_id")
+		stops[tripID] = append(stops[tripID], stopTimes.Value(row, "stop_id"))
+
+		if departure, ok := parseGtfsTime(stopTimes.Value(row, "departure_time")); ok {
+			if current, seen := first[tripID]; !seen || departure < current {
+				first[tripID] = departure
+			}
+		}
+		if arrival, ok := parseGtfsTime(stopTimes.Value(row, "arrival_time")); ok {
+			if current, seen := last[tripID]; !seen || arrival > current {
+				last[tripID] = arrival
+			}
+		}
+	}
+	for tripID, start := range first {
+		if end, ok := last[tripID]; ok && end > start {
+			durations[tripID] = end - start
+		}
+	}
+	return durations, stops
+}
+
+// parseGtfsTime parses a GTFS HH:MM:SS time, which may pass 24:00:00
+func parseGtfsTime(value string) (time.Duration, bool) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var fields [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		fields[i] = n
+	}
+	return time.Duration(fields[0])*time.Hour + time.Duration(fields[1])*time.Minute + time.Duration(fields[2])*time.Second, true
+}
+
+// writeStatsText prints the statistics as an aligned table
+func writeStatsText(w io.Writer, stats []*lineStats) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	_, _ = fmt.Fprintf(tw, "Route\tName\tTrips\tStops\tService hours\t\n")
+	for _, s := range stats {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.2f\t\n", s.RouteID, s.name(), s.Trips, s.Stops, s.ServiceHours)
+	}
+	return tw.Flush()
+}
+
+// writeStatsCSV prints the statistics as CSV
+func writeStatsCSV(w io.Writer, stats []*lineStats) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"route_id", "route_short_name", "route_long_name", "trips", "stops", "service_hours"})
+	for _, s := range stats {
+		_ = writer.Write([]string{
+			s.RouteID, s.ShortName, s.LongName,
+			strconv.Itoa(s.Trips), strconv.Itoa(s.Stops),
+			strconv.FormatFloat(s.ServiceHours, 'f', 2, 64),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeStatsJSON prints the statistics as JSON
+func writeStatsJSON(w io.Writer, stats []*lineStats, total *lineStats) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Lines []*lineStats `json:"lines"`
+		Total *lineStats   `json:"total"`
+	}{stats, total})
+}
+
+// name is the route name shown in the text output
+func (s *lineStats) name() string {
+	if s.ShortName != "" {
+		return s.ShortName
+	}
+	return s.LongName
+}
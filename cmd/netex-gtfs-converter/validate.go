@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/validation"
+)
+
+// reportFormats maps --format values to validation report formats
+var reportFormats = map[string]validation.ReportFormat{
+	"text":     validation.FormatText,
+	"json":     validation.FormatJSON,
+	"html":     validation.FormatHTML,
+	"csv":      validation.FormatCSV,
+	"markdown": validation.FormatMarkdown,
+}
+
+// runValidate implements the validate command; the exit code is 1 when the
+// report has errors or critical issues
+func runValidate(env *environment, cmd *command, args []string) int {
+	fs := env.newFlagSet(cmd)
+	format := fs.String("format", "text", "Report format: text, json, html, csv or markdown")
+	outputPath := fs.String("output", "", "Write the report to this file instead of stdout")
+
+	if code, ok := env.parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	reportFormat, ok := reportFormats[strings.ToLower(*format)]
+	if !ok {
+		env.errorf("unknown --format %q", *format)
+		env.usageHint(cmd.name)
+		return exitUsage
+	}
+
+	report, err := validateInput(env, fs.Arg(0))
+	if err != nil {
+		env.errorf("%v", err)
+		return exitFailure
+	}
+
+	if err := writeReport(*outputPath, env.stdout, func(w io.Writer) error {
+		return validation.NewReporter().GenerateReport(report, reportFormat, w)
+	}); err != nil {
+		env.errorf("%v", err)
+		return exitFailure
+	}
+
+	if !report.Summary.IsValid {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// validateInput validates every entity of a NeTEx or GTFS input
+func validateInput(env *environment, inputPath string) (validation.ValidationReport, error) {
+	data, kind, err := readInput(inputPath)
+	if err != nil {
+		return validation.ValidationReport{}, err
+	}
+	env.logf("Validating %s as %s", inputPath, kind)
+
+	service := validation.NewValidationService()
+	// Progress messages would end up in the report on stdout
+	service.SetConfig(validation.ServiceConfig{
+		EnableRealTimeValidation:    true,
+		EnablePostProcessValidation: true,
+	})
+	service.SetValidatorConfig(env.config.ValidatorConfig())
+	ctx := service.StartConversion()
+
+	if kind == inputGtfs {
+		tables, err := repository.ReadGtfsArchive(data)
+		if err != nil {
+			return validation.ValidationReport{}, err
+		}
+		if err := validateGtfs(service, ctx, tables); err != nil {
+			return validation.ValidationReport{}, err
+		}
+	} else {
+		netexRepository, err := loadNetex(data)
+		if err != nil {
+			return validation.ValidationReport{}, err
+		}
+		validateNetex(service, ctx, netexRepository)
+	}
+
+	return service.FinishConversion(ctx), nil
+}
+
+// validateNetex validates the entities the validation service knows, reaching
+// authorities, routes and journey patterns through the lines and journeys
+// that use them
+func validateNetex(service *validation.ValidationService, ctx *validation.ValidationContext, netexRepository producer.NetexRepository) {
+	seen := make(map[string]bool)
+	once := func(id string) bool {
+		if id == "" || seen[id] {
+			return false
+		}
+		seen[id] = true
+		return true
+	}
+
+	for _, line := range netexRepository.GetLines() {
+		service.ValidateNeTExEntity(ctx, line)
+		if authorityID := netexRepository.GetAuthorityIdForLine(line); once(authorityID) {
+			if authority := netexRepository.GetAuthorityById(authorityID); authority != nil {
+				service.ValidateNeTExEntity(ctx, authority)
+			}
+		}
+		for _, route := range netexRepository.GetRoutesByLine(line) {
+			if route != nil && once(route.ID) {
+				service.ValidateNeTExEntity(ctx, route)
+			}
+		}
+	}
+	for _, journey := range netexRepository.GetServiceJourneys() {
+		service.ValidateNeTExEntity(ctx, journey)
+		if patternID := journey.JourneyPatternRef.Ref; once(patternID) {
+			if pattern := netexRepository.GetJourneyPatternById(patternID); pattern != nil {
+				service.ValidateNeTExEntity(ctx, pattern)
+			}
+		}
+	}
+	for _, stopPlace := range netexRepository.GetAllStopPlaces() {
+		service.ValidateNeTExEntity(ctx, stopPlace)
+	}
+	for _, quay := range netexRepository.GetAllQuays() {
+		service.ValidateNeTExEntity(ctx, quay)
+	}
+	for _, group := range netexRepository.GetHeadwayJourneyGroups() {
+		service.ValidateNeTExEntity(ctx, group)
+	}
+	service.RecordFareIssues(ctx, producer.NewDefaultFareProducer(netexRepository).Produce().Issues)
+}
+
+// validateGtfs validates the GTFS files the validation service knows
+func validateGtfs(service *validation.ValidationService, ctx *validation.ValidationContext, tables map[string]*repository.GtfsTable) error {
+	var (
+		agencies    []*model.Agency
+		routes      []*model.GtfsRoute
+		stops       []*model.Stop
+		trips       []*model.Trip
+		stopTimes   []*model.StopTime
+		calendars   []*model.Calendar
+		shapes      []*model.Shape
+		frequencies []*model.Frequency
+		transfers   []*model.Transfer
+		pathways    []*model.Pathway
+	)
+	files := []struct {
+		name     string
+		entities interface{}
+	}{
+		{"agency.txt", &agencies},
+		{"routes.txt", &routes},
+		{"stops.txt", &stops},
+		{"trips.txt", &trips},
+		{"stop_times.txt", &stopTimes},
+		{"calendar.txt", &calendars},
+		{"shapes.txt", &shapes},
+		{"frequencies.txt", &frequencies},
+		{"transfers.txt", &transfers},
+		{"pathways.txt", &pathways},
+	}
+	for _, file := range files {
+		table, ok := tables[file.name]
+		if !ok {
+			continue
+		}
+		if err := repository.DecodeGtfsTable(table, file.entities); err != nil {
+			return fmt.Errorf("%s: %w", file.name, err)
+		}
+	}
+
+	for _, agency := range agencies {
+		service.ValidateGTFSEntity(ctx, agency)
+	}
+	for _, route := range routes {
+		service.ValidateGTFSEntity(ctx, route)
+	}
+	for _, stop := range stops {
+		service.ValidateGTFSEntity(ctx, stop)
+	}
+	for _, trip := range trips {
+		service.ValidateGTFSEntity(ctx, trip)
+	}
+	for _, stopTime := range stopTimes {
+		service.ValidateGTFSEntity(ctx, stopTime)
+	}
+	for _, calendar := range calendars {
+		service.ValidateGTFSEntity(ctx, calendar)
+	}
+	for _, shape := range shapes {
+		service.ValidateGTFSEntity(ctx, shape)
+	}
+	for _, frequency := range frequencies {
+		service.ValidateGTFSEntity(ctx, frequency)
+	}
+	for _, transfer := range transfers {
+		service.ValidateGTFSEntity(ctx, transfer)
+	}
+	for _, pathway := range pathways {
+		service.ValidateGTFSEntity(ctx, pathway)
+	}
+	return nil
+}
+
+// writeReport writes a report to outputPath, or to stdout when it is empty
+func writeReport(outputPath string, stdout io.Writer, write func(w io.Writer) error) error {
+	if outputPath == "" {
+		return write(stdout)
+	}
+
+	// #nosec G304 -- the path comes from the command line
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("creating report file: %w", err)
+	}
+	err = write(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+// Package config defines the versioned configuration of a conversion: the
+// profile, locale, holidays, feed info, producers, validation thresholds and
+// memory limits that are otherwise set one by one through setters. A Config
+// is read from a JSON or YAML file with Load and applied to an exporter with
+// ConfigureExporter.
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/calendar"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/exporter"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/geometry"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/validation"
+)
+
+// CurrentVersion is the version of the configuration schema
+const CurrentVersion = 1
+
+// ProfileEuropean is the European NeTEx profile, the only supported profile
+const ProfileEuropean = "european"
+
+// Stop time producers
+const (
+	// StopTimesPassingTimes produces a stop time per TimetabledPassingTime
+	StopTimesPassingTimes = "passing_times"
+	// StopTimesInterpolated produces the stop times of a whole journey,
+	// interpolating stops without passing times
+	StopTimesInterpolated = "interpolated"
+)
+
+// Config is a conversion configuration. Its zero value is not usable; start
+// from Default.
+type Config struct {
+	// Version is the schema version, required in files
+	Version int `json:"version"`
+	// Codespace is the NeTEx codespace of the dataset
+	Codespace string `json:"codespace"`
+	// Profile is the NeTEx profile of the dataset
+	Profile string `json:"profile"`
+	// TimeZone and Language override the dataset's FrameDefaults when set
+	TimeZone string `json:"timezone"`
+	Language string `json:"language"`
+
+	Holidays   Holidays   `json:"holidays"`
+	FeedInfo   FeedInfo   `json:"feed_info"`
+	Producers  Producers  `json:"producers"`
+	Shapes     Shapes     `json:"shapes"`
+	Errors     Errors     `json:"errors"`
+	Validation Validation `json:"validation"`
+	Memory     Memory     `json:"memory"`
+}
+
+// Holidays configures the public holidays listed with --verbose
+type Holidays struct {
+	// Country is the ISO 3166 code of the holiday calendar, e.g. NO
+	Country string `json:"country"`
+}
+
+// FeedInfo is the publisher listed in feed_info.txt
+type FeedInfo struct {
+	PublisherName string `json:"publisher_name"`
+	PublisherURL  string `json:"publisher_url"`
+	ContactEmail  string `json:"contact_email"`
+	ContactURL    string `json:"contact_url"`
+}
+
+// Producers selects the producers of the conversion
+type Producers struct {
+	// StopTimes is StopTimesPassingTimes or StopTimesInterpolated
+	StopTimes string `json:"stop_times"`
+	// Shapes enables shapes.txt and shape_dist_traveled
+	Shapes bool `json:"shapes"`
+	// ViaFormat renders destinations with vias in headsigns; empty leaves
+	// vias out
+	ViaFormat string `json:"via_format"`
+	// ZonePolicy chooses the zone_id of stops in several tariff zones:
+	// first, empty or combined
+	ZonePolicy string `json:"zone_policy"`
+	// Multimodal chooses the station of stop places grouped by a multimodal
+	// stop place: child or parent
+	Multimodal string `json:"multimodal"`
+	// PathwayHeuristics infers levels and pathways of stop places without
+	// NeTEx levels and path links from their quays
+	PathwayHeuristics bool `json:"pathway_heuristics"`
+}
+
+// Shapes tunes the shape generator
+type Shapes struct {
+	// SimplificationTolerance is in degrees; 0 disables simplification
+	SimplificationTolerance float64 `json:"simplification_tolerance"`
+	MaxPoints               int     `json:"max_points"`
+	// InterpolationDistance is in meters
+	InterpolationDistance float64 `json:"interpolation_distance"`
+}
+
+// Errors controls error recovery during conversion
+type Errors struct {
+	ContinueOnError bool `json:"continue_on_error"`
+	MaxPerEntity    int  `json:"max_per_entity"`
+}
+
+// Validation sets the thresholds of validation reports
+type Validation struct {
+	// SeverityThreshold is the lowest severity reported: info, warning,
+	// error or critical
+	SeverityThreshold string `json:"severity_threshold"`
+	MaxIssuesPerType  int    `json:"max_issues_per_type"`
+	Strict            bool   `json:"strict"`
+}
+
+// Memory limits memory use; 0 keeps the defaults
+type Memory struct {
+	LimitMB int `json:"limit_mb"`
+}
+
+// severities maps severity_threshold values to validation severities
+var severities = map[string]validation.ValidationSeverity{
+	"info":     validation.SeverityInfo,
+	"warning":  validation.SeverityWarning,
+	"error":    validation.SeverityError,
+	"critical": validation.SeverityCritical,
+}
+
+// Default returns the configuration matching the converter's built-in
+// defaults
+func Default() *Config {
+	return &Config{
+		Version: CurrentVersion,
+		Profile: ProfileEuropean,
+		Producers: Producers{
+			StopTimes:  StopTimesPassingTimes,
+			Shapes:     true,
+			ViaFormat:  producer.DefaultViaFormat,
+			ZonePolicy: string(producer.ZonePolicyFirst),
+			Multimodal: string(producer.MultimodalChild),
+		},
+		Shapes: Shapes{
+			SimplificationTolerance: 0.0001,
+			MaxPoints:               1000,
+			InterpolationDistance:   50,
+		},
+		Errors: Errors{
+			ContinueOnError: true,
+			MaxPerEntity:    100,
+		},
+		Validation: Validation{
+			SeverityThreshold: "info",
+			MaxIssuesPerType:  100,
+		},
+	}
+}
+
+// Validate checks the values of the configuration, reporting every problem
+// at once
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if c.Version != CurrentVersion {
+		add("version: unsupported version %d, expected %d", c.Version, CurrentVersion)
+	}
+	if c.Profile != ProfileEuropean {
+		add("profile: unknown profile %q, expected %q", c.Profile, ProfileEuropean)
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			add("timezone: unknown time zone %q", c.TimeZone)
+		}
+	}
+	if c.Holidays.Country != "" && !isCountryCode(c.Holidays.Country) {
+		add("holidays.country: %q is not a two-letter country code", c.Holidays.Country)
+	}
+
+	for _, field := range []struct{ name, value string }{
+		{"feed_info.publisher_url", c.FeedInfo.PublisherURL},
+		{"feed_info.contact_url", c.FeedInfo.ContactURL},
+	} {
+		if field.value == "" {
+			continue
+		}
+		if u, err := url.Parse(field.value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("%s: %q is not an http or https URL", field.name, field.value)
+		}
+	}
+	if c.FeedInfo.ContactEmail != "" {
+		if _, err := mail.ParseAddress(c.FeedInfo.ContactEmail); err != nil {
+			add("feed_info.contact_email: %q is not an email address", c.FeedInfo.ContactEmail)
+		}
+	}
+
+	if c.Producers.StopTimes != StopTimesPassingTimes && c.Producers.StopTimes != StopTimesInterpolated {
+		add("producers.stop_times: unknown producer %q, expected %q or %q",
+			c.Producers.StopTimes, StopTimesPassingTimes, StopTimesInterpolated)
+	}
+	if !isZonePolicy(c.Producers.ZonePolicy) {
+		add("producers.zone_policy: unknown policy %q, expected first, empty or combined", c.Producers.ZonePolicy)
+	}
+	if !isMultimodalStrategy(c.Producers.Multimodal) {
+		add("producers.multimodal: unknown strategy %q, expected child or parent", c.Producers.Multimodal)
+	}
+	if c.Shapes.SimplificationTolerance < 0 {
+		add("shapes.simplification_tolerance: must not be negative")
+	}
+	if c.Shapes.MaxPoints < 2 {
+		add("shapes.max_points: must be at least 2")
+	}
+	if c.Shapes.InterpolationDistance <= 0 {
+		add("shapes.interpolation_distance: must be positive")
+	}
+	if c.Errors.MaxPerEntity < 0 {
+		add("errors.max_per_entity: must not be negative")
+	}
+	if _, ok := severities[c.Validation.SeverityThreshold]; !ok {
+		add("validation.severity_threshold: unknown severity %q, expected info, warning, error or critical",
+			c.Validation.SeverityThreshold)
+	}
+	if c.Validation.MaxIssuesPerType <= 0 {
+		add("validation.max_issues_per_type: must be positive")
+	}
+	if c.Memory.LimitMB < 0 {
+		add("memory.limit_mb: must not be negative")
+	}
+
+	if len(problems) > 0 {
+		return &Error{Problems: problems}
+	}
+	return nil
+}
+
+// ConfigureExporter applies the configuration to an exporter. The codespace
+// is not applied; it is given to NewEnhancedGtfsExporter.
+func (c *Config) ConfigureExporter(e *exporter.EnhancedGtfsExporter) {
+	e.SetContinueOnError(c.Errors.ContinueOnError)
+	e.SetMaxErrorsPerEntity(c.Errors.MaxPerEntity)
+	e.SetMemoryLimit(c.Memory.LimitMB)
+	e.SetTimeZone(c.TimeZone)
+	e.SetLanguage(c.Language)
+	e.SetViaFormat(c.Producers.ViaFormat)
+	e.SetStopTimeInterpolation(c.Producers.StopTimes == StopTimesInterpolated)
+	e.SetZonePolicy(producer.ZonePolicy(c.Producers.ZonePolicy))
+	e.SetMultimodalStrategy(producer.MultimodalStrategy(c.Producers.Multimodal))
+	e.SetPathwayHeuristics(c.Producers.PathwayHeuristics)
+	e.SetFeedPublisher(exporter.FeedPublisher{
+		Name:         c.FeedInfo.PublisherName,
+		URL:          c.FeedInfo.PublisherURL,
+		ContactEmail: c.FeedInfo.ContactEmail,
+		ContactURL:   c.FeedInfo.ContactURL,
+	})
+
+	shapeGenerator := geometry.NewShapeGenerator()
+	shapeGenerator.SetSimplificationTolerance(c.Shapes.SimplificationTolerance)
+	shapeGenerator.SetMaxPointsPerShape(c.Shapes.MaxPoints)
+	shapeGenerator.SetInterpolationDistance(c.Shapes.InterpolationDistance)
+	e.SetShapeGenerator(shapeGenerator)
+	if !c.Producers.Shapes {
+		e.SetShapeProducer(nil)
+	}
+}
+
+// CalendarServiceConfig returns the calendar service configuration
+func (c *Config) CalendarServiceConfig() calendar.CalendarServiceConfig {
+	return calendar.CalendarServiceConfig{
+		DefaultTimezoneName: c.TimeZone,
+		HolidayCountryCode:  strings.ToUpper(c.Holidays.Country),
+	}
+}
+
+// ValidatorConfig returns the validator configuration
+func (c *Config) ValidatorConfig() validation.ValidationConfig {
+	return validation.ValidationConfig{
+		EnableStrictMode:           c.Validation.Strict,
+		MaxIssuesPerType:           c.Validation.MaxIssuesPerType,
+		EnableContextualValidation: true,
+		SeverityThreshold:          severities[c.Validation.SeverityThreshold],
+		EnableGTFSValidation:       true,
+		EnableNeTExValidation:      true,
+		ValidateReferences:         true,
+		ValidateGeometry:           true,
+		ValidateTiming:             true,
+		ValidateAccessibility:      true,
+	}
+}
+
+// isCountryCode reports whether code is two ASCII letters
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, r := range strings.ToUpper(code) {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isZonePolicy reports whether policy is one of the producer's zone policies
+func isZonePolicy(policy string) bool {
+	for _, known := range producer.ZonePolicies {
+		if policy == string(known) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMultimodalStrategy reports whether strategy is one of the producer's
+// multimodal strategies
+func isMultimodalStrategy(strategy string) bool {
+	for _, known := range producer.MultimodalStrategies {
+		if strategy == string(known) {
+			return true
+		}
+	}
+	return false
+}
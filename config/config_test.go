@@ -0,0 +1,212 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/validation"
+)
+
+const testYAML = `# Conversion of the test dataset
+version: 1
+codespace: TST
+timezone: Europe/Oslo
+language: no   # Norwegian, not false
+holidays:
+  country: NO
+feed_info:
+  publisher_name: "Test: Publisher"
+  publisher_url: https://example.com/#about
+  contact_email: 'ops@example.com'
+producers:
+  stop_times: interpolated
+  shapes: false
+  zone_policy: combined
+  multimodal: parent
+  pathway_heuristics: true
+shapes:
+  simplification_tolerance: 0.0005
+  max_points: 500
+errors:
+  max_per_entity: 20
+validation:
+  severity_threshold: warning
+memory:
+  limit_mb: 1024
+`
+
+func TestParse_YAML(t *testing.T) {
+	cfg, err := Parse([]byte(testYAML), FormatYAML)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	expected := Default()
+	expected.Codespace = "TST"
+	expected.TimeZone = "Europe/Oslo"
+	expected.Language = "no"
+	expected.Holidays = Holidays{Country: "NO"}
+	expected.FeedInfo = FeedInfo{
+		PublisherName: "Test: Publisher",
+		PublisherURL:  "https://example.com/#about",
+		ContactEmail:  "ops@example.com",
+	}
+	expected.Producers.StopTimes = StopTimesInterpolated
+	expected.Producers.Shapes = false
+	expected.Producers.ZonePolicy = "combined"
+	expected.Producers.Multimodal = "parent"
+	expected.Producers.PathwayHeuristics = true
+	expected.Shapes.SimplificationTolerance = 0.0005
+	expected.Shapes.MaxPoints = 500
+	expected.Errors.MaxPerEntity = 20
+	expected.Validation.SeverityThreshold = "warning"
+	expected.Memory.LimitMB = 1024
+
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, cfg)
+	}
+	if cfg.ValidatorConfig().SeverityThreshold != validation.SeverityWarning {
+		t.Errorf("Expected the warning severity threshold, got %v", cfg.ValidatorConfig().SeverityThreshold)
+	}
+	if calendarConfig := cfg.CalendarServiceConfig(); calendarConfig.HolidayCountryCode != "NO" {
+		t.Errorf("Unexpected calendar service config %+v", calendarConfig)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	cfg, err := Parse([]byte(`{"version": 1, "codespace": "TST", "errors": {"continue_on_error": false}, "timezone": null}`), FormatJSON)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if cfg.Codespace != "TST" || cfg.Errors.ContinueOnError || cfg.Errors.MaxPerEntity != 100 {
+		t.Errorf("Expected the file values over the defaults, got %+v", cfg)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		format   Format
+		data     string
+		problems []string
+	}{
+		{
+			name:     "Missing version",
+			format:   FormatYAML,
+			data:     "codespace: TST\n",
+			problems: []string{"version: missing, set it to 1"},
+		},
+		{
+			name:     "Unsupported version",
+			format:   FormatJSON,
+			data:     `{"version": 2}`,
+			problems: []string{"version: unsupported version 2, expected 1"},
+		},
+		{
+			name:   "Unknown keys",
+			format: FormatYAML,
+			data:   "version: 1\ntimezon: Europe/Oslo\nfeed_info:\n  contact_emial: a@b.c\nfoo: bar\n",
+			problems: []string{
+				`feed_info.contact_emial: unknown key, did you mean "feed_info.contact_email"?`,
+				"foo: unknown key",
+				`timezon: unknown key, did you mean "timezone"?`,
+			},
+		},
+		{
+			name:   "Wrong types",
+			format: FormatJSON,
+			data:   `{"version": 1, "codespace": 5, "producers": {"shapes": "yes"}, "memory": {"limit_mb": 1.5}, "holidays": "NO"}`,
+			problems: []string{
+				"codespace: expected a string, got 5",
+				"holidays: expected a mapping of keys, got \"NO\"",
+				"memory.limit_mb: expected an integer, got 1.5",
+				"producers.shapes: expected true or false, got \"yes\"",
+			},
+		},
+		{
+			name:   "Invalid values",
+			format: FormatYAML,
+			data:   "version: 1\nprofile: nordic\ntimezone: Mars/Olympus\nproducers:\n  zone_policy: lowest\n  multimodal: grandparent\nvalidation:\n  severity_threshold: fatal\n",
+			problems: []string{
+				`profile: unknown profile "nordic", expected "european"`,
+				`timezone: unknown time zone "Mars/Olympus"`,
+				`producers.zone_policy: unknown policy "lowest", expected first, empty or combined`,
+				`producers.multimodal: unknown strategy "grandparent", expected child or parent`,
+				`validation.severity_threshold: unknown severity "fatal", expected info, warning, error or critical`,
+			},
+		},
+		{
+			name:     "YAML tab indentation",
+			format:   FormatYAML,
+			data:     "version: 1\nholidays:\n\tcountry: NO\n",
+			problems: []string{"line 3: tabs are not allowed in indentation"},
+		},
+		{
+			name:     "YAML list",
+			format:   FormatYAML,
+			data:     "version: 1\nholidays:\n  - NO\n",
+			problems: []string{"line 3: lists are not supported in configuration files"},
+		},
+		{
+			name:     "YAML duplicate key",
+			format:   FormatYAML,
+			data:     "version: 1\nversion: 1\n",
+			problems: []string{`line 2: duplicate key "version"`},
+		},
+		{
+			name:     "YAML bad indentation",
+			format:   FormatYAML,
+			data:     "version: 1\n  codespace: TST\n",
+			problems: []string{"line 2: unexpected indentation"},
+		},
+		{
+			name:     "JSON syntax",
+			format:   FormatJSON,
+			data:     "{\n  \"version\": 1,\n}",
+			problems: []string{"line 3: invalid character '}' looking for beginning of object key string"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.data), tc.format)
+			configErr, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("Expected a *config.Error, got %v", err)
+			}
+			if !reflect.DeepEqual(configErr.Problems, tc.problems) {
+				t.Errorf("Expected problems\n  %s\ngot\n  %s",
+					strings.Join(tc.problems, "\n  "), strings.Join(configErr.Problems, "\n  "))
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yml")
+	if err := os.WriteFile(yamlPath, []byte("version: 1\ncodespace: TST\nfoo: 1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Load(yamlPath)
+	if err == nil || err.Error() != "invalid configuration "+yamlPath+": foo: unknown key" {
+		t.Errorf("Expected the file name in the error, got %v", err)
+	}
+
+	if _, err := Load(filepath.Join(dir, "config.toml")); err == nil || !strings.Contains(err.Error(), "unknown extension") {
+		t.Errorf("Expected an unknown extension error, got %v", err)
+	}
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestDefault_IsValid(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("Expected the default configuration to be valid, got %v", err)
+	}
+}
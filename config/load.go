@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Format is the syntax of a configuration file
+type Format int
+
+const (
+	FormatJSON Format = iota
+	FormatYAML
+)
+
+// Error lists the problems found in a configuration
+type Error struct {
+	// Source is the file the configuration was read from, if any
+	Source   string
+	Problems []string
+}
+
+func (e *Error) Error() string {
+	prefix := "invalid configuration"
+	if e.Source != "" {
+		prefix = "invalid configuration " + e.Source
+	}
+	if len(e.Problems) == 1 {
+		return prefix + ": " + e.Problems[0]
+	}
+	return prefix + ":\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Load reads a configuration file; the format follows the extension, .json
+// for JSON and .yaml or .yml for YAML. Keys missing from the file keep their
+// Default values.
+func Load(path string) (*Config, error) {
+	var format Format
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		format = FormatJSON
+	case ".yaml", ".yml":
+		format = FormatYAML
+	default:
+		return nil, fmt.Errorf("config file %s: unknown extension, expected .json, .yaml or .yml", path)
+	}
+
+	// #nosec G304 -- the path is chosen by the caller
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	cfg, err := Parse(data, format)
+	if configErr, ok := err.(*Error); ok {
+		configErr.Source = path
+	}
+	return cfg, err
+}
+
+// Parse parses a configuration. Unknown keys and values of the wrong type are
+// errors, as are files without a version.
+func Parse(data []byte, format Format) (*Config, error) {
+	var (
+		tree map[string]interface{}
+		err  error
+	)
+	if format == FormatYAML {
+		tree, err = parseYAML(data)
+	} else {
+		tree, err = parseJSON(data)
+	}
+	if err != nil {
+		return nil, &Error{Problems: []string{err.Error()}}
+	}
+
+	if _, ok := tree["version"]; !ok {
+		return nil, &Error{Problems: []string{fmt.Sprintf("version: missing, set it to %d", CurrentVersion)}}
+	}
+
+	var problems []string
+	checkKeys(tree, reflect.TypeOf(Config{}), "", &problems)
+	if len(problems) > 0 {
+		return nil, &Error{Problems: problems}
+	}
+
+	// The checked tree has the shape of Config, so it decodes without errors
+	normalized, err := json.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	cfg := Default()
+	if err := json.Unmarshal(normalized, cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseJSON decodes a JSON object keeping numbers as written
+func parseJSON(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var tree map[string]interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			line := bytes.Count(data[:syntaxErr.Offset], []byte("\n")) + 1
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		return nil, fmt.Errorf("expected a JSON object: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON object")
+	}
+	return tree, nil
+}
+
+// checkKeys compares a decoded tree with the fields of a struct, reporting
+// unknown keys with the closest known key and values of the wrong type. Null
+// values are removed so that they keep the default.
+func checkKeys(tree map[string]interface{}, structType reflect.Type, path string, problems *[]string) {
+	fields := make(map[string]reflect.StructField, structType.NumField())
+	names := make([]string, 0, structType.NumField())
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		fields[name] = field
+		names = append(names, name)
+	}
+
+	keys := make([]string, 0, len(tree))
+	for key := range tree {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := tree[key]
+		keyPath := path + key
+		field, ok := fields[key]
+		if !ok {
+			problem := fmt.Sprintf("%s: unknown key", keyPath)
+			if suggestion := closestKey(key, names); suggestion != "" {
+				problem += fmt.Sprintf(", did you mean %q?", path+suggestion)
+			}
+			*problems = append(*problems, problem)
+			continue
+		}
+		if value == nil {
+			delete(tree, key)
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Struct:
+			nested, ok := value.(map[string]interface{})
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: expected a mapping of keys, got %s", keyPath, describe(value)))
+				continue
+			}
+			checkKeys(nested, field.Type, keyPath+".", problems)
+		case reflect.String:
+			if _, ok := value.(string); !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: expected a string, got %s", keyPath, describe(value)))
+			}
+		case reflect.Bool:
+			if _, ok := value.(bool); !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: expected true or false, got %s", keyPath, describe(value)))
+			}
+		case reflect.Int:
+			number, ok := value.(json.Number)
+			if ok {
+				_, err := number.Int64()
+				ok = err == nil
+			}
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: expected an integer, got %s", keyPath, describe(value)))
+			}
+		case reflect.Float64:
+			number, ok := value.(json.Number)
+			if ok {
+				_, err := number.Float64()
+				ok = err == nil
+			}
+			if !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: expected a number, got %s", keyPath, describe(value)))
+			}
+		}
+	}
+}
+
+// describe names the type of a decoded value for error messages
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return "a mapping"
+	case []interface{}:
+		return "a list"
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case json.Number:
+		return v.String()
+	case string:
+		return fmt.Sprintf("%q", v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// closestKey returns the known key nearest to an unknown one, or "" when
+// none is close enough to be a likely typo
+func closestKey(key string, known []string) string {
+	best, bestDistance := "", len(key)/2+2
+	for _, candidate := range known {
+		if d := editDistance(strings.ToLower(key), candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
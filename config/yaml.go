@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// yamlNumber matches the plain scalars read as numbers
+var yamlNumber = regexp.MustCompile(`^[-+]?(\d+|\d*\.\d+|\d+\.\d*)([eE][-+]?\d+)?$`)
+
+// yamlLine is a line of a YAML document without its comment
+type yamlLine struct {
+	number int
+	indent int
+	text   string
+}
+
+// parseYAML parses the YAML needed by configuration files: nested block
+// mappings of scalars, with comments. Scalars follow YAML 1.2, so yes, no,
+// on and off are strings and language codes such as no need no quotes.
+// Lists, flow collections, anchors and multi-line scalars are not supported.
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(string(data), "\n") {
+		number := i + 1
+		raw = strings.TrimRight(raw, " \t\r")
+		if i == 0 {
+			raw = strings.TrimPrefix(raw, "\ufeff")
+		}
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", number)
+		}
+		text = strings.TrimRight(stripYAMLComment(text), " \t")
+		if text == "" || (text == "---" && len(lines) == 0) {
+			continue
+		}
+		if text == "..." {
+			break
+		}
+		lines = append(lines, yamlLine{number: number, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	if lines[0].indent != 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", lines[0].number)
+	}
+	tree, rest, err := parseYAMLMapping(lines, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("line %d: unexpected indentation", rest[0].number)
+	}
+	return tree, nil
+}
+
+// parseYAMLMapping parses the mapping whose keys are at indent, returning the
+// lines that follow it
+func parseYAMLMapping(lines []yamlLine, indent int) (map[string]interface{}, []yamlLine, error) {
+	mapping := make(map[string]interface{})
+	for len(lines) > 0 {
+		line := lines[0]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		if strings.HasPrefix(line.text, "- ") || line.text == "-" {
+			return nil, nil, fmt.Errorf("line %d: lists are not supported in configuration files", line.number)
+		}
+
+		key, rawValue, err := splitYAMLKey(line)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, nil, fmt.Errorf("line %d: duplicate key %q", line.number, key)
+		}
+		lines = lines[1:]
+
+		if rawValue != "" {
+			value, err := parseYAMLScalar(rawValue, line.number)
+			if err != nil {
+				return nil, nil, err
+			}
+			mapping[key] = value
+			continue
+		}
+
+		// A key without a value opens a nested mapping, or is null
+		if len(lines) == 0 || lines[0].indent <= indent {
+			mapping[key] = nil
+			continue
+		}
+		nested, rest, err := parseYAMLMapping(lines, lines[0].indent)
+		if err != nil {
+			return nil, nil, err
+		}
+		mapping[key] = nested
+		lines = rest
+	}
+	return mapping, lines, nil
+}
+
+// splitYAMLKey splits "key: value" at the first colon followed by a space or
+// the end of the line
+func splitYAMLKey(line yamlLine) (string, string, error) {
+	text := line.text
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 || end+1 >= len(text) || text[end+1] != ':' {
+			return "", "", fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		key, err := parseYAMLScalar(text[:end+1], line.number)
+		if err != nil {
+			return "", "", err
+		}
+		return key.(string), strings.TrimSpace(text[end+2:]), nil
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			key := strings.TrimSpace(text[:i])
+			if key == "" {
+				break
+			}
+			return key, strings.TrimSpace(text[i+1:]), nil
+		}
+	}
+	return "", "", fmt.Errorf("line %d: expected \"key: value\"", line.number)
+}
+
+// parseYAMLScalar converts a scalar to a string, bool, json.Number or nil
+func parseYAMLScalar(raw string, number int) (interface{}, error) {
+	switch raw[0] {
+	case '"':
+		if closingQuote(raw) != len(raw)-1 {
+			return nil, fmt.Errorf("line %d: unterminated or trailing text after quoted string", number)
+		}
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", number, raw)
+		}
+		return value, nil
+	case '\'':
+		if closingQuote(raw) != len(raw)-1 {
+			return nil, fmt.Errorf("line %d: unterminated or trailing text after quoted string", number)
+		}
+		return strings.ReplaceAll(raw[1:len(raw)-1], "''", "'"), nil
+	case '[', '{':
+		return nil, fmt.Errorf("line %d: flow collections are not supported in configuration files", number)
+	case '&', '*', '!', '|', '>':
+		return nil, fmt.Errorf("line %d: anchors, tags and block scalars are not supported in configuration files", number)
+	}
+
+	switch raw {
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	}
+	if yamlNumber.MatchString(raw) {
+		return json.Number(strings.TrimPrefix(raw, "+")), nil
+	}
+	return raw, nil
+}
+
+// closingQuote returns the index of the quote closing the string text starts
+// with, or -1
+func closingQuote(text string) int {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripYAMLComment removes a comment: a # at the start of the text or after
+// a space, outside quotes
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if quote == '"' && c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			// Quotes only open a string at the start of a key or value
+			if i == 0 || text[i-1] == ' ' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return text[:i]
+		}
+	}
+	return text
+}
@@ -1,11 +1,22 @@
 package exporter
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/output"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
 )
 
@@ -55,12 +66,17 @@ func (m *mockRouteProducer) Produce(line *model.Line) (*model.GtfsRoute, error)
 
 type mockStopProducer struct {
 	shouldFail bool
+	// skippedQuays are quays that produce no stop
+	skippedQuays map[string]bool
 }
 
 func (m *mockStopProducer) ProduceStopFromQuay(quay *model.Quay) (*model.Stop, error) {
 	if m.shouldFail {
 		return nil, ValidationError{Field: "quay", Message: "mock error"}
 	}
+	if m.skippedQuays[quay.ID] {
+		return nil, nil
+	}
 	return &model.Stop{
 		StopID:   quay.ID,
 		StopName: quay.Name,
@@ -81,6 +97,14 @@ func (m *mockStopProducer) ProduceStopFromStopPlace(stopPlace *model.StopPlace)
 	}, nil
 }
 
+func (m *mockStopProducer) ProduceStopFromEntrance(entrance *model.StopPlaceEntrance, stopPlace *model.StopPlace) (*model.Stop, error) {
+	return &model.Stop{StopID: entrance.ID, ParentStation: stopPlace.ID, LocationType: "2"}, nil
+}
+
+func (m *mockStopProducer) ProduceStopFromBoardingPosition(boardingPosition *model.BoardingPosition, quay *model.Quay) (*model.Stop, error) {
+	return &model.Stop{StopID: boardingPosition.ID, ParentStation: quay.ID, LocationType: "4"}, nil
+}
+
 func TestDefaultGtfsExporter_SetProducers(t *testing.T) {
 	stopAreaRepo := repository.NewDefaultStopAreaRepository()
 	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
@@ -331,6 +355,37 @@ func TestDefaultGtfsExporter_ConvertStopsFromQuays(t *testing.T) {
 	t.Log("ConvertStopsToGtfs() completed successfully with empty repository")
 }
 
+func TestDefaultGtfsExporter_ConvertStopsWithSkippedQuay(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+	exporter.SetStopProducer(&mockStopProducer{skippedQuays: map[string]bool{"quay1": true}})
+
+	location := &model.Centroid{Location: &model.Location{Latitude: 60.0, Longitude: 10.0}}
+	quays := []*model.Quay{
+		{ID: "quay1", Name: "Skipped", Centroid: location},
+		{ID: "quay2", Name: "Platform 2", Centroid: location, BoardingPositions: []model.BoardingPosition{{ID: "bp2"}}},
+	}
+	for _, quay := range quays {
+		if err := exporter.netexRepository.SaveEntity(quay); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertStops(false); err != nil {
+		t.Fatalf("convertStops() failed: %v", err)
+	}
+
+	// The boarding position belongs to the quay of the stop, not to the
+	// skipped quay before it
+	boardingArea := exporter.gtfsRepository.GetStopById("bp2")
+	if boardingArea == nil || boardingArea.ParentStation != "quay2" {
+		t.Errorf("Expected boarding area bp2 in quay2, got %+v", boardingArea)
+	}
+	if exporter.gtfsRepository.GetStopById("quay1") != nil {
+		t.Error("Expected no stop for the skipped quay")
+	}
+}
+
 func TestDefaultGtfsExporter_ConvertStopsWithFailingProducer(t *testing.T) {
 	stopAreaRepo := repository.NewDefaultStopAreaRepository()
 	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
@@ -381,6 +436,61 @@ func TestDefaultGtfsExporter_EnsureDefaultAgency(t *testing.T) {
 	}
 }
 
+func TestDefaultGtfsExporter_LocaleFromFrameDefaults(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+
+	entities := []interface{}{
+		&model.FrameDefaults{DefaultLocale: &model.DefaultLocale{TimeZone: "Europe/Paris", DefaultLanguage: "fr"}},
+		&model.Authority{ID: "auth1", Name: "Authority 1", URL: "https://example.com"},
+		&model.Line{ID: "line1", AuthorityRef: "auth1"},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertAgencies(); err != nil {
+		t.Fatalf("convertAgencies() failed: %v", err)
+	}
+	agency := exporter.gtfsRepository.GetAgencyById("auth1")
+	if agency == nil {
+		t.Fatal("Agency was not created")
+	}
+	if agency.AgencyTimezone != "Europe/Paris" || agency.AgencyLang != "fr" {
+		t.Errorf("Expected Europe/Paris and fr, got %s and %s", agency.AgencyTimezone, agency.AgencyLang)
+	}
+
+	// Overrides win over the dataset's defaults
+	exporter.SetTimeZone("Europe/Brussels")
+	exporter.SetLanguage("nl")
+	if err := exporter.convertAgencies(); err != nil {
+		t.Fatalf("convertAgencies() failed: %v", err)
+	}
+	agency = exporter.gtfsRepository.GetAgencyById("auth1")
+	if agency.AgencyTimezone != "Europe/Brussels" || agency.AgencyLang != "nl" {
+		t.Errorf("Expected Europe/Brussels and nl, got %s and %s", agency.AgencyTimezone, agency.AgencyLang)
+	}
+
+	if err := exporter.addFeedInfo(); err != nil {
+		t.Fatalf("addFeedInfo() failed: %v", err)
+	}
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readGtfsArchive(t, reader)["feed_info.txt"]
+	if len(rows) != 2 {
+		t.Fatalf("Expected header and one feed info row, got %v", rows)
+	}
+	for i, name := range rows[0] {
+		if name == "feed_lang" && rows[1][i] != "nl" {
+			t.Errorf("Expected feed_lang 'nl', got '%s'", rows[1][i])
+		}
+	}
+}
+
 func TestDefaultGtfsExporter_AddFeedInfo(t *testing.T) {
 	stopAreaRepo := repository.NewDefaultStopAreaRepository()
 	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
@@ -718,6 +828,180 @@ func TestEnhancedGtfsExporter_ConvertTimetablesToGtfsWithRecovery_ValidData(t *t
 	}
 }
 
+const progressTestNetex = `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<CompositeFrame>
+		<Frames>
+			<ResourceFrame>
+				<Authorities>
+					<Authority id="TEST:authority:1" version="1">
+						<Name>Test Authority</Name>
+					</Authority>
+				</Authorities>
+			</ResourceFrame>
+			<ServiceFrame>
+				<Lines>
+					<Line id="TEST:line:1" version="1">
+						<Name>Test Line</Name>
+						<AuthorityRef>TEST:authority:1</AuthorityRef>
+						<TransportMode>bus</TransportMode>
+					</Line>
+				</Lines>
+			</ServiceFrame>
+		</Frames>
+	</CompositeFrame>
+</PublicationDelivery>`
+
+func TestConvert_ProgressEvents(t *testing.T) {
+	var events []ProgressEvent
+	configured := false
+	result, conversionResult, err := Convert(context.Background(), strings.NewReader(progressTestNetex), Options{
+		Codespace: "TEST",
+		Progress:  func(event ProgressEvent) { events = append(events, event) },
+		Configure: func(*EnhancedGtfsExporter) { configured = true },
+	})
+	if err != nil {
+		t.Fatalf("Convert() failed: %v", err)
+	}
+	if result == nil || conversionResult == nil || !configured {
+		t.Fatal("Convert() should configure the exporter and return an archive and a result")
+	}
+
+	// Every started stage completes, in order
+	var completed []string
+	for _, event := range events {
+		if event.Processed > event.Total && event.Total > 0 {
+			t.Errorf("Processed exceeds total in %+v", event)
+		}
+		if event.Total > 0 && event.Processed == event.Total {
+			completed = append(completed, event.Stage+"/"+event.EntityType)
+		}
+	}
+	expected := []string{"loading/byte", "agencies/authority", "routes/line", "output/archive"}
+	if strings.Join(completed, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected completed stages %v, got %v", expected, completed)
+	}
+	if events[0] != (ProgressEvent{Stage: StageLoading, EntityType: "byte"}) {
+		t.Errorf("Expected loading to start first, got %+v", events[0])
+	}
+}
+
+func TestConvertTo(t *testing.T) {
+	var output bytes.Buffer
+	conversionResult, err := ConvertTo(context.Background(), strings.NewReader(progressTestNetex), &output, Options{Codespace: "TEST"})
+	if err != nil {
+		t.Fatalf("ConvertTo() failed: %v", err)
+	}
+	if !conversionResult.Success {
+		t.Errorf("Expected a successful conversion, got %+v", conversionResult.Errors)
+	}
+
+	files := readGtfsArchive(t, &output)
+	if routes := files["routes.txt"]; len(routes) != 2 || routes[1][0] != "TEST:line:1" {
+		t.Errorf("Expected the line in routes.txt, got %v", routes)
+	}
+}
+
+func TestConvertToSink(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := output.NewDirectorySink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conversionResult, err := ConvertToSink(context.Background(), strings.NewReader(progressTestNetex), sink, Options{Codespace: "TEST"})
+	if err != nil {
+		t.Fatalf("ConvertToSink() failed: %v", err)
+	}
+	if !conversionResult.Success {
+		t.Errorf("Expected a successful conversion, got %+v", conversionResult.Errors)
+	}
+
+	routes, err := os.ReadFile(filepath.Join(dir, "routes.txt"))
+	if err != nil {
+		t.Fatalf("Expected routes.txt in the directory: %v", err)
+	}
+	if !strings.Contains(string(routes), "TEST:line:1") {
+		t.Errorf("Expected the line in routes.txt, got %s", routes)
+	}
+}
+
+// archiveOnlyRepository hides the sink support of a repository
+type archiveOnlyRepository struct {
+	producer.GtfsRepository
+}
+
+func TestCopyArchive(t *testing.T) {
+	repo := repository.NewDefaultGtfsRepository()
+	if err := repo.SaveEntity(&model.Agency{AgencyID: "agency1", AgencyName: "Test Agency"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := copyArchive(archiveOnlyRepository{repo}, output.NewZipSink(&buf)); err != nil {
+		t.Fatalf("copyArchive() failed: %v", err)
+	}
+	files := readGtfsArchive(t, &buf)
+	if agencies := files["agency.txt"]; len(agencies) != 2 || agencies[1][0] != "agency1" {
+		t.Errorf("Expected the agency in agency.txt, got %v", agencies)
+	}
+}
+
+func TestConvert_Cancelled(t *testing.T) {
+	t.Run("Before loading", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, conversionResult, err := Convert(ctx, strings.NewReader(progressTestNetex), Options{Codespace: "TEST"})
+		if err != context.Canceled {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if result != nil {
+			t.Error("A cancelled conversion should not return an archive")
+		}
+		if conversionResult.Success || len(conversionResult.Errors) != 1 || conversionResult.Errors[0].Stage != StageLoading {
+			t.Errorf("Expected a single loading error, got %+v", conversionResult.Errors)
+		}
+	})
+
+	t.Run("During conversion", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var stages []string
+		_, _, err := Convert(ctx, strings.NewReader(progressTestNetex), Options{
+			Codespace: "TEST",
+			Progress: func(event ProgressEvent) {
+				stages = append(stages, event.Stage)
+				if event.Stage == StageRoutes {
+					cancel()
+				}
+			},
+		})
+		if err != context.Canceled {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if last := stages[len(stages)-1]; last != StageRoutes {
+			t.Errorf("Expected no events after cancelling in routes, got %v", stages)
+		}
+	})
+}
+
+func TestProgressToChannel(t *testing.T) {
+	ch := make(chan ProgressEvent, 1)
+	progress := ProgressToChannel(context.Background(), ch)
+	progress(ProgressEvent{Stage: StageRoutes, Processed: 1, Total: 2})
+	if event := <-ch; event.Stage != StageRoutes || event.Processed != 1 {
+		t.Errorf("Unexpected event %+v", event)
+	}
+
+	// A full channel does not block once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	progress = ProgressToChannel(ctx, ch)
+	progress(ProgressEvent{Stage: StageRoutes})
+	progress(ProgressEvent{Stage: StageOutput})
+}
+
 func TestEnhancedGtfsExporter_ConvertStopsToGtfsWithRecovery(t *testing.T) {
 	stopAreaRepo := repository.NewDefaultStopAreaRepository()
 	exporter := NewEnhancedGtfsExporter("TEST", stopAreaRepo)
@@ -762,7 +1046,7 @@ func TestEnhancedGtfsExporter_ErrorRecovery(t *testing.T) {
 	}
 
 	// Test agencies conversion with recovery
-	err := exporter.convertAgenciesWithRecovery()
+	err := exporter.convertAgenciesWithRecovery(context.Background())
 	if err != nil && !exporter.continueOnError {
 		t.Errorf("convertAgenciesWithRecovery() should handle errors gracefully: %v", err)
 	}
@@ -779,7 +1063,7 @@ func TestEnhancedGtfsExporter_ErrorRecovery(t *testing.T) {
 	if err := exporter.ensureDefaultAgencyWithRecovery(); err != nil {
 		t.Fatal(err)
 	}
-	err = exporter.convertRoutesWithRecovery()
+	err = exporter.convertRoutesWithRecovery(context.Background())
 	if err != nil && !exporter.continueOnError {
 		t.Errorf("convertRoutesWithRecovery() should handle errors gracefully: %v", err)
 	}
@@ -811,7 +1095,7 @@ func TestEnhancedGtfsExporter_ContinueOnErrorFalse(t *testing.T) {
 	}
 
 	// Should stop on first error when continueOnError is false
-	err := exporter.convertAgenciesWithRecovery()
+	err := exporter.convertAgenciesWithRecovery(context.Background())
 	if err == nil {
 		t.Error("Expected error when continueOnError is false")
 	}
@@ -838,7 +1122,7 @@ func TestEnhancedGtfsExporter_MaxErrorsPerEntity(t *testing.T) {
 	}
 
 	// Should stop processing after maxErrorsPerEntity
-	err := exporter.convertAgenciesWithRecovery()
+	err := exporter.convertAgenciesWithRecovery(context.Background())
 	if err != nil && !exporter.continueOnError {
 		t.Errorf("Unexpected error: %v", err)
 	}
@@ -865,7 +1149,7 @@ func TestEnhancedGtfsExporter_FieldRecovery(t *testing.T) {
 	}
 
 	// Should recover by providing default values
-	err := exporter.convertAgenciesWithRecovery()
+	err := exporter.convertAgenciesWithRecovery(context.Background())
 	if err != nil {
 		t.Errorf("convertAgenciesWithRecovery() should recover from missing fields: %v", err)
 	}
@@ -921,6 +1205,7 @@ func TestEnhancedGtfsExporter_ServiceJourneyProcessing(t *testing.T) {
 		ID:                "sj1",
 		LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
 		JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+		DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
 		PassingTimes: &model.PassingTimes{
 			TimetabledPassingTime: []model.TimetabledPassingTime{
 				{ArrivalTime: "10:00:00", DepartureTime: "10:00:00"},
@@ -960,25 +1245,170 @@ func TestEnhancedGtfsExporter_ServiceJourneyProcessing(t *testing.T) {
 	}
 }
 
+func TestEnhancedGtfsExporter_DatedServiceJourneys(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewEnhancedGtfsExporter("TEST", stopAreaRepo)
+
+	entities := []interface{}{
+		&model.Authority{ID: "auth1", Name: "Test Authority"},
+		&model.Line{ID: "line1", Name: "Test Line", AuthorityRef: "auth1"},
+		&model.OperatingDay{ID: "od1", CalendarDate: "2024-06-12"},
+		&model.DatedServiceJourney{ID: "dsj1", ServiceJourneyRef: "sjDated", OperatingDayRef: "od1"},
+		&model.DatedServiceJourney{ID: "dsj2", ServiceJourneyRef: "sjDated", OperatingDayRef: "2024-06-26"},
+	}
+	var journeys []*model.ServiceJourney
+	for _, id := range []string{"sjDated", "sjUndated"} {
+		journeys = append(journeys, &model.ServiceJourney{
+			ID:                id,
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+		})
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, sj := range journeys {
+		if err := exporter.processServiceJourneyWithRecovery(sj); err != nil {
+			t.Fatalf("processServiceJourneyWithRecovery() failed: %v", err)
+		}
+	}
+	if err := exporter.convertCalendarsWithRecovery(context.Background()); err != nil {
+		t.Fatalf("convertCalendarsWithRecovery() failed: %v", err)
+	}
+
+	if trip := exporter.gtfsRepository.GetTripById("sjUndated"); trip != nil {
+		t.Errorf("Expected the journey without day types or dated journeys to be skipped, got %+v", trip)
+	}
+	result := exporter.GetConversionResult()
+	if result.SkippedCount["servicejourney"] != 1 || len(result.Warnings) == 0 {
+		t.Errorf("Expected a warning for the skipped journey, got skipped %d and warnings %v", result.SkippedCount["servicejourney"], result.Warnings)
+	}
+
+	trip := exporter.gtfsRepository.GetTripById("sjDated")
+	if trip == nil || !strings.HasPrefix(trip.ServiceID, "dates_") {
+		t.Fatalf("Expected the dated journey on a dates service, got %+v", trip)
+	}
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readGtfsArchive(t, reader)
+	var dates []string
+	for _, row := range files["calendar_dates.txt"][1:] {
+		if row[0] == trip.ServiceID {
+			dates = append(dates, row[1])
+		}
+	}
+	if len(dates) != 2 || dates[0] != "20240612" || dates[1] != "20240626" {
+		t.Errorf("Expected service %s on 20240612 and 20240626, got %v", trip.ServiceID, files["calendar_dates.txt"])
+	}
+}
+
 func TestEnhancedGtfsExporter_CalendarGeneration(t *testing.T) {
 	stopAreaRepo := repository.NewDefaultStopAreaRepository()
 	exporter := NewEnhancedGtfsExporter("TEST", stopAreaRepo)
 
+	entities := []interface{}{
+		&model.DayType{ID: "dt1", Properties: &model.Properties{PropertyOfDay: []model.PropertyOfDay{{DaysOfWeek: "Weekdays"}}}},
+		&model.OperatingPeriod{ID: "op1", FromDate: "2024-06-03", ToDate: "2024-06-30"},
+		&model.OperatingDay{ID: "od1", CalendarDate: "2024-06-12"},
+		&model.DayTypeAssignment{ID: "dta1", DayTypeRef: "dt1", OperatingPeriodRef: "op1", IsAvailable: true},
+		&model.DayTypeAssignment{ID: "dta2", DayTypeRef: "dt1", OperatingDayRef: "od1", IsAvailable: false},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Services recorded while converting trips
+	exporter.serviceJourneys["dt1"] = &model.ServiceJourney{ID: "sj_dt1", DayTypes: &model.DayTypes{DayTypeRef: []string{"dt1"}}}
+	exporter.serviceJourneys["unknown"] = &model.ServiceJourney{ID: "sj_unknown", DayTypes: &model.DayTypes{DayTypeRef: []string{"missing"}}}
+
 	// Test calendar generation with recovery
-	err := exporter.convertCalendarsWithRecovery()
+	err := exporter.convertCalendarsWithRecovery(context.Background())
 	if err != nil {
 		t.Errorf("convertCalendarsWithRecovery() failed: %v", err)
 	}
 
-	// Check that calendar was created
 	result := exporter.GetConversionResult()
-	if result.ProcessedCount["calendar"] == 0 {
-		t.Error("Should have processed at least one calendar")
+	if result.ProcessedCount["calendar"] != 1 {
+		t.Errorf("Expected one calendar, got %d", result.ProcessedCount["calendar"])
+	}
+	if result.ProcessedCount["calendardate"] != 1 {
+		t.Errorf("Expected one calendar date, got %d", result.ProcessedCount["calendardate"])
+	}
+	if len(result.Warnings) == 0 {
+		t.Error("Expected a warning for the service without dates")
+	}
+
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readGtfsArchive(t, reader)
+	calendarRows := files["calendar.txt"]
+	if len(calendarRows) != 2 {
+		t.Fatalf("Expected header and one calendar, got %v", calendarRows)
+	}
+	header, row := calendarRows[0], calendarRows[1]
+	values := make(map[string]string)
+	for i, name := range header {
+		values[name] = row[i]
+	}
+	if values["service_id"] != "dt1" || values["start_date"] != "20240603" || values["end_date"] != "20240628" || values["saturday"] != "0" {
+		t.Errorf("Unexpected calendar: %v", values)
+	}
+}
+
+func TestEnhancedGtfsExporter_FeedInfoFromServices(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewEnhancedGtfsExporter("TEST", stopAreaRepo)
+	exporter.SetFeedPublisher(FeedPublisher{Name: "Transit Authority", URL: "https://transit.example.com", ContactEmail: "gtfs@transit.example.com"})
+
+	entities := []interface{}{
+		&model.PublicationDelivery{PublicationTimestamp: "2024-05-01T10:00:00"},
+		&model.DayType{ID: "dt1", Properties: &model.Properties{PropertyOfDay: []model.PropertyOfDay{{DaysOfWeek: "Weekdays"}}}},
+		&model.OperatingPeriod{ID: "op1", FromDate: "2024-06-01", ToDate: "2024-06-30"},
+		&model.DayTypeAssignment{ID: "dta1", DayTypeRef: "dt1", OperatingPeriodRef: "op1", IsAvailable: true},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exporter.serviceJourneys["dt1"] = &model.ServiceJourney{ID: "sj_dt1", DayTypes: &model.DayTypes{DayTypeRef: []string{"dt1"}}}
+
+	if err := exporter.convertCalendarsWithRecovery(context.Background()); err != nil {
+		t.Fatalf("convertCalendarsWithRecovery() failed: %v", err)
+	}
+	if err := exporter.addFeedInfoWithRecovery(); err != nil {
+		t.Fatalf("addFeedInfoWithRecovery() failed: %v", err)
 	}
 
-	// Should also have calendar dates (holidays)
-	if result.ProcessedCount["calendardate"] == 0 {
-		t.Error("Should have processed calendar dates")
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readGtfsArchive(t, reader)["feed_info.txt"]
+	if len(rows) != 2 {
+		t.Fatalf("Expected header and one feed info row, got %v", rows)
+	}
+	values := make(map[string]string)
+	for i, name := range rows[0] {
+		values[name] = rows[1][i]
+	}
+	if values["feed_publisher_name"] != "Transit Authority" || values["feed_publisher_url"] != "https://transit.example.com" ||
+		values["feed_contact_email"] != "gtfs@transit.example.com" {
+		t.Errorf("Unexpected publisher: %v", values)
+	}
+	// Weekdays of June 2024 run from Monday 3 to Friday 28
+	if values["feed_start_date"] != "20240603" || values["feed_end_date"] != "20240628" {
+		t.Errorf("Expected validity 20240603-20240628, got %s-%s", values["feed_start_date"], values["feed_end_date"])
+	}
+	if values["feed_version"] != "20240501T100000" {
+		t.Errorf("Expected feed_version '20240501T100000', got '%s'", values["feed_version"])
 	}
 }
 
@@ -995,7 +1425,7 @@ func TestEnhancedGtfsExporter_TransferProcessing(t *testing.T) {
 	}
 
 	// Test transfer processing with recovery
-	err := exporter.convertTransfersWithRecovery()
+	err := exporter.convertTransfersWithRecovery(context.Background())
 	if err != nil {
 		t.Errorf("convertTransfersWithRecovery() failed: %v", err)
 	}
@@ -1139,6 +1569,67 @@ func TestDefaultGtfsExporter_ConvertTransfers(t *testing.T) {
 	}
 }
 
+func TestDefaultGtfsExporter_ConvertTransfersResolvesRefs(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+
+	data := `<ServiceJourneyInterchange id="ic1" version="1">
+		<StaySeated>true</StaySeated>
+		<FromPointRef ref="ssp1" version="1"/>
+		<ToPointRef ref="ssp2" version="1"/>
+		<FromJourneyRef ref="sj1" version="1"/>
+		<ToJourneyRef ref="sj2" version="1"/>
+	</ServiceJourneyInterchange>`
+	interchange := &model.ServiceJourneyInterchange{}
+	if err := xml.Unmarshal([]byte(data), interchange); err != nil {
+		t.Fatal(err)
+	}
+	entities := []interface{}{
+		interchange,
+		&model.ScheduledStopPoint{ID: "ssp1", QuayRef: "quay1"},
+		&model.ScheduledStopPoint{ID: "ssp2", QuayRef: "quay2"},
+		// An interchange to a stop outside the feed is dropped
+		&model.ServiceJourneyInterchange{ID: "ic2", FromPointRef: "ssp1", ToPointRef: "ssp-other"},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	gtfsEntities := []interface{}{
+		&model.Stop{StopID: "quay1", StopName: "A"},
+		&model.Stop{StopID: "quay2", StopName: "B"},
+		&model.Trip{TripID: "sj1", RouteID: "r1", ServiceID: "s1"},
+		&model.Trip{TripID: "sj2", RouteID: "r1", ServiceID: "s1"},
+	}
+	for _, entity := range gtfsEntities {
+		if err := exporter.gtfsRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertTransfers(); err != nil {
+		t.Fatalf("convertTransfers() failed: %v", err)
+	}
+
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readGtfsArchive(t, reader)["transfers.txt"]
+	if len(rows) != 2 {
+		t.Fatalf("Expected header and one transfer, got %v", rows)
+	}
+	values := make(map[string]string)
+	for i, name := range rows[0] {
+		values[name] = rows[1][i]
+	}
+	if values["from_stop_id"] != "quay1" || values["to_stop_id"] != "quay2" ||
+		values["from_trip_id"] != "sj1" || values["to_trip_id"] != "sj2" || values["transfer_type"] != "4" {
+		t.Errorf("Unexpected transfer: %v", values)
+	}
+}
+
 func TestDefaultGtfsExporter_AddFeedInfoDetailed(t *testing.T) {
 	stopAreaRepo := repository.NewDefaultStopAreaRepository()
 	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
@@ -1221,7 +1712,7 @@ func TestEnhancedGtfsExporter_LoadNetexWithRecovery(t *testing.T) {
 
 	// Test with invalid data
 	invalidData := strings.NewReader("not valid data")
-	err := exporter.loadNetexWithRecovery(invalidData)
+	err := exporter.loadNetexWithRecovery(context.Background(), invalidData)
 
 	// Should handle error gracefully with continueOnError=true
 	if err != nil && !exporter.continueOnError {
@@ -1442,6 +1933,7 @@ func TestEnhancedGtfsExporter_ComplexServiceJourney(t *testing.T) {
 		ID:                "sj1",
 		LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
 		JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+		DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
 		PassingTimes: &model.PassingTimes{
 			TimetabledPassingTime: []model.TimetabledPassingTime{
 				{ArrivalTime: "10:00:00", DepartureTime: "10:00:00"},
@@ -1535,7 +2027,7 @@ func TestEnhancedGtfsExporter_ValidationRecovery(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err := exporter.convertStopsWithRecovery(false)
+	err := exporter.convertStopsWithRecovery(context.Background(), false)
 	if err != nil && !exporter.continueOnError {
 		t.Errorf("convertStopsWithRecovery should handle validation errors: %v", err)
 	}
@@ -1711,7 +2203,7 @@ func TestEnhancedGtfsExporter_ConvertNetexToGtfsWithRecovery(t *testing.T) {
 	}
 
 	// Test full conversion with recovery
-	err := exporter.convertNetexToGtfsWithRecovery()
+	err := exporter.convertNetexToGtfsWithRecovery(context.Background())
 	if err != nil && !exporter.continueOnError {
 		t.Errorf("convertNetexToGtfsWithRecovery() failed: %v", err)
 	}
@@ -1762,24 +2254,1177 @@ func TestEnhancedGtfsExporter_ConvertStopTimesForTrip(t *testing.T) {
 	trip := &model.Trip{TripID: "trip1", ServiceID: "service1"}
 
 	// Test stop times conversion
-	err := exporter.convertStopTimesForTrip(sj, trip, nil)
+	err := exporter.convertStopTimesForTrip(sj, trip, nil, nil)
 	if err != nil {
 		t.Errorf("convertStopTimesForTrip() failed: %v", err)
 	}
 }
 
-func TestEnhancedGtfsExporter_NoServiceJourneys(t *testing.T) {
-	stopAreaRepo := repository.NewDefaultStopAreaRepository()
-	exporter := NewEnhancedGtfsExporter("TEST", stopAreaRepo)
+const flexibleTestNetex = `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex" xmlns:gml="http://www.opengis.net/gml/3.2">
+	<CompositeFrame>
+		<Frames>
+			<ResourceFrame>
+				<Authorities>
+					<Authority id="TEST:authority:1" version="1">
+						<Name>Test Authority</Name>
+					</Authority>
+				</Authorities>
+			</ResourceFrame>
+			<SiteFrame>
+				<flexibleStopPlaces>
+					<FlexibleStopPlace id="TEST:FlexibleStopPlace:zone" version="1">
+						<Name>Village zone</Name>
+						<areas>
+							<FlexibleArea id="TEST:FlexibleArea:zone" version="1">
+								<gml:Polygon gml:id="zone">
+									<gml:exterior><gml:LinearRing><gml:posList>60.0 10.0 60.0 11.0 61.0 11.0 61.0 10.0 60.0 10.0</gml:posList></gml:LinearRing></gml:exterior>
+								</gml:Polygon>
+							</FlexibleArea>
+						</areas>
+					</FlexibleStopPlace>
+					<FlexibleStopPlace id="TEST:FlexibleStopPlace:group" version="1">
+						<Name>Village stops</Name>
+						<areas>
+							<FlexibleArea id="TEST:FlexibleArea:group" version="1">
+								<members><QuayRef ref="TEST:Quay:1"/></members>
+							</FlexibleArea>
+						</areas>
+					</FlexibleStopPlace>
+				</flexibleStopPlaces>
+			</SiteFrame>
+			<ServiceFrame>
+				<lines>
+					<FlexibleLine id="TEST:FlexibleLine:1" version="1">
+						<Name>Village bus</Name>
+						<AuthorityRef>TEST:authority:1</AuthorityRef>
+						<TransportMode>bus</TransportMode>
+						<FlexibleLineType>flexibleAreasOnly</FlexibleLineType>
+						<BookingContact><Phone>+47 12345678</Phone></BookingContact>
+						<BookWhen>dayOfTravelOnly</BookWhen>
+						<MinimumBookingPeriod>PT1H</MinimumBookingPeriod>
+						<BookingNote>Book one hour ahead</BookingNote>
+					</FlexibleLine>
+				</lines>
+				<stopAssignments>
+					<FlexibleStopAssignment id="TEST:FlexibleStopAssignment:zone" version="1">
+						<ScheduledStopPointRef ref="TEST:ScheduledStopPoint:zone"/>
+						<FlexibleStopPlaceRef ref="TEST:FlexibleStopPlace:zone"/>
+					</FlexibleStopAssignment>
+					<FlexibleStopAssignment id="TEST:FlexibleStopAssignment:group" version="1">
+						<ScheduledStopPointRef ref="TEST:ScheduledStopPoint:group"/>
+						<FlexibleStopPlaceRef ref="TEST:FlexibleStopPlace:group"/>
+					</FlexibleStopAssignment>
+				</stopAssignments>
+				<journeyPatterns>
+					<JourneyPattern id="TEST:JourneyPattern:1" version="1">
+						<pointsInSequence>
+							<StopPointInJourneyPattern id="TEST:StopPointInJourneyPattern:1" version="1" order="1"><ScheduledStopPointRef ref="TEST:ScheduledStopPoint:zone"/></StopPointInJourneyPattern>
+							<StopPointInJourneyPattern id="TEST:StopPointInJourneyPattern:2" version="1" order="2"><ScheduledStopPointRef ref="TEST:ScheduledStopPoint:group"/></StopPointInJourneyPattern>
+						</pointsInSequence>
+					</JourneyPattern>
+				</journeyPatterns>
+			</ServiceFrame>
+			<TimetableFrame>
+				<vehicleJourneys>
+					<ServiceJourney id="TEST:ServiceJourney:1" version="1">
+						<dayTypes><DayTypeRef ref="TEST:DayType:1"/></dayTypes>
+						<JourneyPatternRef ref="TEST:JourneyPattern:1"/>
+						<LineRef ref="TEST:FlexibleLine:1"/>
+						<passingTimes>
+							<TimetabledPassingTime><StopPointInJourneyPatternRef ref="TEST:StopPointInJourneyPattern:1"/><EarliestDepartureTime>08:00:00</EarliestDepartureTime><LatestArrivalTime>12:00:00</LatestArrivalTime></TimetabledPassingTime>
+							<TimetabledPassingTime><StopPointInJourneyPatternRef ref="TEST:StopPointInJourneyPattern:2"/><EarliestDepartureTime>08:00:00</EarliestDepartureTime><LatestArrivalTime>23:30:00</LatestArrivalTime><LatestArrivalDayOffset>1</LatestArrivalDayOffset></TimetabledPassingTime>
+						</passingTimes>
+					</ServiceJourney>
+				</vehicleJourneys>
+			</TimetableFrame>
+		</Frames>
+	</CompositeFrame>
+</PublicationDelivery>`
 
-	// Test with no service journeys
-	err := exporter.convertServicesWithRecovery()
+func TestEnhancedGtfsExporter_FlexibleServices(t *testing.T) {
+	exporter := NewEnhancedGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+	quay := &model.Quay{ID: "TEST:Quay:1", Name: "Village 1", Centroid: &model.Centroid{Location: &model.Location{Latitude: 60.5, Longitude: 10.5}}}
+	if err := exporter.netexRepository.SaveEntity(quay); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	sink, err := output.NewDirectorySink(dir)
 	if err != nil {
-		t.Errorf("convertServicesWithRecovery() should handle empty data: %v", err)
+		t.Fatal(err)
+	}
+	conversionResult, err := exporter.ConvertTimetablesToGtfsSink(context.Background(), strings.NewReader(flexibleTestNetex), sink)
+	if err != nil {
+		t.Fatalf("ConvertTimetablesToGtfsSink() failed: %v", err)
+	}
+	for _, warning := range conversionResult.Warnings {
+		if warning.EntityType == "flexiblestopplace" {
+			t.Errorf("Unexpected flexible stop place warning: %+v", warning)
+		}
 	}
 
-	result := exporter.GetConversionResult()
-	if len(result.Warnings) == 0 {
-		t.Error("Should have warned about no service journeys")
+	readTable := func(name string) []map[string]string {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("Expected %s in the output: %v", name, err)
+		}
+		defer file.Close()
+		rows, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var records []map[string]string
+		for _, row := range rows[1:] {
+			record := make(map[string]string)
+			for i, header := range rows[0] {
+				record[header] = row[i]
+			}
+			records = append(records, record)
+		}
+		return records
+	}
+
+	rules := readTable("booking_rules.txt")
+	if len(rules) != 1 {
+		t.Fatalf("Expected one booking rule, got %v", rules)
+	}
+	rule := rules[0]
+	if rule["booking_rule_id"] != "TEST:FlexibleLine:1_booking" || rule["booking_type"] != "1" ||
+		rule["prior_notice_duration_min"] != "60" || rule["phone_number"] != "+47 12345678" || rule["message"] != "Book one hour ahead" {
+		t.Errorf("Unexpected booking rule %v", rule)
+	}
+
+	if groups := readTable("location_groups.txt"); len(groups) != 1 || groups[0]["location_group_id"] != "TEST:FlexibleStopPlace:group" {
+		t.Errorf("Expected the member group in location_groups.txt, got %v", groups)
+	}
+	if members := readTable("location_group_stops.txt"); len(members) != 1 || members[0]["stop_id"] != "TEST:Quay:1" {
+		t.Errorf("Expected the quay in location_group_stops.txt, got %v", members)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "locations.geojson"))
+	if err != nil {
+		t.Fatalf("Expected locations.geojson in the output: %v", err)
+	}
+	var locations struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID       string `json:"id"`
+			Geometry struct {
+				Type        string         `json:"type"`
+				Coordinates [][][2]float64 `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(data, &locations); err != nil {
+		t.Fatalf("locations.geojson is not valid JSON: %v", err)
+	}
+	if len(locations.Features) != 1 || locations.Features[0].ID != "TEST:FlexibleStopPlace:zone" ||
+		locations.Features[0].Geometry.Type != "Polygon" || locations.Features[0].Geometry.Coordinates[0][1] != [2]float64{11, 60} {
+		t.Errorf("Unexpected locations.geojson %s", data)
+	}
+
+	stopTimes := readTable("stop_times.txt")
+	if len(stopTimes) != 2 {
+		t.Fatalf("Expected 2 stop times, got %v", stopTimes)
+	}
+	expected := []map[string]string{
+		{"location_id": "TEST:FlexibleStopPlace:zone", "start_pickup_drop_off_window": "08:00:00", "end_pickup_drop_off_window": "12:00:00"},
+		{"location_group_id": "TEST:FlexibleStopPlace:group", "start_pickup_drop_off_window": "08:00:00", "end_pickup_drop_off_window": "47:30:00"},
+	}
+	for i, stopTime := range stopTimes {
+		for column, value := range expected[i] {
+			if stopTime[column] != value {
+				t.Errorf("Stop %d: expected %s %q, got %q", i+1, column, value, stopTime[column])
+			}
+		}
+		if stopTime["stop_id"] != "" || stopTime["arrival_time"] != "" || stopTime["departure_time"] != "" {
+			t.Errorf("Stop %d: a flexible stop time should have no stop or times, got %v", i+1, stopTime)
+		}
+		if stopTime["pickup_type"] != "2" || stopTime["pickup_booking_rule_id"] != "TEST:FlexibleLine:1_booking" {
+			t.Errorf("Stop %d: expected a booked pickup, got %v", i+1, stopTime)
+		}
+	}
+}
+
+func TestDefaultGtfsExporter_SkipsFlexibleStopTimes(t *testing.T) {
+	exporter := NewDefaultGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+	exporter.SetStopTimeInterpolation(true)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+
+	entities := []interface{}{
+		&model.FlexibleStopPlace{ID: "flex1", Name: "Village stops"},
+		&model.FlexibleStopAssignment{ID: "fsa1", ScheduledStopPointRef: "ssp:flex", FlexibleStopPlaceRef: "flex1"},
+		&model.JourneyPattern{ID: "jp1", PointsInSequence: &model.PointsInSequence{PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: []interface{}{
+			&model.StopPointInJourneyPattern{ID: "spjp1", Order: 1, ScheduledStopPointRef: "ssp1"},
+			&model.StopPointInJourneyPattern{ID: "spjp2", Order: 2, ScheduledStopPointRef: "ssp:flex"},
+		}}},
+		&model.ServiceJourney{
+			ID:                "sj1",
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+			DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
+			PassingTimes: &model.PassingTimes{TimetabledPassingTime: []model.TimetabledPassingTime{
+				{PointInJourneyPatternRef: "spjp1", DepartureTime: "08:00:00"},
+				{PointInJourneyPatternRef: "spjp2", EarliestDepartureTime: "08:10:00", LatestArrivalTime: "09:00:00"},
+			}},
+		},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	// The default exporter writes no GTFS-Flex locations, so the stop time at
+	// the flexible stop place is left out rather than left dangling
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readGtfsArchive(t, reader)["stop_times.txt"]
+	if len(rows) != 2 {
+		t.Fatalf("Expected only the stop time at ssp1, got %v", rows)
+	}
+	for i, header := range rows[0] {
+		if header == "stop_id" && rows[1][i] != "ssp1" {
+			t.Errorf("Expected the stop time at ssp1, got %v", rows[1])
+		}
+	}
+}
+
+const fareTestNetex = `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<dataObjects>
+		<CompositeFrame id="TEST:CompositeFrame:1" version="1">
+			<FrameDefaults>
+				<DefaultCurrency>EUR</DefaultCurrency>
+			</FrameDefaults>
+			<frames>
+				<ResourceFrame id="TEST:ResourceFrame:1" version="1">
+					<organisations>
+						<Authority id="TEST:Authority:1" version="1">
+							<Name>Test Authority</Name>
+						</Authority>
+					</organisations>
+				</ResourceFrame>
+				<ServiceFrame id="TEST:ServiceFrame:1" version="1">
+					<lines>
+						<Line id="TEST:Line:1" version="1">
+							<Name>City Line</Name>
+							<TransportMode>bus</TransportMode>
+							<AuthorityRef ref="TEST:Authority:1"/>
+						</Line>
+					</lines>
+				</ServiceFrame>
+				<FareFrame id="TEST:FareFrame:1" version="1">
+					<tariffZones>
+						<TariffZone id="TEST:TariffZone:A" version="1"><Name>Zone A</Name></TariffZone>
+						<TariffZone id="TEST:TariffZone:B" version="1"><Name>Zone B</Name></TariffZone>
+					</tariffZones>
+					<tariffs>
+						<Tariff id="TEST:Tariff:1" version="1">
+							<Name>City</Name>
+							<lines><LineRef ref="TEST:Line:1"/></lines>
+							<fareStructureElements>
+								<FareStructureElement id="TEST:FareStructureElement:single" version="1">
+									<GenericParameterAssignment id="TEST:GenericParameterAssignment:single">
+										<validityParameters><TariffZoneRef ref="TEST:TariffZone:A"/></validityParameters>
+										<limitations>
+											<Transferability id="TEST:Transferability:1">
+												<MaximumNumberOfTransfers>2</MaximumNumberOfTransfers>
+												<TransferDuration>PT90M</TransferDuration>
+											</Transferability>
+											<UserProfileRef ref="TEST:UserProfile:child"/>
+										</limitations>
+									</GenericParameterAssignment>
+								</FareStructureElement>
+								<FareStructureElement id="TEST:FareStructureElement:zones" version="1">
+									<distanceMatrixElements>
+										<DistanceMatrixElement id="TEST:DistanceMatrixElement:AB" version="1">
+											<Name>A-B</Name>
+											<prices>
+												<DistanceMatrixElementPrice id="TEST:Price:AB"><Amount>3.5</Amount></DistanceMatrixElementPrice>
+											</prices>
+											<StartTariffZoneRef ref="TEST:TariffZone:A"/>
+											<EndTariffZoneRef ref="TEST:TariffZone:B"/>
+										</DistanceMatrixElement>
+									</distanceMatrixElements>
+								</FareStructureElement>
+							</fareStructureElements>
+						</Tariff>
+					</tariffs>
+					<fareProducts>
+						<PreassignedFareProduct id="TEST:PreassignedFareProduct:single" version="1">
+							<Name>Single</Name>
+							<validableElements>
+								<ValidableElement id="TEST:ValidableElement:single">
+									<fareStructureElements><FareStructureElementRef ref="TEST:FareStructureElement:single"/></fareStructureElements>
+								</ValidableElement>
+							</validableElements>
+							<prices>
+								<FareProductPrice id="TEST:Price:single"><Amount>2.5</Amount><Currency>EUR</Currency></FareProductPrice>
+							</prices>
+						</PreassignedFareProduct>
+						<PreassignedFareProduct id="TEST:PreassignedFareProduct:zonal" version="1">
+							<Name>Zonal</Name>
+							<validableElements>
+								<ValidableElement id="TEST:ValidableElement:zonal">
+									<fareStructureElements><FareStructureElementRef ref="TEST:FareStructureElement:zones"/></fareStructureElements>
+								</ValidableElement>
+							</validableElements>
+						</PreassignedFareProduct>
+					</fareProducts>
+					<salesOfferPackages>
+						<SalesOfferPackage id="TEST:SalesOfferPackage:app" version="1">
+							<Name>App ticket</Name>
+							<distributionAssignments>
+								<DistributionAssignment id="TEST:DistributionAssignment:app">
+									<DistributionChannelType>online</DistributionChannelType>
+								</DistributionAssignment>
+							</distributionAssignments>
+							<salesOfferPackageElements>
+								<SalesOfferPackageElement id="TEST:SalesOfferPackageElement:app">
+									<TypeOfTravelDocumentRef ref="TEST:TypeOfTravelDocument:mobileApp"/>
+									<PreassignedFareProductRef ref="TEST:PreassignedFareProduct:single"/>
+								</SalesOfferPackageElement>
+							</salesOfferPackageElements>
+							<prices>
+								<SalesOfferPackagePrice id="TEST:Price:app"><Amount>2.2</Amount></SalesOfferPackagePrice>
+							</prices>
+						</SalesOfferPackage>
+					</salesOfferPackages>
+					<fareTables>
+						<FareTable id="TEST:FareTable:1" version="1">
+							<cells><Cell id="TEST:Cell:1"/></cells>
+						</FareTable>
+					</fareTables>
+				</FareFrame>
+			</frames>
+		</CompositeFrame>
+	</dataObjects>
+</PublicationDelivery>`
+
+func TestEnhancedGtfsExporter_Fares(t *testing.T) {
+	exporter := NewEnhancedGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+	reader, conversionResult, err := exporter.ConvertTimetablesToGtfsContext(context.Background(), strings.NewReader(fareTestNetex))
+	if err != nil {
+		t.Fatalf("ConvertTimetablesToGtfsContext() failed: %v", err)
+	}
+	files := readGtfsArchive(t, reader)
+
+	table := func(name string) []string {
+		var rows []string
+		for _, row := range files[name] {
+			rows = append(rows, strings.Join(row, ","))
+		}
+		return rows
+	}
+	expected := map[string][]string{
+		"areas.txt":          {"area_id,area_name", "TEST:TariffZone:A,Zone A", "TEST:TariffZone:B,Zone B"},
+		"networks.txt":       {"network_id,network_name", "TEST:Tariff:1,City"},
+		"route_networks.txt": {"network_id,route_id", "TEST:Tariff:1,TEST:Line:1"},
+		"fare_media.txt":     {"fare_media_id,fare_media_name,fare_media_type", "TEST:SalesOfferPackage:app,App ticket,4"},
+		"fare_products.txt": {
+			"fare_product_id,fare_product_name,fare_media_id,amount,currency",
+			"TEST:PreassignedFareProduct:single,Single,TEST:SalesOfferPackage:app,2.20,EUR",
+			"TEST:PreassignedFareProduct:zonal_TEST:DistanceMatrixElement:AB,Zonal A-B,,3.50,EUR",
+		},
+		"fare_leg_rules.txt": {
+			"leg_group_id,network_id,from_area_id,to_area_id,from_timeframe_group_id,to_timeframe_group_id,fare_product_id,rule_priority",
+			"TEST:PreassignedFareProduct:single,TEST:Tariff:1,TEST:TariffZone:A,TEST:TariffZone:A,,,TEST:PreassignedFareProduct:single,",
+			"TEST:PreassignedFareProduct:zonal,TEST:Tariff:1,TEST:TariffZone:A,TEST:TariffZone:B,,,TEST:PreassignedFareProduct:zonal_TEST:DistanceMatrixElement:AB,",
+		},
+		"fare_transfer_rules.txt": {
+			"from_leg_group_id,to_leg_group_id,transfer_count,duration_limit,duration_limit_type,fare_transfer_type,fare_product_id",
+			"TEST:PreassignedFareProduct:single,TEST:PreassignedFareProduct:single,2,5400,1,0,",
+		},
+	}
+	for name, rows := range expected {
+		if got := table(name); strings.Join(got, "\n") != strings.Join(rows, "\n") {
+			t.Errorf("Unexpected %s:\n%s\nexpected:\n%s", name, strings.Join(got, "\n"), strings.Join(rows, "\n"))
+		}
+	}
+	if _, exists := files["stop_areas.txt"]; exists {
+		t.Error("stop_areas.txt should not be written without fare zone stops")
+	}
+
+	// The rider category and the fare table cells cannot be expressed
+	warned := make(map[string]bool)
+	for _, warning := range conversionResult.Warnings {
+		if warning.Stage == StageFares {
+			warned[warning.EntityID] = true
+		}
+	}
+	if !warned["TEST:FareStructureElement:single"] || !warned["TEST:FareTable:1"] || len(warned) != 2 {
+		t.Errorf("Expected fare warnings for the user profile and the fare table, got %v", conversionResult.Warnings)
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertFaresKeepsExportedStops(t *testing.T) {
+	exporter := NewDefaultGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+	exporter.SetFareProducer(fareProducerFunc(func() *producer.Fares {
+		return &producer.Fares{
+			Areas: []*model.Area{{AreaID: "zone1"}},
+			AreaStops: []*model.AreaStop{
+				{AreaID: "zone1", StopID: "quay1"},
+				{AreaID: "zone1", StopID: "quay-unused"},
+			},
+			Networks: []*model.GtfsNetwork{{NetworkID: "tariff1"}},
+			RouteNetworks: []*model.RouteNetwork{
+				{NetworkID: "tariff1", RouteID: "line1"},
+				{NetworkID: "tariff1", RouteID: "line-unknown"},
+			},
+		}
+	}))
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "route1"}
+	if err := exporter.gtfsRepository.SaveEntity(&model.Stop{StopID: "quay1", StopName: "A"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exporter.convertFares(); err != nil {
+		t.Fatalf("convertFares() failed: %v", err)
+	}
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readGtfsArchive(t, reader)
+	if rows := files["stop_areas.txt"]; len(rows) != 2 || rows[1][1] != "quay1" {
+		t.Errorf("Expected only the exported stop in stop_areas.txt, got %v", rows)
+	}
+	if rows := files["route_networks.txt"]; len(rows) != 2 || rows[1][1] != "route1" {
+		t.Errorf("Expected the route of the converted line in route_networks.txt, got %v", rows)
+	}
+}
+
+// fareProducerFunc adapts a function to producer.FareProducer
+type fareProducerFunc func() *producer.Fares
+
+func (f fareProducerFunc) Produce() *producer.Fares { return f() }
+
+func TestEnhancedGtfsExporter_NoServiceJourneys(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewEnhancedGtfsExporter("TEST", stopAreaRepo)
+
+	// Test with no service journeys
+	err := exporter.convertServicesWithRecovery(context.Background())
+	if err != nil {
+		t.Errorf("convertServicesWithRecovery() should handle empty data: %v", err)
+	}
+
+	result := exporter.GetConversionResult()
+	if len(result.Warnings) == 0 {
+		t.Error("Should have warned about no service journeys")
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertServicesSharesServiceIDs(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+	entities := []interface{}{
+		&model.JourneyPattern{ID: "jp1"},
+		&model.DayType{ID: "dtA", Properties: &model.Properties{PropertyOfDay: []model.PropertyOfDay{{DaysOfWeek: "Weekdays"}}}},
+		&model.DayType{ID: "dtB", Properties: &model.Properties{PropertyOfDay: []model.PropertyOfDay{{DaysOfWeek: "Saturday"}}}},
+		&model.OperatingPeriod{ID: "op1", FromDate: "2024-06-03", ToDate: "2024-06-30"},
+		&model.DayTypeAssignment{ID: "dta1", DayTypeRef: "dtA", OperatingPeriodRef: "op1", IsAvailable: true},
+		&model.DayTypeAssignment{ID: "dta2", DayTypeRef: "dtB", OperatingPeriodRef: "op1", IsAvailable: true},
+	}
+	dayTypesByJourney := map[string][]string{
+		"sj1": {"dtA", "dtB"},
+		"sj2": {"dtB", "dtA"},
+		"sj3": {"dtA"},
+	}
+	for id, refs := range dayTypesByJourney {
+		entities = append(entities, &model.ServiceJourney{
+			ID:                id,
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+			DayTypes:          &model.DayTypes{DayTypeRef: refs},
+		})
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	shared := producer.ServiceIDForDayTypes([]string{"dtA", "dtB"})
+	expected := map[string]string{"sj1": shared, "sj2": shared, "sj3": "dtA"}
+	for tripID, serviceID := range expected {
+		trip := exporter.gtfsRepository.GetTripById(tripID)
+		if trip == nil || trip.ServiceID != serviceID {
+			t.Errorf("Expected trip %s on service %s, got %+v", tripID, serviceID, trip)
+		}
+	}
+
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readGtfsArchive(t, reader)
+	calendarRows := files["calendar.txt"]
+	if len(calendarRows) != 3 {
+		t.Fatalf("Expected header and two shared calendars, got %d rows", len(calendarRows))
+	}
+	if len(files["calendar_dates.txt"]) > 1 {
+		t.Errorf("Expected no calendar dates, got %v", files["calendar_dates.txt"][1:])
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertServicesWithShapes(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+
+	coordinates := [][2]float64{{60.0, 10.0}, {60.0, 10.01}, {60.01, 10.01}}
+	var points []interface{}
+	var passingTimes []model.TimetabledPassingTime
+	for i, c := range coordinates {
+		id := fmt.Sprintf("%d", i+1)
+		entities := []interface{}{
+			&model.Quay{ID: "quay" + id, Centroid: &model.Centroid{Location: &model.Location{Latitude: c[0], Longitude: c[1]}}},
+			&model.ScheduledStopPoint{ID: "ssp" + id, QuayRef: "quay" + id},
+		}
+		for _, entity := range entities {
+			if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+				t.Fatal(err)
+			}
+		}
+		points = append(points, &model.StopPointInJourneyPattern{ID: "spjp" + id, Order: i + 1, ScheduledStopPointRef: "ssp" + id})
+		passingTimes = append(passingTimes, model.TimetabledPassingTime{
+			PointInJourneyPatternRef: "spjp" + id,
+			DepartureTime:            fmt.Sprintf("08:0%d:00", i),
+		})
+	}
+
+	jp := &model.JourneyPattern{
+		ID:               "jp1",
+		PointsInSequence: &model.PointsInSequence{PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: points},
+	}
+	if err := exporter.netexRepository.SaveEntity(jp); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"sj1", "sj2"} {
+		sj := &model.ServiceJourney{
+			ID:                id,
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+			DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
+			PassingTimes:      &model.PassingTimes{TimetabledPassingTime: passingTimes},
+		}
+		if err := exporter.netexRepository.SaveEntity(sj); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	for _, id := range []string{"sj1", "sj2"} {
+		trip := exporter.gtfsRepository.GetTripById(id)
+		if trip == nil || trip.ShapeID != "shape_jp1" {
+			t.Fatalf("Expected trip %s with shape_id shape_jp1, got %+v", id, trip)
+		}
+	}
+
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readGtfsArchive(t, reader)
+
+	shapeRows := files["shapes.txt"]
+	if len(shapeRows) < 4 {
+		t.Fatalf("Expected header and at least 3 shape points, got %d rows", len(shapeRows))
+	}
+	// Shape must be produced once even though two trips share the pattern
+	for _, row := range shapeRows[1:] {
+		if row[0] != "shape_jp1" {
+			t.Errorf("Unexpected shape id %s", row[0])
+		}
+		if row[1] == "0" && row[2] == "0" {
+			t.Error("Shape contains a (0,0) point")
+		}
+	}
+	firstSequence := 0
+	for _, row := range shapeRows[1:] {
+		if row[3] == "1" {
+			firstSequence++
+		}
+	}
+	if firstSequence != 1 {
+		t.Errorf("Expected shape points to be saved once, found %d starting points", firstSequence)
+	}
+
+	stopTimeRows := files["stop_times.txt"]
+	distanceColumn := -1
+	for i, header := range stopTimeRows[0] {
+		if header == "shape_dist_traveled" {
+			distanceColumn = i
+		}
+	}
+	if distanceColumn < 0 {
+		t.Fatal("stop_times.txt has no shape_dist_traveled column")
+	}
+	previous := -1.0
+	for _, row := range stopTimeRows[1:4] {
+		var distance float64
+		if _, err := fmt.Sscanf(row[distanceColumn], "%g", &distance); err != nil {
+			t.Fatal(err)
+		}
+		if distance <= previous {
+			t.Errorf("Expected increasing shape_dist_traveled, got %v after %v", distance, previous)
+		}
+		previous = distance
+	}
+	if previous < 1000 {
+		t.Errorf("Expected last stop to be more than 1 km along the shape, got %v", previous)
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertServicesWithoutCoordinates(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+
+	jp := &model.JourneyPattern{
+		ID: "jp1",
+		PointsInSequence: &model.PointsInSequence{
+			PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: []interface{}{
+				&model.StopPointInJourneyPattern{ID: "spjp1", ScheduledStopPointRef: "unknown1"},
+				&model.StopPointInJourneyPattern{ID: "spjp2", ScheduledStopPointRef: "unknown2"},
+			},
+		},
+	}
+	sj := &model.ServiceJourney{
+		ID:                "sj1",
+		LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+		JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+		DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
+	}
+	for _, entity := range []interface{}{jp, sj} {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	if trip := exporter.gtfsRepository.GetTripById("sj1"); trip == nil || trip.ShapeID != "" {
+		t.Errorf("Expected trip without shape, got %+v", trip)
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertServicesHeadsigns(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+
+	entities := []interface{}{
+		&model.DestinationDisplay{ID: "dd:sandvika-via", FrontText: "Sandvika", Vias: &model.Vias{Via: []model.Via{{DestinationDisplayRef: "dd:lysaker"}}}},
+		&model.DestinationDisplay{ID: "dd:lysaker", FrontText: "Lysaker"},
+		&model.DestinationDisplay{ID: "dd:sandvika", FrontText: "Sandvika"},
+		&model.JourneyPattern{
+			ID: "jp1",
+			PointsInSequence: &model.PointsInSequence{
+				PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: []interface{}{
+					&model.StopPointInJourneyPattern{ID: "spjp1", Order: 1, ScheduledStopPointRef: "ssp1", DestinationDisplayRef: "dd:sandvika-via"},
+					&model.StopPointInJourneyPattern{ID: "spjp2", Order: 2, ScheduledStopPointRef: "ssp2"},
+					&model.StopPointInJourneyPattern{ID: "spjp3", Order: 3, ScheduledStopPointRef: "ssp3", DestinationDisplayRef: "dd:sandvika"},
+					&model.StopPointInJourneyPattern{ID: "spjp4", Order: 4, ScheduledStopPointRef: "ssp4"},
+				},
+			},
+		},
+		&model.ServiceJourney{
+			ID:                "sj1",
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+			DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
+			PassingTimes: &model.PassingTimes{TimetabledPassingTime: []model.TimetabledPassingTime{
+				{PointInJourneyPatternRef: "spjp1", DepartureTime: "08:00:00"},
+				{PointInJourneyPatternRef: "spjp2", DepartureTime: "08:05:00"},
+				{PointInJourneyPatternRef: "spjp3", DepartureTime: "08:10:00"},
+				{PointInJourneyPatternRef: "spjp4", ArrivalTime: "08:15:00"},
+			}},
+		},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	trip := exporter.gtfsRepository.GetTripById("sj1")
+	if trip == nil || trip.TripHeadsign != "Sandvika via Lysaker" {
+		t.Fatalf("Expected trip_headsign 'Sandvika via Lysaker', got %+v", trip)
+	}
+
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopTimeRows := readGtfsArchive(t, reader)["stop_times.txt"]
+	headsignColumn := -1
+	for i, header := range stopTimeRows[0] {
+		if header == "stop_headsign" {
+			headsignColumn = i
+		}
+	}
+	if headsignColumn < 0 {
+		t.Fatal("stop_times.txt has no stop_headsign column")
+	}
+	// The headsign only differs from the trip's once the display changes, and
+	// then carries on to the following stops
+	expected := []string{"", "", "Sandvika", "Sandvika"}
+	if len(stopTimeRows) != len(expected)+1 {
+		t.Fatalf("Expected %d stop times, got %d", len(expected), len(stopTimeRows)-1)
+	}
+	for i, row := range stopTimeRows[1:] {
+		if row[headsignColumn] != expected[i] {
+			t.Errorf("Stop %d: expected stop_headsign %q, got %q", i+1, expected[i], row[headsignColumn])
+		}
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertServicesDirections(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+
+	patterns := map[string][]string{
+		"jp:out":   {"ssp1", "ssp2", "ssp3"},
+		"jp:back":  {"ssp3", "ssp2", "ssp1"},
+		"jp:route": {"ssp3", "ssp2"},
+	}
+	for id, stops := range patterns {
+		var points []interface{}
+		for i, stop := range stops {
+			points = append(points, &model.StopPointInJourneyPattern{ID: id + stop, Order: i + 1, ScheduledStopPointRef: stop})
+		}
+		jp := &model.JourneyPattern{ID: id, PointsInSequence: &model.PointsInSequence{PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: points}}
+		if id == "jp:route" {
+			jp.RouteRef = "route:in"
+		}
+		sj := &model.ServiceJourney{
+			ID:                "sj" + id,
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: id},
+			DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
+		}
+		for _, entity := range []interface{}{jp, sj} {
+			if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := exporter.netexRepository.SaveEntity(&model.Route{ID: "route:in", DirectionType: "inbound"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	expected := map[string]string{"sjjp:out": "0", "sjjp:back": "1", "sjjp:route": "1"}
+	for id, direction := range expected {
+		trip := exporter.gtfsRepository.GetTripById(id)
+		if trip == nil || trip.DirectionID != direction {
+			t.Errorf("Expected trip %s with direction_id %s, got %+v", id, direction, trip)
+		}
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertServicesInterpolatesStopTimes(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+	exporter.SetStopTimeInterpolation(true)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+
+	var points []interface{}
+	for i := 1; i <= 4; i++ {
+		id := fmt.Sprintf("%d", i)
+		points = append(points, &model.StopPointInJourneyPattern{ID: "spjp" + id, Order: i, ScheduledStopPointRef: "ssp" + id})
+	}
+	entities := []interface{}{
+		&model.JourneyPattern{ID: "jp1", PointsInSequence: &model.PointsInSequence{PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: points}},
+		&model.ServiceJourney{
+			ID:                "sj1",
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+			DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
+			PassingTimes: &model.PassingTimes{TimetabledPassingTime: []model.TimetabledPassingTime{
+				{PointInJourneyPatternRef: "spjp1", DepartureTime: "08:00:00"},
+				{PointInJourneyPatternRef: "spjp4", ArrivalTime: "08:30:00"},
+			}},
+		},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readGtfsArchive(t, reader)["stop_times.txt"]
+	if len(rows) != 5 {
+		t.Fatalf("Expected 4 stop times, got %d", len(rows)-1)
+	}
+	columns := make(map[string]int)
+	for i, header := range rows[0] {
+		columns[header] = i
+	}
+	expectedTimepoints := []string{"1", "0", "0", "1"}
+	previous := ""
+	for i, row := range rows[1:] {
+		if row[columns["stop_id"]] != fmt.Sprintf("ssp%d", i+1) {
+			t.Errorf("Stop %d: unexpected stop_id %s", i+1, row[columns["stop_id"]])
+		}
+		if row[columns["timepoint"]] != expectedTimepoints[i] {
+			t.Errorf("Stop %d: expected timepoint %s, got %s", i+1, expectedTimepoints[i], row[columns["timepoint"]])
+		}
+		arrival := row[columns["arrival_time"]]
+		if arrival == "" || arrival <= previous {
+			t.Errorf("Stop %d: expected an arrival after %s, got %q", i+1, previous, arrival)
+		}
+		previous = arrival
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertServicesFallsBackFromInterpolation(t *testing.T) {
+	stopAreaRepo := repository.NewDefaultStopAreaRepository()
+	exporter := NewDefaultGtfsExporter("TEST", stopAreaRepo)
+	exporter.SetStopTimeInterpolation(true)
+
+	exporter.lineIdToGtfsRoute["line1"] = &model.GtfsRoute{RouteID: "line1", RouteShortName: "1", RouteType: 3}
+
+	var points []interface{}
+	for i := 1; i <= 2; i++ {
+		id := fmt.Sprintf("%d", i)
+		points = append(points, &model.StopPointInJourneyPattern{ID: "spjp" + id, Order: i, ScheduledStopPointRef: "ssp" + id})
+	}
+	// The times run backwards, so the interpolated sequence fails validation
+	entities := []interface{}{
+		&model.JourneyPattern{ID: "jp1", PointsInSequence: &model.PointsInSequence{PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: points}},
+		&model.ServiceJourney{
+			ID:                "sj1",
+			LineRef:           model.ServiceJourneyLineRef{Ref: "line1"},
+			JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+			DayTypes:          &model.DayTypes{DayTypeRef: []string{"dt1"}},
+			PassingTimes: &model.PassingTimes{TimetabledPassingTime: []model.TimetabledPassingTime{
+				{PointInJourneyPatternRef: "spjp1", DepartureTime: "08:30:00"},
+				{PointInJourneyPatternRef: "spjp2", ArrivalTime: "08:00:00"},
+			}},
+		},
+	}
+	for _, entity := range entities {
+		if err := exporter.netexRepository.SaveEntity(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := exporter.produceAdvancedStopTimes(entities[1].(*model.ServiceJourney), entities[0].(*model.JourneyPattern), &model.Trip{TripID: "sj1"}); err == nil {
+		t.Fatal("Expected the interpolated stop times to fail validation")
+	}
+
+	if err := exporter.convertServices(); err != nil {
+		t.Fatalf("convertServices() failed: %v", err)
+	}
+
+	reader, err := exporter.gtfsRepository.WriteGtfs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := readGtfsArchive(t, reader)["stop_times.txt"]
+	if len(rows) != 3 {
+		t.Fatalf("Expected the 2 timetabled stop times, got %d", len(rows)-1)
+	}
+}
+
+// readGtfsArchive reads every CSV file of a GTFS zip into rows keyed by file name
+func readGtfsArchive(t *testing.T, reader io.Reader) map[string][][]string {
+	t.Helper()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string][][]string)
+	for _, file := range zipReader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, err := csv.NewReader(rc).ReadAll()
+		_ = rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file.Name, err)
+		}
+		files[file.Name] = rows
+	}
+	return files
+}
+
+const stationTestNetex = `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<dataObjects>
+		<CompositeFrame id="TEST:CompositeFrame:1" version="1">
+			<frames>
+				<ResourceFrame id="TEST:ResourceFrame:1" version="1">
+					<organisations>
+						<Authority id="TEST:Authority:1" version="1">
+							<Name>Test Authority</Name>
+						</Authority>
+					</organisations>
+				</ResourceFrame>
+				<SiteFrame id="TEST:SiteFrame:1" version="1">
+					<stopPlaces>
+						<StopPlace id="TEST:StopPlace:central" version="1">
+							<Name>Central</Name>
+							<Centroid><Location><Longitude>10.75</Longitude><Latitude>59.91</Latitude></Location></Centroid>
+						</StopPlace>
+						<StopPlace id="TEST:StopPlace:bus" version="1">
+							<Name>Central bus terminal</Name>
+							<ParentSiteRef ref="TEST:StopPlace:central"/>
+							<Centroid><Location><Longitude>10.76</Longitude><Latitude>59.92</Latitude></Location></Centroid>
+							<levels>
+								<Level id="TEST:Level:street" version="1"><Name>Street</Name><PublicCode>0</PublicCode></Level>
+								<Level id="TEST:Level:platforms" version="1"><Name>Platforms</Name><PublicCode>-1</PublicCode></Level>
+							</levels>
+							<entrances>
+								<StopPlaceEntrance id="TEST:StopPlaceEntrance:north" version="1">
+									<Name>North entrance</Name>
+									<Centroid><Location><Longitude>10.761</Longitude><Latitude>59.921</Latitude></Location></Centroid>
+									<LevelRef ref="TEST:Level:street"/>
+								</StopPlaceEntrance>
+								<StopPlaceEntrance id="TEST:StopPlaceEntrance:unknown" version="1">
+									<Name>Entrance without location</Name>
+								</StopPlaceEntrance>
+							</entrances>
+							<quays>
+								<Quay id="TEST:Quay:1" version="1">
+									<PublicCode>1</PublicCode>
+									<Centroid><Location><Longitude>10.762</Longitude><Latitude>59.922</Latitude></Location></Centroid>
+									<LevelRef ref="TEST:Level:platforms"/>
+									<boardingPositions>
+										<BoardingPosition id="TEST:BoardingPosition:1A" version="1">
+											<PublicCode>A</PublicCode>
+										</BoardingPosition>
+									</boardingPositions>
+								</Quay>
+							</quays>
+							<pathLinks>
+								<SitePathLink id="TEST:SitePathLink:stairs" version="1">
+									<From><PlaceRef ref="TEST:StopPlaceEntrance:north"/></From>
+									<To><PlaceRef ref="TEST:Quay:1"/></To>
+									<Distance>30</Distance>
+									<Transition>down</Transition>
+									<NumberOfSteps>24</NumberOfSteps>
+									<TransferDuration><DefaultDuration>PT1M</DefaultDuration></TransferDuration>
+								</SitePathLink>
+							</pathLinks>
+						</StopPlace>
+					</stopPlaces>
+					<pathLinks>
+						<SitePathLink id="TEST:SitePathLink:boarding" version="1">
+							<From><PlaceRef ref="TEST:Quay:1"/></From>
+							<To><PlaceRef ref="TEST:BoardingPosition:1A"/></To>
+							<Distance>5</Distance>
+						</SitePathLink>
+						<SitePathLink id="TEST:SitePathLink:elsewhere" version="1">
+							<From><PlaceRef ref="TEST:Quay:1"/></From>
+							<To><PlaceRef ref="TEST:Quay:elsewhere"/></To>
+						</SitePathLink>
+					</pathLinks>
+				</SiteFrame>
+			</frames>
+		</CompositeFrame>
+	</dataObjects>
+</PublicationDelivery>`
+
+func TestEnhancedGtfsExporter_StationHierarchy(t *testing.T) {
+	testCases := []struct {
+		strategy producer.MultimodalStrategy
+		station  string
+	}{
+		{producer.MultimodalChild, "TEST:StopPlace:bus"},
+		{producer.MultimodalParent, "TEST:StopPlace:central"},
+	}
+	for _, tc := range testCases {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			exporter := NewEnhancedGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+			exporter.SetMultimodalStrategy(tc.strategy)
+			reader, conversionResult, err := exporter.ConvertTimetablesToGtfsContext(context.Background(), strings.NewReader(stationTestNetex))
+			if err != nil {
+				t.Fatalf("ConvertTimetablesToGtfsContext() failed: %v", err)
+			}
+			files := readGtfsArchive(t, reader)
+
+			// stop_id -> location_type,parent_station
+			stops := make(map[string]string)
+			header := files["stops.txt"][0]
+			column := make(map[string]int)
+			for i, name := range header {
+				column[name] = i
+			}
+			for _, row := range files["stops.txt"][1:] {
+				stops[row[column["stop_id"]]] = row[column["location_type"]] + "," + row[column["parent_station"]]
+			}
+			expected := map[string]string{
+				tc.station:                     "1,",
+				"TEST:Quay:1":                  "0," + tc.station,
+				"TEST:BoardingPosition:1A":     "4,TEST:Quay:1",
+				"TEST:StopPlaceEntrance:north": "2," + tc.station,
+			}
+			if len(stops) != len(expected) {
+				t.Errorf("Expected %d stops, got %v", len(expected), stops)
+			}
+			for stopID, want := range expected {
+				if stops[stopID] != want {
+					t.Errorf("Expected %s to be %q, got %q", stopID, want, stops[stopID])
+				}
+			}
+
+			warned := false
+			for _, warning := range conversionResult.Warnings {
+				if warning.EntityID == "TEST:StopPlaceEntrance:unknown" {
+					warned = true
+				}
+			}
+			if !warned {
+				t.Errorf("Expected a warning for the entrance without location, got %v", conversionResult.Warnings)
+			}
+		})
+	}
+}
+
+func TestEnhancedGtfsExporter_LevelsAndPathways(t *testing.T) {
+	exporter := NewEnhancedGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+	reader, conversionResult, err := exporter.ConvertTimetablesToGtfsContext(context.Background(), strings.NewReader(stationTestNetex))
+	if err != nil {
+		t.Fatalf("ConvertTimetablesToGtfsContext() failed: %v", err)
+	}
+	files := readGtfsArchive(t, reader)
+
+	table := func(name string) string {
+		var rows []string
+		for _, row := range files[name] {
+			rows = append(rows, strings.Join(row, ","))
+		}
+		return strings.Join(rows, "\n")
+	}
+	expected := map[string]string{
+		"levels.txt": "level_id,level_index,level_name\n" +
+			"TEST:Level:street,0,Street\n" +
+			"TEST:Level:platforms,-1,Platforms",
+		"pathways.txt": "pathway_id,from_stop_id,to_stop_id,pathway_mode,is_bidirectional,length,traversal_time,stair_count,max_slope,min_width,signposted_as,reversed_signposted_as\n" +
+			"TEST:SitePathLink:stairs,TEST:StopPlaceEntrance:north,TEST:Quay:1,2,1,30,60,-24,,,,\n" +
+			"TEST:SitePathLink:boarding,TEST:Quay:1,TEST:BoardingPosition:1A,1,1,5,,,,,,",
+	}
+	for name, want := range expected {
+		if got := table(name); got != want {
+			t.Errorf("Unexpected %s:\n%s\nexpected:\n%s", name, got, want)
+		}
+	}
+
+	levelIDs := make(map[string]string)
+	header := files["stops.txt"][0]
+	column := make(map[string]int)
+	for i, name := range header {
+		column[name] = i
+	}
+	for _, row := range files["stops.txt"][1:] {
+		levelIDs[row[column["stop_id"]]] = row[column["level_id"]]
+	}
+	if levelIDs["TEST:Quay:1"] != "TEST:Level:platforms" || levelIDs["TEST:BoardingPosition:1A"] != "TEST:Level:platforms" ||
+		levelIDs["TEST:StopPlaceEntrance:north"] != "TEST:Level:street" {
+		t.Errorf("Unexpected stop levels %v", levelIDs)
+	}
+
+	warned := false
+	for _, warning := range conversionResult.Warnings {
+		if warning.EntityID == "TEST:SitePathLink:elsewhere" {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("Expected a warning for the path link to a stop outside the feed, got %v", conversionResult.Warnings)
+	}
+}
+
+// brokenPathLinkNetex adds a path link without a To place to the stop place
+// of stationTestNetex
+var brokenPathLinkNetex = strings.Replace(stationTestNetex, `							</pathLinks>`, `								<SitePathLink id="TEST:SitePathLink:broken" version="1">
+									<From><PlaceRef ref="TEST:Quay:1"/></From>
+								</SitePathLink>
+							</pathLinks>`, 1)
+
+func TestEnhancedGtfsExporter_SkipsBrokenPathLink(t *testing.T) {
+	exporter := NewEnhancedGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+	reader, conversionResult, err := exporter.ConvertTimetablesToGtfsContext(context.Background(), strings.NewReader(brokenPathLinkNetex))
+	if err != nil {
+		t.Fatalf("ConvertTimetablesToGtfsContext() failed: %v", err)
+	}
+
+	// The other path link of the stop place is still converted
+	pathwayIDs := make(map[string]bool)
+	for _, row := range readGtfsArchive(t, reader)["pathways.txt"][1:] {
+		pathwayIDs[row[0]] = true
+	}
+	if !pathwayIDs["TEST:SitePathLink:stairs"] || pathwayIDs["TEST:SitePathLink:broken"] {
+		t.Errorf("Expected the stairs pathway without the broken one, got %v", pathwayIDs)
+	}
+
+	warned := false
+	for _, warning := range conversionResult.Warnings {
+		if warning.EntityID == "TEST:SitePathLink:broken" {
+			warned = true
+		}
+	}
+	if !warned {
+		t.Errorf("Expected a warning for the broken path link, got %v", conversionResult.Warnings)
+	}
+}
+
+func TestDefaultGtfsExporter_ConvertPathwaysWithBrokenLink(t *testing.T) {
+	exporter := NewDefaultGtfsExporter("TEST", repository.NewDefaultStopAreaRepository())
+	stopPlace := &model.StopPlace{ID: "sp1", PathLinks: []model.SitePathLink{{ID: "broken"}}}
+
+	if err := exporter.convertPathways([]*model.StopPlace{stopPlace}); err == nil {
+		t.Error("Expected convertPathways() to fail on a path link without ends")
 	}
 }
@@ -0,0 +1,105 @@
+package exporter
+
+import (
+	"context"
+	"io"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/errors"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/output"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
+)
+
+// Conversion stages reported in progress events, in the order they run. They
+// match the stages of ConversionResult errors and warnings.
+const (
+	StageLoading   = "loading"
+	StageAgencies  = "agencies"
+	StageStops     = "stops"
+	StageRoutes    = "routes"
+	StageServices  = "services"
+	StageCalendar  = "calendar"
+	StageTransfers = "transfers"
+	StageFares     = "fares"
+	StageOutput    = "output"
+)
+
+// progressInterval is the number of entities between progress events
+const progressInterval = 100
+
+// ProgressEvent reports the progress of a conversion stage
+type ProgressEvent struct {
+	Stage string
+	// EntityType is the type of the entities counted, e.g. quay or
+	// servicejourney. Loading counts bytes of NeTEx XML.
+	EntityType string
+	// Processed counts the entities handled so far; Total is 0 when unknown
+	Processed int64
+	Total     int64
+}
+
+// ProgressFunc receives progress events. Each stage reports 0 of Total when
+// it starts, every progressInterval entities after that, and Total of Total
+// when it completes.
+type ProgressFunc func(ProgressEvent)
+
+// ProgressToChannel returns a ProgressFunc sending events to ch. A send waits
+// until ch has room or ctx is done, so a slow reader slows the conversion
+// down rather than missing events. ch is not closed.
+func ProgressToChannel(ctx context.Context, ch chan<- ProgressEvent) ProgressFunc {
+	return func(event ProgressEvent) {
+		select {
+		case ch <- event:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// Options configures Convert
+type Options struct {
+	// Codespace is the NeTEx codespace of the dataset, required
+	Codespace string
+	// StopAreaRepository provides stop places from a separate stops archive;
+	// nil uses the stop places of the dataset
+	StopAreaRepository producer.StopAreaRepository
+	// Progress receives progress events; nil disables them
+	Progress ProgressFunc
+	// Configure adjusts the exporter before the conversion, e.g. with
+	// config.Config.ConfigureExporter
+	Configure func(*EnhancedGtfsExporter)
+}
+
+// Convert converts a NeTEx dataset, a ZIP archive or a single XML document,
+// to a GTFS ZIP archive with error recovery. Cancelling ctx stops loading,
+// conversion and writing alike, returning the context's error.
+func Convert(ctx context.Context, input io.Reader, opts Options) (io.Reader, *errors.ConversionResult, error) {
+	return newExporter(opts).ConvertTimetablesToGtfsContext(ctx, input)
+}
+
+// ConvertTo converts like Convert, streaming the GTFS ZIP archive to output
+// instead of building it in memory
+func ConvertTo(ctx context.Context, input io.Reader, output io.Writer, opts Options) (*errors.ConversionResult, error) {
+	return newExporter(opts).ConvertTimetablesToGtfsWriter(ctx, input, output)
+}
+
+// ConvertToSink converts like Convert, writing the GTFS files to sink: a ZIP
+// archive, a directory or an object store. The sink is closed after a
+// successful conversion and aborted otherwise.
+func ConvertToSink(ctx context.Context, input io.Reader, sink output.Sink, opts Options) (*errors.ConversionResult, error) {
+	return newExporter(opts).ConvertTimetablesToGtfsSink(ctx, input, sink)
+}
+
+// newExporter creates the exporter described by opts
+func newExporter(opts Options) *EnhancedGtfsExporter {
+	stopAreaRepository := opts.StopAreaRepository
+	if stopAreaRepository == nil {
+		stopAreaRepository = repository.NewDefaultStopAreaRepository()
+	}
+
+	gtfsExporter := NewEnhancedGtfsExporter(opts.Codespace, stopAreaRepository)
+	if opts.Configure != nil {
+		opts.Configure(gtfsExporter)
+	}
+	gtfsExporter.SetProgressHandler(opts.Progress)
+	return gtfsExporter
+}
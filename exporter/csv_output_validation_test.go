@@ -17,6 +17,8 @@ import (
 type mockStopAreaRepository struct{}
 
 func (m *mockStopAreaRepository) GetQuayById(quayId string) *model.Quay               { return nil }
+func (m *mockStopAreaRepository) GetStopPlaceById(id string) *model.StopPlace         { return nil }
+func (m *mockStopAreaRepository) GetAllSitePathLinks() []*model.SitePathLink          { return nil }
 func (m *mockStopAreaRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace { return nil }
 func (m *mockStopAreaRepository) GetAllQuays() []*model.Quay                          { return nil }
 func (m *mockStopAreaRepository) LoadStopAreas(data []byte) error                     { return nil }
@@ -1,8 +1,13 @@
 package exporter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"io"
+	"sort"
 
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/geometry"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/loader"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
@@ -31,6 +36,8 @@ type GtfsExporter interface {
 	SetServiceCalendarDateProducer(producer producer.ServiceCalendarDateProducer)
 	SetShapeProducer(producer producer.ShapeProducer)
 	SetTransferProducer(producer producer.TransferProducer)
+	SetFareProducer(producer producer.FareProducer)
+	SetPathwaysProducer(producer producer.PathwaysProducer)
 	SetFeedInfoProducer(producer producer.FeedInfoProducer)
 
 	// Get repositories for access to data
@@ -39,6 +46,15 @@ type GtfsExporter interface {
 	GetStopAreaRepository() producer.StopAreaRepository
 }
 
+// FeedPublisher is the organisation publishing the converted feed, as listed
+// in feed_info.txt
+type FeedPublisher struct {
+	Name         string
+	URL          string
+	ContactEmail string
+	ContactURL   string
+}
+
 // DefaultGtfsExporter implements the GtfsExporter interface
 type DefaultGtfsExporter struct {
 	codespace string
@@ -57,10 +73,33 @@ type DefaultGtfsExporter struct {
 	serviceCalendarDateProducer producer.ServiceCalendarDateProducer
 	shapeProducer               producer.ShapeProducer
 	transferProducer            producer.TransferProducer
+	fareProducer                producer.FareProducer
+	pathwaysProducer            producer.PathwaysProducer
 	feedInfoProducer            producer.FeedInfoProducer
 
+	// shapeGenerator computes shape_dist_traveled for stop times
+	shapeGenerator *geometry.ShapeGenerator
+	// headsignFormatter renders trip and stop headsigns, shared with the default trip producer
+	headsignFormatter *producer.HeadsignFormatter
+	// advancedStopTimeProducer, when set, produces the stop times of a whole
+	// journey at once, interpolating stops without passing times
+	advancedStopTimeProducer *producer.AdvancedStopTimeProducer
+	// timeZone and language override the dataset's FrameDefaults when set
+	timeZone string
+	language string
+	// feedPublisher describes the publisher in feed_info.txt
+	feedPublisher FeedPublisher
+	// contentHash is the SHA-256 of the loaded NeTEx data, part of feed_version
+	contentHash string
+	// serviceCalendars and serviceCalendarDates are the produced services,
+	// which set the feed's validity period
+	serviceCalendars     []*model.Calendar
+	serviceCalendarDates []*model.CalendarDate
+
 	// internal cache
-	lineIdToGtfsRoute map[string]*model.GtfsRoute
+	lineIdToGtfsRoute    map[string]*model.GtfsRoute
+	journeyPatternShapes map[string][]*model.Shape
+	convertedServices    map[string]bool
 }
 
 // NewDefaultGtfsExporter creates a new default GTFS exporter
@@ -69,11 +108,14 @@ func NewDefaultGtfsExporter(codespace string, stopAreaRepository producer.StopAr
 	gtfsRepo := repository.NewDefaultGtfsRepository()
 
 	exporter := &DefaultGtfsExporter{
-		codespace:          codespace,
-		netexRepository:    netexRepo,
-		gtfsRepository:     gtfsRepo,
-		stopAreaRepository: stopAreaRepository,
-		lineIdToGtfsRoute:  make(map[string]*model.GtfsRoute),
+		codespace:            codespace,
+		netexRepository:      netexRepo,
+		gtfsRepository:       gtfsRepo,
+		stopAreaRepository:   stopAreaRepository,
+		shapeGenerator:       geometry.NewShapeGenerator(),
+		lineIdToGtfsRoute:    make(map[string]*model.GtfsRoute),
+		journeyPatternShapes: make(map[string][]*model.Shape),
+		convertedServices:    make(map[string]bool),
 	}
 
 	// Initialize default producers
@@ -86,13 +128,20 @@ func NewDefaultGtfsExporter(codespace string, stopAreaRepository producer.StopAr
 func (e *DefaultGtfsExporter) initializeDefaultProducers() {
 	e.agencyProducer = producer.NewDefaultAgencyProducer(e.netexRepository)
 	e.routeProducer = producer.NewDefaultRouteProducer(e.netexRepository, e.gtfsRepository)
-	e.tripProducer = producer.NewDefaultTripProducer(e.netexRepository, e.gtfsRepository)
-	e.stopProducer = producer.NewDefaultStopProducer(e.stopAreaRepository, e.gtfsRepository)
+	e.headsignFormatter = producer.NewHeadsignFormatter(e.netexRepository)
+	tripProducer := producer.NewDefaultTripProducer(e.netexRepository, e.gtfsRepository)
+	tripProducer.SetHeadsignFormatter(e.headsignFormatter)
+	e.tripProducer = tripProducer
+	stopProducer := producer.NewDefaultStopProducer(e.stopAreaRepository, e.gtfsRepository)
+	stopProducer.SetNetexRepository(e.netexRepository)
+	e.stopProducer = stopProducer
 	e.stopTimeProducer = producer.NewDefaultStopTimeProducer(e.netexRepository, e.gtfsRepository)
-	e.serviceCalendarProducer = producer.NewDefaultServiceCalendarProducer(e.gtfsRepository)
-	e.serviceCalendarDateProducer = producer.NewDefaultServiceCalendarDateProducer(e.gtfsRepository)
+	e.serviceCalendarProducer = producer.NewDefaultServiceCalendarProducer(e.netexRepository, e.gtfsRepository)
+	e.serviceCalendarDateProducer = producer.NewDefaultServiceCalendarDateProducer(e.netexRepository, e.gtfsRepository)
 	e.shapeProducer = producer.NewDefaultShapeProducer(e.netexRepository, e.gtfsRepository)
 	e.transferProducer = producer.NewDefaultTransferProducer(e.netexRepository, e.gtfsRepository)
+	e.fareProducer = producer.NewDefaultFareProducer(e.netexRepository)
+	e.pathwaysProducer = producer.NewDefaultPathwaysProducer(e.netexRepository, e.gtfsRepository)
 	e.feedInfoProducer = producer.NewDefaultFeedInfoProducer()
 }
 
@@ -136,7 +185,10 @@ func (e *DefaultGtfsExporter) ConvertStopsToGtfs() (io.Reader, error) {
 // loadNetex loads NeTEx data into the repository
 func (e *DefaultGtfsExporter) loadNetex(netexData io.Reader) error {
 	loaderImpl := loader.NewDefaultNetexDatasetLoader()
-	return loaderImpl.Load(netexData, e.netexRepository)
+	hash := sha256.New()
+	err := loaderImpl.Load(io.TeeReader(netexData, hash), e.netexRepository)
+	e.contentHash = hex.EncodeToString(hash.Sum(nil))
+	return err
 }
 
 // convertNetexToGtfs orchestrates the conversion process
@@ -166,6 +218,11 @@ func (e *DefaultGtfsExporter) convertNetexToGtfs() error {
 		return err
 	}
 
+	// Convert fares
+	if err := e.convertFares(); err != nil {
+		return err
+	}
+
 	// Add feed info
 	if err := e.ensureDefaultAgency(); err != nil {
 		return err
@@ -212,6 +269,7 @@ func (e *DefaultGtfsExporter) convertAgencies() error {
 		if agency == nil {
 			continue // Skip if producer returns nil
 		}
+		e.applyLocale(agency)
 
 		// Validate required fields
 		if agency.AgencyName == "" {
@@ -229,67 +287,223 @@ func (e *DefaultGtfsExporter) convertAgencies() error {
 	return nil
 }
 
-// convertStops converts NeTEx stops to GTFS stops
+// convertStops converts NeTEx stops to GTFS stops: quays with their boarding
+// positions, and the stations the stop producer places the quays in with the
+// entrances of their stop places. The levels and path links of the stop places
+// follow.
 func (e *DefaultGtfsExporter) convertStops(exportOnlyUsedStops bool) error {
-	// Export stations (StopPlace) as parent stops where present, then quays as platforms
-	// Use StopAreaRepository if provided; else fall back to NetexRepository
-	seenStations := make(map[string]bool)
-	quays := e.stopAreaRepository.GetAllQuays()
-	if len(quays) == 0 {
-		quays = e.netexRepository.GetAllQuays()
+	quays := e.stopQuays()
+	// stopQuays holds the quay of each produced stop
+	stopQuays := make([]*model.Quay, 0, len(quays))
+	stops := make([]*model.Stop, 0, len(quays))
+	for _, quay := range quays {
+		stop, err := e.stopProducer.ProduceStopFromQuay(quay)
+		if err != nil {
+			return err
+		}
+		if stop != nil {
+			stopQuays = append(stopQuays, quay)
+			stops = append(stops, stop)
+		}
 	}
-	stopPlaces := make(map[string]*model.StopPlace)
-	// try to populate stop places for parent station emission
-	if sps := e.netexRepository.GetAllStopPlaces(); len(sps) > 0 {
-		for _, sp := range sps {
-			stopPlaces[sp.ID] = sp
+
+	stations := make(map[string]bool)
+	for _, stationID := range stationIDs(stops) {
+		stopPlace := e.stopPlaceByID(stationID)
+		if stopPlace == nil {
+			continue
+		}
+		station, err := e.stopProducer.ProduceStopFromStopPlace(stopPlace)
+		if err != nil {
+			return err
+		}
+		if station != nil {
+			if err := e.gtfsRepository.SaveEntity(station); err != nil {
+				return err
+			}
+			stations[stationID] = true
 		}
 	}
-	for _, quay := range quays {
-		sp := e.stopAreaRepository.GetStopPlaceByQuayId(quay.ID)
-		if sp == nil {
-			// try netex repo if stop area repo cannot resolve
-			for _, cand := range stopPlaces {
-				if cand.Quays != nil {
-					for i := range cand.Quays.Quay {
-						if cand.Quays.Quay[i].ID == quay.ID {
-							sp = cand
-							break
-						}
-					}
-				}
-				if sp != nil {
-					break
+
+	for i, stop := range stops {
+		if !stations[stop.ParentStation] {
+			stop.ParentStation = ""
+		}
+		if err := e.gtfsRepository.SaveEntity(stop); err != nil {
+			return err
+		}
+		quay := stopQuays[i]
+		for j := range quay.BoardingPositions {
+			boardingArea, err := e.stopProducer.ProduceStopFromBoardingPosition(&quay.BoardingPositions[j], quay)
+			if err != nil {
+				return err
+			}
+			if boardingArea != nil {
+				if err := e.gtfsRepository.SaveEntity(boardingArea); err != nil {
+					return err
 				}
 			}
 		}
-		if sp != nil && !seenStations[sp.ID] {
-			station, err := e.stopProducer.ProduceStopFromStopPlace(sp)
+	}
+
+	stopPlaces := e.exportedStopPlaces(stopQuays, stations)
+	for _, stopPlace := range stopPlaces {
+		for i := range stopPlace.Entrances {
+			entrance, err := e.stopProducer.ProduceStopFromEntrance(&stopPlace.Entrances[i], stopPlace)
 			if err != nil {
 				return err
 			}
-			if station != nil {
-				if err := e.gtfsRepository.SaveEntity(station); err != nil {
+			if entrance != nil && stations[entrance.ParentStation] {
+				if err := e.gtfsRepository.SaveEntity(entrance); err != nil {
 					return err
 				}
 			}
-			seenStations[sp.ID] = true
 		}
 	}
-	for _, quay := range quays {
-		stop, err := e.stopProducer.ProduceStopFromQuay(quay)
+	return e.convertPathways(stopPlaces)
+}
+
+// convertPathways converts the levels and path links of the exported stop
+// places, and the path links kept outside them. Pathways between places that
+// are not exported stops are left out; a path link that cannot be converted
+// fails the conversion, as a level does.
+func (e *DefaultGtfsExporter) convertPathways(stopPlaces []*model.StopPlace) error {
+	if e.pathwaysProducer == nil {
+		return nil
+	}
+	levels := make(map[string]bool)
+	pathways := make(map[string]bool)
+	savePathway := func(pathway *model.Pathway) error {
+		if pathway == nil || pathways[pathway.PathwayID] || !e.isPathwayEnd(pathway.FromStopID) || !e.isPathwayEnd(pathway.ToStopID) {
+			return nil
+		}
+		pathways[pathway.PathwayID] = true
+		return e.gtfsRepository.SaveEntity(pathway)
+	}
+
+	for _, stopPlace := range stopPlaces {
+		stopPlaceLevels, err := e.pathwaysProducer.ProduceLevelsFromStopPlace(stopPlace)
 		if err != nil {
 			return err
 		}
-		if stop != nil {
-			if err := e.gtfsRepository.SaveEntity(stop); err != nil {
+		for _, level := range stopPlaceLevels {
+			if !levels[level.LevelID] {
+				levels[level.LevelID] = true
+				if err := e.gtfsRepository.SaveEntity(level); err != nil {
+					return err
+				}
+			}
+		}
+
+		stopPlacePathways, err := e.pathwaysProducer.ProducePathwaysFromStopPlace(stopPlace)
+		if err != nil {
+			return err
+		}
+		for _, pathway := range stopPlacePathways {
+			if err := savePathway(pathway); err != nil {
 				return err
 			}
 		}
 	}
+
+	for _, link := range e.sitePathLinks() {
+		pathway, err := e.pathwaysProducer.ProducePathwayFromLink(link)
+		if err != nil {
+			return err
+		}
+		if err := savePathway(pathway); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// sitePathLinks returns the path links kept outside their stop places in the
+// stop area repository and the NeTEx dataset, sorted by ID
+func (e *DefaultGtfsExporter) sitePathLinks() []*model.SitePathLink {
+	var links []*model.SitePathLink
+	links = append(links, e.stopAreaRepository.GetAllSitePathLinks()...)
+	links = append(links, e.netexRepository.GetAllSitePathLinks()...)
+	sort.Slice(links, func(i, j int) bool { return links[i].ID < links[j].ID })
+	return links
+}
+
+// isPathwayEnd reports whether a stop can be the end of a pathway: an
+// exported stop, entrance, node or boarding area, but no station
+func (e *DefaultGtfsExporter) isPathwayEnd(stopID string) bool {
+	stop := e.gtfsRepository.GetStopById(stopID)
+	return stop != nil && stop.LocationType != "1"
+}
+
+// stopQuays returns the quays to export, from the stop area repository when
+// it has any and else from the NeTEx dataset, sorted by ID
+func (e *DefaultGtfsExporter) stopQuays() []*model.Quay {
+	quays := e.stopAreaRepository.GetAllQuays()
+	if len(quays) == 0 {
+		quays = e.netexRepository.GetAllQuays()
+	}
+	sorted := make([]*model.Quay, 0, len(quays))
+	for _, quay := range quays {
+		if quay != nil {
+			sorted = append(sorted, quay)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	return sorted
+}
+
+// stopPlaceByID returns a stop place of the stop area repository or the NeTEx dataset
+func (e *DefaultGtfsExporter) stopPlaceByID(id string) *model.StopPlace {
+	if stopPlace := e.stopAreaRepository.GetStopPlaceById(id); stopPlace != nil {
+		return stopPlace
+	}
+	return e.netexRepository.GetStopPlaceById(id)
+}
+
+// stopPlaceOfQuay returns the stop place of a quay from the stop area
+// repository or the NeTEx dataset
+func (e *DefaultGtfsExporter) stopPlaceOfQuay(quayID string) *model.StopPlace {
+	if stopPlace := e.stopAreaRepository.GetStopPlaceByQuayId(quayID); stopPlace != nil {
+		return stopPlace
+	}
+	return e.netexRepository.GetStopPlaceByQuayId(quayID)
+}
+
+// exportedStopPlaces returns the stop places whose entrances, levels and path
+// links belong to the feed: the stop places of the quays and the stations
+func (e *DefaultGtfsExporter) exportedStopPlaces(quays []*model.Quay, stations map[string]bool) []*model.StopPlace {
+	seen := make(map[string]bool)
+	var stopPlaces []*model.StopPlace
+	add := func(stopPlace *model.StopPlace) {
+		if stopPlace != nil && !seen[stopPlace.ID] {
+			seen[stopPlace.ID] = true
+			stopPlaces = append(stopPlaces, stopPlace)
+		}
+	}
+	for _, quay := range quays {
+		add(e.stopPlaceOfQuay(quay.ID))
+	}
+	for stationID := range stations {
+		add(e.stopPlaceByID(stationID))
+	}
+	sort.Slice(stopPlaces, func(i, j int) bool { return stopPlaces[i].ID < stopPlaces[j].ID })
+	return stopPlaces
+}
+
+// stationIDs returns the sorted parent stations of stops
+func stationIDs(stops []*model.Stop) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, stop := range stops {
+		if stop.ParentStation != "" && !seen[stop.ParentStation] {
+			seen[stop.ParentStation] = true
+			ids = append(ids, stop.ParentStation)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // convertRoutes converts NeTEx lines to GTFS routes
 func (e *DefaultGtfsExporter) convertRoutes() error {
 	lines := e.netexRepository.GetLines()
@@ -366,36 +580,34 @@ func (e *DefaultGtfsExporter) convertServices() error {
 			continue
 		}
 
-		// Optional shape
+		// Optional shape, produced once per JourneyPattern
+		shapePoints, err := e.shapeForJourneyPattern(jp)
+		if err != nil {
+			return err
+		}
 		var shape *model.Shape
-		if jp != nil && e.shapeProducer != nil {
-			s, err := e.shapeProducer.Produce(jp)
-			if err != nil {
-				return err
-			}
-			shape = s
-			if shape != nil {
-				_ = e.gtfsRepository.SaveEntity(shape)
-			}
+		if len(shapePoints) > 0 {
+			shape = shapePoints[0]
 		}
 
-		// Trip headsign: from DestinationDisplay of first StopPoint in JP if present
-		var headsign string
-		// TODO: Implement headsign extraction from DestinationDisplay when available
-
-		// Build Trip
+		// Build Trip; the headsign comes from the pattern's DestinationDisplay
 		trip, err := e.tripProducer.Produce(producer.TripInput{
 			ServiceJourney:     sj,
-			NetexRoute:         nil,
+			NetexRoute:         e.netexRepository.GetRouteById(jp.RouteRef),
+			JourneyPattern:     jp,
 			GtfsRoute:          gtfsRoute,
 			ShapeID:            shapeID(shape),
-			DestinationDisplay: nil,
+			DestinationDisplay: e.journeyPatternDestinationDisplay(jp),
 		})
+		if errors.Is(err, producer.ErrNoServiceDays) {
+			// Without a calendar the trip would reference a missing service
+			continue
+		}
 		if err != nil {
 			return err
 		}
-		if headsign != "" {
-			trip.TripHeadsign = headsign
+		if trip == nil {
+			continue // Cancelled journey
 		}
 		if shape != nil {
 			trip.ShapeID = shape.ShapeID
@@ -406,70 +618,164 @@ func (e *DefaultGtfsExporter) convertServices() error {
 
 		// Stop times from PassingTimes
 		if sj.PassingTimes != nil {
-			seq := 1
-			for _, pt := range sj.PassingTimes.TimetabledPassingTime {
-				st, err := e.stopTimeProducer.Produce(producer.StopTimeInput{
-					TimetabledPassingTime: &pt,
-					JourneyPattern:        jp,
-					Trip:                  trip,
-					Shape:                 shape,
-					CurrentHeadSign:       headsign,
-				})
-				if err != nil {
-					return err
-				}
-				if st != nil {
-					st.StopSequence = seq
-					seq++
-					if err := e.gtfsRepository.SaveEntity(st); err != nil {
-						return err
-					}
+			var stopTimes []*model.StopTime
+			// Interpolation does not apply to the time windows of flexible stops
+			if e.advancedStopTimeProducer != nil && !e.hasFlexibleStops(sj) {
+				// A failed interpolation falls back to the timetabled passing times
+				if stopTimes, err = e.produceAdvancedStopTimes(sj, jp, trip); err != nil {
+					stopTimes = nil
 				}
 			}
-		}
-
-		// Service calendar and dates
-		if e.serviceCalendarProducer != nil {
-			// Simplified: serviceID equals ServiceJourney ID
-			serviceID := sj.ID
-			var dayTypes []*model.DayType
-			if sj.DayTypes != nil {
-				for _, id := range sj.DayTypes.DayTypeRef {
-					dayTypes = append(dayTypes, e.netexRepository.GetDayTypeById(id))
+			if stopTimes == nil {
+				if stopTimes, err = e.producePassingTimeStopTimes(sj, jp, trip, shape); err != nil {
+					return err
 				}
 			}
-			cal, err := e.serviceCalendarProducer.Produce(serviceID, dayTypes)
-			if err != nil {
-				return err
-			}
-			if cal != nil {
-				if err := e.gtfsRepository.SaveEntity(cal); err != nil {
+			e.assignShapeDistances(stopTimes, shapePoints)
+			for _, st := range stopTimes {
+				if err := e.gtfsRepository.SaveEntity(st); err != nil {
 					return err
 				}
 			}
 		}
-		if e.serviceCalendarDateProducer != nil && sj.DayTypes != nil {
-			serviceID := sj.ID
-			var assignments []*model.DayTypeAssignment
-			for _, id := range sj.DayTypes.DayTypeRef {
-				dt := e.netexRepository.GetDayTypeById(id)
-				if dt != nil {
-					as := e.netexRepository.GetDayTypeAssignmentsByDayType(dt)
-					assignments = append(assignments, as...)
-				}
-			}
-			cds, err := e.serviceCalendarDateProducer.Produce(serviceID, assignments)
-			if err != nil {
-				return err
+
+		// Service calendar and dates, converted once per shared service
+		if _, _, err := e.convertTripService(trip.ServiceID, sj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// producePassingTimeStopTimes produces one stop time per timetabled passing
+// time of a journey, without interpolating missing times
+func (e *DefaultGtfsExporter) producePassingTimeStopTimes(sj *model.ServiceJourney, jp *model.JourneyPattern, trip *model.Trip, shape *model.Shape) ([]*model.StopTime, error) {
+	headsigns := e.stopHeadsigns(sj, trip.TripHeadsign)
+	var stopTimes []*model.StopTime
+	seq := 1
+	for i := range sj.PassingTimes.TimetabledPassingTime {
+		st, err := e.stopTimeProducer.Produce(producer.StopTimeInput{
+			TimetabledPassingTime: &sj.PassingTimes.TimetabledPassingTime[i],
+			JourneyPattern:        jp,
+			Trip:                  trip,
+			Shape:                 shape,
+			CurrentHeadSign:       headsigns[i],
+		})
+		if err != nil {
+			return nil, err
+		}
+		// Flexible stop places are only converted to GTFS-Flex locations by
+		// the EnhancedGtfsExporter; here their stop times would reference
+		// missing locations
+		if st == nil || st.LocationID != "" || st.LocationGroupID != "" {
+			continue
+		}
+		st.StopSequence = seq
+		seq++
+		stopTimes = append(stopTimes, st)
+	}
+	return stopTimes, nil
+}
+
+// hasFlexibleStops reports whether a journey has passing times with a time
+// window or at a flexible stop place
+func (e *DefaultGtfsExporter) hasFlexibleStops(sj *model.ServiceJourney) bool {
+	for _, passingTime := range sj.PassingTimes.TimetabledPassingTime {
+		if passingTime.EarliestDepartureTime != "" || passingTime.LatestArrivalTime != "" {
+			return true
+		}
+		sspRef := e.netexRepository.GetScheduledStopPointRefByPointInJourneyPatternRef(passingTime.PointInJourneyPatternRef)
+		if sspRef != "" && e.netexRepository.GetFlexibleStopPlaceByScheduledStopPointId(sspRef) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// convertTripService produces the calendar of a trip's service from the
+// journey's day types, or else from the operating days of its dated journeys
+func (e *DefaultGtfsExporter) convertTripService(serviceID string, sj *model.ServiceJourney) (*model.Calendar, []*model.CalendarDate, error) {
+	if sj.DayTypes != nil && producer.ServiceIDForDayTypes(sj.DayTypes.DayTypeRef) != "" {
+		return e.convertServiceCalendar(serviceID, sj.DayTypes.DayTypeRef)
+	}
+	return e.convertDatedServiceCalendar(serviceID, sj.ID)
+}
+
+// convertServiceCalendar produces the calendar and calendar dates of a service
+// from its day types. A service shared by several trips is converted only once.
+func (e *DefaultGtfsExporter) convertServiceCalendar(serviceID string, dayTypeRefs []string) (*model.Calendar, []*model.CalendarDate, error) {
+	if e.convertedServices[serviceID] {
+		return nil, nil, nil
+	}
+	e.convertedServices[serviceID] = true
+
+	var dayTypes []*model.DayType
+	var assignments []*model.DayTypeAssignment
+	for _, id := range dayTypeRefs {
+		dayType := e.netexRepository.GetDayTypeById(id)
+		if dayType == nil {
+			continue
+		}
+		dayTypes = append(dayTypes, dayType)
+		assignments = append(assignments, e.netexRepository.GetDayTypeAssignmentsByDayType(dayType)...)
+	}
+
+	var calendar *model.Calendar
+	if e.serviceCalendarProducer != nil {
+		cal, err := e.serviceCalendarProducer.Produce(serviceID, dayTypes)
+		if err != nil {
+			return nil, nil, err
+		}
+		if cal != nil {
+			if err := e.gtfsRepository.SaveEntity(cal); err != nil {
+				return nil, nil, err
 			}
-			for _, cd := range cds {
-				if err := e.gtfsRepository.SaveEntity(cd); err != nil {
-					return err
-				}
+			calendar = cal
+			e.serviceCalendars = append(e.serviceCalendars, cal)
+		}
+	}
+
+	var calendarDates []*model.CalendarDate
+	if e.serviceCalendarDateProducer != nil {
+		cds, err := e.serviceCalendarDateProducer.Produce(serviceID, assignments)
+		if err != nil {
+			return calendar, nil, err
+		}
+		for _, cd := range cds {
+			if err := e.gtfsRepository.SaveEntity(cd); err != nil {
+				return calendar, calendarDates, err
 			}
+			calendarDates = append(calendarDates, cd)
 		}
+		e.serviceCalendarDates = append(e.serviceCalendarDates, calendarDates...)
 	}
-	return nil
+
+	return calendar, calendarDates, nil
+}
+
+// convertDatedServiceCalendar produces the calendar and calendar dates of a
+// service running on the operating days of a journey's dated service journeys
+func (e *DefaultGtfsExporter) convertDatedServiceCalendar(serviceID, serviceJourneyID string) (*model.Calendar, []*model.CalendarDate, error) {
+	if e.convertedServices[serviceID] {
+		return nil, nil, nil
+	}
+	e.convertedServices[serviceID] = true
+
+	calendar, calendarDates := producer.DatedServiceCalendar(e.netexRepository, serviceID, serviceJourneyID)
+	if calendar != nil {
+		if err := e.gtfsRepository.SaveEntity(calendar); err != nil {
+			return nil, nil, err
+		}
+		e.serviceCalendars = append(e.serviceCalendars, calendar)
+	}
+	for i, cd := range calendarDates {
+		if err := e.gtfsRepository.SaveEntity(cd); err != nil {
+			return calendar, calendarDates[:i], err
+		}
+	}
+	e.serviceCalendarDates = append(e.serviceCalendarDates, calendarDates...)
+
+	return calendar, calendarDates, nil
 }
 
 // convertTransfers converts NeTEx interchanges to GTFS transfers
@@ -479,7 +785,7 @@ func (e *DefaultGtfsExporter) convertTransfers() error {
 	for _, interchange := range interchanges {
 		transfer, err := e.transferProducer.Produce(interchange)
 		if err != nil {
-			return err
+			return ConversionError{Stage: "transfers", EntityID: interchange.ID, Err: err}
 		}
 		if transfer != nil {
 			if err := e.gtfsRepository.SaveEntity(transfer); err != nil {
@@ -491,10 +797,62 @@ func (e *DefaultGtfsExporter) convertTransfers() error {
 	return nil
 }
 
+// convertFares converts NeTEx fare frames to GTFS Fares v2
+func (e *DefaultGtfsExporter) convertFares() error {
+	if e.fareProducer == nil {
+		return nil
+	}
+	for _, entity := range e.fareEntities(e.fareProducer.Produce()) {
+		if err := e.gtfsRepository.SaveEntity(entity); err != nil {
+			return ConversionError{Stage: "fares", Err: err}
+		}
+	}
+	return nil
+}
+
+// fareEntities returns the GTFS Fares v2 entities to save, leaving out the
+// stop areas of stops that were not exported and assigning the networks to
+// the routes of their lines
+func (e *DefaultGtfsExporter) fareEntities(fares *producer.Fares) []interface{} {
+	var entities []interface{}
+	if fares == nil {
+		return entities
+	}
+	for _, area := range fares.Areas {
+		entities = append(entities, area)
+	}
+	for _, areaStop := range fares.AreaStops {
+		if e.gtfsRepository.GetStopById(areaStop.StopID) != nil {
+			entities = append(entities, areaStop)
+		}
+	}
+	for _, network := range fares.Networks {
+		entities = append(entities, network)
+	}
+	for _, routeNetwork := range fares.RouteNetworks {
+		if route := e.lineIdToGtfsRoute[routeNetwork.RouteID]; route != nil {
+			entities = append(entities, &model.RouteNetwork{NetworkID: routeNetwork.NetworkID, RouteID: route.RouteID})
+		}
+	}
+	for _, media := range fares.FareMedia {
+		entities = append(entities, media)
+	}
+	for _, product := range fares.FareProducts {
+		entities = append(entities, product)
+	}
+	for _, rule := range fares.FareLegRules {
+		entities = append(entities, rule)
+	}
+	for _, rule := range fares.FareTransferRules {
+		entities = append(entities, rule)
+	}
+	return entities
+}
+
 // addFeedInfo adds feed information to the GTFS dataset
 func (e *DefaultGtfsExporter) addFeedInfo() error {
 	if e.feedInfoProducer != nil {
-		feedInfo, err := e.feedInfoProducer.ProduceFeedInfo()
+		feedInfo, err := e.feedInfoProducer.ProduceFeedInfo(e.feedInfoInput())
 		if err != nil {
 			return err
 		}
@@ -505,6 +863,248 @@ func (e *DefaultGtfsExporter) addFeedInfo() error {
 	return nil
 }
 
+// feedInfoInput collects the publisher, publication and services feed info is produced from
+func (e *DefaultGtfsExporter) feedInfoInput() producer.FeedInfoInput {
+	return producer.FeedInfoInput{
+		PublisherName:        e.feedPublisher.Name,
+		PublisherURL:         e.feedPublisher.URL,
+		ContactEmail:         e.feedPublisher.ContactEmail,
+		ContactURL:           e.feedPublisher.ContactURL,
+		Language:             e.resolvedLanguage(),
+		PublicationTimestamp: e.netexRepository.GetPublicationTimestamp(),
+		ContentHash:          e.contentHash,
+		Calendars:            e.serviceCalendars,
+		CalendarDates:        e.serviceCalendarDates,
+	}
+}
+
+// shapeForJourneyPattern returns the shape points for a journey pattern, producing and
+// saving them the first time the pattern is seen
+func (e *DefaultGtfsExporter) shapeForJourneyPattern(jp *model.JourneyPattern) ([]*model.Shape, error) {
+	if jp == nil || e.shapeProducer == nil {
+		return nil, nil
+	}
+	if points, ok := e.journeyPatternShapes[jp.ID]; ok {
+		return points, nil
+	}
+
+	points, err := e.shapeProducer.Produce(jp)
+	if err != nil {
+		return nil, ConversionError{Stage: "shapes", EntityID: jp.ID, Err: err}
+	}
+	for _, point := range points {
+		if err := e.gtfsRepository.SaveEntity(point); err != nil {
+			return nil, ConversionError{Stage: "shapes", EntityID: jp.ID, Err: err}
+		}
+	}
+	e.journeyPatternShapes[jp.ID] = points
+	return points, nil
+}
+
+// assignShapeDistances fills shape_dist_traveled for a trip's stop times by projecting each
+// stop onto the trip's shape. Stop times are left untouched when any stop cannot be located.
+func (e *DefaultGtfsExporter) assignShapeDistances(stopTimes []*model.StopTime, shapePoints []*model.Shape) {
+	if len(shapePoints) < 2 || len(stopTimes) == 0 {
+		return
+	}
+
+	locations := make([]geometry.Point, len(stopTimes))
+	for i, st := range stopTimes {
+		location, ok := e.stopLocation(st.StopID)
+		if !ok {
+			return
+		}
+		locations[i] = location
+	}
+
+	distance := 0.0
+	for i, st := range stopTimes {
+		distance = e.shapeGenerator.CalculateShapeDistanceForStopTime(locations[i], shapePoints, distance)
+		st.ShapeDistTraveled = distance
+	}
+}
+
+// stopLocation resolves the coordinates of a GTFS stop, falling back to the NeTEx quay
+func (e *DefaultGtfsExporter) stopLocation(stopID string) (geometry.Point, bool) {
+	if stop := e.gtfsRepository.GetStopById(stopID); stop != nil && (stop.StopLat != 0 || stop.StopLon != 0) {
+		return geometry.Point{Lat: stop.StopLat, Lon: stop.StopLon}, true
+	}
+	if quay := e.netexRepository.GetQuayById(stopID); quay != nil && quay.Centroid != nil && quay.Centroid.Location != nil {
+		if quay.Centroid.Location.Latitude != 0 || quay.Centroid.Location.Longitude != 0 {
+			return geometry.Point{Lat: quay.Centroid.Location.Latitude, Lon: quay.Centroid.Location.Longitude}, true
+		}
+	}
+	return geometry.Point{}, false
+}
+
+// journeyPatternDestinationDisplay returns the DestinationDisplay of a journey
+// pattern, falling back to the one of its first stop point
+func (e *DefaultGtfsExporter) journeyPatternDestinationDisplay(jp *model.JourneyPattern) *model.DestinationDisplay {
+	if jp == nil {
+		return nil
+	}
+	if jp.DestinationDisplayRef != "" {
+		if display := e.netexRepository.GetDestinationDisplayById(jp.DestinationDisplayRef); display != nil {
+			return display
+		}
+	}
+	if jp.PointsInSequence != nil {
+		for _, point := range jp.PointsInSequence.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern {
+			if stopPoint, ok := point.(*model.StopPointInJourneyPattern); ok {
+				if stopPoint.DestinationDisplayRef == "" {
+					return nil
+				}
+				return e.netexRepository.GetDestinationDisplayById(stopPoint.DestinationDisplayRef)
+			}
+		}
+	}
+	return nil
+}
+
+// stopHeadsigns returns the stop_headsign of each passing time of a journey. A
+// stop point's DestinationDisplay stays in effect until the next one changes it,
+// and is only emitted where it differs from the trip headsign.
+func (e *DefaultGtfsExporter) stopHeadsigns(sj *model.ServiceJourney, tripHeadsign string) []string {
+	if sj.PassingTimes == nil {
+		return nil
+	}
+	headsigns := make([]string, len(sj.PassingTimes.TimetabledPassingTime))
+	current := tripHeadsign
+	for i, pt := range sj.PassingTimes.TimetabledPassingTime {
+		if headsign := e.stopPointHeadsign(e.netexRepository.GetStopPointInJourneyPatternById(pt.PointInJourneyPatternRef)); headsign != "" {
+			current = headsign
+		}
+		if current != tripHeadsign {
+			headsigns[i] = current
+		}
+	}
+	return headsigns
+}
+
+// patternHeadsigns returns the stop_headsign at each stop point of a journey
+// pattern, keyed by stop point ID, following the same rules as stopHeadsigns
+func (e *DefaultGtfsExporter) patternHeadsigns(jp *model.JourneyPattern, tripHeadsign string) map[string]string {
+	headsigns := make(map[string]string)
+	if jp == nil || jp.PointsInSequence == nil {
+		return headsigns
+	}
+	current := tripHeadsign
+	for _, point := range jp.PointsInSequence.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern {
+		stopPoint, ok := point.(*model.StopPointInJourneyPattern)
+		if !ok {
+			continue
+		}
+		if headsign := e.stopPointHeadsign(stopPoint); headsign != "" {
+			current = headsign
+		}
+		if current != tripHeadsign {
+			headsigns[stopPoint.ID] = current
+		}
+	}
+	return headsigns
+}
+
+// stopPointHeadsign returns the headsign of a stop point's DestinationDisplay, or ""
+func (e *DefaultGtfsExporter) stopPointHeadsign(stopPoint *model.StopPointInJourneyPattern) string {
+	if stopPoint == nil || stopPoint.DestinationDisplayRef == "" {
+		return ""
+	}
+	return e.headsignFormatter.Format(e.netexRepository.GetDestinationDisplayById(stopPoint.DestinationDisplayRef))
+}
+
+// produceAdvancedStopTimes produces the stop times of every stop of a journey
+// pattern. Stops without a passing time get interpolated times with timepoint=0,
+// and the sequence is validated before it is returned.
+func (e *DefaultGtfsExporter) produceAdvancedStopTimes(sj *model.ServiceJourney, jp *model.JourneyPattern, trip *model.Trip) ([]*model.StopTime, error) {
+	stopTimes, err := e.advancedStopTimeProducer.ProduceAdvanced(producer.TripStopTimeInput{
+		ServiceJourney: sj,
+		Trip:           trip,
+		StopHeadsigns:  e.patternHeadsigns(jp, trip.TripHeadsign),
+	})
+	if err != nil {
+		return nil, ConversionError{Stage: "stoptimes", EntityID: sj.ID, Err: err}
+	}
+	if err := e.advancedStopTimeProducer.ValidateStopTimeSequence(stopTimes); err != nil {
+		return nil, ConversionError{Stage: "stoptimes", EntityID: sj.ID, Err: err}
+	}
+	return stopTimes, nil
+}
+
+// SetStopTimeInterpolation switches between producing stop times per passing
+// time (the default) and per journey with the AdvancedStopTimeProducer, which
+// fills in stops that have no passing time
+func (e *DefaultGtfsExporter) SetStopTimeInterpolation(enabled bool) {
+	if !enabled {
+		e.advancedStopTimeProducer = nil
+		return
+	}
+	if e.advancedStopTimeProducer == nil {
+		e.advancedStopTimeProducer = producer.NewAdvancedStopTimeProducer(e.netexRepository, e.gtfsRepository)
+	}
+}
+
+// SetAdvancedStopTimeProducer sets the producer used when stop time
+// interpolation is enabled; nil disables interpolation
+func (e *DefaultGtfsExporter) SetAdvancedStopTimeProducer(p *producer.AdvancedStopTimeProducer) {
+	e.advancedStopTimeProducer = p
+}
+
+// SetTimeZone sets agency_timezone, overriding the TimeZone of the dataset's
+// FrameDefaults; an empty value restores the dataset's
+func (e *DefaultGtfsExporter) SetTimeZone(timeZone string) {
+	e.timeZone = timeZone
+}
+
+// SetLanguage sets agency_lang and feed_lang, overriding the DefaultLanguage
+// of the dataset's FrameDefaults; an empty value restores the dataset's
+func (e *DefaultGtfsExporter) SetLanguage(language string) {
+	e.language = language
+}
+
+// SetFeedPublisher sets the publisher and contact details of feed_info.txt
+func (e *DefaultGtfsExporter) SetFeedPublisher(publisher FeedPublisher) {
+	e.feedPublisher = publisher
+}
+
+// SetShapeGenerator replaces the generator that builds shapes and computes
+// shape_dist_traveled, e.g. to change its simplification tolerance
+func (e *DefaultGtfsExporter) SetShapeGenerator(shapeGenerator *geometry.ShapeGenerator) {
+	e.shapeGenerator = shapeGenerator
+	if shapeProducer, ok := e.shapeProducer.(*producer.DefaultShapeProducer); ok {
+		shapeProducer.SetShapeGenerator(shapeGenerator)
+	}
+}
+
+// SetViaFormat sets how vias are rendered in trip and stop headsigns, e.g.
+// "{destination} via {vias}"; an empty format leaves vias out
+func (e *DefaultGtfsExporter) SetViaFormat(format string) {
+	e.headsignFormatter.SetViaFormat(format)
+}
+
+// SetZonePolicy sets how the default stop producer chooses the zone_id of a
+// stop in several tariff zones
+func (e *DefaultGtfsExporter) SetZonePolicy(policy producer.ZonePolicy) {
+	if stopProducer, ok := e.stopProducer.(*producer.DefaultStopProducer); ok {
+		stopProducer.SetZonePolicy(policy)
+	}
+}
+
+// SetMultimodalStrategy sets whether the default stop producer makes stop
+// places or their multimodal parents the stations of quays
+func (e *DefaultGtfsExporter) SetMultimodalStrategy(strategy producer.MultimodalStrategy) {
+	if stopProducer, ok := e.stopProducer.(*producer.DefaultStopProducer); ok {
+		stopProducer.SetMultimodalStrategy(strategy)
+	}
+}
+
+// SetPathwayHeuristics sets whether the default pathways producer infers
+// levels and pathways of stop places without NeTEx levels and path links
+func (e *DefaultGtfsExporter) SetPathwayHeuristics(enabled bool) {
+	if pathwaysProducer, ok := e.pathwaysProducer.(*producer.DefaultPathwaysProducer); ok {
+		pathwaysProducer.SetHeuristicFallback(enabled)
+	}
+}
+
 func shapeID(s *model.Shape) string {
 	if s == nil {
 		return ""
@@ -518,18 +1118,47 @@ func (e *DefaultGtfsExporter) ensureDefaultAgency() error {
 	if e.gtfsRepository.GetDefaultAgency() != nil {
 		return nil
 	}
-	tz := e.netexRepository.GetTimeZone()
-	if tz == "" {
-		tz = defaultTimezone
-	}
 	agency := &model.Agency{
 		AgencyID:       "default",
 		AgencyName:     "Default Agency",
-		AgencyTimezone: tz,
+		AgencyTimezone: e.resolvedTimeZone(),
+		AgencyLang:     e.resolvedLanguage(),
 	}
 	return e.gtfsRepository.SaveEntity(agency)
 }
 
+// resolvedTimeZone returns the timezone override, else the dataset's default
+// timezone, else UTC
+func (e *DefaultGtfsExporter) resolvedTimeZone() string {
+	if e.timeZone != "" {
+		return e.timeZone
+	}
+	if tz := e.netexRepository.GetTimeZone(); tz != "" {
+		return tz
+	}
+	return defaultTimezone
+}
+
+// resolvedLanguage returns the language override, else the dataset's default
+// language, which may be empty
+func (e *DefaultGtfsExporter) resolvedLanguage() string {
+	if e.language != "" {
+		return e.language
+	}
+	return e.netexRepository.GetDefaultLanguage()
+}
+
+// applyLocale sets the timezone and language of a produced agency: overrides
+// replace the producer's values and the dataset's language fills in a missing one
+func (e *DefaultGtfsExporter) applyLocale(agency *model.Agency) {
+	if e.timeZone != "" {
+		agency.AgencyTimezone = e.timeZone
+	}
+	if e.language != "" || agency.AgencyLang == "" {
+		agency.AgencyLang = e.resolvedLanguage()
+	}
+}
+
 // Setter methods for extension points
 func (e *DefaultGtfsExporter) SetAgencyProducer(producer producer.AgencyProducer) {
 	e.agencyProducer = producer
@@ -567,6 +1196,14 @@ func (e *DefaultGtfsExporter) SetTransferProducer(producer producer.TransferProd
 	e.transferProducer = producer
 }
 
+func (e *DefaultGtfsExporter) SetFareProducer(producer producer.FareProducer) {
+	e.fareProducer = producer
+}
+
+func (e *DefaultGtfsExporter) SetPathwaysProducer(producer producer.PathwaysProducer) {
+	e.pathwaysProducer = producer
+}
+
 func (e *DefaultGtfsExporter) SetFeedInfoProducer(producer producer.FeedInfoProducer) {
 	e.feedInfoProducer = producer
 }
@@ -1,12 +1,21 @@
 package exporter
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
 	"fmt"
 	"io"
+	"sort"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/errors"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/geometry"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/loader"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/output"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
 )
@@ -19,6 +28,24 @@ type EnhancedGtfsExporter struct {
 	continueOnError     bool
 	maxErrorsPerEntity  int
 	errorCountsByEntity map[string]int
+	// memoryLimitMB caps the memory of the loader and repositories; 0 keeps
+	// their defaults
+	memoryLimitMB int
+	// progress receives progress events; nil disables them
+	progress ProgressFunc
+
+	// first journey of each service referenced by converted trips, whose day
+	// types or dated journeys give the service dates
+	serviceJourneys map[string]*model.ServiceJourney
+
+	// flexibleServiceProducer produces the GTFS-Flex locations and booking rules
+	flexibleServiceProducer *producer.EuropeanFlexibleServiceProducer
+	// IDs of the produced GTFS-Flex locations and location groups
+	flexLocationIDs map[string]bool
+	// flexible lines with booking arrangements by GTFS route ID
+	flexibleLines map[string]*model.Line
+	// booking rule IDs by the ID of the element owning the booking arrangements
+	bookingRuleIDs map[string]string
 }
 
 // NewEnhancedGtfsExporter creates a new enhanced GTFS exporter with error recovery
@@ -28,11 +55,14 @@ func NewEnhancedGtfsExporter(codespace string, stopAreaRepository producer.StopA
 	gtfsRepo := repository.NewOptimizedGtfsRepository()
 
 	base := &DefaultGtfsExporter{
-		codespace:          codespace,
-		netexRepository:    netexRepo,
-		gtfsRepository:     gtfsRepo,
-		stopAreaRepository: stopAreaRepository,
-		lineIdToGtfsRoute:  make(map[string]*model.GtfsRoute),
+		codespace:            codespace,
+		netexRepository:      netexRepo,
+		gtfsRepository:       gtfsRepo,
+		stopAreaRepository:   stopAreaRepository,
+		shapeGenerator:       geometry.NewShapeGenerator(),
+		lineIdToGtfsRoute:    make(map[string]*model.GtfsRoute),
+		journeyPatternShapes: make(map[string][]*model.Shape),
+		convertedServices:    make(map[string]bool),
 	}
 	base.initializeDefaultProducers()
 
@@ -45,6 +75,12 @@ func NewEnhancedGtfsExporter(codespace string, stopAreaRepository producer.StopA
 		continueOnError:     true,
 		maxErrorsPerEntity:  10,
 		errorCountsByEntity: make(map[string]int),
+		serviceJourneys:     make(map[string]*model.ServiceJourney),
+
+		flexibleServiceProducer: producer.NewEuropeanFlexibleServiceProducer(netexRepo, gtfsRepo),
+		flexLocationIDs:         make(map[string]bool),
+		flexibleLines:           make(map[string]*model.Line),
+		bookingRuleIDs:          make(map[string]string),
 	}
 
 	return enhanced
@@ -60,6 +96,27 @@ func (e *EnhancedGtfsExporter) SetMaxErrorsPerEntity(max int) {
 	e.maxErrorsPerEntity = max
 }
 
+// SetMemoryLimit sets the memory limit, in MB, of the streaming loader and of
+// the optimized repositories
+func (e *EnhancedGtfsExporter) SetMemoryLimit(limitMB int) {
+	e.memoryLimitMB = limitMB
+	if limitMB <= 0 {
+		return
+	}
+	if netexRepo, ok := e.netexRepository.(*repository.OptimizedNetexRepository); ok {
+		netexRepo.SetMemoryLimit(uint64(limitMB))
+	}
+	if gtfsRepo, ok := e.gtfsRepository.(*repository.OptimizedGtfsRepository); ok {
+		gtfsRepo.SetMemoryLimit(uint64(limitMB))
+	}
+}
+
+// SetProgressHandler sets the function receiving progress events; nil
+// disables them
+func (e *EnhancedGtfsExporter) SetProgressHandler(progress ProgressFunc) {
+	e.progress = progress
+}
+
 // GetConversionResult returns the detailed conversion result
 func (e *EnhancedGtfsExporter) GetConversionResult() *errors.ConversionResult {
 	return e.conversionResult
@@ -67,6 +124,57 @@ func (e *EnhancedGtfsExporter) GetConversionResult() *errors.ConversionResult {
 
 // ConvertTimetablesToGtfsWithRecovery converts with error recovery
 func (e *EnhancedGtfsExporter) ConvertTimetablesToGtfsWithRecovery(netexData io.Reader) (io.Reader, *errors.ConversionResult, error) {
+	return e.ConvertTimetablesToGtfsContext(context.Background(), netexData)
+}
+
+// ConvertTimetablesToGtfsContext converts with error recovery, stopping with
+// the context's error when it is cancelled. Cancellation ends the conversion
+// even when continuing on errors.
+func (e *EnhancedGtfsExporter) ConvertTimetablesToGtfsContext(ctx context.Context, netexData io.Reader) (io.Reader, *errors.ConversionResult, error) {
+	if err := e.convertTimetables(ctx, netexData); err != nil {
+		return nil, e.conversionResult, err
+	}
+
+	// Write GTFS archive
+	result, err := e.writeGtfs(ctx)
+	if err := e.cancelled(ctx, StageOutput); err != nil {
+		return nil, e.conversionResult, err
+	}
+	e.conversionResult.Finalize()
+
+	if err != nil {
+		e.conversionResult.AddError("output", "gtfs", "archive", err, false)
+		if !e.continueOnError {
+			return nil, e.conversionResult, err
+		}
+	}
+
+	// Return result even with non-fatal errors if continueOnError is true
+	return result, e.conversionResult, nil
+}
+
+// ConvertTimetablesToGtfsWriter converts with error recovery like
+// ConvertTimetablesToGtfsContext, streaming the GTFS archive to w instead of
+// returning it. Errors writing the archive are returned whether or not
+// conversion continues on errors, as w then holds a truncated archive.
+func (e *EnhancedGtfsExporter) ConvertTimetablesToGtfsWriter(ctx context.Context, netexData io.Reader, w io.Writer) (*errors.ConversionResult, error) {
+	return e.ConvertTimetablesToGtfsSink(ctx, netexData, output.NewZipSink(w))
+}
+
+// ConvertTimetablesToGtfsSink converts like ConvertTimetablesToGtfsWriter,
+// writing the GTFS files to sink. The sink is closed after a successful
+// write and aborted when the conversion or the write fails.
+func (e *EnhancedGtfsExporter) ConvertTimetablesToGtfsSink(ctx context.Context, netexData io.Reader, sink output.Sink) (*errors.ConversionResult, error) {
+	if err := e.convertTimetables(ctx, netexData); err != nil {
+		_ = sink.Abort()
+		return e.conversionResult, err
+	}
+	return e.conversionResult, e.streamGtfs(ctx, sink)
+}
+
+// convertTimetables loads and converts a NeTEx dataset, returning an error
+// when the conversion must stop before writing
+func (e *EnhancedGtfsExporter) convertTimetables(ctx context.Context, netexData io.Reader) error {
 	e.conversionResult = errors.NewConversionResult()
 	e.recoveryManager = errors.NewRecoveryManager(e.conversionResult)
 
@@ -74,85 +182,149 @@ func (e *EnhancedGtfsExporter) ConvertTimetablesToGtfsWithRecovery(netexData io.
 		e.conversionResult.AddError("validation", "exporter", "codespace",
 			fmt.Errorf("codespace is required"), false)
 		e.conversionResult.Finalize()
-		return nil, e.conversionResult, ErrMissingCodespace
+		return ErrMissingCodespace
 	}
 
 	// Load NeTEx data with recovery
-	if err := e.loadNetexWithRecovery(netexData); err != nil {
+	if err := e.loadNetexWithRecovery(ctx, netexData); err != nil {
+		if err := e.cancelled(ctx, StageLoading); err != nil {
+			return err
+		}
 		if !e.continueOnError || e.conversionResult.HasFatalErrors() {
 			e.conversionResult.Finalize()
-			return nil, e.conversionResult, err
+			return err
 		}
 	}
 
 	// Convert to GTFS with recovery
-	if err := e.convertNetexToGtfsWithRecovery(); err != nil {
+	if err := e.convertNetexToGtfsWithRecovery(ctx); err != nil {
+		if err := e.cancelled(ctx, "conversion"); err != nil {
+			return err
+		}
 		if !e.continueOnError || e.conversionResult.HasFatalErrors() {
 			e.conversionResult.Finalize()
-			return nil, e.conversionResult, err
+			return err
 		}
 	}
 
-	// Write GTFS archive
-	result, err := e.gtfsRepository.WriteGtfs()
+	return nil
+}
+
+// ConvertStopsToGtfsWithRecovery converts stops with error recovery
+func (e *EnhancedGtfsExporter) ConvertStopsToGtfsWithRecovery() (io.Reader, *errors.ConversionResult, error) {
+	return e.ConvertStopsToGtfsContext(context.Background())
+}
+
+// ConvertStopsToGtfsContext converts stops with error recovery, stopping with
+// the context's error when it is cancelled
+func (e *EnhancedGtfsExporter) ConvertStopsToGtfsContext(ctx context.Context) (io.Reader, *errors.ConversionResult, error) {
+	if err := e.convertStops(ctx); err != nil {
+		return nil, e.conversionResult, err
+	}
+
+	result, err := e.writeGtfs(ctx)
+	if err := e.cancelled(ctx, StageOutput); err != nil {
+		return nil, e.conversionResult, err
+	}
 	e.conversionResult.Finalize()
 
 	if err != nil {
 		e.conversionResult.AddError("output", "gtfs", "archive", err, false)
-		if !e.continueOnError {
-			return nil, e.conversionResult, err
-		}
 	}
 
-	// Return result even with non-fatal errors if continueOnError is true
-	return result, e.conversionResult, nil
+	return result, e.conversionResult, err
 }
 
-// ConvertStopsToGtfsWithRecovery converts stops with error recovery
-func (e *EnhancedGtfsExporter) ConvertStopsToGtfsWithRecovery() (io.Reader, *errors.ConversionResult, error) {
+// ConvertStopsToGtfsWriter converts stops with error recovery like
+// ConvertStopsToGtfsContext, streaming the GTFS archive to w instead of
+// returning it
+func (e *EnhancedGtfsExporter) ConvertStopsToGtfsWriter(ctx context.Context, w io.Writer) (*errors.ConversionResult, error) {
+	return e.ConvertStopsToGtfsSink(ctx, output.NewZipSink(w))
+}
+
+// ConvertStopsToGtfsSink converts stops like ConvertStopsToGtfsWriter,
+// writing the GTFS files to sink
+func (e *EnhancedGtfsExporter) ConvertStopsToGtfsSink(ctx context.Context, sink output.Sink) (*errors.ConversionResult, error) {
+	if err := e.convertStops(ctx); err != nil {
+		_ = sink.Abort()
+		return e.conversionResult, err
+	}
+	return e.conversionResult, e.streamGtfs(ctx, sink)
+}
+
+// convertStops converts the stops of the stop area repository, returning an
+// error when the conversion must stop before writing
+func (e *EnhancedGtfsExporter) convertStops(ctx context.Context) error {
 	e.conversionResult = errors.NewConversionResult()
 	e.recoveryManager = errors.NewRecoveryManager(e.conversionResult)
 
-	if err := e.convertStopsWithRecovery(false); err != nil {
+	if err := e.convertStopsWithRecovery(ctx, false); err != nil {
+		if err := e.cancelled(ctx, StageStops); err != nil {
+			return err
+		}
 		if !e.continueOnError || e.conversionResult.HasFatalErrors() {
 			e.conversionResult.Finalize()
-			return nil, e.conversionResult, err
+			return err
 		}
 	}
 
 	if err := e.ensureDefaultAgencyWithRecovery(); err != nil {
 		if !e.continueOnError || e.conversionResult.HasFatalErrors() {
 			e.conversionResult.Finalize()
-			return nil, e.conversionResult, err
+			return err
 		}
 	}
 
 	if err := e.addFeedInfoWithRecovery(); err != nil {
 		if !e.continueOnError || e.conversionResult.HasFatalErrors() {
 			e.conversionResult.Finalize()
-			return nil, e.conversionResult, err
+			return err
 		}
 	}
 
-	result, err := e.gtfsRepository.WriteGtfs()
-	e.conversionResult.Finalize()
+	return nil
+}
 
+// streamGtfs writes the GTFS files to sink and finalizes the conversion
+// result
+func (e *EnhancedGtfsExporter) streamGtfs(ctx context.Context, sink output.Sink) error {
+	err := e.writeGtfsTo(ctx, sink)
+	if err := e.cancelled(ctx, StageOutput); err != nil {
+		return err
+	}
 	if err != nil {
 		e.conversionResult.AddError("output", "gtfs", "archive", err, false)
 	}
-
-	return result, e.conversionResult, err
+	e.conversionResult.Finalize()
+	return err
 }
 
 // loadNetexWithRecovery loads NeTEx data with error handling
-func (e *EnhancedGtfsExporter) loadNetexWithRecovery(netexData io.Reader) error {
+func (e *EnhancedGtfsExporter) loadNetexWithRecovery(ctx context.Context, netexData io.Reader) error {
 	// Use streaming loader which handles ZIP files and different XML structures better
 	streamingLoader := loader.NewStreamingNetexDatasetLoader()
+	load := streamingLoader.Load
+	if streaming, ok := streamingLoader.(*loader.StreamingNetexDatasetLoader); ok {
+		if e.memoryLimitMB > 0 {
+			streaming.SetMemoryLimit(e.memoryLimitMB)
+		}
+		if e.progress != nil {
+			streaming.SetProgressCallback(func(_ string, processed, total int64) {
+				e.progress(ProgressEvent{Stage: StageLoading, EntityType: "byte", Processed: processed, Total: total})
+			})
+		}
+		load = func(data io.Reader, repo producer.NetexRepository) error {
+			return streaming.LoadContext(ctx, data, repo)
+		}
+	}
 
-	// Progress monitoring would be implemented here if available in the loader
-	// TODO: Add progress callback functionality to NetexDatasetLoader
-
-	err := streamingLoader.Load(netexData, e.netexRepository)
+	e.reportProgress(StageLoading, "byte", 0, 0)
+	hash := sha256.New()
+	err := load(io.TeeReader(netexData, hash), e.netexRepository)
+	e.contentHash = hex.EncodeToString(hash.Sum(nil))
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	if err != nil {
 		e.conversionResult.AddError("loading", "netex", "dataset", err, false)
 		// Don't return error immediately - let recovery handle it
@@ -165,34 +337,44 @@ func (e *EnhancedGtfsExporter) loadNetexWithRecovery(netexData io.Reader) error
 }
 
 // convertNetexToGtfsWithRecovery orchestrates conversion with recovery
-func (e *EnhancedGtfsExporter) convertNetexToGtfsWithRecovery() error {
+func (e *EnhancedGtfsExporter) convertNetexToGtfsWithRecovery(ctx context.Context) error {
 	// Convert agencies with recovery
-	if err := e.convertAgenciesWithRecovery(); err != nil && !e.continueOnError {
+	if err := e.convertAgenciesWithRecovery(ctx); e.stopsConversion(ctx, err) {
 		return err
 	}
 
 	// Convert stops with recovery
-	if err := e.convertStopsWithRecovery(true); err != nil && !e.continueOnError {
+	if err := e.convertStopsWithRecovery(ctx, true); e.stopsConversion(ctx, err) {
+		return err
+	}
+
+	// Convert flexible stop places with recovery
+	if err := e.convertFlexibleStopPlacesWithRecovery(ctx); e.stopsConversion(ctx, err) {
 		return err
 	}
 
 	// Convert routes with recovery
-	if err := e.convertRoutesWithRecovery(); err != nil && !e.continueOnError {
+	if err := e.convertRoutesWithRecovery(ctx); e.stopsConversion(ctx, err) {
 		return err
 	}
 
 	// Convert services and trips with recovery
-	if err := e.convertServicesWithRecovery(); err != nil && !e.continueOnError {
+	if err := e.convertServicesWithRecovery(ctx); e.stopsConversion(ctx, err) {
 		return err
 	}
 
 	// Convert calendars with recovery
-	if err := e.convertCalendarsWithRecovery(); err != nil && !e.continueOnError {
+	if err := e.convertCalendarsWithRecovery(ctx); e.stopsConversion(ctx, err) {
 		return err
 	}
 
 	// Convert transfers with recovery
-	if err := e.convertTransfersWithRecovery(); err != nil && !e.continueOnError {
+	if err := e.convertTransfersWithRecovery(ctx); e.stopsConversion(ctx, err) {
+		return err
+	}
+
+	// Convert fares with recovery
+	if err := e.convertFaresWithRecovery(ctx); e.stopsConversion(ctx, err) {
 		return err
 	}
 
@@ -205,7 +387,7 @@ func (e *EnhancedGtfsExporter) convertNetexToGtfsWithRecovery() error {
 }
 
 // convertAgenciesWithRecovery converts agencies with error recovery
-func (e *EnhancedGtfsExporter) convertAgenciesWithRecovery() error {
+func (e *EnhancedGtfsExporter) convertAgenciesWithRecovery(ctx context.Context) error {
 	lines := e.netexRepository.GetLines()
 	if len(lines) == 0 {
 		e.conversionResult.AddWarning("agencies", "line", "all", "No lines found, will create default agency")
@@ -241,7 +423,13 @@ func (e *EnhancedGtfsExporter) convertAgenciesWithRecovery() error {
 	}
 
 	// Convert each authority to GTFS agency
+	processed := 0
 	for authorityID := range authorityIDs {
+		if err := e.step(ctx, StageAgencies, "authority", processed, len(authorityIDs)); err != nil {
+			return err
+		}
+		processed++
+
 		if e.shouldSkipDueToErrors("authority") {
 			continue
 		}
@@ -292,6 +480,7 @@ func (e *EnhancedGtfsExporter) convertAgenciesWithRecovery() error {
 			e.conversionResult.IncrementSkipped("authority")
 			continue
 		}
+		e.applyLocale(agency)
 
 		// Validate required fields with recovery
 		if agency.AgencyName == "" {
@@ -307,11 +496,7 @@ func (e *EnhancedGtfsExporter) convertAgenciesWithRecovery() error {
 		if agency.AgencyTimezone == "" {
 			recoveredTZ := e.recoveryManager.SafeFieldAccess("authority", authorityID, "agency_timezone",
 				func() (interface{}, error) {
-					tz := e.netexRepository.GetTimeZone()
-					if tz == "" {
-						tz = defaultTimezone
-					}
-					return tz, nil
+					return e.resolvedTimeZone(), nil
 				})
 			if tz, ok := recoveredTZ.(string); ok {
 				agency.AgencyTimezone = tz
@@ -338,117 +523,33 @@ func (e *EnhancedGtfsExporter) convertAgenciesWithRecovery() error {
 			e.conversionResult.IncrementProcessed("authority")
 		}
 	}
+	e.reportProgress(StageAgencies, "authority", len(authorityIDs), len(authorityIDs))
 
 	return nil
 }
 
-// convertStopsWithRecovery converts stops with error recovery
-func (e *EnhancedGtfsExporter) convertStopsWithRecovery(exportOnlyUsedStops bool) error {
-	seenStations := make(map[string]bool)
-	quays := e.stopAreaRepository.GetAllQuays()
-	if len(quays) == 0 {
-		quays = e.netexRepository.GetAllQuays()
-	}
-
+// convertStopsWithRecovery converts stops with error recovery: quays with
+// their boarding positions, and their stations with the entrances of their
+// stop places. The levels and path links of the stop places follow.
+func (e *EnhancedGtfsExporter) convertStopsWithRecovery(ctx context.Context, exportOnlyUsedStops bool) error {
+	quays := e.stopQuays()
 	if len(quays) == 0 {
 		e.conversionResult.AddWarning("stops", "quay", "all", "No quays found")
 		return nil
 	}
 
-	stopPlaces := make(map[string]*model.StopPlace)
-	if sps := e.netexRepository.GetAllStopPlaces(); len(sps) > 0 {
-		for _, sp := range sps {
-			if sp != nil {
-				stopPlaces[sp.ID] = sp
-			}
-		}
-	}
-
-	// Process parent stations first
-	for _, quay := range quays {
-		if e.shouldSkipDueToErrors("quay") {
-			continue
-		}
-
-		if quay == nil {
-			e.conversionResult.IncrementSkipped("quay")
-			continue
-		}
-
-		sp := e.stopAreaRepository.GetStopPlaceByQuayId(quay.ID)
-		if sp == nil {
-			// Try to find in netex repo
-			for _, cand := range stopPlaces {
-				if cand.Quays != nil {
-					for i := range cand.Quays.Quay {
-						if cand.Quays.Quay[i].ID == quay.ID {
-							sp = cand
-							break
-						}
-					}
-				}
-				if sp != nil {
-					break
-				}
-			}
-		}
-
-		if sp != nil && !seenStations[sp.ID] {
-			// Validate and recover stop place data
-			validatedStopPlace, ok := e.recoveryManager.ValidateAndRecover("stopplace", sp.ID, sp,
-				func(data interface{}) error {
-					stopPlace := data.(*model.StopPlace)
-					if stopPlace.Centroid == nil || stopPlace.Centroid.Location == nil {
-						return fmt.Errorf("stop place location is required")
-					}
-					return nil
-				})
-
-			if !ok {
-				e.incrementErrorCount("stopplace")
-				e.conversionResult.AddWarning("stops", "stopplace", sp.ID, "Skipping invalid stop place")
-				continue
-			}
-
-			sp = validatedStopPlace.(*model.StopPlace)
-
-			station, err := e.stopProducer.ProduceStopFromStopPlace(sp)
-			if err != nil {
-				recoveredStation, recovered := e.recoveryManager.TryRecover("stops", "stopplace", sp.ID, err, sp)
-				if recovered && recoveredStation != nil {
-					station = recoveredStation.(*model.Stop)
-				} else {
-					e.incrementErrorCount("stopplace")
-					if !e.continueOnError {
-						return err
-					}
-					continue
-				}
-			}
-
-			if station != nil {
-				if err := e.gtfsRepository.SaveEntity(station); err != nil {
-					e.conversionResult.AddError("stops", "stopplace", sp.ID, err, true)
-					e.incrementErrorCount("stopplace")
-				} else {
-					e.conversionResult.IncrementProcessed("stopplace")
-				}
-			}
-			seenStations[sp.ID] = true
+	// Produce quays first, their parent stations decide which stations to write
+	var stopQuays []*model.Quay
+	var stops []*model.Stop
+	for i, quay := range quays {
+		if err := e.step(ctx, StageStops, "quay", i, len(quays)); err != nil {
+			return err
 		}
-	}
 
-	// Process quays/platforms
-	for _, quay := range quays {
 		if e.shouldSkipDueToErrors("quay") {
 			continue
 		}
 
-		if quay == nil {
-			e.conversionResult.IncrementSkipped("quay")
-			continue
-		}
-
 		// Validate and recover quay data
 		validatedQuay, ok := e.recoveryManager.ValidateAndRecover("quay", quay.ID, quay,
 			func(data interface{}) error {
@@ -481,27 +582,275 @@ func (e *EnhancedGtfsExporter) convertStopsWithRecovery(exportOnlyUsedStops bool
 		}
 
 		if stop != nil {
-			if err := e.gtfsRepository.SaveEntity(stop); err != nil {
-				e.conversionResult.AddError("stops", "quay", quay.ID, err, true)
-				e.incrementErrorCount("quay")
+			stopQuays = append(stopQuays, quay)
+			stops = append(stops, stop)
+		}
+	}
+
+	// Process parent stations
+	stations := make(map[string]bool)
+	parentStations := stationIDs(stops)
+	for i, stationID := range parentStations {
+		if err := e.step(ctx, StageStops, "stopplace", i, len(parentStations)); err != nil {
+			return err
+		}
+
+		stopPlace := e.stopPlaceByID(stationID)
+		if stopPlace == nil {
+			e.conversionResult.AddWarning("stops", "stopplace", stationID, "Skipping unknown parent station")
+			e.conversionResult.IncrementSkipped("stopplace")
+			continue
+		}
+
+		station, err := e.stopProducer.ProduceStopFromStopPlace(stopPlace)
+		if err != nil {
+			recoveredStation, recovered := e.recoveryManager.TryRecover("stops", "stopplace", stationID, err, stopPlace)
+			if recovered && recoveredStation != nil {
+				station = recoveredStation.(*model.Stop)
 			} else {
-				e.conversionResult.IncrementProcessed("quay")
+				e.incrementErrorCount("stopplace")
+				if !e.continueOnError {
+					return err
+				}
+				continue
+			}
+		}
+
+		if station == nil {
+			continue
+		}
+		if station.StopLat == 0 && station.StopLon == 0 {
+			e.incrementErrorCount("stopplace")
+			e.conversionResult.AddWarning("stops", "stopplace", stationID, "Skipping stop place without location")
+			continue
+		}
+		if err := e.gtfsRepository.SaveEntity(station); err != nil {
+			e.conversionResult.AddError("stops", "stopplace", stationID, err, true)
+			e.incrementErrorCount("stopplace")
+			continue
+		}
+		stations[stationID] = true
+		e.conversionResult.IncrementProcessed("stopplace")
+	}
+	e.reportProgress(StageStops, "stopplace", len(parentStations), len(parentStations))
+
+	// Write quays/platforms and their boarding positions
+	for i, stop := range stops {
+		if !stations[stop.ParentStation] {
+			stop.ParentStation = ""
+		}
+		if err := e.gtfsRepository.SaveEntity(stop); err != nil {
+			e.conversionResult.AddError("stops", "quay", stop.StopID, err, true)
+			e.incrementErrorCount("quay")
+			continue
+		}
+		e.conversionResult.IncrementProcessed("quay")
+
+		quay := stopQuays[i]
+		for j := range quay.BoardingPositions {
+			boardingPosition := &quay.BoardingPositions[j]
+			boardingArea, err := e.stopProducer.ProduceStopFromBoardingPosition(boardingPosition, quay)
+			if err != nil {
+				e.conversionResult.AddError("stops", "boardingposition", boardingPosition.ID, err, true)
+				e.incrementErrorCount("boardingposition")
+				continue
+			}
+			if boardingArea == nil {
+				continue
 			}
+			if err := e.gtfsRepository.SaveEntity(boardingArea); err != nil {
+				e.conversionResult.AddError("stops", "boardingposition", boardingPosition.ID, err, true)
+				e.incrementErrorCount("boardingposition")
+				continue
+			}
+			e.conversionResult.IncrementProcessed("boardingposition")
 		}
 	}
+	e.reportProgress(StageStops, "quay", len(quays), len(quays))
+
+	// Write the entrances of the stations
+	stopPlaces := e.exportedStopPlaces(stopQuays, stations)
+	for _, stopPlace := range stopPlaces {
+		for i := range stopPlace.Entrances {
+			entrance := &stopPlace.Entrances[i]
+			stop, err := e.stopProducer.ProduceStopFromEntrance(entrance, stopPlace)
+			if err != nil {
+				e.conversionResult.AddError("stops", "entrance", entrance.ID, err, true)
+				e.incrementErrorCount("entrance")
+				continue
+			}
+			if stop == nil {
+				continue
+			}
+			if !stations[stop.ParentStation] || (stop.StopLat == 0 && stop.StopLon == 0) {
+				e.conversionResult.AddWarning("stops", "entrance", entrance.ID, "Skipping entrance without location or station")
+				e.conversionResult.IncrementSkipped("entrance")
+				continue
+			}
+			if err := e.gtfsRepository.SaveEntity(stop); err != nil {
+				e.conversionResult.AddError("stops", "entrance", entrance.ID, err, true)
+				e.incrementErrorCount("entrance")
+				continue
+			}
+			e.conversionResult.IncrementProcessed("entrance")
+		}
+	}
+
+	return e.convertPathwaysWithRecovery(ctx, stopPlaces)
+}
+
+// convertPathwaysWithRecovery converts the levels and path links of the
+// exported stop places, and the path links kept outside them, with error
+// recovery. Path links are left out unless both ends are exported stops, and
+// reported when only one of them is.
+func (e *EnhancedGtfsExporter) convertPathwaysWithRecovery(ctx context.Context, stopPlaces []*model.StopPlace) error {
+	if e.pathwaysProducer == nil {
+		return nil
+	}
+	levels := make(map[string]bool)
+	pathways := make(map[string]bool)
+	savePathway := func(pathway *model.Pathway) {
+		if pathway == nil || pathways[pathway.PathwayID] {
+			return
+		}
+		fromExported, toExported := e.isPathwayEnd(pathway.FromStopID), e.isPathwayEnd(pathway.ToStopID)
+		if !fromExported || !toExported {
+			if fromExported || toExported {
+				e.conversionResult.AddWarning("stops", "pathway", pathway.PathwayID,
+					fmt.Sprintf("Skipping pathway from %s to %s, which are not both exported stops", pathway.FromStopID, pathway.ToStopID))
+				e.conversionResult.IncrementSkipped("pathway")
+			}
+			return
+		}
+		pathways[pathway.PathwayID] = true
+		if err := e.gtfsRepository.SaveEntity(pathway); err != nil {
+			e.conversionResult.AddError("stops", "pathway", pathway.PathwayID, err, true)
+			e.incrementErrorCount("pathway")
+			return
+		}
+		e.conversionResult.IncrementProcessed("pathway")
+	}
+
+	for i, stopPlace := range stopPlaces {
+		if err := e.step(ctx, StageStops, "pathway", i, len(stopPlaces)); err != nil {
+			return err
+		}
+
+		stopPlaceLevels, err := e.pathwaysProducer.ProduceLevelsFromStopPlace(stopPlace)
+		if err != nil {
+			e.conversionResult.AddError("stops", "level", stopPlace.ID, err, true)
+			e.incrementErrorCount("level")
+		}
+		for _, level := range stopPlaceLevels {
+			if levels[level.LevelID] {
+				continue
+			}
+			levels[level.LevelID] = true
+			if err := e.gtfsRepository.SaveEntity(level); err != nil {
+				e.conversionResult.AddError("stops", "level", level.LevelID, err, true)
+				e.incrementErrorCount("level")
+				continue
+			}
+			e.conversionResult.IncrementProcessed("level")
+		}
+
+		stopPlacePathways, err := e.pathwaysProducer.ProducePathwaysFromStopPlace(stopPlace)
+		var linkErr *producer.PathLinkError
+		switch {
+		case stderrors.As(err, &linkErr):
+			// Only the skipped links are lost, the others are still saved
+			for _, linkID := range linkErr.LinkIDs() {
+				e.conversionResult.AddWarning("stops", "pathway", linkID, fmt.Sprintf("Skipping path link: %v", linkErr.Links[linkID]))
+				e.conversionResult.IncrementSkipped("pathway")
+			}
+		case err != nil:
+			e.conversionResult.AddWarning("stops", "pathway", stopPlace.ID, fmt.Sprintf("Skipping path links: %v", err))
+			continue
+		}
+		for _, pathway := range stopPlacePathways {
+			savePathway(pathway)
+		}
+	}
+	e.reportProgress(StageStops, "pathway", len(stopPlaces), len(stopPlaces))
+
+	for _, link := range e.sitePathLinks() {
+		pathway, err := e.pathwaysProducer.ProducePathwayFromLink(link)
+		if err != nil {
+			e.conversionResult.AddWarning("stops", "pathway", link.ID, fmt.Sprintf("Skipping path link: %v", err))
+			e.conversionResult.IncrementSkipped("pathway")
+			continue
+		}
+		savePathway(pathway)
+	}
+
+	return nil
+}
+
+// convertFlexibleStopPlacesWithRecovery converts flexible stop places to
+// GTFS-Flex zones of their polygons, or else to location groups of their
+// member stops
+func (e *EnhancedGtfsExporter) convertFlexibleStopPlacesWithRecovery(ctx context.Context) error {
+	stopPlaces := e.netexRepository.GetAllFlexibleStopPlaces()
+	if len(stopPlaces) == 0 {
+		return nil
+	}
+	sort.Slice(stopPlaces, func(i, j int) bool { return stopPlaces[i].ID < stopPlaces[j].ID })
+
+	for i, stopPlace := range stopPlaces {
+		if err := e.step(ctx, StageStops, "flexiblestopplace", i, len(stopPlaces)); err != nil {
+			return err
+		}
+
+		if location := e.flexibleServiceProducer.ProduceLocation(stopPlace); location != nil {
+			if err := e.gtfsRepository.SaveEntity(location); err != nil {
+				e.conversionResult.AddError("stops", "flexiblestopplace", stopPlace.ID, err, true)
+				e.incrementErrorCount("flexiblestopplace")
+				continue
+			}
+			e.flexLocationIDs[location.LocationID] = true
+			e.conversionResult.IncrementProcessed("flexiblestopplace")
+			continue
+		}
+
+		group, groupStops := e.flexibleServiceProducer.ProduceLocationGroup(stopPlace)
+		if group == nil {
+			e.conversionResult.AddWarning("stops", "flexiblestopplace", stopPlace.ID,
+				"Flexible stop place has neither a polygon nor converted member stops")
+			e.conversionResult.IncrementSkipped("flexiblestopplace")
+			continue
+		}
+		entities := []interface{}{group}
+		for _, groupStop := range groupStops {
+			entities = append(entities, groupStop)
+		}
+		for _, entity := range entities {
+			if err := e.gtfsRepository.SaveEntity(entity); err != nil {
+				e.conversionResult.AddError("stops", "flexiblestopplace", stopPlace.ID, err, true)
+				e.incrementErrorCount("flexiblestopplace")
+				break
+			}
+		}
+		e.flexLocationIDs[group.LocationGroupID] = true
+		e.conversionResult.IncrementProcessed("flexiblestopplace")
+	}
+	e.reportProgress(StageStops, "flexiblestopplace", len(stopPlaces), len(stopPlaces))
 
 	return nil
 }
 
 // convertRoutesWithRecovery converts routes with error recovery
-func (e *EnhancedGtfsExporter) convertRoutesWithRecovery() error {
+func (e *EnhancedGtfsExporter) convertRoutesWithRecovery(ctx context.Context) error {
 	lines := e.netexRepository.GetLines()
 	if len(lines) == 0 {
 		e.conversionResult.AddWarning("routes", "line", "all", "No lines found")
 		return nil
 	}
 
-	for _, line := range lines {
+	for i, line := range lines {
+		if err := e.step(ctx, StageRoutes, "line", i, len(lines)); err != nil {
+			return err
+		}
+
 		if e.shouldSkipDueToErrors("line") {
 			continue
 		}
@@ -578,14 +927,18 @@ func (e *EnhancedGtfsExporter) convertRoutesWithRecovery() error {
 		} else {
 			e.conversionResult.IncrementProcessed("line")
 			e.lineIdToGtfsRoute[line.ID] = route
+			if line.Booking != nil {
+				e.flexibleLines[route.RouteID] = line
+			}
 		}
 	}
+	e.reportProgress(StageRoutes, "line", len(lines), len(lines))
 
 	return nil
 }
 
 // convertServicesWithRecovery converts services with error recovery
-func (e *EnhancedGtfsExporter) convertServicesWithRecovery() error {
+func (e *EnhancedGtfsExporter) convertServicesWithRecovery(ctx context.Context) error {
 	serviceJourneys := e.netexRepository.GetServiceJourneys()
 
 	if len(serviceJourneys) == 0 {
@@ -593,7 +946,11 @@ func (e *EnhancedGtfsExporter) convertServicesWithRecovery() error {
 		return nil
 	}
 
-	for _, sj := range serviceJourneys {
+	for i, sj := range serviceJourneys {
+		if err := e.step(ctx, StageServices, "servicejourney", i, len(serviceJourneys)); err != nil {
+			return err
+		}
+
 		if e.shouldSkipDueToErrors("servicejourney") {
 			continue
 		}
@@ -608,6 +965,7 @@ func (e *EnhancedGtfsExporter) convertServicesWithRecovery() error {
 			return err
 		}
 	}
+	e.reportProgress(StageServices, "servicejourney", len(serviceJourneys), len(serviceJourneys))
 
 	return nil
 }
@@ -686,6 +1044,9 @@ func (e *EnhancedGtfsExporter) processServiceJourneyWithRecovery(sj *model.Servi
 				if route != nil {
 					if err := e.gtfsRepository.SaveEntity(route); err == nil {
 						e.lineIdToGtfsRoute[line.ID] = route
+						if line.Booking != nil {
+							e.flexibleLines[route.RouteID] = line
+						}
 						gtfsRoute = route
 					}
 				}
@@ -703,25 +1064,41 @@ func (e *EnhancedGtfsExporter) processServiceJourneyWithRecovery(sj *model.Servi
 
 	// Resolve NeTEx route for the service journey
 	var netexRoute *model.Route
-	// Note: Route resolution would need to be implemented in repository
+	if jp != nil {
+		netexRoute = e.netexRepository.GetRouteById(jp.RouteRef)
+	}
 
 	// Get destination display for trip headsign
-	var destinationDisplay *model.DestinationDisplay
-	// Note: Destination display resolution would need to be implemented
+	destinationDisplay := e.journeyPatternDestinationDisplay(jp)
 
-	// Skip shape generation to avoid invalid coordinates
+	// Shape per JourneyPattern; patterns without resolvable coordinates get none
 	var shapeID string
-	// Shape generation disabled to prevent GTFS validation errors with placeholder coordinates
+	shapePoints, err := e.shapeForJourneyPattern(jp)
+	if err != nil {
+		e.conversionResult.AddWarning("shapes", "journeypattern", sj.JourneyPatternRef.Ref,
+			fmt.Sprintf("Failed to generate shape: %v", err))
+		shapePoints = nil
+	}
+	if len(shapePoints) > 0 {
+		shapeID = shapePoints[0].ShapeID
+	}
 
 	// Create trip with recovery
 	trip, err := e.tripProducer.Produce(producer.TripInput{
 		ServiceJourney:     sj,
 		NetexRoute:         netexRoute,
+		JourneyPattern:     jp,
 		GtfsRoute:          gtfsRoute,
 		ShapeID:            shapeID,
 		DestinationDisplay: destinationDisplay,
 	})
 
+	if stderrors.Is(err, producer.ErrNoServiceDays) {
+		e.conversionResult.AddWarning("services", "servicejourney", sj.ID,
+			"Skipping service journey without day types or dated service journeys")
+		e.conversionResult.IncrementSkipped("servicejourney")
+		return nil
+	}
 	if err != nil {
 		recoveredTrip, recovered := e.recoveryManager.TryRecover("services", "servicejourney", sj.ID, err, sj)
 		if recovered && recoveredTrip != nil {
@@ -743,8 +1120,12 @@ func (e *EnhancedGtfsExporter) processServiceJourneyWithRecovery(sj *model.Servi
 		return nil
 	}
 
+	if _, exists := e.serviceJourneys[trip.ServiceID]; !exists {
+		e.serviceJourneys[trip.ServiceID] = sj
+	}
+
 	// Generate stop times for this trip
-	if err := e.convertStopTimesForTrip(sj, trip, jp); err != nil {
+	if err := e.convertStopTimesForTrip(sj, trip, jp, shapePoints); err != nil {
 		e.conversionResult.AddWarning("services", "servicejourney", sj.ID,
 			fmt.Sprintf("Failed to generate stop times: %v", err))
 	}
@@ -754,125 +1135,185 @@ func (e *EnhancedGtfsExporter) processServiceJourneyWithRecovery(sj *model.Servi
 }
 
 // convertStopTimesForTrip generates stop times for a trip from service journey passing times
-func (e *EnhancedGtfsExporter) convertStopTimesForTrip(sj *model.ServiceJourney, trip *model.Trip, jp *model.JourneyPattern) error {
+func (e *EnhancedGtfsExporter) convertStopTimesForTrip(sj *model.ServiceJourney, trip *model.Trip, jp *model.JourneyPattern, shapePoints []*model.Shape) error {
 	if sj.PassingTimes == nil || len(sj.PassingTimes.TimetabledPassingTime) == 0 {
 		return fmt.Errorf("no passing times found for service journey %s", sj.ID)
 	}
 
-	// Use available quays from the netex repository as a fallback mapping
-	// This is a simple approach until we can properly implement the journey pattern mapping
-	allQuays := e.netexRepository.GetAllQuays()
+	// Interpolation does not apply to the time windows of flexible stops
+	var stopTimes []*model.StopTime
+	if e.advancedStopTimeProducer != nil && !e.hasFlexibleStops(sj) {
+		var err error
+		if stopTimes, err = e.produceAdvancedStopTimes(sj, jp, trip); err != nil {
+			e.conversionResult.AddWarning("stoptimes", "trip", trip.TripID,
+				fmt.Sprintf("Interpolation failed, using timetabled passing times only: %v", err))
+			stopTimes = nil
+		}
+	}
+	if stopTimes == nil {
+		stopTimes = e.passingTimeStopTimes(sj, trip, jp, shapePoints)
+	}
 
-	for i, passingTime := range sj.PassingTimes.TimetabledPassingTime {
-		// Simple fallback: try to find a stop for this passing time using available quays
-		var stop *model.Stop
-		if i < len(allQuays) {
-			quay := allQuays[i]
-			if quay != nil {
-				if generatedStop, err := e.stopProducer.ProduceStopFromQuay(quay); err == nil && generatedStop != nil {
-					stop = generatedStop
-				}
-			}
+	e.assignShapeDistances(stopTimes, shapePoints)
+	for _, stopTime := range stopTimes {
+		if err := e.gtfsRepository.SaveEntity(stopTime); err != nil {
+			return fmt.Errorf("failed to save stop time: %w", err)
 		}
+	}
 
-		if stop == nil {
+	return nil
+}
+
+// passingTimeStopTimes produces one stop time per timetabled passing time,
+// skipping passing times whose stop cannot be resolved
+func (e *EnhancedGtfsExporter) passingTimeStopTimes(sj *model.ServiceJourney, trip *model.Trip, jp *model.JourneyPattern, shapePoints []*model.Shape) []*model.StopTime {
+	var shape *model.Shape
+	if len(shapePoints) > 0 {
+		shape = shapePoints[0]
+	}
+	headsigns := e.stopHeadsigns(sj, trip.TripHeadsign)
+
+	var stopTimes []*model.StopTime
+	for i := range sj.PassingTimes.TimetabledPassingTime {
+		stopTime, err := e.stopTimeProducer.Produce(producer.StopTimeInput{
+			TimetabledPassingTime: &sj.PassingTimes.TimetabledPassingTime[i],
+			JourneyPattern:        jp,
+			Trip:                  trip,
+			Shape:                 shape,
+			CurrentHeadSign:       headsigns[i],
+		})
+		if err != nil {
+			e.conversionResult.AddWarning("stoptimes", "trip", trip.TripID,
+				fmt.Sprintf("Failed to produce stop time for passing time %d: %v", i, err))
+			continue
+		}
+		if stopTime == nil || (stopTime.StopID == "" && stopTime.LocationID == "" && stopTime.LocationGroupID == "") {
 			e.conversionResult.AddWarning("stoptimes", "trip", trip.TripID,
 				fmt.Sprintf("Stop not found for passing time %d", i))
 			continue
 		}
-
-		// Convert time strings
-		arrivalTime := passingTime.ArrivalTime
-		departureTime := passingTime.DepartureTime
-
-		// Create stop time
-		stopTime := &model.StopTime{
-			TripID:            trip.TripID,
-			StopID:            stop.StopID,
-			StopSequence:      i + 1,
-			ArrivalTime:       arrivalTime,
-			DepartureTime:     departureTime,
-			PickupType:        "0",
-			DropOffType:       "0",
-			Timepoint:         "1",
-			ShapeDistTraveled: float64(i * 1000), // Generate increasing distances: 0m, 1000m, 2000m, etc.
+		if flexID := stopTime.LocationID + stopTime.LocationGroupID; flexID != "" && !e.flexLocationIDs[flexID] {
+			e.conversionResult.AddWarning("stoptimes", "trip", trip.TripID,
+				fmt.Sprintf("Flexible stop place %s of passing time %d was not converted", flexID, i))
+			continue
 		}
+		stopPoint := e.netexRepository.GetStopPointInJourneyPatternById(sj.PassingTimes.TimetabledPassingTime[i].PointInJourneyPatternRef)
+		e.applyBookingRules(stopTime, sj, trip, stopPoint)
 
-		// Handle departure-only (first stop) and arrival-only (last stop)
-		if stopTime.ArrivalTime == "" && stopTime.DepartureTime != "" {
-			stopTime.ArrivalTime = stopTime.DepartureTime
-		}
-		if stopTime.DepartureTime == "" && stopTime.ArrivalTime != "" {
-			stopTime.DepartureTime = stopTime.ArrivalTime
+		// Timetabled passing times are exact
+		if stopTime.Timepoint == "" {
+			stopTime.Timepoint = "1"
 		}
+		stopTime.StopSequence = len(stopTimes) + 1
+		stopTimes = append(stopTimes, stopTime)
+	}
+	return stopTimes
+}
 
-		// Save stop time
-		if err := e.gtfsRepository.SaveEntity(stopTime); err != nil {
-			return fmt.Errorf("failed to save stop time: %w", err)
-		}
+// applyBookingRules attaches the booking rule of the stop point, else of the
+// journey's flexible service, else of its flexible line, to the pickup and
+// drop-off of a stop time. Booking turns regular pickups and drop-offs into
+// ones to arrange with the agency.
+func (e *EnhancedGtfsExporter) applyBookingRules(stopTime *model.StopTime, sj *model.ServiceJourney, trip *model.Trip, stopPoint *model.StopPointInJourneyPattern) {
+	var ruleID string
+	switch {
+	case stopPoint != nil && stopPoint.BookingArrangements != nil:
+		ruleID = e.bookingRuleID(stopPoint.ID, stopPoint.BookingArrangements)
+	case sj.FlexibleServiceProperties != nil && sj.FlexibleServiceProperties.Arrangements() != nil:
+		ruleID = e.bookingRuleID(sj.ID, sj.FlexibleServiceProperties.Arrangements())
+	case e.flexibleLines[trip.RouteID] != nil:
+		line := e.flexibleLines[trip.RouteID]
+		ruleID = e.bookingRuleID(line.ID, line.Booking)
+	}
+	if ruleID == "" {
+		return
 	}
 
-	return nil
+	if stopTime.PickupType != "1" {
+		if stopTime.PickupType == "0" {
+			stopTime.PickupType = "2"
+		}
+		stopTime.PickupBookingRuleID = ruleID
+	}
+	if stopTime.DropOffType != "1" {
+		if stopTime.DropOffType == "0" {
+			stopTime.DropOffType = "2"
+		}
+		stopTime.DropOffBookingRuleID = ruleID
+	}
 }
 
-// convertCalendarsWithRecovery converts calendars with error recovery
-func (e *EnhancedGtfsExporter) convertCalendarsWithRecovery() error {
-	// Create a default calendar for the service
-	// This is a simplified approach until we have full NeTEx calendar parsing
-	defaultCalendar := &model.Calendar{
-		ServiceID: "default_service",
-		Monday:    true,
-		Tuesday:   true,
-		Wednesday: true,
-		Thursday:  true,
-		Friday:    true,
-		Saturday:  true,
-		Sunday:    false,
-		StartDate: "20240101",
-		EndDate:   "20241231",
-	}
-
-	if err := e.gtfsRepository.SaveEntity(defaultCalendar); err != nil {
-		e.conversionResult.AddError("calendar", "calendar", "default_service", err, true)
-		if !e.continueOnError {
-			return err
+// bookingRuleID returns the ID of the booking rule of the arrangements of
+// an element, producing and saving the rule on first use; "" when none could
+// be produced
+func (e *EnhancedGtfsExporter) bookingRuleID(ownerID string, arrangements *model.BookingArrangements) string {
+	if ruleID, exists := e.bookingRuleIDs[ownerID]; exists {
+		return ruleID
+	}
+
+	e.bookingRuleIDs[ownerID] = ""
+	rule, err := e.flexibleServiceProducer.ProduceBookingRules(&model.FlexibleService{ID: ownerID, BookingArrangements: arrangements})
+	if err != nil || rule == nil {
+		if err != nil {
+			e.conversionResult.AddWarning("services", "bookingarrangements", ownerID,
+				fmt.Sprintf("Failed to produce booking rule: %v", err))
 		}
-	} else {
-		e.conversionResult.IncrementProcessed("calendar")
+		return ""
+	}
+	if err := e.gtfsRepository.SaveEntity(rule); err != nil {
+		e.conversionResult.AddError("services", "bookingarrangements", ownerID, err, true)
+		return ""
 	}
 
-	// Add some calendar dates (holidays/exceptions)
-	holidays := []string{
-		"20240101", // New Year
-		"20240315", // Sample holiday
-		"20240501", // Labor Day
-		"20240814", // Sample holiday
-		"20241225", // Christmas
+	e.bookingRuleIDs[ownerID] = rule.BookingRuleID
+	return rule.BookingRuleID
+}
+
+// convertCalendarsWithRecovery converts the calendars of the services used by trips
+func (e *EnhancedGtfsExporter) convertCalendarsWithRecovery(ctx context.Context) error {
+	serviceIDs := make([]string, 0, len(e.serviceJourneys))
+	for serviceID := range e.serviceJourneys {
+		serviceIDs = append(serviceIDs, serviceID)
 	}
+	sort.Strings(serviceIDs)
 
-	for _, holiday := range holidays {
-		calendarDate := &model.CalendarDate{
-			ServiceID:     "default_service",
-			Date:          holiday,
-			ExceptionType: 2, // Service removed
+	for i, serviceID := range serviceIDs {
+		if err := e.step(ctx, StageCalendar, "calendar", i, len(serviceIDs)); err != nil {
+			return err
 		}
 
-		if err := e.gtfsRepository.SaveEntity(calendarDate); err != nil {
-			e.conversionResult.AddWarning("calendar", "calendardate", holiday,
-				fmt.Sprintf("Failed to save calendar date: %v", err))
-		} else {
+		calendar, calendarDates, err := e.convertTripService(serviceID, e.serviceJourneys[serviceID])
+		if calendar != nil {
+			e.conversionResult.IncrementProcessed("calendar")
+		}
+		for range calendarDates {
 			e.conversionResult.IncrementProcessed("calendardate")
 		}
+		if err != nil {
+			e.conversionResult.AddError("calendar", "calendar", serviceID, err, true)
+			if !e.continueOnError {
+				return err
+			}
+			continue
+		}
+		if calendar == nil && len(calendarDates) == 0 {
+			e.conversionResult.AddWarning("calendar", "calendar", serviceID, "No service dates found for day types")
+		}
 	}
+	e.reportProgress(StageCalendar, "calendar", len(serviceIDs), len(serviceIDs))
 
 	return nil
 }
 
 // convertTransfersWithRecovery converts transfers with error recovery
-func (e *EnhancedGtfsExporter) convertTransfersWithRecovery() error {
+func (e *EnhancedGtfsExporter) convertTransfersWithRecovery(ctx context.Context) error {
 	interchanges := e.netexRepository.GetServiceJourneyInterchanges()
 
-	for _, interchange := range interchanges {
+	for i, interchange := range interchanges {
+		if err := e.step(ctx, StageTransfers, "interchange", i, len(interchanges)); err != nil {
+			return err
+		}
+
 		if e.shouldSkipDueToErrors("interchange") {
 			continue
 		}
@@ -904,8 +1345,50 @@ func (e *EnhancedGtfsExporter) convertTransfersWithRecovery() error {
 			} else {
 				e.conversionResult.IncrementProcessed("interchange")
 			}
+		} else {
+			e.conversionResult.IncrementSkipped("interchange")
+		}
+	}
+	e.reportProgress(StageTransfers, "interchange", len(interchanges), len(interchanges))
+
+	return nil
+}
+
+// convertFaresWithRecovery converts fare frames with error recovery. Fare
+// elements GTFS Fares v2 cannot express are reported as warnings.
+func (e *EnhancedGtfsExporter) convertFaresWithRecovery(ctx context.Context) error {
+	if e.fareProducer == nil {
+		return nil
+	}
+	fares := e.fareProducer.Produce()
+	if fares == nil {
+		return nil
+	}
+	for _, issue := range fares.Issues {
+		e.conversionResult.AddWarning(StageFares, issue.EntityType, issue.EntityID, issue.Message)
+	}
+
+	entities := e.fareEntities(fares)
+	for i, entity := range entities {
+		if err := e.step(ctx, StageFares, "fare", i, len(entities)); err != nil {
+			return err
+		}
+
+		if e.shouldSkipDueToErrors("fare") {
+			continue
+		}
+
+		if err := e.gtfsRepository.SaveEntity(entity); err != nil {
+			e.conversionResult.AddError(StageFares, "fare", "", err, true)
+			e.incrementErrorCount("fare")
+			if !e.continueOnError {
+				return err
+			}
+			continue
 		}
+		e.conversionResult.IncrementProcessed("fare")
 	}
+	e.reportProgress(StageFares, "fare", len(entities), len(entities))
 
 	return nil
 }
@@ -918,14 +1401,10 @@ func (e *EnhancedGtfsExporter) ensureDefaultAgencyWithRecovery() error {
 
 	tz := e.recoveryManager.SafeFieldAccess("agency", "default", "agency_timezone",
 		func() (interface{}, error) {
-			timezone := e.netexRepository.GetTimeZone()
-			if timezone == "" {
-				timezone = "UTC"
-			}
-			return timezone, nil
+			return e.resolvedTimeZone(), nil
 		})
 
-	timezone := "UTC"
+	timezone := defaultTimezone
 	if tz != nil {
 		if tzStr, ok := tz.(string); ok {
 			timezone = tzStr
@@ -936,6 +1415,7 @@ func (e *EnhancedGtfsExporter) ensureDefaultAgencyWithRecovery() error {
 		AgencyID:       "default",
 		AgencyName:     "Default Agency",
 		AgencyTimezone: timezone,
+		AgencyLang:     e.resolvedLanguage(),
 	}
 
 	if err := e.gtfsRepository.SaveEntity(agency); err != nil {
@@ -950,7 +1430,7 @@ func (e *EnhancedGtfsExporter) ensureDefaultAgencyWithRecovery() error {
 // addFeedInfoWithRecovery adds feed info with recovery
 func (e *EnhancedGtfsExporter) addFeedInfoWithRecovery() error {
 	if e.feedInfoProducer != nil {
-		feedInfo, err := e.feedInfoProducer.ProduceFeedInfo()
+		feedInfo, err := e.feedInfoProducer.ProduceFeedInfo(e.feedInfoInput())
 		if err != nil {
 			recoveredFeedInfo, recovered := e.recoveryManager.TryRecover("feedinfo", "feedinfo", "default", err, nil)
 			if recovered && recoveredFeedInfo != nil {
@@ -975,8 +1455,123 @@ func (e *EnhancedGtfsExporter) addFeedInfoWithRecovery() error {
 	return nil
 }
 
+// writeGtfs writes the GTFS archive in memory
+func (e *EnhancedGtfsExporter) writeGtfs(ctx context.Context) (io.Reader, error) {
+	var buf bytes.Buffer
+	if err := e.writeGtfsTo(ctx, output.NewZipSink(&buf)); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// writeGtfsTo streams the GTFS files to sink when the repository supports
+// it, cancelling with ctx, and unpacks the repository's archive into sink
+// otherwise. The sink is closed on success and aborted on failure.
+func (e *EnhancedGtfsExporter) writeGtfsTo(ctx context.Context, sink output.Sink) error {
+	e.reportProgress(StageOutput, "archive", 0, 1)
+
+	var err error
+	if writer, ok := e.gtfsRepository.(sinkGtfsWriter); ok {
+		err = writer.WriteGtfsToSink(ctx, sink)
+	} else {
+		err = copyArchive(e.gtfsRepository, sink)
+	}
+	if err == nil {
+		e.reportProgress(StageOutput, "archive", 1, 1)
+	}
+	return err
+}
+
+// sinkGtfsWriter is implemented by GTFS repositories that stream their files
+// to an output sink and whose writing can be cancelled
+type sinkGtfsWriter interface {
+	WriteGtfsToSink(ctx context.Context, sink output.Sink) error
+}
+
+// copyArchive copies the files of the archive of a repository to sink
+func copyArchive(gtfsRepository producer.GtfsRepository, sink output.Sink) error {
+	err := func() error {
+		archive, err := gtfsRepository.WriteGtfs()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return err
+		}
+		zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		for _, file := range zipReader.File {
+			if err := copyArchiveFile(file, sink); err != nil {
+				return fmt.Errorf("copying %s: %w", file.Name, err)
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		_ = sink.Abort()
+		return err
+	}
+	return sink.Close()
+}
+
+// copyArchiveFile copies one file of an archive to sink
+func copyArchiveFile(file *zip.File, sink output.Sink) error {
+	reader, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = reader.Close() }()
+
+	writer, err := sink.Create(file.Name)
+	if err != nil {
+		return err
+	}
+	// #nosec G110 -- the archive was just written by the repository
+	if _, err := io.Copy(writer, reader); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
 // Helper methods
 
+// step starts the entity at index of a stage loop: it reports progress every
+// progressInterval entities and returns the context's error once cancelled
+func (e *EnhancedGtfsExporter) step(ctx context.Context, stage, entityType string, index, total int) error {
+	if index%progressInterval == 0 {
+		e.reportProgress(stage, entityType, index, total)
+	}
+	return ctx.Err()
+}
+
+// reportProgress sends a progress event to the progress handler, if any
+func (e *EnhancedGtfsExporter) reportProgress(stage, entityType string, processed, total int) {
+	if e.progress != nil {
+		e.progress(ProgressEvent{Stage: stage, EntityType: entityType, Processed: int64(processed), Total: int64(total)})
+	}
+}
+
+// stopsConversion reports whether a stage error ends the conversion: always
+// once ctx is cancelled, otherwise only when not continuing on errors
+func (e *EnhancedGtfsExporter) stopsConversion(ctx context.Context, err error) bool {
+	return err != nil && (!e.continueOnError || ctx.Err() != nil)
+}
+
+// cancelled records the cancellation of ctx in the conversion result and
+// returns the context's error, or nil while ctx is live
+func (e *EnhancedGtfsExporter) cancelled(ctx context.Context, stage string) error {
+	err := ctx.Err()
+	if err != nil {
+		e.conversionResult.AddError(stage, "conversion", "cancelled", err, false)
+		e.conversionResult.Finalize()
+	}
+	return err
+}
+
 // shouldSkipDueToErrors checks if processing should be skipped due to too many errors
 func (e *EnhancedGtfsExporter) shouldSkipDueToErrors(entityType string) bool {
 	return e.errorCountsByEntity[entityType] >= e.maxErrorsPerEntity
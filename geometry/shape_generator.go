@@ -3,6 +3,9 @@ package geometry
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
 )
@@ -50,6 +53,11 @@ func (sg *ShapeGenerator) GenerateShape(journeyPattern *model.JourneyPattern, ne
 		return nil, nil
 	}
 
+	// Prefer real track geometry when the pattern references links
+	if linkRepo, ok := netexRepo.(LinkRepositoryInterface); ok && hasLinks(journeyPattern) {
+		return sg.generateShapeFromLinks(journeyPattern, netexRepo, linkRepo), nil
+	}
+
 	// Extract stop locations from journey pattern
 	stopLocations, err := sg.extractStopLocations(journeyPattern, netexRepo)
 	if err != nil {
@@ -60,6 +68,12 @@ func (sg *ShapeGenerator) GenerateShape(journeyPattern *model.JourneyPattern, ne
 		return nil, nil // Need at least 2 points for a shape
 	}
 
+	// A shape that silently drops stops would misplace shape_dist_traveled for the
+	// remaining ones, so skip the pattern entirely when any stop cannot be located
+	if len(stopLocations) != len(stopPointsOf(journeyPattern)) {
+		return nil, nil
+	}
+
 	// Generate shape points
 	shapePoints := sg.generateShapePoints(journeyPattern.ID, stopLocations)
 
@@ -79,6 +93,12 @@ type NetexRepositoryInterface interface {
 	GetStopPlaceByQuayId(quayId string) *model.StopPlace
 }
 
+// LinkRepositoryInterface is implemented by repositories that provide link geometry
+type LinkRepositoryInterface interface {
+	GetServiceLinkById(id string) *model.ServiceLink
+	GetRouteLinkById(id string) *model.RouteLink
+}
+
 // StopLocation represents a stop with its geographic location
 type StopLocation struct {
 	ID       string
@@ -117,6 +137,234 @@ func (sg *ShapeGenerator) extractStopLocations(journeyPattern *model.JourneyPatt
 	return locations, nil
 }
 
+// hasLinks reports whether a journey pattern references any links
+func hasLinks(journeyPattern *model.JourneyPattern) bool {
+	return journeyPattern.LinksInSequence != nil && len(journeyPattern.LinksInSequence.LinkInJourneyPattern) > 0
+}
+
+// stopPointsOf returns the stop points of a journey pattern in sequence
+func stopPointsOf(journeyPattern *model.JourneyPattern) []*model.StopPointInJourneyPattern {
+	if journeyPattern.PointsInSequence == nil {
+		return nil
+	}
+	var stopPoints []*model.StopPointInJourneyPattern
+	for _, pointInterface := range journeyPattern.PointsInSequence.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern {
+		if stopPoint, ok := pointInterface.(*model.StopPointInJourneyPattern); ok {
+			stopPoints = append(stopPoints, stopPoint)
+		}
+	}
+	return stopPoints
+}
+
+// generateShapeFromLinks stitches the link projections of a journey pattern into one polyline.
+// Stop pairs without usable link geometry fall back to a straight line between the stops; if
+// such a stop cannot be located the whole pattern is skipped.
+func (sg *ShapeGenerator) generateShapeFromLinks(journeyPattern *model.JourneyPattern, repo NetexRepositoryInterface, linkRepo LinkRepositoryInterface) []*model.Shape {
+	stopPoints := stopPointsOf(journeyPattern)
+	if len(stopPoints) < 2 {
+		return nil
+	}
+
+	segments := sg.resolveLinkSegments(journeyPattern, stopPoints, linkRepo)
+
+	var polyline []Point
+	for i, segment := range segments {
+		if len(segment) < 2 {
+			from, err := sg.resolveStopLocation(stopPoints[i], repo)
+			if err != nil {
+				return nil
+			}
+			to, err := sg.resolveStopLocation(stopPoints[i+1], repo)
+			if err != nil {
+				return nil
+			}
+			segment = []Point{from, to}
+		}
+		for _, point := range segment {
+			// Consecutive links share their end and start points
+			if n := len(polyline); n > 0 && polyline[n-1] == point {
+				continue
+			}
+			polyline = append(polyline, point)
+		}
+	}
+
+	if len(polyline) < 2 {
+		return nil
+	}
+
+	shapePoints := sg.buildShapePoints(journeyPattern.ID, polyline)
+	if len(shapePoints) > sg.maxPointsPerShape {
+		shapePoints = sg.simplifyShape(shapePoints)
+	}
+	return shapePoints
+}
+
+// resolveLinkSegments returns the link geometry for each consecutive pair of stop points.
+// Links are matched on their From/To scheduled stop point refs, or by position when the
+// pattern has exactly one link per stop pair. Entries are nil where no geometry is available.
+func (sg *ShapeGenerator) resolveLinkSegments(journeyPattern *model.JourneyPattern, stopPoints []*model.StopPointInJourneyPattern, linkRepo LinkRepositoryInterface) [][]Point {
+	links := make([]*model.LinkInJourneyPattern, len(journeyPattern.LinksInSequence.LinkInJourneyPattern))
+	copy(links, journeyPattern.LinksInSequence.LinkInJourneyPattern)
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Order < links[j].Order })
+
+	byStopPair := make(map[string][]Point)
+	ordered := make([][]Point, len(links))
+	for i, link := range links {
+		var from, to string
+		var projections *model.Projections
+		switch {
+		case link.ServiceLinkRef != "":
+			if serviceLink := linkRepo.GetServiceLinkById(link.ServiceLinkRef); serviceLink != nil {
+				from, to, projections = serviceLink.FromPointRef.Ref, serviceLink.ToPointRef.Ref, serviceLink.Projections
+			}
+		case link.RouteLinkRef != "":
+			if routeLink := linkRepo.GetRouteLinkById(link.RouteLinkRef); routeLink != nil {
+				from, to, projections = routeLink.FromPointRef.Ref, routeLink.ToPointRef.Ref, routeLink.Projections
+			}
+		}
+		if projections == nil || projections.LinkSequenceProjection == nil {
+			continue
+		}
+		points := parseLineString(projections.LinkSequenceProjection.LineString)
+		if len(points) < 2 {
+			continue
+		}
+		ordered[i] = points
+		if from != "" && to != "" {
+			byStopPair[from+"|"+to] = points
+		}
+	}
+
+	segments := make([][]Point, len(stopPoints)-1)
+	for i := range segments {
+		key := stopPoints[i].ScheduledStopPointRef + "|" + stopPoints[i+1].ScheduledStopPointRef
+		if points, ok := byStopPair[key]; ok {
+			segments[i] = points
+		} else if len(ordered) == len(segments) {
+			segments[i] = ordered[i]
+		}
+	}
+	return segments
+}
+
+// parseLineString converts a gml:LineString into points. Coordinates are read as
+// latitude/longitude pairs (EPSG:4326) unless the srsName declares CRS84 axis order.
+// Projected reference systems are not supported and yield no points.
+func parseLineString(lineString *model.LineString) []Point {
+	if lineString == nil {
+		return nil
+	}
+
+	lonFirst := false
+	if srs := strings.ToUpper(lineString.SrsName); srs != "" {
+		switch {
+		case strings.Contains(srs, "CRS84"):
+			lonFirst = true
+		case !strings.HasSuffix(srs, "4326"):
+			return nil
+		}
+	}
+
+	dimension := lineString.SrsDimension
+	if dimension < 2 {
+		dimension = 2
+	}
+
+	fields := strings.Fields(lineString.PosList)
+	if len(fields) == 0 {
+		fields = strings.Fields(strings.Join(lineString.Pos, " "))
+	}
+	if len(fields) == 0 || len(fields)%dimension != 0 {
+		return nil
+	}
+
+	points := make([]Point, 0, len(fields)/dimension)
+	for i := 0; i < len(fields); i += dimension {
+		first, err1 := strconv.ParseFloat(fields[i], 64)
+		second, err2 := strconv.ParseFloat(fields[i+1], 64)
+		if err1 != nil || err2 != nil {
+			return nil
+		}
+		point := Point{Lat: first, Lon: second}
+		if lonFirst {
+			point = Point{Lat: second, Lon: first}
+		}
+		if math.Abs(point.Lat) > 90 || math.Abs(point.Lon) > 180 || (point.Lat == 0 && point.Lon == 0) {
+			return nil
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// PolygonRings converts a gml:Polygon into closed rings, the exterior first.
+// Coordinates are read like those of a gml:LineString. It returns nil when the
+// exterior cannot be read; unreadable interiors are left out.
+func PolygonRings(polygon *model.Polygon) [][]Point {
+	if polygon == nil || polygon.Exterior == nil {
+		return nil
+	}
+
+	ring := func(linearRing *model.LinearRing) []Point {
+		points := parseLineString(&model.LineString{
+			SrsName:      polygon.SrsName,
+			SrsDimension: polygon.SrsDimension,
+			PosList:      linearRing.PosList,
+			Pos:          linearRing.Pos,
+		})
+		if len(points) > 0 && points[0] != points[len(points)-1] {
+			points = append(points, points[0])
+		}
+		if len(points) < 4 {
+			return nil
+		}
+		return points
+	}
+
+	exterior := ring(polygon.Exterior)
+	if exterior == nil {
+		return nil
+	}
+	rings := [][]Point{exterior}
+	for i := range polygon.Interior {
+		if interior := ring(&polygon.Interior[i]); interior != nil {
+			rings = append(rings, interior)
+		}
+	}
+	return rings
+}
+
+// buildShapePoints creates GTFS shape points along a polyline with cumulative distances
+func (sg *ShapeGenerator) buildShapePoints(shapeID string, polyline []Point) []*model.Shape {
+	shapePoints := make([]*model.Shape, len(polyline))
+	distance := 0.0
+	for i, point := range polyline {
+		if i > 0 {
+			distance += sg.haversineDistance(polyline[i-1], point)
+		}
+		shapePoints[i] = &model.Shape{
+			ShapeID:           "shape_" + shapeID,
+			ShapePtLat:        point.Lat,
+			ShapePtLon:        point.Lon,
+			ShapePtSequence:   i + 1,
+			ShapeDistTraveled: distance,
+		}
+	}
+	return shapePoints
+}
+
+// locationFromCentroid returns the centroid location, treating (0,0) as missing
+func locationFromCentroid(centroid *model.Centroid) (Point, bool) {
+	if centroid == nil || centroid.Location == nil {
+		return Point{}, false
+	}
+	if centroid.Location.Latitude == 0 && centroid.Location.Longitude == 0 {
+		return Point{}, false
+	}
+	return Point{Lat: centroid.Location.Latitude, Lon: centroid.Location.Longitude}, true
+}
+
 // resolveStopLocation resolves the geographic location of a stop
 func (sg *ShapeGenerator) resolveStopLocation(stopPoint *model.StopPointInJourneyPattern, repo NetexRepositoryInterface) (Point, error) {
 	// Get scheduled stop point reference
@@ -131,14 +379,16 @@ func (sg *ShapeGenerator) resolveStopLocation(stopPoint *model.StopPointInJourne
 		return Point{}, fmt.Errorf("scheduled stop point not found: %s", sspRef)
 	}
 
-	// Try to get location from quay first
+	// Try to get location from quay first, then from the stop place containing it
 	if ssp.QuayRef != "" {
 		if quay := repo.GetQuayById(ssp.QuayRef); quay != nil {
-			if quay.Centroid != nil && quay.Centroid.Location != nil {
-				return Point{
-					Lat: quay.Centroid.Location.Latitude,
-					Lon: quay.Centroid.Location.Longitude,
-				}, nil
+			if location, ok := locationFromCentroid(quay.Centroid); ok {
+				return location, nil
+			}
+		}
+		if stopPlace := repo.GetStopPlaceByQuayId(ssp.QuayRef); stopPlace != nil {
+			if location, ok := locationFromCentroid(stopPlace.Centroid); ok {
+				return location, nil
 			}
 		}
 	}
@@ -146,11 +396,8 @@ func (sg *ShapeGenerator) resolveStopLocation(stopPoint *model.StopPointInJourne
 	// Fall back to stop place location
 	if ssp.StopPlaceRef != "" {
 		if stopPlace := repo.GetStopPlaceByQuayId(ssp.StopPlaceRef); stopPlace != nil {
-			if stopPlace.Centroid != nil && stopPlace.Centroid.Location != nil {
-				return Point{
-					Lat: stopPlace.Centroid.Location.Latitude,
-					Lon: stopPlace.Centroid.Location.Longitude,
-				}, nil
+			if location, ok := locationFromCentroid(stopPlace.Centroid); ok {
+				return location, nil
 			}
 		}
 	}
@@ -394,12 +641,62 @@ func (sg *ShapeGenerator) haversineDistance(point1, point2 Point) float64 {
 	return earthRadius * c
 }
 
-// CalculateShapeDistanceForStopTime calculates shape distance for a stop time
-func (sg *ShapeGenerator) CalculateShapeDistanceForStopTime(stopID string, shapes []*model.Shape) float64 {
-	// This would require matching the stop location to the nearest shape point
-	// For now, return 0 - in a full implementation, this would:
-	// 1. Get the stop's geographic location
-	// 2. Find the nearest point on the shape
-	// 3. Return the shape_dist_traveled at that point
-	return 0.0
+// CalculateShapeDistanceForStopTime calculates the shape_dist_traveled for a stop located at
+// stopLocation by projecting it onto the nearest shape segment. Only segments ending at or
+// after fromDistance are considered, so passing the previous stop's distance keeps the
+// result monotonic on looping routes. Returns fromDistance when the shape has no points.
+func (sg *ShapeGenerator) CalculateShapeDistanceForStopTime(stopLocation Point, shapes []*model.Shape, fromDistance float64) float64 {
+	if len(shapes) == 0 {
+		return fromDistance
+	}
+	if len(shapes) == 1 {
+		return math.Max(shapes[0].ShapeDistTraveled, fromDistance)
+	}
+
+	bestDistance := math.MaxFloat64
+	result := fromDistance
+
+	for i := 0; i < len(shapes)-1; i++ {
+		start, end := shapes[i], shapes[i+1]
+		if end.ShapeDistTraveled < fromDistance {
+			continue
+		}
+
+		ratio := sg.projectionRatio(stopLocation,
+			Point{Lat: start.ShapePtLat, Lon: start.ShapePtLon},
+			Point{Lat: end.ShapePtLat, Lon: end.ShapePtLon})
+		if segmentLength := end.ShapeDistTraveled - start.ShapeDistTraveled; segmentLength > 0 {
+			// Never project onto the part of the segment before fromDistance
+			ratio = math.Max(ratio, (fromDistance-start.ShapeDistTraveled)/segmentLength)
+		}
+		projected := sg.interpolatePoint(
+			Point{Lat: start.ShapePtLat, Lon: start.ShapePtLon},
+			Point{Lat: end.ShapePtLat, Lon: end.ShapePtLon}, ratio)
+
+		distance := sg.haversineDistance(stopLocation, projected)
+		if distance < bestDistance {
+			bestDistance = distance
+			result = start.ShapeDistTraveled + (end.ShapeDistTraveled-start.ShapeDistTraveled)*ratio
+		}
+	}
+
+	return math.Max(result, fromDistance)
+}
+
+// projectionRatio returns the position (0..1) of the point's projection onto a line segment
+func (sg *ShapeGenerator) projectionRatio(point, lineStart, lineEnd Point) float64 {
+	// Scale longitude so that the projection is done in approximately metric space
+	lonScale := math.Cos(point.Lat * math.Pi / 180)
+
+	A := (point.Lon - lineStart.Lon) * lonScale
+	B := point.Lat - lineStart.Lat
+	C := (lineEnd.Lon - lineStart.Lon) * lonScale
+	D := lineEnd.Lat - lineStart.Lat
+
+	lenSq := C*C + D*D
+	if lenSq == 0 {
+		return 0
+	}
+
+	return math.Max(0, math.Min(1, (A*C+B*D)/lenSq))
 }
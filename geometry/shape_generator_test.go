@@ -12,6 +12,8 @@ type mockNetexRepository struct {
 	scheduledStopPoints map[string]*model.ScheduledStopPoint
 	quays               map[string]*model.Quay
 	stopPlaces          map[string]*model.StopPlace
+	serviceLinks        map[string]*model.ServiceLink
+	routeLinks          map[string]*model.RouteLink
 }
 
 func (m *mockNetexRepository) GetScheduledStopPointRefByPointInJourneyPatternRef(pjpRef string) string {
@@ -30,6 +32,14 @@ func (m *mockNetexRepository) GetStopPlaceByQuayId(quayId string) *model.StopPla
 	return m.stopPlaces[quayId]
 }
 
+func (m *mockNetexRepository) GetServiceLinkById(id string) *model.ServiceLink {
+	return m.serviceLinks[id]
+}
+
+func (m *mockNetexRepository) GetRouteLinkById(id string) *model.RouteLink {
+	return m.routeLinks[id]
+}
+
 func TestNewShapeGenerator(t *testing.T) {
 	sg := NewShapeGenerator()
 	if sg == nil {
@@ -499,14 +509,229 @@ func TestShapeGenerator_CalculateShapeDistanceForStopTime(t *testing.T) {
 	sg := NewShapeGenerator()
 
 	shapes := []*model.Shape{
-		{ShapeDistTraveled: 0.0},
-		{ShapeDistTraveled: 100.0},
+		{ShapePtLat: 60.0, ShapePtLon: 10.0, ShapePtSequence: 1, ShapeDistTraveled: 0.0},
+		{ShapePtLat: 60.0, ShapePtLon: 10.1, ShapePtSequence: 2, ShapeDistTraveled: 1000.0},
+		{ShapePtLat: 60.1, ShapePtLon: 10.1, ShapePtSequence: 3, ShapeDistTraveled: 2000.0},
+	}
+
+	tests := []struct {
+		name         string
+		location     Point
+		fromDistance float64
+		expected     float64
+	}{
+		{"first point", Point{Lat: 60.0, Lon: 10.0}, 0, 0},
+		{"middle of first segment", Point{Lat: 60.001, Lon: 10.05}, 0, 500},
+		{"shape vertex", Point{Lat: 60.0, Lon: 10.1}, 0, 1000},
+		{"last point", Point{Lat: 60.1, Lon: 10.1}, 0, 2000},
+		{"beyond the end", Point{Lat: 60.2, Lon: 10.1}, 0, 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			distance := sg.CalculateShapeDistanceForStopTime(tt.location, shapes, tt.fromDistance)
+			if math.Abs(distance-tt.expected) > 1.0 {
+				t.Errorf("Expected %f, got %f", tt.expected, distance)
+			}
+		})
+	}
+
+	if distance := sg.CalculateShapeDistanceForStopTime(Point{Lat: 60.0, Lon: 10.0}, nil, 0); distance != 0.0 {
+		t.Errorf("Expected 0.0 for empty shape, got %f", distance)
+	}
+}
+
+func TestShapeGenerator_CalculateShapeDistanceForStopTimeLoop(t *testing.T) {
+	sg := NewShapeGenerator()
+
+	// Circular route returning to its origin
+	shapes := []*model.Shape{
+		{ShapePtLat: 60.0, ShapePtLon: 10.0, ShapePtSequence: 1, ShapeDistTraveled: 0.0},
+		{ShapePtLat: 60.0, ShapePtLon: 10.1, ShapePtSequence: 2, ShapeDistTraveled: 1000.0},
+		{ShapePtLat: 60.0, ShapePtLon: 10.0, ShapePtSequence: 3, ShapeDistTraveled: 2000.0},
+	}
+
+	origin := Point{Lat: 60.0, Lon: 10.0}
+	if distance := sg.CalculateShapeDistanceForStopTime(origin, shapes, 0); distance != 0.0 {
+		t.Errorf("Expected origin at 0.0, got %f", distance)
+	}
+	if distance := sg.CalculateShapeDistanceForStopTime(origin, shapes, 1000.0); math.Abs(distance-2000.0) > 1.0 {
+		t.Errorf("Expected returning stop at 2000.0, got %f", distance)
+	}
+}
+
+func TestShapeGenerator_GenerateShapeSkipsUnresolvedStops(t *testing.T) {
+	sg := NewShapeGenerator()
+
+	repo := &mockNetexRepository{
+		scheduledStopPoints: map[string]*model.ScheduledStopPoint{
+			"ssp1": {ID: "ssp1", QuayRef: "quay1"},
+			"ssp2": {ID: "ssp2", QuayRef: "quay2"},
+			"ssp3": {ID: "ssp3", QuayRef: "quay3"},
+		},
+		quays: map[string]*model.Quay{
+			"quay1": {ID: "quay1", Centroid: &model.Centroid{Location: &model.Location{Latitude: 60.0, Longitude: 10.0}}},
+			"quay2": {ID: "quay2", Centroid: &model.Centroid{Location: &model.Location{Latitude: 60.1, Longitude: 10.1}}},
+			// (0,0) is a placeholder, not a real location
+			"quay3": {ID: "quay3", Centroid: &model.Centroid{Location: &model.Location{Latitude: 0, Longitude: 0}}},
+		},
 	}
 
-	distance := sg.CalculateShapeDistanceForStopTime("stop1", shapes)
+	jp := &model.JourneyPattern{
+		ID: "test-pattern",
+		PointsInSequence: &model.PointsInSequence{
+			PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: []interface{}{
+				&model.StopPointInJourneyPattern{ID: "spjp1", ScheduledStopPointRef: "ssp1"},
+				&model.StopPointInJourneyPattern{ID: "spjp2", ScheduledStopPointRef: "ssp2"},
+				&model.StopPointInJourneyPattern{ID: "spjp3", ScheduledStopPointRef: "ssp3"},
+			},
+		},
+	}
 
-	// Current implementation returns 0.0
-	if distance != 0.0 {
-		t.Errorf("Expected 0.0 (placeholder implementation), got %f", distance)
+	shapes, err := sg.GenerateShape(jp, repo)
+	if err != nil {
+		t.Fatalf("GenerateShape() failed: %v", err)
+	}
+	if len(shapes) != 0 {
+		t.Errorf("Expected no shape for pattern with unresolvable stop, got %d points", len(shapes))
+	}
+}
+
+func TestShapeGenerator_ParseLineString(t *testing.T) {
+	tests := []struct {
+		name       string
+		lineString *model.LineString
+		expected   []Point
+	}{
+		{"nil", nil, nil},
+		{"lat lon pos list", &model.LineString{PosList: "60.0 10.0 60.1 10.1"}, []Point{{60.0, 10.0}, {60.1, 10.1}}},
+		{"explicit WGS84", &model.LineString{SrsName: "EPSG:4326", PosList: "60.0 10.0\n60.1 10.1"}, []Point{{60.0, 10.0}, {60.1, 10.1}}},
+		{"CRS84 lon lat", &model.LineString{SrsName: "urn:ogc:def:crs:OGC:1.3:CRS84", PosList: "10.0 60.0 10.1 60.1"}, []Point{{60.0, 10.0}, {60.1, 10.1}}},
+		{"three dimensions", &model.LineString{SrsDimension: 3, PosList: "60.0 10.0 5 60.1 10.1 7"}, []Point{{60.0, 10.0}, {60.1, 10.1}}},
+		{"pos elements", &model.LineString{Pos: []string{"60.0 10.0", "60.1 10.1"}}, []Point{{60.0, 10.0}, {60.1, 10.1}}},
+		{"projected reference system", &model.LineString{SrsName: "EPSG:25833", PosList: "600000 6600000 600100 6600100"}, nil},
+		{"odd coordinate count", &model.LineString{PosList: "60.0 10.0 60.1"}, nil},
+		{"zero coordinates", &model.LineString{PosList: "0 0 60.1 10.1"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := parseLineString(tt.lineString)
+			if len(points) != len(tt.expected) {
+				t.Fatalf("Expected %d points, got %d", len(tt.expected), len(points))
+			}
+			for i := range points {
+				if points[i] != tt.expected[i] {
+					t.Errorf("Point %d: expected %v, got %v", i, tt.expected[i], points[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPolygonRings(t *testing.T) {
+	polygon := &model.Polygon{
+		Exterior: &model.LinearRing{PosList: "60.0 10.0 60.0 10.2 60.2 10.2 60.2 10.0"},
+		Interior: []model.LinearRing{
+			{PosList: "60.05 10.05 60.05 10.1 60.1 10.1 60.05 10.05"},
+			{PosList: "60.05 10.05"},
+		},
+	}
+
+	rings := PolygonRings(polygon)
+	if len(rings) != 2 {
+		t.Fatalf("Expected an exterior and one valid interior ring, got %d rings", len(rings))
+	}
+	if len(rings[0]) != 5 || rings[0][0] != rings[0][4] {
+		t.Errorf("Expected the exterior ring to be closed, got %v", rings[0])
+	}
+	if len(rings[1]) != 4 {
+		t.Errorf("Expected the closed interior ring unchanged, got %v", rings[1])
+	}
+
+	if rings := PolygonRings(&model.Polygon{Exterior: &model.LinearRing{PosList: "60.0 10.0 60.1 10.1"}}); rings != nil {
+		t.Errorf("Expected no rings for a degenerate exterior, got %v", rings)
+	}
+	if rings := PolygonRings(nil); rings != nil {
+		t.Errorf("Expected no rings for a nil polygon, got %v", rings)
+	}
+}
+
+func TestShapeGenerator_GenerateShapeFromLinks(t *testing.T) {
+	sg := NewShapeGenerator()
+
+	location := func(lat, lon float64) *model.Centroid {
+		return &model.Centroid{Location: &model.Location{Latitude: lat, Longitude: lon}}
+	}
+	repo := &mockNetexRepository{
+		scheduledStopPoints: map[string]*model.ScheduledStopPoint{
+			"ssp1": {ID: "ssp1", QuayRef: "quay1"},
+			"ssp2": {ID: "ssp2", QuayRef: "quay2"},
+			"ssp3": {ID: "ssp3", QuayRef: "quay3"},
+		},
+		quays: map[string]*model.Quay{
+			"quay1": {ID: "quay1", Centroid: location(60.0, 10.0)},
+			"quay2": {ID: "quay2", Centroid: location(60.0, 10.02)},
+			"quay3": {ID: "quay3", Centroid: location(60.01, 10.02)},
+		},
+		serviceLinks: map[string]*model.ServiceLink{
+			// Detour north of the straight line between ssp1 and ssp2
+			"sl1": {
+				ID:           "sl1",
+				FromPointRef: model.LinkPointRef{Ref: "ssp1"},
+				ToPointRef:   model.LinkPointRef{Ref: "ssp2"},
+				Projections: &model.Projections{LinkSequenceProjection: &model.LinkSequenceProjection{
+					LineString: &model.LineString{PosList: "60.0 10.0 60.005 10.01 60.0 10.02"},
+				}},
+			},
+		},
+	}
+
+	jp := &model.JourneyPattern{
+		ID: "jp1",
+		PointsInSequence: &model.PointsInSequence{
+			PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: []interface{}{
+				&model.StopPointInJourneyPattern{ID: "spjp1", ScheduledStopPointRef: "ssp1"},
+				&model.StopPointInJourneyPattern{ID: "spjp2", ScheduledStopPointRef: "ssp2"},
+				&model.StopPointInJourneyPattern{ID: "spjp3", ScheduledStopPointRef: "ssp3"},
+			},
+		},
+		LinksInSequence: &model.LinksInSequence{
+			LinkInJourneyPattern: []*model.LinkInJourneyPattern{
+				{ID: "lijp1", Order: 1, ServiceLinkRef: "sl1"},
+				{ID: "lijp2", Order: 2, ServiceLinkRef: "missing"},
+			},
+		},
+	}
+
+	shapes, err := sg.GenerateShape(jp, repo)
+	if err != nil {
+		t.Fatalf("GenerateShape() failed: %v", err)
+	}
+
+	expected := []Point{{60.0, 10.0}, {60.005, 10.01}, {60.0, 10.02}, {60.01, 10.02}}
+	if len(shapes) != len(expected) {
+		t.Fatalf("Expected %d shape points, got %d", len(expected), len(shapes))
+	}
+	for i, shape := range shapes {
+		if shape.ShapePtLat != expected[i].Lat || shape.ShapePtLon != expected[i].Lon {
+			t.Errorf("Point %d: expected %v, got (%f, %f)", i, expected[i], shape.ShapePtLat, shape.ShapePtLon)
+		}
+		if shape.ShapeID != "shape_jp1" || shape.ShapePtSequence != i+1 {
+			t.Errorf("Point %d: unexpected id/sequence %s/%d", i, shape.ShapeID, shape.ShapePtSequence)
+		}
+		if i > 0 && shape.ShapeDistTraveled <= shapes[i-1].ShapeDistTraveled {
+			t.Errorf("Point %d: distance should increase", i)
+		}
+	}
+
+	// Without a resolvable location for the fallback segment the pattern is skipped
+	repo.quays["quay3"] = &model.Quay{ID: "quay3"}
+	shapes, err = sg.GenerateShape(jp, repo)
+	if err != nil {
+		t.Fatalf("GenerateShape() failed: %v", err)
+	}
+	if len(shapes) != 0 {
+		t.Errorf("Expected no shape when a fallback stop is unresolvable, got %d points", len(shapes))
 	}
 }
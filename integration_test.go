@@ -485,6 +485,8 @@ func performSingleConversion(t *testing.T, netexFile string) {
 type mockStopAreaRepository struct{}
 
 func (m *mockStopAreaRepository) GetQuayById(quayId string) *model.Quay               { return nil }
+func (m *mockStopAreaRepository) GetStopPlaceById(id string) *model.StopPlace         { return nil }
+func (m *mockStopAreaRepository) GetAllSitePathLinks() []*model.SitePathLink          { return nil }
 func (m *mockStopAreaRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace { return nil }
 func (m *mockStopAreaRepository) GetAllQuays() []*model.Quay                          { return nil }
 func (m *mockStopAreaRepository) LoadStopAreas(data []byte) error                     { return nil }
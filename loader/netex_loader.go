@@ -96,6 +96,25 @@ func (l *DefaultNetexDatasetLoader) parseAndLoadXML(xmlData []byte, repository p
 
 	frames := compositeFrame.Frames
 
+	// Keep the delivery header for the feed version
+	if pubDelivery.PublicationTimestamp != "" {
+		header := &model.PublicationDelivery{PublicationTimestamp: strings.TrimSpace(pubDelivery.PublicationTimestamp)}
+		if err := repository.SaveEntity(header); err != nil {
+			return fmt.Errorf("failed to save publication delivery: %w", err)
+		}
+	}
+
+	// Timezone and language the data is given in. The defaults of a contained
+	// frame are saved after the composite's so that they take precedence.
+	for _, defaults := range append([]*model.FrameDefaults{compositeFrame.FrameDefaults}, containedFrameDefaults(frames)...) {
+		if defaults == nil {
+			continue
+		}
+		if err := repository.SaveEntity(defaults); err != nil {
+			return fmt.Errorf("failed to save frame defaults: %w", err)
+		}
+	}
+
 	// Load data from different frame types
 	if err := l.loadResourceFrame(frames.ResourceFrame, repository); err != nil {
 		return fmt.Errorf("failed to load resource frame: %w", err)
@@ -117,9 +136,38 @@ func (l *DefaultNetexDatasetLoader) parseAndLoadXML(xmlData []byte, repository p
 		return fmt.Errorf("failed to load site frame: %w", err)
 	}
 
+	if err := l.loadFareFrame(frames.FareFrame, repository); err != nil {
+		return fmt.Errorf("failed to load fare frame: %w", err)
+	}
+
 	return nil
 }
 
+// containedFrameDefaults returns the FrameDefaults of the frames of a
+// CompositeFrame, in the order the frames are loaded
+func containedFrameDefaults(frames *model.Frames) []*model.FrameDefaults {
+	var defaults []*model.FrameDefaults
+	if frames.ResourceFrame != nil {
+		defaults = append(defaults, frames.ResourceFrame.FrameDefaults)
+	}
+	if frames.ServiceFrame != nil {
+		defaults = append(defaults, frames.ServiceFrame.FrameDefaults)
+	}
+	if frames.ServiceCalendarFrame != nil {
+		defaults = append(defaults, frames.ServiceCalendarFrame.FrameDefaults)
+	}
+	if frames.TimetableFrame != nil {
+		defaults = append(defaults, frames.TimetableFrame.FrameDefaults)
+	}
+	if frames.SiteFrame != nil {
+		defaults = append(defaults, frames.SiteFrame.FrameDefaults)
+	}
+	if frames.FareFrame != nil {
+		defaults = append(defaults, frames.FareFrame.FrameDefaults)
+	}
+	return defaults
+}
+
 // parseNetworksFromXML extracts Network entities from XML using XML decoder
 func (l *DefaultNetexDatasetLoader) parseNetworksFromXML(xmlData []byte, repository producer.NetexRepository) error {
 	// Use XML decoder to find Network elements in the raw XML
@@ -169,13 +217,22 @@ func (l *DefaultNetexDatasetLoader) loadResourceFrame(frame *model.ResourceFrame
 
 	// Load authorities
 	if frame.Authorities != nil {
-		for _, authority := range frame.Authorities.Authority {
-			if err := repository.SaveEntity(&authority); err != nil {
+		for i := range frame.Authorities.Authority {
+			authority := &frame.Authorities.Authority[i]
+			if err := repository.SaveEntity(authority); err != nil {
 				return fmt.Errorf("failed to save authority %s: %w", authority.ID, err)
 			}
 		}
 	}
 
+	// Load vehicle types
+	for i := range frame.VehicleTypes {
+		vehicleType := &frame.VehicleTypes[i]
+		if err := repository.SaveEntity(vehicleType); err != nil {
+			return fmt.Errorf("failed to save vehicle type %s: %w", vehicleType.ID, err)
+		}
+	}
+
 	return nil
 }
 
@@ -187,8 +244,9 @@ func (l *DefaultNetexDatasetLoader) loadServiceFrame(frame *model.ServiceFrame,
 
 	// Load lines
 	if frame.Lines != nil {
-		for _, line := range frame.Lines.Line {
-			if err := repository.SaveEntity(&line); err != nil {
+		for i := range frame.Lines.Line {
+			line := &frame.Lines.Line[i]
+			if err := repository.SaveEntity(line); err != nil {
 				return fmt.Errorf("failed to save line %s: %w", line.ID, err)
 			}
 		}
@@ -196,8 +254,9 @@ func (l *DefaultNetexDatasetLoader) loadServiceFrame(frame *model.ServiceFrame,
 
 	// Load routes
 	if frame.Routes != nil {
-		for _, route := range frame.Routes.Route {
-			if err := repository.SaveEntity(&route); err != nil {
+		for i := range frame.Routes.Route {
+			route := &frame.Routes.Route[i]
+			if err := repository.SaveEntity(route); err != nil {
 				return fmt.Errorf("failed to save route %s: %w", route.ID, err)
 			}
 		}
@@ -205,8 +264,9 @@ func (l *DefaultNetexDatasetLoader) loadServiceFrame(frame *model.ServiceFrame,
 
 	// Load journey patterns
 	if frame.JourneyPatterns != nil {
-		for _, journeyPattern := range frame.JourneyPatterns.JourneyPattern {
-			if err := repository.SaveEntity(&journeyPattern); err != nil {
+		for i := range frame.JourneyPatterns.JourneyPattern {
+			journeyPattern := &frame.JourneyPatterns.JourneyPattern[i]
+			if err := repository.SaveEntity(journeyPattern); err != nil {
 				return fmt.Errorf("failed to save journey pattern %s: %w", journeyPattern.ID, err)
 			}
 		}
@@ -214,8 +274,9 @@ func (l *DefaultNetexDatasetLoader) loadServiceFrame(frame *model.ServiceFrame,
 
 	// Load destination displays
 	if frame.DestinationDisplays != nil {
-		for _, destDisplay := range frame.DestinationDisplays.DestinationDisplay {
-			if err := repository.SaveEntity(&destDisplay); err != nil {
+		for i := range frame.DestinationDisplays.DestinationDisplay {
+			destDisplay := &frame.DestinationDisplays.DestinationDisplay[i]
+			if err := repository.SaveEntity(destDisplay); err != nil {
 				return fmt.Errorf("failed to save destination display %s: %w", destDisplay.ID, err)
 			}
 		}
@@ -223,17 +284,39 @@ func (l *DefaultNetexDatasetLoader) loadServiceFrame(frame *model.ServiceFrame,
 
 	// Load scheduled stop points
 	if frame.ScheduledStopPoints != nil {
-		for _, stopPoint := range frame.ScheduledStopPoints.ScheduledStopPoint {
-			if err := repository.SaveEntity(&stopPoint); err != nil {
+		for i := range frame.ScheduledStopPoints.ScheduledStopPoint {
+			stopPoint := &frame.ScheduledStopPoints.ScheduledStopPoint[i]
+			if err := repository.SaveEntity(stopPoint); err != nil {
 				return fmt.Errorf("failed to save scheduled stop point %s: %w", stopPoint.ID, err)
 			}
 		}
 	}
 
+	// Load service links
+	if frame.ServiceLinks != nil {
+		for i := range frame.ServiceLinks.ServiceLink {
+			serviceLink := &frame.ServiceLinks.ServiceLink[i]
+			if err := repository.SaveEntity(serviceLink); err != nil {
+				return fmt.Errorf("failed to save service link %s: %w", serviceLink.ID, err)
+			}
+		}
+	}
+
+	// Load route links
+	if frame.RouteLinks != nil {
+		for i := range frame.RouteLinks.RouteLink {
+			routeLink := &frame.RouteLinks.RouteLink[i]
+			if err := repository.SaveEntity(routeLink); err != nil {
+				return fmt.Errorf("failed to save route link %s: %w", routeLink.ID, err)
+			}
+		}
+	}
+
 	// Load service journey interchanges
 	if frame.ServiceJourneyInterchanges != nil {
-		for _, interchange := range frame.ServiceJourneyInterchanges.ServiceJourneyInterchange {
-			if err := repository.SaveEntity(&interchange); err != nil {
+		for i := range frame.ServiceJourneyInterchanges.ServiceJourneyInterchange {
+			interchange := &frame.ServiceJourneyInterchanges.ServiceJourneyInterchange[i]
+			if err := repository.SaveEntity(interchange); err != nil {
 				return fmt.Errorf("failed to save service journey interchange %s: %w", interchange.ID, err)
 			}
 		}
@@ -250,8 +333,9 @@ func (l *DefaultNetexDatasetLoader) loadServiceCalendarFrame(frame *model.Servic
 
 	// Load day types
 	if frame.DayTypes != nil {
-		for _, dayType := range frame.DayTypes.DayType {
-			if err := repository.SaveEntity(&dayType); err != nil {
+		for i := range frame.DayTypes.DayType {
+			dayType := &frame.DayTypes.DayType[i]
+			if err := repository.SaveEntity(dayType); err != nil {
 				return fmt.Errorf("failed to save day type %s: %w", dayType.ID, err)
 			}
 		}
@@ -259,8 +343,9 @@ func (l *DefaultNetexDatasetLoader) loadServiceCalendarFrame(frame *model.Servic
 
 	// Load operating days
 	if frame.OperatingDays != nil {
-		for _, operatingDay := range frame.OperatingDays.OperatingDay {
-			if err := repository.SaveEntity(&operatingDay); err != nil {
+		for i := range frame.OperatingDays.OperatingDay {
+			operatingDay := &frame.OperatingDays.OperatingDay[i]
+			if err := repository.SaveEntity(operatingDay); err != nil {
 				return fmt.Errorf("failed to save operating day %s: %w", operatingDay.ID, err)
 			}
 		}
@@ -268,17 +353,25 @@ func (l *DefaultNetexDatasetLoader) loadServiceCalendarFrame(frame *model.Servic
 
 	// Load operating periods
 	if frame.OperatingPeriods != nil {
-		for _, operatingPeriod := range frame.OperatingPeriods.OperatingPeriod {
-			if err := repository.SaveEntity(&operatingPeriod); err != nil {
+		for i := range frame.OperatingPeriods.OperatingPeriod {
+			operatingPeriod := &frame.OperatingPeriods.OperatingPeriod[i]
+			if err := repository.SaveEntity(operatingPeriod); err != nil {
 				return fmt.Errorf("failed to save operating period %s: %w", operatingPeriod.ID, err)
 			}
 		}
+		for i := range frame.OperatingPeriods.UicOperatingPeriod {
+			uicPeriod := &frame.OperatingPeriods.UicOperatingPeriod[i]
+			if err := repository.SaveEntity(uicPeriod); err != nil {
+				return fmt.Errorf("failed to save UIC operating period %s: %w", uicPeriod.ID, err)
+			}
+		}
 	}
 
 	// Load day type assignments
 	if frame.DayTypeAssignments != nil {
-		for _, dayTypeAssignment := range frame.DayTypeAssignments.DayTypeAssignment {
-			if err := repository.SaveEntity(&dayTypeAssignment); err != nil {
+		for i := range frame.DayTypeAssignments.DayTypeAssignment {
+			dayTypeAssignment := &frame.DayTypeAssignments.DayTypeAssignment[i]
+			if err := repository.SaveEntity(dayTypeAssignment); err != nil {
 				return fmt.Errorf("failed to save day type assignment %s: %w", dayTypeAssignment.ID, err)
 			}
 		}
@@ -295,8 +388,9 @@ func (l *DefaultNetexDatasetLoader) loadTimetableFrame(frame *model.TimetableFra
 
 	// Load service journeys
 	if frame.ServiceJourneys != nil {
-		for _, serviceJourney := range frame.ServiceJourneys.ServiceJourney {
-			if err := repository.SaveEntity(&serviceJourney); err != nil {
+		for i := range frame.ServiceJourneys.ServiceJourney {
+			serviceJourney := &frame.ServiceJourneys.ServiceJourney[i]
+			if err := repository.SaveEntity(serviceJourney); err != nil {
 				return fmt.Errorf("failed to save service journey %s: %w", serviceJourney.ID, err)
 			}
 		}
@@ -304,8 +398,9 @@ func (l *DefaultNetexDatasetLoader) loadTimetableFrame(frame *model.TimetableFra
 
 	// Load dated service journeys
 	if frame.DatedServiceJourneys != nil {
-		for _, datedServiceJourney := range frame.DatedServiceJourneys.DatedServiceJourney {
-			if err := repository.SaveEntity(&datedServiceJourney); err != nil {
+		for i := range frame.DatedServiceJourneys.DatedServiceJourney {
+			datedServiceJourney := &frame.DatedServiceJourneys.DatedServiceJourney[i]
+			if err := repository.SaveEntity(datedServiceJourney); err != nil {
 				return fmt.Errorf("failed to save dated service journey %s: %w", datedServiceJourney.ID, err)
 			}
 		}
@@ -314,7 +409,7 @@ func (l *DefaultNetexDatasetLoader) loadTimetableFrame(frame *model.TimetableFra
 	return nil
 }
 
-// loadSiteFrame loads stop places and quays
+// loadSiteFrame loads stop places, quays and path links
 func (l *DefaultNetexDatasetLoader) loadSiteFrame(frame *model.SiteFrame, repository producer.NetexRepository) error {
 	if frame == nil {
 		return nil
@@ -322,15 +417,17 @@ func (l *DefaultNetexDatasetLoader) loadSiteFrame(frame *model.SiteFrame, reposi
 
 	// Load stop places
 	if frame.StopPlaces != nil {
-		for _, stopPlace := range frame.StopPlaces.StopPlace {
-			if err := repository.SaveEntity(&stopPlace); err != nil {
+		for i := range frame.StopPlaces.StopPlace {
+			stopPlace := &frame.StopPlaces.StopPlace[i]
+			if err := repository.SaveEntity(stopPlace); err != nil {
 				return fmt.Errorf("failed to save stop place %s: %w", stopPlace.ID, err)
 			}
 
 			// Load quays within stop places
 			if stopPlace.Quays != nil {
-				for _, quay := range stopPlace.Quays.Quay {
-					if err := repository.SaveEntity(&quay); err != nil {
+				for j := range stopPlace.Quays.Quay {
+					quay := &stopPlace.Quays.Quay[j]
+					if err := repository.SaveEntity(quay); err != nil {
 						return fmt.Errorf("failed to save quay %s: %w", quay.ID, err)
 					}
 				}
@@ -338,5 +435,48 @@ func (l *DefaultNetexDatasetLoader) loadSiteFrame(frame *model.SiteFrame, reposi
 		}
 	}
 
+	// Load path links kept outside their stop places
+	for i := range frame.PathLinks {
+		link := &frame.PathLinks[i]
+		if err := repository.SaveEntity(link); err != nil {
+			return fmt.Errorf("failed to save path link %s: %w", link.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// loadFareFrame loads fare zones, tariffs, fare products, sales offer packages
+// and fare tables
+func (l *DefaultNetexDatasetLoader) loadFareFrame(frame *model.FareFrame, repository producer.NetexRepository) error {
+	if frame == nil {
+		return nil
+	}
+
+	var entities []interface{}
+	for i := range frame.TariffZones {
+		entities = append(entities, &frame.TariffZones[i])
+	}
+	for i := range frame.FareZones {
+		entities = append(entities, &frame.FareZones[i])
+	}
+	for i := range frame.Tariffs {
+		entities = append(entities, &frame.Tariffs[i])
+	}
+	for i := range frame.FareProducts {
+		entities = append(entities, &frame.FareProducts[i])
+	}
+	for i := range frame.SalesOfferPackages {
+		entities = append(entities, &frame.SalesOfferPackages[i])
+	}
+	for i := range frame.FareTables {
+		entities = append(entities, &frame.FareTables[i])
+	}
+
+	for _, entity := range entities {
+		if err := repository.SaveEntity(entity); err != nil {
+			return fmt.Errorf("failed to save %T: %w", entity, err)
+		}
+	}
 	return nil
 }
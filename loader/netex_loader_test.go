@@ -2,10 +2,12 @@ package loader
 
 import (
 	"bytes"
+	"encoding/xml"
 	"strings"
 	"testing"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/repository"
 )
 
 // mockNetexRepository implements NetexRepository for testing
@@ -32,6 +34,15 @@ func (m *mockNetexRepository) GetLines() []*model.Line {
 	return lines
 }
 
+func (m *mockNetexRepository) GetLineById(id string) *model.Line {
+	for _, entity := range m.entities {
+		if line, ok := entity.(*model.Line); ok && line.ID == id {
+			return line
+		}
+	}
+	return nil
+}
+
 func (m *mockNetexRepository) GetAuthorities() []*model.Authority {
 	authorities := make([]*model.Authority, 0)
 	for _, entity := range m.entities {
@@ -91,6 +102,34 @@ func (m *mockNetexRepository) GetQuayById(id string) *model.Quay {
 	return nil
 }
 
+func (m *mockNetexRepository) GetAllSitePathLinks() []*model.SitePathLink {
+	var links []*model.SitePathLink
+	for _, entity := range m.entities {
+		if link, ok := entity.(*model.SitePathLink); ok {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func (m *mockNetexRepository) GetVehicleTypeById(id string) *model.VehicleType {
+	for _, entity := range m.entities {
+		if vehicleType, ok := entity.(*model.VehicleType); ok && vehicleType.ID == id {
+			return vehicleType
+		}
+	}
+	return nil
+}
+
+func (m *mockNetexRepository) GetStopPlaceById(id string) *model.StopPlace {
+	for _, entity := range m.entities {
+		if stopPlace, ok := entity.(*model.StopPlace); ok && stopPlace.ID == id {
+			return stopPlace
+		}
+	}
+	return nil
+}
+
 func (m *mockNetexRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace {
 	for _, entity := range m.entities {
 		if stopPlace, ok := entity.(*model.StopPlace); ok && stopPlace.ID == quayId {
@@ -127,6 +166,14 @@ func (m *mockNetexRepository) GetTimeZone() string {
 	return "Europe/Oslo"
 }
 
+func (m *mockNetexRepository) GetDefaultLanguage() string {
+	return ""
+}
+
+func (m *mockNetexRepository) GetPublicationTimestamp() string {
+	return ""
+}
+
 func (m *mockNetexRepository) GetAllQuays() []*model.Quay {
 	return m.GetQuays()
 }
@@ -199,6 +246,19 @@ func (m *mockNetexRepository) GetOperatingDayById(id string) *model.OperatingDay
 	return nil
 }
 
+func (m *mockNetexRepository) GetOperatingPeriodById(id string) *model.OperatingPeriod {
+	return nil
+}
+
+func (m *mockNetexRepository) GetUicOperatingPeriodById(id string) *model.UicOperatingPeriod {
+	for _, entity := range m.entities {
+		if period, ok := entity.(*model.UicOperatingPeriod); ok && period.ID == id {
+			return period
+		}
+	}
+	return nil
+}
+
 func (m *mockNetexRepository) GetDayTypeAssignmentsByDayType(dayType *model.DayType) []*model.DayTypeAssignment {
 	return nil
 }
@@ -227,6 +287,114 @@ func (m *mockNetexRepository) GetHeadwayJourneyGroupById(id string) *model.Headw
 	return nil
 }
 
+func (m *mockNetexRepository) GetServiceLinkById(id string) *model.ServiceLink {
+	for _, entity := range m.entities {
+		if link, ok := entity.(*model.ServiceLink); ok && link.ID == id {
+			return link
+		}
+	}
+	return nil
+}
+
+func (m *mockNetexRepository) GetRouteLinkById(id string) *model.RouteLink {
+	for _, entity := range m.entities {
+		if link, ok := entity.(*model.RouteLink); ok && link.ID == id {
+			return link
+		}
+	}
+	return nil
+}
+
+func (m *mockNetexRepository) GetFlexibleStopPlaceById(id string) *model.FlexibleStopPlace {
+	for _, entity := range m.entities {
+		if stopPlace, ok := entity.(*model.FlexibleStopPlace); ok && stopPlace.ID == id {
+			return stopPlace
+		}
+	}
+	return nil
+}
+
+func (m *mockNetexRepository) GetFlexibleStopPlaceByScheduledStopPointId(sspId string) *model.FlexibleStopPlace {
+	for _, entity := range m.entities {
+		if assignment, ok := entity.(*model.FlexibleStopAssignment); ok && assignment.ScheduledStopPointRef == sspId {
+			return m.GetFlexibleStopPlaceById(assignment.FlexibleStopPlaceRef)
+		}
+	}
+	return nil
+}
+
+func (m *mockNetexRepository) GetAllFlexibleStopPlaces() []*model.FlexibleStopPlace {
+	var stopPlaces []*model.FlexibleStopPlace
+	for _, entity := range m.entities {
+		if stopPlace, ok := entity.(*model.FlexibleStopPlace); ok {
+			stopPlaces = append(stopPlaces, stopPlace)
+		}
+	}
+	return stopPlaces
+}
+
+func (m *mockNetexRepository) GetDefaultCurrency() string { return "" }
+
+func (m *mockNetexRepository) GetAllTariffZones() []*model.TariffZone {
+	var entities []*model.TariffZone
+	for _, entity := range m.entities {
+		if e, ok := entity.(*model.TariffZone); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+func (m *mockNetexRepository) GetAllFareZones() []*model.FareZone {
+	var entities []*model.FareZone
+	for _, entity := range m.entities {
+		if e, ok := entity.(*model.FareZone); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+func (m *mockNetexRepository) GetAllTariffs() []*model.Tariff {
+	var entities []*model.Tariff
+	for _, entity := range m.entities {
+		if e, ok := entity.(*model.Tariff); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+func (m *mockNetexRepository) GetAllPreassignedFareProducts() []*model.PreassignedFareProduct {
+	var entities []*model.PreassignedFareProduct
+	for _, entity := range m.entities {
+		if e, ok := entity.(*model.PreassignedFareProduct); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+func (m *mockNetexRepository) GetAllSalesOfferPackages() []*model.SalesOfferPackage {
+	var entities []*model.SalesOfferPackage
+	for _, entity := range m.entities {
+		if e, ok := entity.(*model.SalesOfferPackage); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+func (m *mockNetexRepository) GetAllFareTables() []*model.FareTable {
+	var entities []*model.FareTable
+	for _, entity := range m.entities {
+		if e, ok := entity.(*model.FareTable); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
 func TestNewDefaultNetexDatasetLoader(t *testing.T) {
 	loader := NewDefaultNetexDatasetLoader()
 	if loader == nil {
@@ -295,6 +463,17 @@ func TestDefaultNetexDatasetLoader_ParseAndLoadXML(t *testing.T) {
 						<Name>Test Authority</Name>
 					</Authority>
 				</Authorities>
+				<vehicleTypes>
+					<VehicleType id="lowFloorBus" version="1">
+						<Name>Low floor bus</Name>
+						<LowFloor>true</LowFloor>
+						<capacities>
+							<VehicleTypeCapacity>
+								<BicyclePlaces>2</BicyclePlaces>
+							</VehicleTypeCapacity>
+						</capacities>
+					</VehicleType>
+				</vehicleTypes>
 			</ResourceFrame>
 		</Frames>
 	</CompositeFrame>
@@ -313,6 +492,421 @@ func TestDefaultNetexDatasetLoader_ParseAndLoadXML(t *testing.T) {
 	if len(authorities) > 0 && authorities[0].ID != "test-authority" {
 		t.Errorf("Expected authority ID 'test-authority', got '%s'", authorities[0].ID)
 	}
+
+	vehicleType := repo.GetVehicleTypeById("lowFloorBus")
+	if vehicleType == nil {
+		t.Fatal("Expected vehicle type 'lowFloorBus' to be loaded")
+	}
+	if vehicleType.LowFloor != "true" || vehicleType.VehicleTypeCapacity == nil || vehicleType.VehicleTypeCapacity.BicyclePlaces != "2" {
+		t.Errorf("Expected low floor vehicle type with 2 bicycle places, got %+v", vehicleType)
+	}
+}
+
+func TestDefaultNetexDatasetLoader_ParseFrameDefaults(t *testing.T) {
+	loader := &DefaultNetexDatasetLoader{}
+	repo := &mockNetexRepository{}
+
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<CompositeFrame>
+		<FrameDefaults>
+			<DefaultLocale>
+				<TimeZoneOffset>+1</TimeZoneOffset>
+				<TimeZone>Europe/Paris</TimeZone>
+				<DefaultLanguage>fr</DefaultLanguage>
+			</DefaultLocale>
+		</FrameDefaults>
+		<Frames/>
+	</CompositeFrame>
+</PublicationDelivery>`
+
+	if err := loader.parseAndLoadXML([]byte(xmlData), repo); err != nil {
+		t.Fatalf("parseAndLoadXML() failed: %v", err)
+	}
+
+	var defaults *model.FrameDefaults
+	for _, entity := range repo.entities {
+		if d, ok := entity.(*model.FrameDefaults); ok {
+			defaults = d
+		}
+	}
+	if defaults == nil || defaults.DefaultLocale == nil {
+		t.Fatal("Expected frame defaults with a default locale")
+	}
+	if defaults.DefaultLocale.TimeZone != "Europe/Paris" || defaults.DefaultLocale.DefaultLanguage != "fr" {
+		t.Errorf("Unexpected default locale: %+v", defaults.DefaultLocale)
+	}
+}
+
+func TestDefaultNetexDatasetLoader_FrameDefaultsOverrideComposite(t *testing.T) {
+	loader := &DefaultNetexDatasetLoader{}
+	repo := repository.NewDefaultNetexRepository()
+
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<CompositeFrame>
+		<FrameDefaults>
+			<DefaultLocale>
+				<TimeZone>Europe/Paris</TimeZone>
+				<DefaultLanguage>fr</DefaultLanguage>
+			</DefaultLocale>
+		</FrameDefaults>
+		<Frames>
+			<ServiceFrame id="SF:1" version="1">
+				<FrameDefaults>
+					<DefaultLocale>
+						<TimeZone>Europe/Brussels</TimeZone>
+					</DefaultLocale>
+				</FrameDefaults>
+			</ServiceFrame>
+		</Frames>
+	</CompositeFrame>
+</PublicationDelivery>`
+
+	if err := loader.parseAndLoadXML([]byte(xmlData), repo); err != nil {
+		t.Fatalf("parseAndLoadXML() failed: %v", err)
+	}
+
+	if tz := repo.GetTimeZone(); tz != "Europe/Brussels" {
+		t.Errorf("Expected the ServiceFrame's timezone Europe/Brussels, got %q", tz)
+	}
+	// The ServiceFrame declares no language, so the composite's still applies
+	if lang := repo.GetDefaultLanguage(); lang != "fr" {
+		t.Errorf("Expected the CompositeFrame's language fr, got %q", lang)
+	}
+}
+
+func TestDefaultNetexDatasetLoader_ParsePointsInSequence(t *testing.T) {
+	loader := &DefaultNetexDatasetLoader{}
+	repo := &mockNetexRepository{}
+
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<CompositeFrame>
+		<Frames>
+			<ServiceFrame>
+				<JourneyPatterns>
+					<JourneyPattern id="jp1" version="1">
+						<pointsInSequence>
+							<StopPointInJourneyPattern id="spjp1" version="1" order="1">
+								<ScheduledStopPointRef ref="ssp1"/>
+								<DestinationDisplayRef ref="dd1"/>
+							</StopPointInJourneyPattern>
+							<TimingPointInJourneyPattern id="tpjp1" version="1"/>
+							<StopPointInJourneyPattern id="spjp2" version="1" order="2">
+								<ScheduledStopPointRef>ssp2</ScheduledStopPointRef>
+								<ForBoarding>false</ForBoarding>
+								<RequestStop>true</RequestStop>
+							</StopPointInJourneyPattern>
+						</pointsInSequence>
+					</JourneyPattern>
+				</JourneyPatterns>
+			</ServiceFrame>
+		</Frames>
+	</CompositeFrame>
+</PublicationDelivery>`
+
+	if err := loader.parseAndLoadXML([]byte(xmlData), repo); err != nil {
+		t.Fatalf("parseAndLoadXML() failed: %v", err)
+	}
+
+	jp := repo.GetJourneyPatternById("jp1")
+	if jp == nil || jp.PointsInSequence == nil {
+		t.Fatal("Expected journey pattern with points in sequence")
+	}
+
+	points := jp.PointsInSequence.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern
+	if len(points) != 2 {
+		t.Fatalf("Expected 2 stop points, got %d", len(points))
+	}
+
+	expected := []struct{ id, ssp, dd string }{
+		{"spjp1", "ssp1", "dd1"},
+		{"spjp2", "ssp2", ""},
+	}
+	for i, exp := range expected {
+		stopPoint, ok := points[i].(*model.StopPointInJourneyPattern)
+		if !ok {
+			t.Fatalf("Expected *model.StopPointInJourneyPattern, got %T", points[i])
+		}
+		if stopPoint.ID != exp.id || stopPoint.ScheduledStopPointRef != exp.ssp || stopPoint.DestinationDisplayRef != exp.dd {
+			t.Errorf("Unexpected stop point %d: %+v", i, stopPoint)
+		}
+	}
+
+	first := points[0].(*model.StopPointInJourneyPattern)
+	if first.ForBoarding != nil || first.ForAlighting != nil || first.RequestStop {
+		t.Errorf("Expected omitted boarding flags on spjp1, got %+v", first)
+	}
+	second := points[1].(*model.StopPointInJourneyPattern)
+	if second.ForBoarding == nil || *second.ForBoarding || second.ForAlighting != nil || !second.RequestStop {
+		t.Errorf("Expected spjp2 to be a request stop without boarding, got %+v", second)
+	}
+}
+
+func TestDefaultNetexDatasetLoader_ParseServiceLinks(t *testing.T) {
+	loader := &DefaultNetexDatasetLoader{}
+	repo := &mockNetexRepository{}
+
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex" xmlns:gml="http://www.opengis.net/gml/3.2">
+	<CompositeFrame>
+		<Frames>
+			<ServiceFrame>
+				<routeLinks>
+					<RouteLink id="rl1" version="1">
+						<FromPointRef ref="rp1"/>
+						<ToPointRef ref="rp2"/>
+					</RouteLink>
+				</routeLinks>
+				<serviceLinks>
+					<ServiceLink id="sl1" version="1">
+						<Distance>1234.5</Distance>
+						<FromPointRef ref="ssp1"/>
+						<ToPointRef ref="ssp2"/>
+						<projections>
+							<LinkSequenceProjection id="lsp1" version="1">
+								<gml:LineString gml:id="ls1" srsName="EPSG:4326">
+									<gml:posList>60.0 10.0 60.1 10.1</gml:posList>
+								</gml:LineString>
+							</LinkSequenceProjection>
+						</projections>
+					</ServiceLink>
+					<ServiceLink id="sl2" version="1">
+						<FromPointRef ref="ssp2"/>
+						<ToPointRef ref="ssp3"/>
+					</ServiceLink>
+				</serviceLinks>
+				<JourneyPatterns>
+					<JourneyPattern id="jp1" version="1">
+						<linksInSequence>
+							<ServiceLinkInJourneyPattern id="lijp1" version="1" order="1">
+								<ServiceLinkRef ref="sl1"/>
+							</ServiceLinkInJourneyPattern>
+							<LinkInJourneyPattern id="lijp2" version="1" order="2">
+								<ServiceLinkRef ref="sl2"/>
+							</LinkInJourneyPattern>
+						</linksInSequence>
+					</JourneyPattern>
+				</JourneyPatterns>
+			</ServiceFrame>
+		</Frames>
+	</CompositeFrame>
+</PublicationDelivery>`
+
+	if err := loader.parseAndLoadXML([]byte(xmlData), repo); err != nil {
+		t.Fatalf("parseAndLoadXML() failed: %v", err)
+	}
+
+	sl1 := repo.GetServiceLinkById("sl1")
+	if sl1 == nil {
+		t.Fatal("Expected service link sl1")
+	}
+	if sl1.FromPointRef.Ref != "ssp1" || sl1.ToPointRef.Ref != "ssp2" || sl1.Distance != 1234.5 {
+		t.Errorf("Unexpected service link: %+v", sl1)
+	}
+	if sl1.Projections == nil || sl1.Projections.LinkSequenceProjection == nil ||
+		sl1.Projections.LinkSequenceProjection.LineString == nil ||
+		sl1.Projections.LinkSequenceProjection.LineString.PosList != "60.0 10.0 60.1 10.1" {
+		t.Errorf("Expected posList to be parsed, got %+v", sl1.Projections)
+	}
+	if repo.GetServiceLinkById("sl2") == nil {
+		t.Error("Expected service link sl2")
+	}
+	if rl := repo.GetRouteLinkById("rl1"); rl == nil || rl.FromPointRef.Ref != "rp1" {
+		t.Errorf("Expected route link rl1, got %+v", rl)
+	}
+
+	jp := repo.GetJourneyPatternById("jp1")
+	if jp == nil || jp.LinksInSequence == nil {
+		t.Fatal("Expected journey pattern with links in sequence")
+	}
+	links := jp.LinksInSequence.LinkInJourneyPattern
+	if len(links) != 2 || links[0].ServiceLinkRef != "sl1" || links[1].ServiceLinkRef != "sl2" || links[1].Order != 2 {
+		t.Errorf("Unexpected links in sequence: %+v", links)
+	}
+}
+
+func TestDefaultNetexDatasetLoader_ParseServiceCalendar(t *testing.T) {
+	loader := &DefaultNetexDatasetLoader{}
+	repo := &mockNetexRepository{}
+
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<CompositeFrame>
+		<Frames>
+			<ServiceCalendarFrame>
+				<DayTypes>
+					<DayType id="dt1" version="1">
+						<properties>
+							<PropertyOfDay>
+								<DaysOfWeek>Monday Tuesday</DaysOfWeek>
+							</PropertyOfDay>
+						</properties>
+					</DayType>
+				</DayTypes>
+				<OperatingPeriods>
+					<OperatingPeriod id="op1" version="1">
+						<FromDate>2025-01-06T00:00:00</FromDate>
+						<ToDate>2025-03-30T00:00:00</ToDate>
+					</OperatingPeriod>
+					<UicOperatingPeriod id="uic1" version="1">
+						<FromDate>2025-03-03T00:00:00</FromDate>
+						<ToDate>2025-03-09T00:00:00</ToDate>
+						<ValidDayBits>1111100</ValidDayBits>
+					</UicOperatingPeriod>
+				</OperatingPeriods>
+				<DayTypeAssignments>
+					<DayTypeAssignment id="dta1" version="1">
+						<OperatingPeriodRef ref="op1"/>
+						<DayTypeRef ref="dt1"/>
+					</DayTypeAssignment>
+					<DayTypeAssignment id="dta2" version="1">
+						<OperatingDayRef>od1</OperatingDayRef>
+						<DayTypeRef>dt1</DayTypeRef>
+						<IsAvailable>false</IsAvailable>
+					</DayTypeAssignment>
+					<DayTypeAssignment id="dta3" version="1">
+						<UicOperatingPeriodRef ref="uic1"/>
+						<DayTypeRef ref="dt2"/>
+					</DayTypeAssignment>
+				</DayTypeAssignments>
+			</ServiceCalendarFrame>
+			<TimetableFrame>
+				<ServiceJourneys>
+					<ServiceJourney id="sj1" version="1">
+						<dayTypes>
+							<DayTypeRef ref="dt1"/>
+						</dayTypes>
+					</ServiceJourney>
+				</ServiceJourneys>
+			</TimetableFrame>
+		</Frames>
+	</CompositeFrame>
+</PublicationDelivery>`
+
+	if err := loader.parseAndLoadXML([]byte(xmlData), repo); err != nil {
+		t.Fatalf("parseAndLoadXML() failed: %v", err)
+	}
+
+	assignments := make(map[string]*model.DayTypeAssignment)
+	for _, entity := range repo.entities {
+		if a, ok := entity.(*model.DayTypeAssignment); ok {
+			assignments[a.ID] = a
+		}
+	}
+	if a := assignments["dta1"]; a == nil || a.OperatingPeriodRef != "op1" || a.DayTypeRef != "dt1" || !a.IsAvailable {
+		t.Errorf("Expected available assignment of op1 to dt1, got %+v", a)
+	}
+	if a := assignments["dta2"]; a == nil || a.OperatingDayRef != "od1" || a.DayTypeRef != "dt1" || a.IsAvailable {
+		t.Errorf("Expected unavailable assignment of od1 to dt1, got %+v", a)
+	}
+
+	if a := assignments["dta3"]; a == nil || a.OperatingPeriodRef != "uic1" {
+		t.Errorf("Expected assignment of UIC period uic1, got %+v", a)
+	}
+	var dayType *model.DayType
+	for _, entity := range repo.entities {
+		if dt, ok := entity.(*model.DayType); ok && dt.ID == "dt1" {
+			dayType = dt
+		}
+	}
+	if dayType == nil || dayType.Properties == nil || len(dayType.Properties.PropertyOfDay) != 1 || dayType.Properties.PropertyOfDay[0].DaysOfWeek != "Monday Tuesday" {
+		t.Errorf("Expected day type dt1 running Monday and Tuesday, got %+v", dayType)
+	}
+
+	if uic := repo.GetUicOperatingPeriodById("uic1"); uic == nil || uic.ValidDayBits != "1111100" || uic.FromDate != "2025-03-03T00:00:00" {
+		t.Errorf("Expected UIC operating period uic1, got %+v", uic)
+	}
+
+	journeys := repo.GetServiceJourneys()
+	if len(journeys) != 1 || journeys[0].DayTypes == nil || len(journeys[0].DayTypes.DayTypeRef) != 1 || journeys[0].DayTypes.DayTypeRef[0] != "dt1" {
+		t.Errorf("Expected service journey referencing dt1, got %+v", journeys)
+	}
+}
+
+func TestDefaultNetexDatasetLoader_ParseDestinationDisplays(t *testing.T) {
+	loader := &DefaultNetexDatasetLoader{}
+	repo := &mockNetexRepository{}
+
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<CompositeFrame>
+		<Frames>
+			<ServiceFrame>
+				<DestinationDisplays>
+					<DestinationDisplay id="dd1" version="1">
+						<FrontText>Oslo S</FrontText>
+						<vias>
+							<Via><DestinationDisplayRef ref="dd2"/></Via>
+							<Via><Name>Skøyen</Name></Via>
+						</vias>
+					</DestinationDisplay>
+					<DestinationDisplay id="dd2" version="1">
+						<FrontText>Lysaker</FrontText>
+					</DestinationDisplay>
+				</DestinationDisplays>
+				<JourneyPatterns>
+					<JourneyPattern id="jp1" version="1">
+						<RouteRef ref="route1"/>
+						<DestinationDisplayRef ref="dd1"/>
+					</JourneyPattern>
+				</JourneyPatterns>
+			</ServiceFrame>
+			<TimetableFrame>
+				<ServiceJourneys>
+					<ServiceJourney id="sj1" version="1">
+						<passingTimes>
+							<TimetabledPassingTime>
+								<StopPointInJourneyPatternRef ref="spjp1"/>
+								<DepartureTime>08:00:00</DepartureTime>
+							</TimetabledPassingTime>
+							<TimetabledPassingTime>
+								<PointInJourneyPatternRef>spjp2</PointInJourneyPatternRef>
+								<ArrivalTime>08:10:00</ArrivalTime>
+							</TimetabledPassingTime>
+						</passingTimes>
+					</ServiceJourney>
+				</ServiceJourneys>
+			</TimetableFrame>
+		</Frames>
+	</CompositeFrame>
+</PublicationDelivery>`
+
+	if err := loader.parseAndLoadXML([]byte(xmlData), repo); err != nil {
+		t.Fatalf("parseAndLoadXML() failed: %v", err)
+	}
+
+	displays := make(map[string]*model.DestinationDisplay)
+	var journeyPattern *model.JourneyPattern
+	for _, entity := range repo.entities {
+		switch e := entity.(type) {
+		case *model.DestinationDisplay:
+			displays[e.ID] = e
+		case *model.JourneyPattern:
+			journeyPattern = e
+		}
+	}
+
+	dd := displays["dd1"]
+	if dd == nil || dd.FrontText != "Oslo S" || dd.Vias == nil || len(dd.Vias.Via) != 2 {
+		t.Fatalf("Expected destination display dd1 with two vias, got %+v", dd)
+	}
+	if dd.Vias.Via[0].DestinationDisplayRef != "dd2" || dd.Vias.Via[1].Name != "Skøyen" {
+		t.Errorf("Unexpected vias %+v", dd.Vias.Via)
+	}
+	if journeyPattern == nil || journeyPattern.RouteRef != "route1" || journeyPattern.DestinationDisplayRef != "dd1" {
+		t.Errorf("Expected journey pattern referencing route1 and dd1, got %+v", journeyPattern)
+	}
+
+	journeys := repo.GetServiceJourneys()
+	if len(journeys) != 1 || journeys[0].PassingTimes == nil || len(journeys[0].PassingTimes.TimetabledPassingTime) != 2 {
+		t.Fatalf("Expected service journey with two passing times, got %+v", journeys)
+	}
+	passingTimes := journeys[0].PassingTimes.TimetabledPassingTime
+	if passingTimes[0].PointInJourneyPatternRef != "spjp1" || passingTimes[1].PointInJourneyPatternRef != "spjp2" {
+		t.Errorf("Expected passing times at spjp1 and spjp2, got %q and %q", passingTimes[0].PointInJourneyPatternRef, passingTimes[1].PointInJourneyPatternRef)
+	}
 }
 
 func TestDefaultNetexDatasetLoader_ParseAndLoadXMLInvalidStructure(t *testing.T) {
@@ -497,6 +1091,7 @@ func TestDefaultNetexDatasetLoader_LoadSiteFrame(t *testing.T) {
 				},
 			},
 		},
+		PathLinks: []model.SitePathLink{{ID: "link1"}},
 	}
 
 	err = loader.loadSiteFrame(frame, repo)
@@ -513,6 +1108,56 @@ func TestDefaultNetexDatasetLoader_LoadSiteFrame(t *testing.T) {
 	if len(quays) != 2 {
 		t.Errorf("Expected 2 quays, got %d", len(quays))
 	}
+
+	if links := repo.GetAllSitePathLinks(); len(links) != 1 {
+		t.Errorf("Expected 1 path link, got %d", len(links))
+	}
+}
+
+func TestDefaultNetexDatasetLoader_LoadFareFrame(t *testing.T) {
+	loader := &DefaultNetexDatasetLoader{}
+	repo := &mockNetexRepository{}
+
+	if err := loader.loadFareFrame(nil, repo); err != nil {
+		t.Errorf("loadFareFrame(nil) should not error, got: %v", err)
+	}
+
+	data := `<FareFrame id="ff1" version="1">
+		<tariffZones><TariffZone id="tz1"><Name>Zone 1</Name></TariffZone></tariffZones>
+		<fareZones><FareZone id="fz1"><members><ScheduledStopPointRef ref="ssp1"/></members></FareZone></fareZones>
+		<tariffs><Tariff id="tariff1"><fareStructureElements>
+			<FareStructureElement id="fse1"><distanceMatrixElements>
+				<DistanceMatrixElement id="dme1"><StartTariffZoneRef>tz1</StartTariffZoneRef><EndTariffZoneRef ref="tz2"/></DistanceMatrixElement>
+			</distanceMatrixElements></FareStructureElement>
+		</fareStructureElements></Tariff></tariffs>
+		<fareProducts><PreassignedFareProduct id="pfp1"><prices>
+			<FareProductPrice id="price1"><Amount>2.5</Amount><PreassignedFareProductRef ref="pfp1"/></FareProductPrice>
+		</prices></PreassignedFareProduct></fareProducts>
+		<salesOfferPackages><SalesOfferPackage id="sop1"/></salesOfferPackages>
+		<fareTables><FareTable id="ft1"/></fareTables>
+	</FareFrame>`
+	frame := &model.FareFrame{}
+	if err := xml.Unmarshal([]byte(data), frame); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.loadFareFrame(frame, repo); err != nil {
+		t.Fatalf("loadFareFrame() failed: %v", err)
+	}
+
+	if len(repo.GetAllTariffZones()) != 1 || len(repo.GetAllFareZones()) != 1 || len(repo.GetAllTariffs()) != 1 ||
+		len(repo.GetAllPreassignedFareProducts()) != 1 || len(repo.GetAllSalesOfferPackages()) != 1 || len(repo.GetAllFareTables()) != 1 {
+		t.Fatalf("Expected one of each fare element, got %d entities", len(repo.entities))
+	}
+	if refs := repo.GetAllFareZones()[0].ScheduledStopPointRefs(); len(refs) != 1 || refs[0] != "ssp1" {
+		t.Errorf("Expected the fare zone member ssp1, got %v", refs)
+	}
+	matrix := repo.GetAllTariffs()[0].FareStructureElements[0].DistanceMatrixElements[0]
+	if matrix.StartTariffZoneRef != "tz1" || matrix.EndTariffZoneRef != "tz2" {
+		t.Errorf("Expected the zone pair tz1-tz2, got %s-%s", matrix.StartTariffZoneRef, matrix.EndTariffZoneRef)
+	}
+	if price := repo.GetAllPreassignedFareProducts()[0].Prices[0]; price.Amount != "2.5" || price.PreassignedFareProductRef != "pfp1" {
+		t.Errorf("Unexpected price %+v", price)
+	}
 }
 
 func TestDefaultNetexDatasetLoader_ParseNetworksFromXML(t *testing.T) {
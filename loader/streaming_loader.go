@@ -4,6 +4,7 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/xml"
 	"fmt"
 	"io"
@@ -15,6 +16,10 @@ import (
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
 )
 
+// cancelCheckInterval is the number of XML tokens read between checks of the
+// context
+const cancelCheckInterval = 1000
+
 // StreamingNetexDatasetLoader implements large file streaming with memory optimization
 type StreamingNetexDatasetLoader struct {
 	maxMemoryMB      int
@@ -49,6 +54,12 @@ func (l *StreamingNetexDatasetLoader) SetProgressCallback(callback func(filename
 
 // Load implements NetexDatasetLoader with streaming and memory optimization
 func (l *StreamingNetexDatasetLoader) Load(data io.Reader, repository producer.NetexRepository) error {
+	return l.LoadContext(context.Background(), data, repository)
+}
+
+// LoadContext loads like Load, stopping with the context's error when it is
+// cancelled. Entities saved before cancellation stay in the repository.
+func (l *StreamingNetexDatasetLoader) LoadContext(ctx context.Context, data io.Reader, repository producer.NetexRepository) error {
 	// Read all data into memory first (we need it for ZIP processing)
 	zipData, err := io.ReadAll(data)
 	if err != nil {
@@ -57,15 +68,21 @@ func (l *StreamingNetexDatasetLoader) Load(data io.Reader, repository producer.N
 
 	// Determine if it's a ZIP file
 	if len(zipData) >= 4 && zipData[0] == 'P' && zipData[1] == 'K' {
-		return l.loadFromZIPStreaming(zipData, repository)
+		return l.loadFromZIPStreaming(ctx, zipData, repository)
 	}
 
 	// Single XML file
-	return l.loadFromXMLStreaming(bytes.NewReader(zipData), repository, "input.xml")
+	if err := l.loadFromXMLStreaming(ctx, bytes.NewReader(zipData), repository, "input.xml"); err != nil {
+		return err
+	}
+	if l.progressCallback != nil {
+		l.progressCallback("input.xml", int64(len(zipData)), int64(len(zipData)))
+	}
+	return nil
 }
 
 // loadFromZIPStreaming processes ZIP files with controlled memory usage
-func (l *StreamingNetexDatasetLoader) loadFromZIPStreaming(zipData []byte, repository producer.NetexRepository) error {
+func (l *StreamingNetexDatasetLoader) loadFromZIPStreaming(ctx context.Context, zipData []byte, repository producer.NetexRepository) error {
 	zipReader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
 	if err != nil {
 		return fmt.Errorf("failed to open ZIP archive: %w", err)
@@ -107,7 +124,16 @@ func (l *StreamingNetexDatasetLoader) loadFromZIPStreaming(zipData []byte, repos
 			semaphore <- struct{}{}        // Acquire
 			defer func() { <-semaphore }() // Release
 
-			if err := l.processZIPFile(f, repository); err != nil {
+			if err := ctx.Err(); err != nil {
+				errorMutex.Lock()
+				if processingError == nil {
+					processingError = err
+				}
+				errorMutex.Unlock()
+				return
+			}
+
+			if err := l.processZIPFile(ctx, f, repository); err != nil {
 				errorMutex.Lock()
 				if processingError == nil {
 					processingError = fmt.Errorf("failed to process file %s: %w", f.Name, err)
@@ -137,18 +163,18 @@ func (l *StreamingNetexDatasetLoader) loadFromZIPStreaming(zipData []byte, repos
 }
 
 // processZIPFile processes a single file from ZIP archive
-func (l *StreamingNetexDatasetLoader) processZIPFile(file *zip.File, repository producer.NetexRepository) error {
+func (l *StreamingNetexDatasetLoader) processZIPFile(ctx context.Context, file *zip.File, repository producer.NetexRepository) error {
 	rc, err := file.Open()
 	if err != nil {
 		return err
 	}
 	defer func() { _ = rc.Close() }()
 
-	return l.loadFromXMLStreaming(rc, repository, file.Name)
+	return l.loadFromXMLStreaming(ctx, rc, repository, file.Name)
 }
 
 // loadFromXMLStreaming processes XML with streaming parser
-func (l *StreamingNetexDatasetLoader) loadFromXMLStreaming(reader io.Reader, repository producer.NetexRepository, filename string) error {
+func (l *StreamingNetexDatasetLoader) loadFromXMLStreaming(runCtx context.Context, reader io.Reader, repository producer.NetexRepository, filename string) error {
 	// Use buffered reader for better performance
 	bufferedReader := bufio.NewReaderSize(reader, l.bufferSize)
 
@@ -157,6 +183,7 @@ func (l *StreamingNetexDatasetLoader) loadFromXMLStreaming(reader io.Reader, rep
 
 	// Track processing context
 	ctx := &streamingContext{
+		runCtx:     runCtx,
 		filename:   filename,
 		repository: repository,
 		processed:  0,
@@ -168,6 +195,8 @@ func (l *StreamingNetexDatasetLoader) loadFromXMLStreaming(reader io.Reader, rep
 
 // streamingContext holds context for streaming processing
 type streamingContext struct {
+	// runCtx cancels the load
+	runCtx     context.Context
 	filename   string
 	repository producer.NetexRepository
 	processed  int64
@@ -178,6 +207,12 @@ type streamingContext struct {
 // processXMLStream processes XML tokens in a streaming manner
 func (l *StreamingNetexDatasetLoader) processXMLStream(decoder *xml.Decoder, ctx *streamingContext) error {
 	for {
+		if ctx.processed%cancelCheckInterval == 0 {
+			if err := ctx.runCtx.Err(); err != nil {
+				return err
+			}
+		}
+
 		token, err := decoder.Token()
 		if err == io.EOF {
 			break
@@ -211,12 +246,24 @@ func (l *StreamingNetexDatasetLoader) handleStartElement(decoder *xml.Decoder, e
 		return nil
 
 	// High-level entities that should be processed immediately
+	case "PublicationTimestamp":
+		var timestamp string
+		if err := decoder.DecodeElement(&timestamp, element); err != nil {
+			return fmt.Errorf("failed to decode PublicationTimestamp in %s: %w", ctx.filename, err)
+		}
+		return ctx.repository.SaveEntity(&model.PublicationDelivery{PublicationTimestamp: strings.TrimSpace(timestamp)})
+	case "FrameDefaults":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.FrameDefaults{} })
 	case "Authority":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.Authority{} })
+	case "VehicleType":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.VehicleType{} })
 	case "Network":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.Network{} })
 	case "Line":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.Line{} })
+	case "FlexibleLine":
+		return l.processFlexibleLine(decoder, element, ctx)
 	case "Route":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.Route{} })
 	case "JourneyPattern":
@@ -227,6 +274,10 @@ func (l *StreamingNetexDatasetLoader) handleStartElement(decoder *xml.Decoder, e
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.DestinationDisplay{} })
 	case "ScheduledStopPoint":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.ScheduledStopPoint{} })
+	case "ServiceLink":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.ServiceLink{} })
+	case "RouteLink":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.RouteLink{} })
 	case "StopPointInJourneyPattern":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.StopPointInJourneyPattern{} })
 	case "ServiceJourney":
@@ -241,12 +292,32 @@ func (l *StreamingNetexDatasetLoader) handleStartElement(decoder *xml.Decoder, e
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.OperatingDay{} })
 	case "OperatingPeriod":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.OperatingPeriod{} })
+	case "UicOperatingPeriod":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.UicOperatingPeriod{} })
 	case "DayTypeAssignment":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.DayTypeAssignment{} })
 	case "StopPlace":
-		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.StopPlace{} })
+		return l.processStopPlace(decoder, element, ctx)
 	case "Quay":
 		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.Quay{} })
+	case "SitePathLink":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.SitePathLink{} })
+	case "FlexibleStopPlace":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.FlexibleStopPlace{} })
+	case "FlexibleStopAssignment":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.FlexibleStopAssignment{} })
+	case "TariffZone":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.TariffZone{} })
+	case "FareZone":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.FareZone{} })
+	case "Tariff":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.Tariff{} })
+	case "PreassignedFareProduct":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.PreassignedFareProduct{} })
+	case "SalesOfferPackage":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.SalesOfferPackage{} })
+	case "FareTable":
+		return l.processEntity(decoder, element, ctx, func() interface{} { return &model.FareTable{} })
 	}
 
 	return nil
@@ -293,6 +364,41 @@ func (l *StreamingNetexDatasetLoader) processServiceJourneyPattern(decoder *xml.
 	return nil
 }
 
+// processStopPlace saves a StopPlace and the quays nested in it
+func (l *StreamingNetexDatasetLoader) processStopPlace(decoder *xml.Decoder, element *xml.StartElement, ctx *streamingContext) error {
+	stopPlace := &model.StopPlace{}
+	if err := decoder.DecodeElement(stopPlace, element); err != nil {
+		return fmt.Errorf("failed to decode StopPlace in %s: %w", ctx.filename, err)
+	}
+
+	if err := ctx.repository.SaveEntity(stopPlace); err != nil {
+		return fmt.Errorf("failed to save stop place in %s: %w", ctx.filename, err)
+	}
+	if stopPlace.Quays != nil {
+		for i := range stopPlace.Quays.Quay {
+			if err := ctx.repository.SaveEntity(&stopPlace.Quays.Quay[i]); err != nil {
+				return fmt.Errorf("failed to save quay in %s: %w", ctx.filename, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// processFlexibleLine saves a FlexibleLine as a Line
+func (l *StreamingNetexDatasetLoader) processFlexibleLine(decoder *xml.Decoder, element *xml.StartElement, ctx *streamingContext) error {
+	var flexibleLine model.FlexibleLine
+	if err := decoder.DecodeElement(&flexibleLine, element); err != nil {
+		return fmt.Errorf("failed to decode FlexibleLine in %s: %w", ctx.filename, err)
+	}
+
+	if err := ctx.repository.SaveEntity(flexibleLine.ToLine()); err != nil {
+		return fmt.Errorf("failed to save line in %s: %w", ctx.filename, err)
+	}
+
+	return nil
+}
+
 // isXMLFile checks if a file is an XML file
 func (l *StreamingNetexDatasetLoader) isXMLFile(filename string) bool {
 	lower := strings.ToLower(filename)
@@ -1,11 +1,15 @@
 package loader
 
 import (
+	"context"
+	"errors"
 	"io"
 	"runtime"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
 )
 
 const (
@@ -227,9 +231,13 @@ func TestStreamingNetexDatasetLoader_ProcessEntities(t *testing.T) {
 		<Name>Network 1</Name>
 		<AuthorityRef ref="auth1" />
 	</Network>
+	<VehicleType id="bus1" version="1">
+		<WheelchairAccessible>true</WheelchairAccessible>
+	</VehicleType>
 	<Line id="line1" version="1">
 		<Name>Line 1</Name>
 		<AuthorityRef>auth1</AuthorityRef>
+		<VehicleTypeRef ref="bus1" />
 	</Line>
 	<Route id="route1" version="1">
 		<Name>Route 1</Name>
@@ -265,6 +273,50 @@ func TestStreamingNetexDatasetLoader_ProcessEntities(t *testing.T) {
 	if len(repo.entities) < 5 { // Should have at least Authority, Network, Line, Route, StopPlace, Quay
 		t.Errorf("Expected at least 5 entities loaded, got %d", len(repo.entities))
 	}
+
+	if line := repo.GetLineById("line1"); line == nil || line.VehicleTypeRef != "bus1" {
+		t.Errorf("Expected line1 with vehicle type bus1, got %+v", line)
+	}
+	if vehicleType := repo.GetVehicleTypeById("bus1"); vehicleType == nil || vehicleType.WheelchairAccessible != "true" {
+		t.Errorf("Expected wheelchair accessible vehicle type bus1, got %+v", vehicleType)
+	}
+}
+
+func TestStreamingNetexDatasetLoader_NestedQuays(t *testing.T) {
+	loader := NewStreamingNetexDatasetLoader()
+	repo := &mockNetexRepository{}
+
+	xmlData := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<SiteFrame>
+		<stopPlaces>
+			<StopPlace id="stop1" version="1">
+				<Name>Stop Place 1</Name>
+				<quays>
+					<Quay id="quay1" version="1"><Name>Platform 1</Name></Quay>
+					<Quay id="quay2" version="1"><Name>Platform 2</Name></Quay>
+				</quays>
+			</StopPlace>
+		</stopPlaces>
+	</SiteFrame>
+</PublicationDelivery>`
+
+	if err := loader.Load(strings.NewReader(xmlData), repo); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	var stopPlaces, quays int
+	for _, entity := range repo.entities {
+		switch entity.(type) {
+		case *model.StopPlace:
+			stopPlaces++
+		case *model.Quay:
+			quays++
+		}
+	}
+	if stopPlaces != 1 || quays != 2 {
+		t.Errorf("Expected the stop place and its 2 quays, got %d stop places and %d quays", stopPlaces, quays)
+	}
 }
 
 func TestStreamingNetexDatasetLoader_ProgressCallback(t *testing.T) {
@@ -292,8 +344,25 @@ func TestStreamingNetexDatasetLoader_ProgressCallback(t *testing.T) {
 		t.Fatalf("Load() failed: %v", err)
 	}
 
-	// For single XML files, we don't expect progress callbacks since it's processed directly
-	// This mainly tests that the callback doesn't cause issues
+	// A single XML file reports its whole size once loaded
+	if len(callbackCalls) != 1 || callbackCalls[0].processed != int64(len(testXMLData)) || callbackCalls[0].processed != callbackCalls[0].total {
+		t.Errorf("Expected one callback for the whole document, got %+v", callbackCalls)
+	}
+}
+
+func TestStreamingNetexDatasetLoader_LoadContextCancelled(t *testing.T) {
+	loader := NewStreamingNetexDatasetLoader().(*StreamingNetexDatasetLoader)
+	repo := &mockNetexRepository{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := loader.LoadContext(ctx, strings.NewReader(testXMLData), repo); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(repo.GetAuthorities()) != 0 {
+		t.Error("A cancelled load should not save entities")
+	}
 }
 
 func TestStreamingNetexDatasetLoader_ConcurrentProcessing(t *testing.T) {
@@ -144,8 +144,10 @@ type BookingArrangements struct {
 
 	BookingContact       *BookingContact `xml:"BookingContact,omitempty"`
 	BookingMethods       []string        `xml:"BookingMethods>BookingMethod,omitempty"` // online, phone, etc.
-	LatestBookingTime    string          `xml:"LatestBookingTime,omitempty"`            // ISO duration before travel
+	BookWhen             string          `xml:"BookWhen,omitempty"`                     // advanceOnly, untilPreviousDay, dayOfTravelOnly, etc.
+	LatestBookingTime    string          `xml:"LatestBookingTime,omitempty"`            // time of day
 	MinimumBookingPeriod string          `xml:"MinimumBookingPeriod,omitempty"`         // ISO duration
+	BookingUrl           string          `xml:"BookingUrl,omitempty"`
 	BookingNote          string          `xml:"BookingNote,omitempty"`
 }
 
@@ -169,6 +171,9 @@ type FlexibleArea struct {
 	Description string   `xml:"Description,omitempty"`
 	Polygon     *Polygon `xml:"Polygon,omitempty"`
 
+	// Stops grouped by the area
+	Members *FlexibleAreaMembers `xml:"members,omitempty"`
+
 	// Hail and ride areas
 	FlexibleQuays []FlexibleQuay `xml:"FlexibleQuays>FlexibleQuay,omitempty"`
 }
@@ -187,14 +192,48 @@ type FlexibleQuay struct {
 type Polygon struct {
 	XMLName xml.Name `xml:"Polygon"`
 
-	Exterior *LinearRing `xml:"exterior>LinearRing,omitempty"`
+	SrsName      string `xml:"srsName,attr,omitempty"`
+	SrsDimension int    `xml:"srsDimension,attr,omitempty"`
+
+	Exterior *LinearRing  `xml:"exterior>LinearRing,omitempty"`
+	Interior []LinearRing `xml:"interior>LinearRing,omitempty"`
 }
 
 // LinearRing represents a closed ring of coordinates
 type LinearRing struct {
 	XMLName xml.Name `xml:"LinearRing"`
 
-	PosList string `xml:"posList,omitempty"` // Space-separated coordinate pairs
+	PosList string   `xml:"posList,omitempty"` // Space-separated coordinate pairs
+	Pos     []string `xml:"pos,omitempty"`
+}
+
+// FlexibleAreaMembers lists the stops of a flexible area
+type FlexibleAreaMembers struct {
+	QuayRef               []refValue `xml:"QuayRef"`
+	StopPlaceRef          []refValue `xml:"StopPlaceRef"`
+	ScheduledStopPointRef []refValue `xml:"ScheduledStopPointRef"`
+}
+
+// QuayRefs returns the referenced quay IDs
+func (m *FlexibleAreaMembers) QuayRefs() []string { return refValues(m.QuayRef) }
+
+// StopPlaceRefs returns the referenced stop place IDs
+func (m *FlexibleAreaMembers) StopPlaceRefs() []string { return refValues(m.StopPlaceRef) }
+
+// ScheduledStopPointRefs returns the referenced scheduled stop point IDs
+func (m *FlexibleAreaMembers) ScheduledStopPointRefs() []string {
+	return refValues(m.ScheduledStopPointRef)
+}
+
+// refValues returns the non-empty IDs of refs
+func refValues(refs []refValue) []string {
+	var ids []string
+	for _, ref := range refs {
+		if id := ref.value(); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
 }
 
 // VehicleType represents detailed vehicle specifications
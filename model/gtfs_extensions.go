@@ -41,6 +41,18 @@ type BookingRule struct {
 	DropOffMessage         string `csv:"drop_off_message,omitempty"`
 	PhoneNumber            string `csv:"phone_number,omitempty"`
 	InfoURL                string `csv:"info_url,omitempty"`
+	BookingURL             string `csv:"booking_url,omitempty"`
+}
+
+// FlexLocation is a zone of locations.geojson in which flexible services pick
+// up and drop off riders
+type FlexLocation struct {
+	LocationID string
+	StopName   string
+	StopDesc   string
+	// Polygons holds the rings of each polygon of the zone as [lon, lat]
+	// positions; the first ring of a polygon is its exterior
+	Polygons [][][][2]float64
 }
 
 // European-specific extensions
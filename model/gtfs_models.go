@@ -63,18 +63,27 @@ type Stop struct {
 
 // StopTime represents a GTFS stop time
 type StopTime struct {
-	TripID            string
-	ArrivalTime       string
-	DepartureTime     string
-	StopID            string
-	StopSequence      int
-	StopHeadsign      string
-	PickupType        string
-	DropOffType       string
-	ContinuousPickup  string
-	ContinuousDropOff string
-	ShapeDistTraveled float64
-	Timepoint         string
+	TripID        string
+	ArrivalTime   string
+	DepartureTime string
+	StopID        string
+	// LocationGroupID or LocationID replace StopID for GTFS-Flex stop times
+	LocationGroupID string
+	LocationID      string
+	StopSequence    int
+	StopHeadsign    string
+	// The pickup and drop-off window of GTFS-Flex stop times, which have no
+	// arrival and departure times
+	StartPickupDropOffWindow string
+	EndPickupDropOffWindow   string
+	PickupType               string
+	DropOffType              string
+	ContinuousPickup         string
+	ContinuousDropOff        string
+	ShapeDistTraveled        float64
+	Timepoint                string
+	PickupBookingRuleID      string
+	DropOffBookingRuleID     string
 }
 
 // Calendar represents a GTFS calendar
@@ -142,13 +151,15 @@ type Frequency struct {
 
 // FareAttribute represents a GTFS fare attribute
 type FareAttribute struct {
-	AgencyID         string
-	FareID           string
-	Price            float64
-	CurrencyType     string
-	PaymentMethod    int
-	Transfers        int
-	TransferDuration int
+	FareID        string
+	Price         float64
+	CurrencyType  string
+	PaymentMethod int
+	// Transfers is 0, 1 or 2, or empty for unlimited transfers
+	Transfers string
+	AgencyID  string
+	// TransferDuration is in seconds, empty when transfers do not expire
+	TransferDuration string
 }
 
 // FareRule represents a GTFS fare rule
@@ -160,6 +171,74 @@ type FareRule struct {
 	ContainsID    string
 }
 
+// Area represents a GTFS Fares v2 area
+type Area struct {
+	AreaID   string
+	AreaName string
+}
+
+// AreaStop represents a row of stop_areas.txt, assigning a stop to an area
+type AreaStop struct {
+	AreaID string
+	StopID string
+}
+
+// GtfsNetwork represents a GTFS network of routes
+type GtfsNetwork struct {
+	NetworkID   string
+	NetworkName string
+}
+
+// RouteNetwork represents a row of route_networks.txt, assigning a route to a network
+type RouteNetwork struct {
+	NetworkID string
+	RouteID   string
+}
+
+// FareMedia represents a GTFS fare media
+type FareMedia struct {
+	FareMediaID   string
+	FareMediaName string
+	// FareMediaType is 0 (none), 1 (paper ticket), 2 (transit card),
+	// 3 (contactless bank card) or 4 (mobile app)
+	FareMediaType int
+}
+
+// FareProduct represents a GTFS fare product, priced per fare media
+type FareProduct struct {
+	FareProductID   string
+	FareProductName string
+	FareMediaID     string
+	// Amount has the decimal places of the currency
+	Amount   string
+	Currency string
+}
+
+// FareLegRule represents a GTFS fare leg rule
+type FareLegRule struct {
+	LegGroupID           string
+	NetworkID            string
+	FromAreaID           string
+	ToAreaID             string
+	FromTimeframeGroupID string
+	ToTimeframeGroupID   string
+	FareProductID        string
+	RulePriority         string
+}
+
+// FareTransferRule represents a GTFS fare transfer rule
+type FareTransferRule struct {
+	FromLegGroupID string
+	ToLegGroupID   string
+	// TransferCount is -1 for unlimited transfers, empty between different leg groups
+	TransferCount string
+	// DurationLimit is in seconds, empty when transfers do not expire
+	DurationLimit     string
+	DurationLimitType string
+	FareTransferType  int
+	FareProductID     string
+}
+
 // Level represents a GTFS level
 type Level struct {
 	LevelID    string
@@ -167,18 +246,22 @@ type Level struct {
 	LevelName  string
 }
 
-// Pathway represents a GTFS pathway
+// Pathway represents a GTFS pathway; its optional measures are left empty
+// when unknown
 type Pathway struct {
-	PathwayID            string
-	FromStopID           string
-	ToStopID             string
-	PathwayMode          int
-	IsBidirectional      int
-	Length               float64
-	TraversalTime        int
-	StairCount           int
-	MaxSlope             float64
-	MinWidth             float64
+	PathwayID       string
+	FromStopID      string
+	ToStopID        string
+	PathwayMode     int
+	IsBidirectional int
+	// Length is in metres
+	Length float64 `csv:",omitempty"`
+	// TraversalTime is in seconds
+	TraversalTime int `csv:",omitempty"`
+	// StairCount is negative for stairs going down from FromStopID
+	StairCount           int     `csv:",omitempty"`
+	MaxSlope             float64 `csv:",omitempty"`
+	MinWidth             float64 `csv:",omitempty"`
 	SignpostedAs         string
 	ReversedSignpostedAs string
 }
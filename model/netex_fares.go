@@ -0,0 +1,319 @@
+package model
+
+import "encoding/xml"
+
+// NeTEx fare elements of FareFrames and the zones they price
+
+// FareFrame contains fare zones, tariffs, fare products, sales offer packages
+// and fare tables
+type FareFrame struct {
+	XMLName            xml.Name                 `xml:"FareFrame"`
+	ID                 string                   `xml:"id,attr"`
+	Version            string                   `xml:"version,attr"`
+	FrameDefaults      *FrameDefaults           `xml:"FrameDefaults"`
+	TariffZones        []TariffZone             `xml:"tariffZones>TariffZone"`
+	FareZones          []FareZone               `xml:"fareZones>FareZone"`
+	Tariffs            []Tariff                 `xml:"tariffs>Tariff"`
+	FareProducts       []PreassignedFareProduct `xml:"fareProducts>PreassignedFareProduct"`
+	SalesOfferPackages []SalesOfferPackage      `xml:"salesOfferPackages>SalesOfferPackage"`
+	FareTables         []FareTable              `xml:"fareTables>FareTable"`
+}
+
+// TariffZone represents a NeTEx TariffZone, a zone fares are calculated by
+type TariffZone struct {
+	XMLName     xml.Name `xml:"TariffZone"`
+	ID          string   `xml:"id,attr"`
+	Version     string   `xml:"version,attr"`
+	Name        string   `xml:"Name"`
+	Description string   `xml:"Description"`
+}
+
+// FareZone represents a NeTEx FareZone, a tariff zone of a fare frame that
+// lists the scheduled stop points it covers
+type FareZone struct {
+	XMLName     xml.Name   `xml:"FareZone"`
+	ID          string     `xml:"id,attr"`
+	Version     string     `xml:"version,attr"`
+	Name        string     `xml:"Name"`
+	Description string     `xml:"Description"`
+	Members     []refValue `xml:"members>ScheduledStopPointRef"`
+}
+
+// ScheduledStopPointRefs returns the IDs of the zone's scheduled stop points
+func (z *FareZone) ScheduledStopPointRefs() []string { return refValues(z.Members) }
+
+// Tariff represents a NeTEx Tariff, the fare structure of a set of lines
+type Tariff struct {
+	XMLName               xml.Name               `xml:"Tariff"`
+	ID                    string                 `xml:"id,attr"`
+	Version               string                 `xml:"version,attr"`
+	Name                  string                 `xml:"Name"`
+	Lines                 []refValue             `xml:"lines>LineRef"`
+	FareStructureElements []FareStructureElement `xml:"fareStructureElements>FareStructureElement"`
+}
+
+// LineRefs returns the IDs of the lines the tariff applies to
+func (t *Tariff) LineRefs() []string { return refValues(t.Lines) }
+
+// FareStructureElement is a part of a tariff: the zones or the zone pairs
+// it is valid between and the limitations of its use
+type FareStructureElement struct {
+	ID                         string                      `xml:"id,attr"`
+	Version                    string                      `xml:"version,attr"`
+	Name                       string                      `xml:"Name"`
+	DistanceMatrixElements     []DistanceMatrixElement     `xml:"distanceMatrixElements>DistanceMatrixElement"`
+	GenericParameterAssignment *GenericParameterAssignment `xml:"GenericParameterAssignment"`
+}
+
+// DistanceMatrixElement is a journey between two tariff zones with its prices
+type DistanceMatrixElement struct {
+	ID                 string      `xml:"id,attr"`
+	Version            string      `xml:"version,attr"`
+	Name               string      `xml:"Name"`
+	StartTariffZoneRef string      `xml:"-"`
+	EndTariffZoneRef   string      `xml:"-"`
+	Prices             []FarePrice `xml:"prices>DistanceMatrixElementPrice"`
+}
+
+// UnmarshalXML accepts the zone references given either as a ref attribute or as text
+func (e *DistanceMatrixElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain DistanceMatrixElement
+	var aux struct {
+		Plain
+		StartTariffZoneRef refValue `xml:"StartTariffZoneRef"`
+		EndTariffZoneRef   refValue `xml:"EndTariffZoneRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*e = DistanceMatrixElement(aux.Plain)
+	e.StartTariffZoneRef = aux.StartTariffZoneRef.value()
+	e.EndTariffZoneRef = aux.EndTariffZoneRef.value()
+	return nil
+}
+
+// GenericParameterAssignment holds the validity parameters and limitations
+// of a fare structure element
+type GenericParameterAssignment struct {
+	ID                 string              `xml:"id,attr"`
+	ValidityParameters *ValidityParameters `xml:"validityParameters"`
+	Limitations        *FareLimitations    `xml:"limitations"`
+}
+
+// ValidityParameters lists the zones a fare structure element is valid in
+type ValidityParameters struct {
+	TariffZoneRef []refValue `xml:"TariffZoneRef"`
+	FareZoneRef   []refValue `xml:"FareZoneRef"`
+}
+
+// ZoneRefs returns the IDs of the tariff and fare zones
+func (p *ValidityParameters) ZoneRefs() []string {
+	return append(refValues(p.TariffZoneRef), refValues(p.FareZoneRef)...)
+}
+
+// FareLimitations restricts the use of a fare structure element
+type FareLimitations struct {
+	Transferability     *Transferability     `xml:"Transferability"`
+	UsageValidityPeriod *UsageValidityPeriod `xml:"UsageValidityPeriod"`
+	UserProfile         *UserProfile         `xml:"UserProfile"`
+	UserProfileRef      []refValue           `xml:"UserProfileRef"`
+}
+
+// UserProfileRefs returns the IDs of the user profiles, given inline or by reference
+func (l *FareLimitations) UserProfileRefs() []string {
+	refs := refValues(l.UserProfileRef)
+	if l.UserProfile != nil && l.UserProfile.ID != "" {
+		refs = append(refs, l.UserProfile.ID)
+	}
+	return refs
+}
+
+// Transferability describes the transfers a fare allows
+type Transferability struct {
+	ID                       string `xml:"id,attr"`
+	CanTransfer              string `xml:"CanTransfer"`
+	MaximumNumberOfTransfers string `xml:"MaximumNumberOfTransfers"`
+	// TransferDuration is an ISO 8601 duration (e.g. PT90M)
+	TransferDuration string `xml:"TransferDuration"`
+}
+
+// UsageValidityPeriod is how long a fare stays valid once used
+type UsageValidityPeriod struct {
+	ID string `xml:"id,attr"`
+	// StandardDuration is an ISO 8601 duration (e.g. PT1H)
+	StandardDuration string `xml:"StandardDuration"`
+}
+
+// UserProfile limits a fare to a category of riders
+type UserProfile struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"Name"`
+}
+
+// PreassignedFareProduct represents a NeTEx PreassignedFareProduct, a fare
+// product bought before travel
+type PreassignedFareProduct struct {
+	XMLName            xml.Name           `xml:"PreassignedFareProduct"`
+	ID                 string             `xml:"id,attr"`
+	Version            string             `xml:"version,attr"`
+	Name               string             `xml:"Name"`
+	ChargingMomentType string             `xml:"ChargingMomentType"`
+	ProductType        string             `xml:"ProductType"`
+	ValidableElements  []ValidableElement `xml:"validableElements>ValidableElement"`
+	Prices             []FarePrice        `xml:"prices>FareProductPrice"`
+}
+
+// FareStructureElementRefs returns the IDs of the fare structure elements the
+// product gives access to
+func (p *PreassignedFareProduct) FareStructureElementRefs() []string {
+	var refs []string
+	for _, element := range p.ValidableElements {
+		refs = append(refs, refValues(element.FareStructureElementRef)...)
+	}
+	return refs
+}
+
+// ValidableElement is the part of a fare structure a product gives access to
+type ValidableElement struct {
+	ID                      string     `xml:"id,attr"`
+	FareStructureElementRef []refValue `xml:"fareStructureElements>FareStructureElementRef"`
+}
+
+// SalesOfferPackage represents a NeTEx SalesOfferPackage, the fare products
+// sold together on a travel document through distribution channels
+type SalesOfferPackage struct {
+	XMLName                 xml.Name                   `xml:"SalesOfferPackage"`
+	ID                      string                     `xml:"id,attr"`
+	Version                 string                     `xml:"version,attr"`
+	Name                    string                     `xml:"Name"`
+	DistributionAssignments []DistributionAssignment   `xml:"distributionAssignments>DistributionAssignment"`
+	Elements                []SalesOfferPackageElement `xml:"salesOfferPackageElements>SalesOfferPackageElement"`
+	Prices                  []FarePrice                `xml:"prices>SalesOfferPackagePrice"`
+}
+
+// ProductRefs returns the IDs of the fare products in the package
+func (p *SalesOfferPackage) ProductRefs() []string {
+	var refs []string
+	for _, element := range p.Elements {
+		if element.PreassignedFareProductRef != "" {
+			refs = append(refs, element.PreassignedFareProductRef)
+		}
+	}
+	return refs
+}
+
+// DistributionAssignment is a channel a sales offer package is sold through
+type DistributionAssignment struct {
+	ID                      string `xml:"id,attr"`
+	DistributionChannelType string `xml:"DistributionChannelType"`
+	PaymentMethods          string `xml:"PaymentMethods"`
+}
+
+// SalesOfferPackageElement is a fare product of a sales offer package with
+// the travel document it is issued on
+type SalesOfferPackageElement struct {
+	ID                        string `xml:"id,attr"`
+	TypeOfTravelDocumentRef   string `xml:"-"`
+	PreassignedFareProductRef string `xml:"-"`
+}
+
+// UnmarshalXML accepts the references given either as a ref attribute or as text
+func (e *SalesOfferPackageElement) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain SalesOfferPackageElement
+	var aux struct {
+		Plain
+		TypeOfTravelDocumentRef   refValue `xml:"TypeOfTravelDocumentRef"`
+		PreassignedFareProductRef refValue `xml:"PreassignedFareProductRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*e = SalesOfferPackageElement(aux.Plain)
+	e.TypeOfTravelDocumentRef = aux.TypeOfTravelDocumentRef.value()
+	e.PreassignedFareProductRef = aux.PreassignedFareProductRef.value()
+	return nil
+}
+
+// FarePrice is a price of a fare product, a sales offer package or a
+// distance matrix element; references left empty are taken from the element
+// or the fare table holding the price
+type FarePrice struct {
+	ID       string `xml:"id,attr"`
+	Amount   string `xml:"Amount"`
+	Currency string `xml:"Currency"`
+
+	PreassignedFareProductRef string `xml:"-"`
+	SalesOfferPackageRef      string `xml:"-"`
+	DistanceMatrixElementRef  string `xml:"-"`
+}
+
+// UnmarshalXML accepts the references given either as a ref attribute or as text
+func (p *FarePrice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain FarePrice
+	var aux struct {
+		Plain
+		PreassignedFareProductRef refValue `xml:"PreassignedFareProductRef"`
+		SalesOfferPackageRef      refValue `xml:"SalesOfferPackageRef"`
+		DistanceMatrixElementRef  refValue `xml:"DistanceMatrixElementRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*p = FarePrice(aux.Plain)
+	p.PreassignedFareProductRef = aux.PreassignedFareProductRef.value()
+	p.SalesOfferPackageRef = aux.SalesOfferPackageRef.value()
+	p.DistanceMatrixElementRef = aux.DistanceMatrixElementRef.value()
+	return nil
+}
+
+// FareTable represents a NeTEx FareTable; its prices default to the
+// products and packages it prices
+type FareTable struct {
+	XMLName   xml.Name            `xml:"FareTable"`
+	ID        string              `xml:"id,attr"`
+	Version   string              `xml:"version,attr"`
+	Name      string              `xml:"Name"`
+	PricesFor *FareTablePricesFor `xml:"pricesFor"`
+
+	FareProductPrices           []FarePrice `xml:"prices>FareProductPrice"`
+	SalesOfferPackagePrices     []FarePrice `xml:"prices>SalesOfferPackagePrice"`
+	DistanceMatrixElementPrices []FarePrice `xml:"prices>DistanceMatrixElementPrice"`
+	// Cells are only counted; prices arranged in cells are not supported
+	Cells []struct {
+		ID string `xml:"id,attr"`
+	} `xml:"cells>Cell"`
+}
+
+// FareTablePricesFor lists the products and packages a fare table prices
+type FareTablePricesFor struct {
+	PreassignedFareProductRef []refValue `xml:"PreassignedFareProductRef"`
+	SalesOfferPackageRef      []refValue `xml:"SalesOfferPackageRef"`
+}
+
+// Prices returns the prices of the table with the references of pricesFor
+// filled in where a price has none and the table prices a single element
+func (t *FareTable) Prices() []FarePrice {
+	var product, salesOfferPackage string
+	if t.PricesFor != nil {
+		if refs := refValues(t.PricesFor.PreassignedFareProductRef); len(refs) == 1 {
+			product = refs[0]
+		}
+		if refs := refValues(t.PricesFor.SalesOfferPackageRef); len(refs) == 1 {
+			salesOfferPackage = refs[0]
+		}
+	}
+
+	var prices []FarePrice
+	for _, group := range [][]FarePrice{t.FareProductPrices, t.SalesOfferPackagePrices, t.DistanceMatrixElementPrices} {
+		for _, price := range group {
+			if price.PreassignedFareProductRef == "" {
+				price.PreassignedFareProductRef = product
+			}
+			if price.SalesOfferPackageRef == "" {
+				price.SalesOfferPackageRef = salesOfferPackage
+			}
+			prices = append(prices, price)
+		}
+	}
+	return prices
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 // NeTEx XML structures
@@ -23,6 +24,27 @@ type Line struct {
 	NetworkRef       string        `xml:"NetworkRef"`
 	BrandingRef      string        `xml:"BrandingRef"`
 	Presentation     *Presentation `xml:"Presentation"`
+	// VehicleTypeRef is the default vehicle type of the line's journeys
+	VehicleTypeRef string `xml:"-"`
+	// FlexibleLineType and Booking are set on lines loaded from a FlexibleLine
+	FlexibleLineType string               `xml:"-"`
+	Booking          *BookingArrangements `xml:"-"`
+}
+
+// UnmarshalXML accepts VehicleTypeRef given as a ref attribute or as element text
+func (l *Line) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain Line
+	var aux struct {
+		Plain
+		VehicleTypeRef refValue `xml:"VehicleTypeRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*l = Line(aux.Plain)
+	l.XMLName = start.Name
+	l.VehicleTypeRef = aux.VehicleTypeRef.value()
+	return nil
 }
 
 // Presentation represents NeTEx presentation information
@@ -98,6 +120,27 @@ type ServiceJourney struct {
 	PassingTimes      *PassingTimes            `xml:"passingTimes"`
 	DayTypes          *DayTypes                `xml:"dayTypes"`
 	NoticeAssignments *NoticeAssignments       `xml:"NoticeAssignments"`
+	// FlexibleServiceProperties is set on demand-responsive journeys
+	FlexibleServiceProperties *FlexibleServiceProperties `xml:"FlexibleServiceProperties"`
+	// VehicleTypeRef overrides the vehicle type of the journey's pattern and line
+	VehicleTypeRef string `xml:"-"`
+}
+
+// UnmarshalXML accepts VehicleTypeRef given as a ref attribute or as element text
+func (sj *ServiceJourney) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	// Embedded with an exported name so nested unmarshalers stay reachable
+	type Plain ServiceJourney
+	var aux struct {
+		Plain
+		VehicleTypeRef refValue `xml:"VehicleTypeRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*sj = ServiceJourney(aux.Plain)
+	sj.XMLName = start.Name
+	sj.VehicleTypeRef = aux.VehicleTypeRef.value()
+	return nil
 }
 
 // ServiceJourneyPatternRef represents a journey pattern reference in a service journey
@@ -132,6 +175,30 @@ type TimetabledPassingTime struct {
 	LatestTime               string             `xml:"LatestTime"`
 	DayOffset                int                `xml:"DayOffset"`
 	NoticeAssignments        *NoticeAssignments `xml:"NoticeAssignments"`
+	// EarliestDepartureTime and LatestArrivalTime bound the time window of
+	// a flexible stop
+	EarliestDepartureTime      string `xml:"EarliestDepartureTime"`
+	EarliestDepartureDayOffset int    `xml:"EarliestDepartureDayOffset"`
+	LatestArrivalTime          string `xml:"LatestArrivalTime"`
+	LatestArrivalDayOffset     int    `xml:"LatestArrivalDayOffset"`
+}
+
+// UnmarshalXML accepts the point reference as PointInJourneyPatternRef or
+// StopPointInJourneyPatternRef, given either as a ref attribute or as text
+func (tpt *TimetabledPassingTime) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain TimetabledPassingTime
+	var aux struct {
+		Plain
+		PointInJourneyPatternRef     refValue `xml:"PointInJourneyPatternRef"`
+		StopPointInJourneyPatternRef refValue `xml:"StopPointInJourneyPatternRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*tpt = TimetabledPassingTime(aux.Plain)
+	tpt.XMLName = start.Name
+	tpt.PointInJourneyPatternRef = firstNonBlank(aux.StopPointInJourneyPatternRef.value(), aux.PointInJourneyPatternRef.value())
+	return nil
 }
 
 // DayTypes represents day type assignments
@@ -140,6 +207,24 @@ type DayTypes struct {
 	DayTypeRef []string `xml:"DayTypeRef"`
 }
 
+// UnmarshalXML accepts DayTypeRef given as ref attributes or as element text
+func (dt *DayTypes) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var aux struct {
+		DayTypeRef []refValue `xml:"DayTypeRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	dt.XMLName = start.Name
+	dt.DayTypeRef = nil
+	for _, ref := range aux.DayTypeRef {
+		if id := ref.value(); id != "" {
+			dt.DayTypeRef = append(dt.DayTypeRef, id)
+		}
+	}
+	return nil
+}
+
 // NoticeAssignments represents notice assignments
 type NoticeAssignments struct {
 	XMLName          xml.Name           `xml:"NoticeAssignments"`
@@ -164,7 +249,30 @@ type JourneyPattern struct {
 	RouteRef              string            `xml:"RouteRef"`
 	DirectionType         string            `xml:"DirectionType"`
 	PointsInSequence      *PointsInSequence `xml:"pointsInSequence"`
+	LinksInSequence       *LinksInSequence  `xml:"linksInSequence"`
 	DestinationDisplayRef string            `xml:"DestinationDisplayRef"`
+	VehicleTypeRef        string            `xml:"VehicleTypeRef"`
+}
+
+// UnmarshalXML accepts RouteRef, DestinationDisplayRef and VehicleTypeRef given as ref attributes or as element text
+func (jp *JourneyPattern) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	// Embedded with an exported name so nested unmarshalers stay reachable
+	type Plain JourneyPattern
+	var aux struct {
+		Plain
+		RouteRef              refValue `xml:"RouteRef"`
+		DestinationDisplayRef refValue `xml:"DestinationDisplayRef"`
+		VehicleTypeRef        refValue `xml:"VehicleTypeRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*jp = JourneyPattern(aux.Plain)
+	jp.XMLName = start.Name
+	jp.RouteRef = aux.RouteRef.value()
+	jp.DestinationDisplayRef = aux.DestinationDisplayRef.value()
+	jp.VehicleTypeRef = aux.VehicleTypeRef.value()
+	return nil
 }
 
 // ServiceJourneyPattern represents a NeTEx ServiceJourneyPattern (same structure as JourneyPattern)
@@ -178,7 +286,9 @@ type ServiceJourneyPattern struct {
 	RouteRef              ServiceJourneyPatternRouteRef              `xml:"RouteRef"`
 	DirectionType         string                                     `xml:"DirectionType"`
 	PointsInSequence      *PointsInSequence                          `xml:"pointsInSequence"`
+	LinksInSequence       *LinksInSequence                           `xml:"linksInSequence"`
 	DestinationDisplayRef ServiceJourneyPatternDestinationDisplayRef `xml:"DestinationDisplayRef"`
+	VehicleTypeRef        refValue                                   `xml:"VehicleTypeRef"`
 }
 
 // ServiceJourneyPatternRouteRef represents a route reference in a service journey pattern
@@ -206,7 +316,9 @@ func (sjp *ServiceJourneyPattern) ToJourneyPattern() *JourneyPattern {
 		RouteRef:              sjp.RouteRef.Ref,
 		DirectionType:         sjp.DirectionType,
 		PointsInSequence:      sjp.PointsInSequence,
+		LinksInSequence:       sjp.LinksInSequence,
 		DestinationDisplayRef: sjp.DestinationDisplayRef.Ref,
+		VehicleTypeRef:        sjp.VehicleTypeRef.value(),
 	}
 }
 
@@ -216,6 +328,175 @@ type PointsInSequence struct {
 	PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern []interface{} `xml:",any"`
 }
 
+// UnmarshalXML decodes StopPointInJourneyPattern children into typed points and skips
+// point types the converter does not use.
+func (p *PointsInSequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	p.XMLName = start.Name
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "StopPointInJourneyPattern" {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			var aux struct {
+				StopPointInJourneyPattern
+				ScheduledStopPointRef refValue `xml:"ScheduledStopPointRef"`
+				DestinationDisplayRef refValue `xml:"DestinationDisplayRef"`
+			}
+			if err := d.DecodeElement(&aux, &t); err != nil {
+				return err
+			}
+			point := aux.StopPointInJourneyPattern
+			point.XMLName = t.Name
+			point.ScheduledStopPointRef = aux.ScheduledStopPointRef.value()
+			point.DestinationDisplayRef = aux.DestinationDisplayRef.value()
+			p.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern = append(
+				p.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern, &point)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// LinksInSequence represents the sequence of links in a journey pattern
+type LinksInSequence struct {
+	XMLName              xml.Name                `xml:"linksInSequence"`
+	LinkInJourneyPattern []*LinkInJourneyPattern `xml:"-"`
+}
+
+// LinkInJourneyPattern references the ServiceLink or RouteLink travelled between two points
+type LinkInJourneyPattern struct {
+	ID             string
+	Version        string
+	Order          int
+	ServiceLinkRef string
+	RouteLinkRef   string
+}
+
+// UnmarshalXML decodes LinkInJourneyPattern and ServiceLinkInJourneyPattern children
+func (l *LinksInSequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	l.XMLName = start.Name
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "LinkInJourneyPattern" && t.Name.Local != "ServiceLinkInJourneyPattern" {
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			var aux struct {
+				ID             string   `xml:"id,attr"`
+				Version        string   `xml:"version,attr"`
+				Order          int      `xml:"order,attr"`
+				ServiceLinkRef refValue `xml:"ServiceLinkRef"`
+				RouteLinkRef   refValue `xml:"RouteLinkRef"`
+			}
+			if err := d.DecodeElement(&aux, &t); err != nil {
+				return err
+			}
+			l.LinkInJourneyPattern = append(l.LinkInJourneyPattern, &LinkInJourneyPattern{
+				ID:             aux.ID,
+				Version:        aux.Version,
+				Order:          aux.Order,
+				ServiceLinkRef: aux.ServiceLinkRef.value(),
+				RouteLinkRef:   aux.RouteLinkRef.value(),
+			})
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// ServiceLink represents a NeTEx ServiceLink between two scheduled stop points
+type ServiceLink struct {
+	XMLName      xml.Name     `xml:"ServiceLink"`
+	ID           string       `xml:"id,attr"`
+	Version      string       `xml:"version,attr"`
+	Name         string       `xml:"Name"`
+	Distance     float64      `xml:"Distance"`
+	FromPointRef LinkPointRef `xml:"FromPointRef"`
+	ToPointRef   LinkPointRef `xml:"ToPointRef"`
+	Projections  *Projections `xml:"projections"`
+}
+
+// RouteLink represents a NeTEx RouteLink between two route points
+type RouteLink struct {
+	XMLName      xml.Name     `xml:"RouteLink"`
+	ID           string       `xml:"id,attr"`
+	Version      string       `xml:"version,attr"`
+	Name         string       `xml:"Name"`
+	Distance     float64      `xml:"Distance"`
+	FromPointRef LinkPointRef `xml:"FromPointRef"`
+	ToPointRef   LinkPointRef `xml:"ToPointRef"`
+	Projections  *Projections `xml:"projections"`
+}
+
+// LinkPointRef represents the start or end point reference of a link
+type LinkPointRef struct {
+	Ref        string `xml:"ref,attr"`
+	VersionRef string `xml:"versionRef,attr"`
+}
+
+// Projections contains the geometric projections of a link
+type Projections struct {
+	XMLName                xml.Name                `xml:"projections"`
+	LinkSequenceProjection *LinkSequenceProjection `xml:"LinkSequenceProjection"`
+}
+
+// LinkSequenceProjection carries the GML line geometry of a link
+type LinkSequenceProjection struct {
+	XMLName    xml.Name    `xml:"LinkSequenceProjection"`
+	ID         string      `xml:"id,attr"`
+	Version    string      `xml:"version,attr"`
+	LineString *LineString `xml:"LineString"`
+}
+
+// LineString represents a gml:LineString
+type LineString struct {
+	XMLName      xml.Name `xml:"LineString"`
+	ID           string   `xml:"id,attr"`
+	SrsName      string   `xml:"srsName,attr"`
+	SrsDimension int      `xml:"srsDimension,attr"`
+	PosList      string   `xml:"posList"`
+	Pos          []string `xml:"pos"`
+}
+
+// firstNonBlank returns the first value that is not empty after trimming whitespace
+func firstNonBlank(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// refValue decodes a NeTEx reference given either as a ref attribute or as element text
+type refValue struct {
+	Ref   string `xml:"ref,attr"`
+	Value string `xml:",chardata"`
+}
+
+// value returns the referenced ID, preferring the ref attribute
+func (r refValue) value() string {
+	if ref := strings.TrimSpace(r.Ref); ref != "" {
+		return ref
+	}
+	return strings.TrimSpace(r.Value)
+}
+
 // ScheduledStopPoint represents a scheduled stop point (may link to Quay/StopPlace elsewhere)
 type ScheduledStopPoint struct {
 	XMLName      xml.Name `xml:"ScheduledStopPoint"`
@@ -228,17 +509,20 @@ type ScheduledStopPoint struct {
 
 // StopPointInJourneyPattern represents a stop point in a journey pattern
 type StopPointInJourneyPattern struct {
-	XMLName               xml.Name           `xml:"StopPointInJourneyPattern"`
-	ID                    string             `xml:"id,attr"`
-	Version               string             `xml:"version,attr"`
-	Order                 int                `xml:"Order"`
-	ScheduledStopPointRef string             `xml:"ScheduledStopPointRef"`
-	DestinationDisplayRef string             `xml:"DestinationDisplayRef"`
-	ForAlighting          bool               `xml:"ForAlighting"`
-	ForBoarding           bool               `xml:"ForBoarding"`
-	IsWaitPoint           bool               `xml:"IsWaitPoint"`
-	WaitTime              string             `xml:"WaitTime"`
-	NoticeAssignments     *NoticeAssignments `xml:"NoticeAssignments"`
+	XMLName               xml.Name `xml:"StopPointInJourneyPattern"`
+	ID                    string   `xml:"id,attr"`
+	Version               string   `xml:"version,attr"`
+	Order                 int      `xml:"Order"`
+	ScheduledStopPointRef string   `xml:"ScheduledStopPointRef"`
+	DestinationDisplayRef string   `xml:"DestinationDisplayRef"`
+	// ForAlighting and ForBoarding are nil when omitted, which NeTEx treats as allowed
+	ForAlighting        *bool                `xml:"ForAlighting"`
+	ForBoarding         *bool                `xml:"ForBoarding"`
+	RequestStop         bool                 `xml:"RequestStop"`
+	BookingArrangements *BookingArrangements `xml:"BookingArrangements"`
+	IsWaitPoint         bool                 `xml:"IsWaitPoint"`
+	WaitTime            string               `xml:"WaitTime"`
+	NoticeAssignments   *NoticeAssignments   `xml:"NoticeAssignments"`
 }
 
 // Route represents a NeTEx Route
@@ -275,6 +559,41 @@ type StopPlace struct {
 	Quays                   *Quays                   `xml:"Quays"`
 	AccessibilityAssessment *AccessibilityAssessment `xml:"AccessibilityAssessment"`
 	NoticeAssignments       *NoticeAssignments       `xml:"NoticeAssignments"`
+	TariffZones             []refValue               `xml:"tariffZones>TariffZoneRef"`
+	FareZones               []refValue               `xml:"tariffZones>FareZoneRef"`
+	Entrances               []StopPlaceEntrance      `xml:"entrances>StopPlaceEntrance"`
+	Levels                  []NetexLevel             `xml:"levels>Level"`
+	PathLinks               []SitePathLink           `xml:"pathLinks>SitePathLink"`
+	// ParentSiteRef is the multimodal stop place grouping this one
+	ParentSiteRef string `xml:"-"`
+}
+
+// TariffZoneRefs returns the IDs of the tariff and fare zones the stop place is in
+func (sp *StopPlace) TariffZoneRefs() []string {
+	return append(refValues(sp.TariffZones), refValues(sp.FareZones)...)
+}
+
+// UnmarshalXML accepts the stop place's quays in a Quays or a quays container
+func (sp *StopPlace) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	// Embedded with an exported name so nested unmarshalers stay reachable
+	type Plain StopPlace
+	var aux struct {
+		Plain
+		LowerQuays *struct {
+			Quay []Quay `xml:"Quay"`
+		} `xml:"quays"`
+		ParentSiteRef refValue `xml:"ParentSiteRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*sp = StopPlace(aux.Plain)
+	sp.XMLName = start.Name
+	sp.ParentSiteRef = aux.ParentSiteRef.value()
+	if sp.Quays == nil && aux.LowerQuays != nil {
+		sp.Quays = &Quays{XMLName: xml.Name{Local: "Quays"}, Quay: aux.LowerQuays.Quay}
+	}
+	return nil
 }
 
 // Quays represents a collection of quays
@@ -295,6 +614,109 @@ type Quay struct {
 	Centroid                *Centroid                `xml:"Centroid"`
 	AccessibilityAssessment *AccessibilityAssessment `xml:"AccessibilityAssessment"`
 	NoticeAssignments       *NoticeAssignments       `xml:"NoticeAssignments"`
+	TariffZones             []refValue               `xml:"tariffZones>TariffZoneRef"`
+	FareZones               []refValue               `xml:"tariffZones>FareZoneRef"`
+	BoardingPositions       []BoardingPosition       `xml:"boardingPositions>BoardingPosition"`
+	Level                   refValue                 `xml:"LevelRef"`
+}
+
+// TariffZoneRefs returns the IDs of the tariff and fare zones the quay is in;
+// a quay without zones is in the zones of its stop place
+func (q *Quay) TariffZoneRefs() []string {
+	return append(refValues(q.TariffZones), refValues(q.FareZones)...)
+}
+
+// LevelRef returns the ID of the level the quay is on
+func (q *Quay) LevelRef() string { return q.Level.value() }
+
+// StopPlaceEntrance represents a NeTEx StopPlaceEntrance, a way into a stop place
+type StopPlaceEntrance struct {
+	XMLName     xml.Name  `xml:"StopPlaceEntrance"`
+	ID          string    `xml:"id,attr"`
+	Version     string    `xml:"version,attr"`
+	Name        string    `xml:"Name"`
+	Description string    `xml:"Description"`
+	PublicCode  string    `xml:"PublicCode"`
+	Centroid    *Centroid `xml:"Centroid"`
+	Level       refValue  `xml:"LevelRef"`
+}
+
+// LevelRef returns the ID of the level the entrance is on
+func (e *StopPlaceEntrance) LevelRef() string { return e.Level.value() }
+
+// BoardingPosition represents a NeTEx BoardingPosition, a place on a quay
+// where passengers board, such as a door position
+type BoardingPosition struct {
+	XMLName     xml.Name  `xml:"BoardingPosition"`
+	ID          string    `xml:"id,attr"`
+	Version     string    `xml:"version,attr"`
+	Name        string    `xml:"Name"`
+	Description string    `xml:"Description"`
+	PublicCode  string    `xml:"PublicCode"`
+	Centroid    *Centroid `xml:"Centroid"`
+	Level       refValue  `xml:"LevelRef"`
+}
+
+// LevelRef returns the ID of the level the boarding position is on
+func (b *BoardingPosition) LevelRef() string { return b.Level.value() }
+
+// NetexLevel represents a NeTEx Level of a stop place; Level is the GTFS level
+type NetexLevel struct {
+	XMLName     xml.Name `xml:"Level"`
+	ID          string   `xml:"id,attr"`
+	Version     string   `xml:"version,attr"`
+	Name        string   `xml:"Name"`
+	ShortName   string   `xml:"ShortName"`
+	Description string   `xml:"Description"`
+	// PublicCode is the floor number, e.g. "0", "1" or "-1"
+	PublicCode string `xml:"PublicCode"`
+}
+
+// SitePathLink represents a NeTEx SitePathLink, a path between two places of
+// a stop place such as quays, entrances and boarding positions
+type SitePathLink struct {
+	XMLName     xml.Name     `xml:"SitePathLink"`
+	ID          string       `xml:"id,attr"`
+	Version     string       `xml:"version,attr"`
+	Name        string       `xml:"Name"`
+	Description string       `xml:"Description"`
+	PublicCode  string       `xml:"PublicCode"`
+	Label       string       `xml:"Label"`
+	From        *PathLinkEnd `xml:"From"`
+	To          *PathLinkEnd `xml:"To"`
+	// Distance is in metres
+	Distance float64 `xml:"Distance"`
+	// AllowedUse is twoWay, oneWay (or forwards) or backwards
+	AllowedUse string `xml:"AllowedUse"`
+	// Transition is up, down, level or upAndDown
+	Transition    string `xml:"Transition"`
+	NumberOfSteps int    `xml:"NumberOfSteps"`
+	// AccessFeatureType is the kind of path, e.g. stairs, escalator, lift,
+	// travelator, ramp or barrier
+	AccessFeatureType string            `xml:"AccessFeatureType"`
+	MinimumWidth      float64           `xml:"MinimumWidth"`
+	TransferDuration  *TransferDuration `xml:"TransferDuration"`
+}
+
+// PathLinkEnd is an end of a path link
+type PathLinkEnd struct {
+	Place refValue `xml:"PlaceRef"`
+}
+
+// PlaceRef returns the ID of the place at the end of a path link
+func (e *PathLinkEnd) PlaceRef() string {
+	if e == nil {
+		return ""
+	}
+	return e.Place.value()
+}
+
+// TransferDuration holds the ISO 8601 durations to traverse a path link
+type TransferDuration struct {
+	DefaultDuration                     string `xml:"DefaultDuration"`
+	FrequentTravellerDuration           string `xml:"FrequentTravellerDuration"`
+	OccasionalTravellerDuration         string `xml:"OccasionalTravellerDuration"`
+	MobilityRestrictedTravellerDuration string `xml:"MobilityRestrictedTravellerDuration"`
 }
 
 // Centroid represents a geometric centroid
@@ -343,16 +765,55 @@ type DestinationDisplay struct {
 	Vias      *Vias    `xml:"Vias"`
 }
 
+// UnmarshalXML accepts vias in either a vias or a Vias container, with
+// DestinationDisplayRef given as a ref attribute or as element text
+func (dd *DestinationDisplay) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type via struct {
+		DestinationDisplayRef refValue `xml:"DestinationDisplayRef"`
+		Name                  string   `xml:"Name"`
+	}
+	var aux struct {
+		ID        string `xml:"id,attr"`
+		Version   string `xml:"version,attr"`
+		FrontText string `xml:"FrontText"`
+		SideText  string `xml:"SideText"`
+		Vias      []via  `xml:"vias>Via"`
+		ViasUpper []via  `xml:"Vias>Via"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*dd = DestinationDisplay{
+		XMLName:   start.Name,
+		ID:        aux.ID,
+		Version:   aux.Version,
+		FrontText: strings.TrimSpace(aux.FrontText),
+		SideText:  strings.TrimSpace(aux.SideText),
+	}
+	for _, v := range append(aux.Vias, aux.ViasUpper...) {
+		if dd.Vias == nil {
+			dd.Vias = &Vias{XMLName: xml.Name{Local: "Vias"}}
+		}
+		dd.Vias.Via = append(dd.Vias.Via, Via{
+			XMLName:               xml.Name{Local: "Via"},
+			DestinationDisplayRef: v.DestinationDisplayRef.value(),
+			Name:                  strings.TrimSpace(v.Name),
+		})
+	}
+	return nil
+}
+
 // Vias represents via information
 type Vias struct {
 	XMLName xml.Name `xml:"Vias"`
 	Via     []Via    `xml:"Via"`
 }
 
-// Via represents a via point
+// Via represents a via point, either a DestinationDisplay or a plain name
 type Via struct {
 	XMLName               xml.Name `xml:"Via"`
 	DestinationDisplayRef string   `xml:"DestinationDisplayRef"`
+	Name                  string   `xml:"Name"`
 }
 
 // ServiceJourneyInterchange represents a service journey interchange
@@ -370,6 +831,28 @@ type ServiceJourneyInterchange struct {
 	Priority            int      `xml:"Priority"`
 }
 
+// UnmarshalXML accepts point and journey refs given as ref attributes or as element text
+func (sji *ServiceJourneyInterchange) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain ServiceJourneyInterchange
+	var aux struct {
+		Plain
+		FromJourneyRef refValue `xml:"FromJourneyRef"`
+		ToJourneyRef   refValue `xml:"ToJourneyRef"`
+		FromPointRef   refValue `xml:"FromPointRef"`
+		ToPointRef     refValue `xml:"ToPointRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*sji = ServiceJourneyInterchange(aux.Plain)
+	sji.XMLName = start.Name
+	sji.FromJourneyRef = aux.FromJourneyRef.value()
+	sji.ToJourneyRef = aux.ToJourneyRef.value()
+	sji.FromPointRef = aux.FromPointRef.value()
+	sji.ToPointRef = aux.ToPointRef.value()
+	return nil
+}
+
 // DayType represents a NeTEx DayType
 type DayType struct {
 	XMLName    xml.Name    `xml:"DayType"`
@@ -379,6 +862,29 @@ type DayType struct {
 	Properties *Properties `xml:"Properties"`
 }
 
+// UnmarshalXML accepts day properties in either a properties or a Properties container
+func (dt *DayType) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain DayType
+	var aux struct {
+		Plain
+		LowerProperties *struct {
+			PropertyOfDay []PropertyOfDay `xml:"PropertyOfDay"`
+		} `xml:"properties"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*dt = DayType(aux.Plain)
+	dt.XMLName = start.Name
+	if dt.Properties == nil && aux.LowerProperties != nil {
+		dt.Properties = &Properties{
+			XMLName:       xml.Name{Space: start.Name.Space, Local: "Properties"},
+			PropertyOfDay: aux.LowerProperties.PropertyOfDay,
+		}
+	}
+	return nil
+}
+
 // Properties represents day type properties
 type Properties struct {
 	XMLName       xml.Name        `xml:"Properties"`
@@ -408,7 +914,20 @@ type OperatingPeriod struct {
 	ToDate   string   `xml:"ToDate"`
 }
 
-// DayTypeAssignment represents a day type assignment
+// UicOperatingPeriod represents a NeTEx UicOperatingPeriod. ValidDayBits holds one
+// character per day starting at FromDate, '1' when the service runs on that day.
+type UicOperatingPeriod struct {
+	XMLName      xml.Name `xml:"UicOperatingPeriod"`
+	ID           string   `xml:"id,attr"`
+	Version      string   `xml:"version,attr"`
+	Name         string   `xml:"Name"`
+	FromDate     string   `xml:"FromDate"`
+	ToDate       string   `xml:"ToDate"`
+	ValidDayBits string   `xml:"ValidDayBits"`
+}
+
+// DayTypeAssignment represents a day type assignment.
+// IsAvailable defaults to true when the element is absent from the XML, as in NeTEx.
 type DayTypeAssignment struct {
 	XMLName            xml.Name `xml:"DayTypeAssignment"`
 	ID                 string   `xml:"id,attr"`
@@ -416,9 +935,39 @@ type DayTypeAssignment struct {
 	DayTypeRef         string   `xml:"DayTypeRef"`
 	OperatingDayRef    string   `xml:"OperatingDayRef"`
 	OperatingPeriodRef string   `xml:"OperatingPeriodRef"`
+	Date               string   `xml:"Date"`
 	IsAvailable        bool     `xml:"IsAvailable"`
 }
 
+// UnmarshalXML accepts references given as ref attributes or as element text.
+// A UicOperatingPeriodRef is stored as OperatingPeriodRef.
+func (a *DayTypeAssignment) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var aux struct {
+		ID                 string   `xml:"id,attr"`
+		Version            string   `xml:"version,attr"`
+		DayTypeRef         refValue `xml:"DayTypeRef"`
+		OperatingDayRef    refValue `xml:"OperatingDayRef"`
+		OperatingPeriodRef refValue `xml:"OperatingPeriodRef"`
+		UicPeriodRef       refValue `xml:"UicOperatingPeriodRef"`
+		Date               string   `xml:"Date"`
+		IsAvailable        *bool    `xml:"IsAvailable"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*a = DayTypeAssignment{
+		XMLName:            start.Name,
+		ID:                 aux.ID,
+		Version:            aux.Version,
+		DayTypeRef:         aux.DayTypeRef.value(),
+		OperatingDayRef:    aux.OperatingDayRef.value(),
+		OperatingPeriodRef: firstNonBlank(aux.OperatingPeriodRef.value(), aux.UicPeriodRef.value()),
+		Date:               strings.TrimSpace(aux.Date),
+		IsAvailable:        aux.IsAvailable == nil || *aux.IsAvailable,
+	}
+	return nil
+}
+
 // DatedServiceJourney represents a dated service journey
 type DatedServiceJourney struct {
 	XMLName           xml.Name           `xml:"DatedServiceJourney"`
@@ -434,10 +983,11 @@ type DatedServiceJourney struct {
 
 // PublicationDelivery represents the root NeTEx XML structure
 type PublicationDelivery struct {
-	XMLName        xml.Name        `xml:"PublicationDelivery"`
-	Version        string          `xml:"version,attr"`
-	DataObjects    *DataObjects    `xml:"DataObjects"`
-	CompositeFrame *CompositeFrame `xml:"CompositeFrame"`
+	XMLName              xml.Name        `xml:"PublicationDelivery"`
+	Version              string          `xml:"version,attr"`
+	PublicationTimestamp string          `xml:"PublicationTimestamp"`
+	DataObjects          *DataObjects    `xml:"DataObjects"`
+	CompositeFrame       *CompositeFrame `xml:"CompositeFrame"`
 }
 
 // DataObjects contains the main data structures
@@ -448,10 +998,27 @@ type DataObjects struct {
 
 // CompositeFrame contains frames with different types of data
 type CompositeFrame struct {
-	XMLName xml.Name `xml:"CompositeFrame"`
-	ID      string   `xml:"id,attr"`
-	Version string   `xml:"version,attr"`
-	Frames  *Frames  `xml:"Frames"`
+	XMLName       xml.Name       `xml:"CompositeFrame"`
+	ID            string         `xml:"id,attr"`
+	Version       string         `xml:"version,attr"`
+	FrameDefaults *FrameDefaults `xml:"FrameDefaults"`
+	Frames        *Frames        `xml:"Frames"`
+}
+
+// FrameDefaults holds the default locale and currency of the data in a frame
+type FrameDefaults struct {
+	XMLName         xml.Name       `xml:"FrameDefaults"`
+	DefaultLocale   *DefaultLocale `xml:"DefaultLocale"`
+	DefaultCurrency string         `xml:"DefaultCurrency"`
+}
+
+// DefaultLocale is the timezone and language the data of a frame is given in
+type DefaultLocale struct {
+	XMLName         xml.Name `xml:"DefaultLocale"`
+	TimeZoneOffset  string   `xml:"TimeZoneOffset"`
+	TimeZone        string   `xml:"TimeZone"`
+	SummerTimeZone  string   `xml:"SummerTimeZone"`
+	DefaultLanguage string   `xml:"DefaultLanguage"`
 }
 
 // Frames contains different frame types
@@ -462,14 +1029,17 @@ type Frames struct {
 	ServiceCalendarFrame *ServiceCalendarFrame `xml:"ServiceCalendarFrame"`
 	TimetableFrame       *TimetableFrame       `xml:"TimetableFrame"`
 	SiteFrame            *SiteFrame            `xml:"SiteFrame"`
+	FareFrame            *FareFrame            `xml:"FareFrame"`
 }
 
 // ResourceFrame contains authorities and other resources
 type ResourceFrame struct {
-	XMLName     xml.Name     `xml:"ResourceFrame"`
-	ID          string       `xml:"id,attr"`
-	Version     string       `xml:"version,attr"`
-	Authorities *Authorities `xml:"Authorities"`
+	XMLName       xml.Name       `xml:"ResourceFrame"`
+	ID            string         `xml:"id,attr"`
+	Version       string         `xml:"version,attr"`
+	FrameDefaults *FrameDefaults `xml:"FrameDefaults"`
+	Authorities   *Authorities   `xml:"Authorities"`
+	VehicleTypes  []VehicleType  `xml:"vehicleTypes>VehicleType"`
 }
 
 // Authorities contains authority definitions
@@ -483,14 +1053,29 @@ type ServiceFrame struct {
 	XMLName                    xml.Name                    `xml:"ServiceFrame"`
 	ID                         string                      `xml:"id,attr"`
 	Version                    string                      `xml:"version,attr"`
+	FrameDefaults              *FrameDefaults              `xml:"FrameDefaults"`
 	Lines                      *Lines                      `xml:"Lines"`
 	Routes                     *Routes                     `xml:"Routes"`
 	JourneyPatterns            *JourneyPatterns            `xml:"JourneyPatterns"`
 	DestinationDisplays        *DestinationDisplays        `xml:"DestinationDisplays"`
 	ScheduledStopPoints        *ScheduledStopPoints        `xml:"ScheduledStopPoints"`
+	ServiceLinks               *ServiceLinks               `xml:"serviceLinks"`
+	RouteLinks                 *RouteLinks                 `xml:"routeLinks"`
 	ServiceJourneyInterchanges *ServiceJourneyInterchanges `xml:"ServiceJourneyInterchanges"`
 }
 
+// ServiceLinks contains service link definitions
+type ServiceLinks struct {
+	XMLName     xml.Name      `xml:"serviceLinks"`
+	ServiceLink []ServiceLink `xml:"ServiceLink"`
+}
+
+// RouteLinks contains route link definitions
+type RouteLinks struct {
+	XMLName   xml.Name    `xml:"routeLinks"`
+	RouteLink []RouteLink `xml:"RouteLink"`
+}
+
 // Lines contains line definitions
 type Lines struct {
 	XMLName xml.Name `xml:"Lines"`
@@ -532,6 +1117,7 @@ type ServiceCalendarFrame struct {
 	XMLName            xml.Name            `xml:"ServiceCalendarFrame"`
 	ID                 string              `xml:"id,attr"`
 	Version            string              `xml:"version,attr"`
+	FrameDefaults      *FrameDefaults      `xml:"FrameDefaults"`
 	DayTypes           *DayTypesFrame      `xml:"DayTypes"`
 	OperatingDays      *OperatingDays      `xml:"OperatingDays"`
 	OperatingPeriods   *OperatingPeriods   `xml:"OperatingPeriods"`
@@ -552,8 +1138,9 @@ type OperatingDays struct {
 
 // OperatingPeriods contains operating period definitions
 type OperatingPeriods struct {
-	XMLName         xml.Name          `xml:"OperatingPeriods"`
-	OperatingPeriod []OperatingPeriod `xml:"OperatingPeriod"`
+	XMLName            xml.Name             `xml:"OperatingPeriods"`
+	OperatingPeriod    []OperatingPeriod    `xml:"OperatingPeriod"`
+	UicOperatingPeriod []UicOperatingPeriod `xml:"UicOperatingPeriod"`
 }
 
 // DayTypeAssignments contains day type assignment definitions
@@ -567,6 +1154,7 @@ type TimetableFrame struct {
 	XMLName              xml.Name              `xml:"TimetableFrame"`
 	ID                   string                `xml:"id,attr"`
 	Version              string                `xml:"version,attr"`
+	FrameDefaults        *FrameDefaults        `xml:"FrameDefaults"`
 	ServiceJourneys      *ServiceJourneys      `xml:"ServiceJourneys"`
 	DatedServiceJourneys *DatedServiceJourneys `xml:"DatedServiceJourneys"`
 	HeadwayJourneyGroups *HeadwayJourneyGroups `xml:"HeadwayJourneyGroups"`
@@ -586,15 +1174,18 @@ type DatedServiceJourneys struct {
 
 // SiteFrame contains stop places and quays
 type SiteFrame struct {
-	XMLName    xml.Name    `xml:"SiteFrame"`
-	ID         string      `xml:"id,attr"`
-	Version    string      `xml:"version,attr"`
-	StopPlaces *StopPlaces `xml:"stopPlacesGroup"` //nolint:staticcheck // XML tag conflict is intentional for NeTEx compatibility
+	XMLName       xml.Name       `xml:"SiteFrame"`
+	ID            string         `xml:"id,attr"`
+	Version       string         `xml:"version,attr"`
+	FrameDefaults *FrameDefaults `xml:"FrameDefaults"`
+	StopPlaces    *StopPlaces    `xml:"stopPlaces"`
+	// PathLinks are the path links kept outside their stop places
+	PathLinks []SitePathLink `xml:"pathLinks>SitePathLink"`
 }
 
 // StopPlaces contains stop place definitions
 type StopPlaces struct {
-	XMLName   xml.Name    `xml:"StopPlace"`
+	XMLName   xml.Name    `xml:"stopPlaces"`
 	StopPlace []StopPlace `xml:"StopPlace"`
 }
 
@@ -714,3 +1305,126 @@ type TimeBand struct {
 	// Frequency for this time band
 	ScheduledHeadwayInterval string `xml:"ScheduledHeadwayInterval,omitempty"` // ISO 8601 duration
 }
+
+// BookingElements are the booking properties NeTEx gives inline on flexible
+// lines and flexible service properties
+type BookingElements struct {
+	BookingContact       *BookingContact `xml:"BookingContact"`
+	BookWhen             string          `xml:"BookWhen"`
+	LatestBookingTime    string          `xml:"LatestBookingTime"`
+	MinimumBookingPeriod string          `xml:"MinimumBookingPeriod"`
+	BookingUrl           string          `xml:"BookingUrl"`
+	BookingNote          string          `xml:"BookingNote"`
+}
+
+// Arrangements returns the booking elements as booking arrangements, or nil
+// when none is set
+func (b BookingElements) Arrangements() *BookingArrangements {
+	if b == (BookingElements{}) {
+		return nil
+	}
+	return &BookingArrangements{
+		BookingContact:       b.BookingContact,
+		BookWhen:             b.BookWhen,
+		LatestBookingTime:    b.LatestBookingTime,
+		MinimumBookingPeriod: b.MinimumBookingPeriod,
+		BookingUrl:           b.BookingUrl,
+		BookingNote:          b.BookingNote,
+	}
+}
+
+// FlexibleLine represents a NeTEx FlexibleLine, a line of demand-responsive
+// services. It is stored as a Line.
+type FlexibleLine struct {
+	XMLName          xml.Name      `xml:"FlexibleLine"`
+	ID               string        `xml:"id,attr"`
+	Version          string        `xml:"version,attr"`
+	Name             string        `xml:"Name"`
+	ShortName        string        `xml:"ShortName"`
+	PublicCode       string        `xml:"PublicCode"`
+	Description      string        `xml:"Description"`
+	URL              string        `xml:"Url"`
+	TransportMode    string        `xml:"TransportMode"`
+	TransportSubmode string        `xml:"TransportSubmode"`
+	AuthorityRef     string        `xml:"AuthorityRef"`
+	OperatorRef      string        `xml:"OperatorRef"`
+	NetworkRef       string        `xml:"NetworkRef"`
+	BrandingRef      string        `xml:"BrandingRef"`
+	Presentation     *Presentation `xml:"Presentation"`
+	FlexibleLineType string        `xml:"FlexibleLineType"`
+	VehicleTypeRef   refValue      `xml:"VehicleTypeRef"`
+	BookingElements
+}
+
+// ToLine converts the flexible line to a Line carrying its booking
+// arrangements
+func (fl *FlexibleLine) ToLine() *Line {
+	return &Line{
+		XMLName:          xml.Name{Local: "Line"},
+		ID:               fl.ID,
+		Version:          fl.Version,
+		Name:             fl.Name,
+		ShortName:        fl.ShortName,
+		PublicCode:       fl.PublicCode,
+		Description:      fl.Description,
+		URL:              fl.URL,
+		TransportMode:    fl.TransportMode,
+		TransportSubmode: fl.TransportSubmode,
+		AuthorityRef:     fl.AuthorityRef,
+		OperatorRef:      fl.OperatorRef,
+		NetworkRef:       fl.NetworkRef,
+		BrandingRef:      fl.BrandingRef,
+		Presentation:     fl.Presentation,
+		VehicleTypeRef:   fl.VehicleTypeRef.value(),
+		FlexibleLineType: fl.FlexibleLineType,
+		Booking:          fl.Arrangements(),
+	}
+}
+
+// FlexibleServiceProperties describes the demand-responsive service of a
+// ServiceJourney
+type FlexibleServiceProperties struct {
+	XMLName             xml.Name `xml:"FlexibleServiceProperties"`
+	ID                  string   `xml:"id,attr"`
+	FlexibleServiceType string   `xml:"FlexibleServiceType"`
+	BookingElements
+}
+
+// FlexibleStopPlace represents a NeTEx FlexibleStopPlace, the zone or the
+// group of stops a flexible service serves as one stop
+type FlexibleStopPlace struct {
+	XMLName       xml.Name       `xml:"FlexibleStopPlace"`
+	ID            string         `xml:"id,attr"`
+	Version       string         `xml:"version,attr"`
+	Name          string         `xml:"Name"`
+	Description   string         `xml:"Description"`
+	TransportMode string         `xml:"TransportMode"`
+	Areas         []FlexibleArea `xml:"areas>FlexibleArea"`
+}
+
+// FlexibleStopAssignment assigns a ScheduledStopPoint to a FlexibleStopPlace
+type FlexibleStopAssignment struct {
+	XMLName               xml.Name `xml:"FlexibleStopAssignment"`
+	ID                    string   `xml:"id,attr"`
+	Version               string   `xml:"version,attr"`
+	ScheduledStopPointRef string   `xml:"-"`
+	FlexibleStopPlaceRef  string   `xml:"-"`
+}
+
+// UnmarshalXML accepts the references given either as a ref attribute or as text
+func (a *FlexibleStopAssignment) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type Plain FlexibleStopAssignment
+	var aux struct {
+		Plain
+		ScheduledStopPointRef refValue `xml:"ScheduledStopPointRef"`
+		FlexibleStopPlaceRef  refValue `xml:"FlexibleStopPlaceRef"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+	*a = FlexibleStopAssignment(aux.Plain)
+	a.XMLName = start.Name
+	a.ScheduledStopPointRef = aux.ScheduledStopPointRef.value()
+	a.FlexibleStopPlaceRef = aux.FlexibleStopPlaceRef.value()
+	return nil
+}
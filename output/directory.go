@@ -0,0 +1,105 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// gtfsFiles lists the file names of the GTFS reference. A directory sink
+// removes those its feed does not include, so that files of an earlier feed
+// do not mix with the new one.
+var gtfsFiles = []string{
+	"agency.txt", "stops.txt", "routes.txt", "trips.txt", "stop_times.txt",
+	"calendar.txt", "calendar_dates.txt", "fare_attributes.txt", "fare_rules.txt",
+	"timeframes.txt", "fare_media.txt", "fare_products.txt", "fare_leg_rules.txt",
+	"fare_leg_join_rules.txt", "fare_transfer_rules.txt", "areas.txt", "stop_areas.txt",
+	"networks.txt", "route_networks.txt", "shapes.txt", "frequencies.txt",
+	"transfers.txt", "pathways.txt", "levels.txt", "location_groups.txt",
+	"location_group_stops.txt", "locations.geojson", "booking_rules.txt",
+	"translations.txt", "feed_info.txt", "attributions.txt",
+}
+
+// DirectorySink writes the files of a feed as plain files in a directory.
+// Files are written under temporary names and renamed on Close.
+type DirectorySink struct {
+	dir string
+	// files maps the names of the written files to their temporary files
+	files map[string]*os.File
+	order []string
+}
+
+// NewDirectorySink creates a sink writing to dir, creating it if needed.
+// Other files in dir are kept, except GTFS files the new feed lacks.
+func NewDirectorySink(dir string) (*DirectorySink, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	return &DirectorySink{dir: dir, files: make(map[string]*os.File)}, nil
+}
+
+// Create starts a file of the feed
+func (s *DirectorySink) Create(name string) (io.WriteCloser, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid feed file name %q", name)
+	}
+	if _, exists := s.files[name]; exists {
+		return nil, fmt.Errorf("feed file %s written twice", name)
+	}
+
+	file, err := createTemp(s.dir, name)
+	if err != nil {
+		return nil, err
+	}
+	s.files[name] = file
+	s.order = append(s.order, name)
+	return file, nil
+}
+
+// Close moves the written files to their names and removes GTFS files of an
+// earlier feed
+func (s *DirectorySink) Close() error {
+	for _, name := range s.order {
+		// Files are closed by their writers; closing again is harmless
+		_ = s.files[name].Close()
+		if err := os.Rename(s.files[name].Name(), filepath.Join(s.dir, name)); err != nil {
+			_ = s.Abort()
+			return fmt.Errorf("writing %s: %w", filepath.Join(s.dir, name), err)
+		}
+		delete(s.files, name)
+	}
+
+	for _, name := range gtfsFiles {
+		if !s.written(name) {
+			if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("removing stale %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Abort removes the temporary files not yet moved
+func (s *DirectorySink) Abort() error {
+	var firstErr error
+	for name, file := range s.files {
+		_ = file.Close()
+		if err := os.Remove(file.Name()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.files, name)
+	}
+	return firstErr
+}
+
+// written reports whether the feed includes the named file
+func (s *DirectorySink) written(name string) bool {
+	for _, written := range s.order {
+		if written == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,295 @@
+package output
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config locates and authenticates an S3-compatible object store
+type S3Config struct {
+	// Endpoint is the base URL of the store, e.g. http://localhost:9000 for
+	// MinIO; empty uses AWS S3 in Region
+	Endpoint string
+	Region   string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+
+	// PathStyle addresses objects as endpoint/bucket/key instead of
+	// bucket.endpoint/key, as MinIO and most other stores expect
+	PathStyle bool
+
+	// HTTPClient sends the requests; nil uses http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// S3ConfigFromEnv reads the configuration from the environment variables of
+// the AWS tools: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN,
+// AWS_REGION or AWS_DEFAULT_REGION, and AWS_ENDPOINT_URL_S3 or
+// AWS_ENDPOINT_URL. A custom endpoint uses path-style addressing.
+func S3ConfigFromEnv() S3Config {
+	cfg := S3Config{
+		Endpoint:        firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+		Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	cfg.PathStyle = cfg.Endpoint != ""
+	return cfg
+}
+
+// ParseS3URL splits an s3://bucket/key URL
+func ParseS3URL(rawURL string) (bucket, key string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "s3" {
+		return "", "", fmt.Errorf("invalid S3 URL %q, expected s3://bucket/key", rawURL)
+	}
+	key = strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" || strings.HasSuffix(key, "/") {
+		return "", "", fmt.Errorf("invalid S3 URL %q, expected s3://bucket/key", rawURL)
+	}
+	return u.Host, key, nil
+}
+
+// S3Sink uploads a feed as a ZIP archive to an S3-compatible object store.
+// The archive is spooled to a temporary file and uploaded with a single PUT
+// on Close.
+type S3Sink struct {
+	*ZipSink
+	ctx    context.Context
+	cfg    S3Config
+	bucket string
+	key    string
+	file   *os.File
+	hash   hash.Hash
+}
+
+// NewS3Sink creates a sink uploading to key in bucket; ctx cancels the
+// upload
+func NewS3Sink(ctx context.Context, cfg S3Config, bucket, key string) (*S3Sink, error) {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 credentials missing: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+
+	file, err := os.CreateTemp("", "gtfs-*.zip")
+	if err != nil {
+		return nil, fmt.Errorf("creating upload file: %w", err)
+	}
+	payloadHash := sha256.New()
+	return &S3Sink{
+		ZipSink: NewZipSink(io.MultiWriter(file, payloadHash)),
+		ctx:     ctx,
+		cfg:     cfg,
+		bucket:  bucket,
+		key:     key,
+		file:    file,
+		hash:    payloadHash,
+	}, nil
+}
+
+// Close completes the archive and uploads it
+func (s *S3Sink) Close() error {
+	defer func() {
+		_ = s.file.Close()
+		_ = os.Remove(s.file.Name())
+	}()
+
+	if err := s.ZipSink.Close(); err != nil {
+		return err
+	}
+	size, err := s.file.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = s.file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return fmt.Errorf("reading upload file: %w", err)
+	}
+
+	objectURL, err := s.objectURL()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPut, objectURL, io.NopCloser(s.file))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/zip")
+	signV4(req, hex.EncodeToString(s.hash.Sum(nil)), s.cfg, time.Now())
+
+	client := s.cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("uploading to s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("uploading to s3://%s/%s: %s%s", s.bucket, s.key, resp.Status, s3ErrorDetail(resp.Body))
+	}
+	return nil
+}
+
+// Abort removes the spooled archive without uploading it
+func (s *S3Sink) Abort() error {
+	_ = s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// objectURL returns the URL of the object
+func (s *S3Sink) objectURL() (string, error) {
+	endpoint := s.cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + s.cfg.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" || (base.Scheme != "http" && base.Scheme != "https") {
+		return "", fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+
+	path := "/" + s3Escape(s.key)
+	if s.cfg.PathStyle {
+		path = "/" + s3Escape(s.bucket) + path
+	} else {
+		base.Host = s.bucket + "." + base.Host
+	}
+	return base.Scheme + "://" + base.Host + strings.TrimSuffix(base.Path, "/") + path, nil
+}
+
+// s3ErrorDetail reads the code and message of an S3 error response
+func s3ErrorDetail(body io.Reader) string {
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if err := xml.NewDecoder(io.LimitReader(body, 64*1024)).Decode(&s3Err); err != nil || s3Err.Code == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (%s: %s)", s3Err.Code, s3Err.Message)
+}
+
+// signV4 signs req with AWS Signature Version 4 for the s3 service, covering
+// the host and every header already set on req
+func signV4(req *http.Request, payloadHash string, cfg S3Config, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", cfg.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		if strings.EqualFold(name, "Authorization") {
+			continue
+		}
+		trimmed := make([]string, len(values))
+		for i, value := range values {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		headers[strings.ToLower(name)] = strings.Join(trimmed, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + cfg.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+cfg.SecretAccessKey), date)
+	key = hmacSHA256(key, cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by name for signing
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(name)+"="+s3Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes every byte except unreserved characters and
+// slashes, as Signature Version 4 expects
+func s3Escape(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// firstEnv returns the first non-empty environment variable of names
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,118 @@
+// Package output defines where converted GTFS feeds are written. A Sink
+// receives the files of a feed one at a time; ZipSink and ZipFileSink pack
+// them in a ZIP archive, DirectorySink writes plain text files and S3Sink
+// uploads the archive to an S3-compatible object store.
+package output
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Sink stores the files of a GTFS feed. Files are written one at a time: the
+// writer returned by Create is closed before the next call. Close completes
+// the feed once every file is written; after a failure Abort discards it
+// instead.
+type Sink interface {
+	Create(name string) (io.WriteCloser, error)
+	Close() error
+	Abort() error
+}
+
+// ZipSink writes a feed as a ZIP archive to a writer
+type ZipSink struct {
+	zipWriter *zip.Writer
+}
+
+// NewZipSink creates a sink writing a ZIP archive to w. Closing the sink
+// completes the archive but does not close w.
+func NewZipSink(w io.Writer) *ZipSink {
+	return &ZipSink{zipWriter: zip.NewWriter(w)}
+}
+
+// Create starts a file of the archive
+func (s *ZipSink) Create(name string) (io.WriteCloser, error) {
+	w, err := s.zipWriter.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return nopCloser{w}, nil
+}
+
+// Close writes the archive's central directory
+func (s *ZipSink) Close() error {
+	return s.zipWriter.Close()
+}
+
+// Abort leaves w with a truncated archive; the caller discards it
+func (s *ZipSink) Abort() error {
+	return nil
+}
+
+// ZipFileSink writes a ZIP archive to a temporary file next to its path and
+// moves it there on Close, so that a failed conversion leaves no partial
+// archive and keeps an existing one
+type ZipFileSink struct {
+	*ZipSink
+	file *os.File
+	path string
+}
+
+// NewZipFileSink creates a sink writing a ZIP archive to path
+func NewZipFileSink(path string) (*ZipFileSink, error) {
+	file, err := createTemp(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+	return &ZipFileSink{ZipSink: NewZipSink(file), file: file, path: path}, nil
+}
+
+// Close completes the archive and moves it to its path
+func (s *ZipFileSink) Close() error {
+	err := s.ZipSink.Close()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(s.file.Name(), s.path)
+	}
+	if err != nil {
+		_ = os.Remove(s.file.Name())
+		return fmt.Errorf("writing %s: %w", s.path, err)
+	}
+	return nil
+}
+
+// Abort removes the partly written archive
+func (s *ZipFileSink) Abort() error {
+	_ = s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// createTemp creates a hidden temporary file for name in dir, readable like
+// a file created by os.Create
+func createTemp(dir, name string) (*os.File, error) {
+	file, err := os.CreateTemp(dir, "."+name+".*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("creating %s: %w", filepath.Join(dir, name), err)
+	}
+	// #nosec G302 -- GTFS feeds are published data
+	if err := file.Chmod(0o644); err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, fmt.Errorf("creating %s: %w", filepath.Join(dir, name), err)
+	}
+	return file, nil
+}
+
+// nopCloser adds a Close method that does nothing to a writer
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
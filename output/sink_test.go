@@ -0,0 +1,193 @@
+package output
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// writeFeed writes files to sink, closing each writer
+func writeFeed(t *testing.T, sink Sink, files map[string]string) {
+	t.Helper()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, err := sink.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%s) failed: %v", name, err)
+		}
+		if _, err := io.WriteString(w, files[name]); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readZip returns the files of a ZIP archive
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Invalid ZIP archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+// dirEntries returns the names in dir
+func dirEntries(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+var testFeed = map[string]string{
+	"agency.txt": "agency_id,agency_name\nA,Agency\n",
+	"stops.txt":  "stop_id,stop_name\nS1,Stop\n",
+}
+
+func TestZipSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewZipSink(&buf)
+	writeFeed(t, sink, testFeed)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	if files := readZip(t, buf.Bytes()); !reflect.DeepEqual(files, testFeed) {
+		t.Errorf("Expected %v, got %v", testFeed, files)
+	}
+}
+
+func TestZipFileSink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "gtfs.zip")
+	if err := os.WriteFile(path, []byte("previous"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	aborted, err := NewZipFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFeed(t, aborted, testFeed)
+	if err := aborted.Abort(); err != nil {
+		t.Fatalf("Abort() failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "previous" {
+		t.Errorf("Expected Abort to keep the previous archive, got %q", data)
+	}
+
+	sink, err := NewZipFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFeed(t, sink, testFeed)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := readZip(t, data); !reflect.DeepEqual(files, testFeed) {
+		t.Errorf("Expected %v, got %v", testFeed, files)
+	}
+	if names := dirEntries(t, dir); !reflect.DeepEqual(names, []string{"gtfs.zip"}) {
+		t.Errorf("Expected no temporary files left, got %v", names)
+	}
+}
+
+func TestDirectorySink(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gtfs")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"agency.txt": "old",
+		"shapes.txt": "old",
+		"README.md":  "kept",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink, err := NewDirectorySink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFeed(t, sink, testFeed)
+	if _, err := sink.Create("stops.txt"); err == nil {
+		t.Error("Expected an error for a file written twice")
+	}
+	if _, err := sink.Create("../agency.txt"); err == nil {
+		t.Error("Expected an error for a path outside the directory")
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	expected := []string{"README.md", "agency.txt", "stops.txt"}
+	if names := dirEntries(t, dir); !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+	for name, content := range testFeed {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != content {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, data)
+		}
+	}
+}
+
+func TestDirectorySink_Abort(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "agency.txt"), []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := NewDirectorySink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFeed(t, sink, testFeed)
+	if err := sink.Abort(); err != nil {
+		t.Fatalf("Abort() failed: %v", err)
+	}
+
+	if names := dirEntries(t, dir); !reflect.DeepEqual(names, []string{"agency.txt"}) {
+		t.Errorf("Expected only the previous feed, got %v", names)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "agency.txt")); string(data) != "old" {
+		t.Errorf("Expected the previous agency.txt, got %q", data)
+	}
+}
@@ -66,6 +66,10 @@ func (p *AdvancedStopTimeProducer) ProduceAdvanced(tripInput TripStopTimeInput)
 		return nil, fmt.Errorf("failed to apply timetabled times: %w", err)
 	}
 
+	for _, stop := range stopSequence {
+		stop.StopHeadsign = tripInput.StopHeadsigns[stop.PointInJourneyPatternRef]
+	}
+
 	// Interpolate missing times
 	err = p.interpolateMissingTimes(stopSequence, tripInput.Shape)
 	if err != nil {
@@ -95,6 +99,8 @@ type TripStopTimeInput struct {
 	Trip            *model.Trip
 	Shape           *model.Shape
 	CurrentHeadSign string
+	// StopHeadsigns overrides CurrentHeadSign per StopPointInJourneyPattern ID
+	StopHeadsigns map[string]string
 }
 
 // StopInSequence represents a stop with calculated timing information
@@ -432,24 +438,17 @@ func (p *AdvancedStopTimeProducer) resolveStopIDAdvanced(stopPoint *model.StopPo
 		return ""
 	}
 
-	// Try scheduled stop point reference
+	// Resolve like DefaultStopTimeProducer so both modes emit the same stop_ids
 	if stopPoint.ScheduledStopPointRef != "" {
 		if ssp := p.netexRepository.GetScheduledStopPointById(stopPoint.ScheduledStopPointRef); ssp != nil {
-			if ssp.QuayRef != "" {
-				// Verify quay exists
-				if p.netexRepository.GetQuayById(ssp.QuayRef) != nil {
-					return ssp.QuayRef
-				}
+			switch {
+			case ssp.QuayRef != "":
+				return ssp.QuayRef
+			case ssp.StopPlaceRef != "":
+				return ssp.StopPlaceRef
 			}
-			if ssp.StopPlaceRef != "" {
-				// Verify stop place exists
-				if stopPlace := p.netexRepository.GetStopPlaceByQuayId(ssp.StopPlaceRef); stopPlace != nil {
-					return ssp.StopPlaceRef
-				}
-			}
-			// Fallback to scheduled stop point ID
-			return ssp.ID
 		}
+		return stopPoint.ScheduledStopPointRef
 	}
 
 	// Final fallback
@@ -457,15 +456,8 @@ func (p *AdvancedStopTimeProducer) resolveStopIDAdvanced(stopPoint *model.StopPo
 }
 
 func (p *AdvancedStopTimeProducer) applyBoardingRestrictions(stop *StopInSequence, stopPoint *model.StopPointInJourneyPattern) {
-	// Check for boarding and alighting restrictions
-	// This would be enhanced based on NeTEx boarding/alighting conditions
-
-	// For now, keep defaults unless specific restrictions are found
-	// In a full implementation, this would check:
-	// - stopPoint.BoardingUse
-	// - stopPoint.AlightingUse
-	// - Time-based restrictions
-	// - Accessibility restrictions
+	// ForBoarding/ForAlighting and request stops, as in the default producer
+	stop.PickupType, stop.DropOffType = pickupDropOffTypes(stopPoint)
 }
 
 func (p *AdvancedStopTimeProducer) timeToSeconds(t time.Time, dayOffset int) int {
@@ -486,9 +478,8 @@ func (p *AdvancedStopTimeProducer) secondsToTime(seconds int) time.Time {
 	return baseDate
 }
 
-func (p *AdvancedStopTimeProducer) formatTimeForGTFS(t time.Time, dayOffset int) string {
-	hours := t.Hour() + (dayOffset * 24)
-	return fmt.Sprintf("%02d:%02d:%02d", hours, t.Minute(), t.Second())
+func (p *AdvancedStopTimeProducer) formatSecondsForGTFS(seconds int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, (seconds%3600)/60, seconds%60)
 }
 
 func (p *AdvancedStopTimeProducer) estimateDistanceBetweenStops(stop1, stop2 *StopInSequence, shape *model.Shape) float64 {
@@ -511,12 +502,19 @@ func (p *AdvancedStopTimeProducer) convertToGtfsStopTime(stop *StopInSequence, t
 		ShapeDistTraveled: stop.ShapeDistTraveled,
 	}
 
-	// Format times
+	// Format times from seconds so that times past midnight keep their day offset
 	if stop.ArrivalTime != nil {
-		gtfsStop.ArrivalTime = p.formatTimeForGTFS(*stop.ArrivalTime, 0)
+		gtfsStop.ArrivalTime = p.formatSecondsForGTFS(stop.ArrivalSeconds)
 	}
 	if stop.DepartureTime != nil {
-		gtfsStop.DepartureTime = p.formatTimeForGTFS(*stop.DepartureTime, 0)
+		gtfsStop.DepartureTime = p.formatSecondsForGTFS(stop.DepartureSeconds)
+	}
+
+	// Only timetabled passing times are exact
+	if stop.HasTimetabledTime {
+		gtfsStop.Timepoint = "1"
+	} else {
+		gtfsStop.Timepoint = "0"
 	}
 
 	// Set headsign
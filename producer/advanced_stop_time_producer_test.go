@@ -264,6 +264,57 @@ func TestAdvancedStopTimeProducer_ExtrapolationBackward(t *testing.T) {
 	}
 }
 
+func TestAdvancedStopTimeProducer_TimepointsAndHeadsigns(t *testing.T) {
+	mockRepo := &mockNetexRepositoryWithJourneyPatterns{
+		journeyPatterns: map[string]*model.JourneyPattern{
+			"jp1": createTestJourneyPattern(),
+		},
+	}
+	producer := NewAdvancedStopTimeProducer(mockRepo, &mockGtfsRepository{})
+
+	serviceJourney := &model.ServiceJourney{
+		ID:                "sj1",
+		JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: "jp1"},
+		PassingTimes: &model.PassingTimes{
+			TimetabledPassingTime: []model.TimetabledPassingTime{
+				{PointInJourneyPatternRef: "stop1", DepartureTime: "23:50:00"},
+				{PointInJourneyPatternRef: "stop3", ArrivalTime: "00:10:00", DayOffset: 1},
+			},
+		},
+	}
+
+	stopTimes, err := producer.ProduceAdvanced(TripStopTimeInput{
+		ServiceJourney: serviceJourney,
+		Trip:           &model.Trip{TripID: "trip1"},
+		StopHeadsigns:  map[string]string{"stop3": "Terminus"},
+	})
+	if err != nil {
+		t.Fatalf("ProduceAdvanced failed: %v", err)
+	}
+	if len(stopTimes) != 3 {
+		t.Fatalf("Expected 3 stop times, got %d", len(stopTimes))
+	}
+
+	expected := []struct {
+		stopID, arrival, timepoint, headsign string
+	}{
+		{"ssp1", "23:50:00", "1", ""},
+		{"ssp2", "24:00:00", "0", ""},
+		{"ssp3", "24:10:00", "1", "Terminus"},
+	}
+	for i, exp := range expected {
+		st := stopTimes[i]
+		if st.StopID != exp.stopID || st.ArrivalTime != exp.arrival || st.Timepoint != exp.timepoint || st.StopHeadsign != exp.headsign {
+			t.Errorf("Stop %d: expected %+v, got stop_id=%s arrival=%s timepoint=%s headsign=%q",
+				i+1, exp, st.StopID, st.ArrivalTime, st.Timepoint, st.StopHeadsign)
+		}
+	}
+
+	if err := producer.ValidateStopTimeSequence(stopTimes); err != nil {
+		t.Errorf("Expected valid sequence across midnight, got %v", err)
+	}
+}
+
 func TestAdvancedStopTimeProducer_ValidateStopTimeSequence(t *testing.T) {
 	producer := NewAdvancedStopTimeProducer(nil, nil)
 
@@ -1,11 +1,17 @@
 package producer
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/calendar"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/geometry"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
 )
 
@@ -44,7 +50,7 @@ func (p *DefaultAgencyProducer) Produce(authority *model.Authority) (*model.Agen
 		AgencyName:     firstNonEmpty(authority.Name, authority.ShortName),
 		AgencyURL:      url,
 		AgencyTimezone: tz,
-		AgencyLang:     "",
+		AgencyLang:     p.netexRepository.GetDefaultLanguage(),
 		AgencyPhone:    phone,
 		AgencyEmail:    email,
 	}, nil
@@ -125,31 +131,133 @@ func valueOrEmpty[T any](ptr *T, getter func(*T) string) string {
 	return getter(ptr)
 }
 
+// ErrNoServiceDays is returned for service journeys that have neither day
+// types nor dated service journeys, and so no calendar to run on
+var ErrNoServiceDays = errors.New("service journey has no day types or dated service journeys")
+
+// ServiceIDForDayTypes derives a stable service_id from a set of DayTypeRefs, so
+// that journeys with the same day types share one calendar whatever their order.
+// A single day type keeps its own ID; several are sorted and hashed into a short
+// ID. It returns "" when there is no day type.
+func ServiceIDForDayTypes(dayTypeRefs []string) string {
+	refs := make([]string, 0, len(dayTypeRefs))
+	seen := make(map[string]bool, len(dayTypeRefs))
+	for _, ref := range dayTypeRefs {
+		ref = strings.TrimSpace(ref)
+		if ref != "" && !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	switch len(refs) {
+	case 0:
+		return ""
+	case 1:
+		return refs[0]
+	}
+	sort.Strings(refs)
+	return hashedServiceID("daytypes", refs)
+}
+
+// ServiceIDForDates derives a stable service_id from a set of service dates,
+// so that dated journeys running on the same days share one calendar
+func ServiceIDForDates(dates []time.Time) string {
+	if len(dates) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(dates))
+	for _, date := range dates {
+		keys = append(keys, date.Format(gtfsDateLayout))
+	}
+	sort.Strings(keys)
+	return hashedServiceID("dates", keys)
+}
+
+// hashedServiceID returns a short service_id for the sorted keys of a service
+func hashedServiceID(prefix string, keys []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return prefix + "_" + hex.EncodeToString(sum[:8])
+}
+
+// DatedServiceDates returns the sorted operating days of the dated service
+// journeys of a service journey; cancelled dated journeys are left out
+func DatedServiceDates(netexRepository NetexRepository, serviceJourneyID string) []time.Time {
+	if netexRepository == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var dates []time.Time
+	for _, dated := range netexRepository.GetDatedServiceJourneysByServiceJourneyId(serviceJourneyID) {
+		if dated == nil || dated.ServiceAlteration == "cancelled" {
+			continue
+		}
+		value := dated.OperatingDayRef
+		if operatingDay := netexRepository.GetOperatingDayById(dated.OperatingDayRef); operatingDay != nil {
+			value = operatingDay.CalendarDate
+		}
+		date, ok := parseNetexDate(value)
+		if !ok || seen[date.Format(gtfsDateLayout)] {
+			continue
+		}
+		seen[date.Format(gtfsDateLayout)] = true
+		dates = append(dates, date)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
+
+// DatedServiceCalendar returns the compressed calendar and calendar dates of a
+// service running on the operating days of a service journey's dated journeys
+func DatedServiceCalendar(netexRepository NetexRepository, serviceID, serviceJourneyID string) (*model.Calendar, []*model.CalendarDate) {
+	return calendar.CompressServiceDates(serviceID, DatedServiceDates(netexRepository, serviceJourneyID))
+}
+
 // DefaultTripProducer implements TripProducer
 type DefaultTripProducer struct {
-	netexRepository NetexRepository
-	gtfsRepository  GtfsRepository
+	netexRepository   NetexRepository
+	gtfsRepository    GtfsRepository
+	headsignFormatter *HeadsignFormatter
+	directionResolver *DirectionResolver
+	vehicleProducer   *EuropeanVehicleProducer
 }
 
 func NewDefaultTripProducer(netexRepository NetexRepository, gtfsRepository GtfsRepository) *DefaultTripProducer {
 	return &DefaultTripProducer{
-		netexRepository: netexRepository,
-		gtfsRepository:  gtfsRepository,
+		netexRepository:   netexRepository,
+		gtfsRepository:    gtfsRepository,
+		headsignFormatter: NewHeadsignFormatter(netexRepository),
+		directionResolver: NewDirectionResolver(netexRepository),
+		vehicleProducer:   NewEuropeanVehicleProducer(netexRepository, gtfsRepository),
 	}
 }
 
+// SetHeadsignFormatter sets the formatter used for trip headsigns
+func (p *DefaultTripProducer) SetHeadsignFormatter(formatter *HeadsignFormatter) {
+	p.headsignFormatter = formatter
+}
+
+// SetDirectionResolver sets the resolver used for trip direction_ids
+func (p *DefaultTripProducer) SetDirectionResolver(resolver *DirectionResolver) {
+	p.directionResolver = resolver
+}
+
+// SetVehicleProducer sets the producer used for trip wheelchair and bike accessibility
+func (p *DefaultTripProducer) SetVehicleProducer(vehicleProducer *EuropeanVehicleProducer) {
+	p.vehicleProducer = vehicleProducer
+}
+
 func (p *DefaultTripProducer) Produce(input TripInput) (*model.Trip, error) {
 	trip := &model.Trip{
 		TripID:  input.ServiceJourney.ID,
 		RouteID: input.GtfsRoute.RouteID,
 	}
 
-	// Use default service ID to match calendar.txt
-	trip.ServiceID = "default_service"
+	// Trips running on the same day types, or dates, share one service
+	trip.ServiceID = p.serviceID(input.ServiceJourney)
 
 	// Set headsign from destination display
 	if input.DestinationDisplay != nil {
-		trip.TripHeadsign = firstNonEmpty(input.DestinationDisplay.FrontText, input.DestinationDisplay.SideText)
+		trip.TripHeadsign = p.headsignFormatter.Format(input.DestinationDisplay)
 	}
 
 	// Set shape ID if provided
@@ -157,14 +265,13 @@ func (p *DefaultTripProducer) Produce(input TripInput) (*model.Trip, error) {
 		trip.ShapeID = input.ShapeID
 	}
 
-	// Determine direction based on route direction
-	if input.NetexRoute != nil && input.NetexRoute.DirectionType != "" {
-		switch input.NetexRoute.DirectionType {
-		case "inbound", "return":
-			trip.DirectionID = "1"
-		case "outbound", "outward":
-			trip.DirectionID = "0"
-		}
+	// Direction from the pattern or route DirectionType, else from the line's terminals
+	trip.DirectionID = p.directionResolver.DirectionID(input.ServiceJourney.LineRef.Ref, input.JourneyPattern, input.NetexRoute)
+
+	// Accessibility and bikes from the vehicle type of the journey, its pattern or its line
+	if vehicleType := p.vehicleType(input); vehicleType != nil {
+		trip.WheelchairAccessible = p.vehicleProducer.ProduceVehicleAccessibility(vehicleType)
+		trip.BikesAllowed = p.vehicleProducer.ProduceBikesAllowed(vehicleType)
 	}
 
 	// Handle service alterations (cancelled trips)
@@ -172,25 +279,138 @@ func (p *DefaultTripProducer) Produce(input TripInput) (*model.Trip, error) {
 		// Skip cancelled trips
 		return nil, nil
 	}
+	if trip.ServiceID == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNoServiceDays, input.ServiceJourney.ID)
+	}
 
 	return trip, nil
 }
 
+// serviceID returns the service of a journey's day types, or else of the
+// operating days of its dated service journeys
+func (p *DefaultTripProducer) serviceID(sj *model.ServiceJourney) string {
+	if sj.DayTypes != nil {
+		if serviceID := ServiceIDForDayTypes(sj.DayTypes.DayTypeRef); serviceID != "" {
+			return serviceID
+		}
+	}
+	return ServiceIDForDates(DatedServiceDates(p.netexRepository, sj.ID))
+}
+
+// vehicleType resolves the VehicleTypeRef of a service journey, falling back
+// to the default of its journey pattern and then of its line
+func (p *DefaultTripProducer) vehicleType(input TripInput) *model.VehicleType {
+	if p.netexRepository == nil {
+		return nil
+	}
+	refs := []string{input.ServiceJourney.VehicleTypeRef}
+	if input.JourneyPattern != nil {
+		refs = append(refs, input.JourneyPattern.VehicleTypeRef)
+	}
+	if line := p.tripLine(input); line != nil {
+		refs = append(refs, line.VehicleTypeRef)
+	}
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		if vehicleType := p.netexRepository.GetVehicleTypeById(ref); vehicleType != nil {
+			return vehicleType
+		}
+	}
+	return nil
+}
+
+// tripLine looks up the line of a trip, directly or through its route
+func (p *DefaultTripProducer) tripLine(input TripInput) *model.Line {
+	lineID := input.ServiceJourney.LineRef.Ref
+	if lineID == "" {
+		route := input.NetexRoute
+		if route == nil && input.JourneyPattern != nil && input.JourneyPattern.RouteRef != "" {
+			route = p.netexRepository.GetRouteById(input.JourneyPattern.RouteRef)
+		}
+		if route != nil {
+			lineID = route.LineRef.Ref
+		}
+	}
+	if lineID == "" {
+		return nil
+	}
+	return p.netexRepository.GetLineById(lineID)
+}
+
+// ZonePolicy chooses the zone_id of a stop in several tariff zones
+type ZonePolicy string
+
+const (
+	// ZonePolicyFirst takes the first zone listed
+	ZonePolicyFirst ZonePolicy = "first"
+	// ZonePolicyEmpty leaves zone_id empty
+	ZonePolicyEmpty ZonePolicy = "empty"
+	// ZonePolicyCombined joins the sorted zone IDs with "+" into a zone of
+	// its own, e.g. "A+B"
+	ZonePolicyCombined ZonePolicy = "combined"
+)
+
+// ZonePolicies lists the zone policies
+var ZonePolicies = []ZonePolicy{ZonePolicyFirst, ZonePolicyEmpty, ZonePolicyCombined}
+
+// MultimodalStrategy chooses the station of stop places grouped by a
+// multimodal stop place through their ParentSiteRef. GTFS stations have no
+// parents, so one of the two levels is left out.
+type MultimodalStrategy string
+
+const (
+	// MultimodalChild keeps each stop place as the station of its quays and
+	// leaves the multimodal parent out
+	MultimodalChild MultimodalStrategy = "child"
+	// MultimodalParent makes the multimodal parent the station of the quays
+	// and entrances of all its stop places
+	MultimodalParent MultimodalStrategy = "parent"
+)
+
+// MultimodalStrategies lists the multimodal strategies
+var MultimodalStrategies = []MultimodalStrategy{MultimodalChild, MultimodalParent}
+
 // DefaultStopProducer implements StopProducer
 type DefaultStopProducer struct {
 	stopAreaRepository StopAreaRepository
 	gtfsRepository     GtfsRepository
+	// netexRepository, when set, resolves the stop places of quays the stop
+	// area repository does not know
+	netexRepository    NetexRepository
+	zonePolicy         ZonePolicy
+	multimodalStrategy MultimodalStrategy
 }
 
 func NewDefaultStopProducer(stopAreaRepository StopAreaRepository, gtfsRepository GtfsRepository) *DefaultStopProducer {
 	return &DefaultStopProducer{
 		stopAreaRepository: stopAreaRepository,
 		gtfsRepository:     gtfsRepository,
+		zonePolicy:         ZonePolicyFirst,
+		multimodalStrategy: MultimodalChild,
 	}
 }
 
+// SetNetexRepository sets the repository resolving the stop places of quays
+// loaded with the timetable
+func (p *DefaultStopProducer) SetNetexRepository(netexRepository NetexRepository) {
+	p.netexRepository = netexRepository
+}
+
+// SetZonePolicy sets how the zone_id of a stop in several tariff zones is chosen
+func (p *DefaultStopProducer) SetZonePolicy(policy ZonePolicy) {
+	p.zonePolicy = policy
+}
+
+// SetMultimodalStrategy sets the station of stop places grouped by a
+// multimodal stop place
+func (p *DefaultStopProducer) SetMultimodalStrategy(strategy MultimodalStrategy) {
+	p.multimodalStrategy = strategy
+}
+
 func (p *DefaultStopProducer) ProduceStopFromQuay(quay *model.Quay) (*model.Stop, error) {
-	stopPlace := p.stopAreaRepository.GetStopPlaceByQuayId(quay.ID)
+	stopPlace := p.stopPlaceOf(quay.ID)
 	name := firstNonEmpty(quay.Name, quay.ShortName, quay.PublicCode)
 	if name == "" && stopPlace != nil {
 		name = firstNonEmpty(stopPlace.Name, stopPlace.ShortName)
@@ -201,8 +421,12 @@ func (p *DefaultStopProducer) ProduceStopFromQuay(quay *model.Quay) (*model.Stop
 		lon = quay.Centroid.Location.Longitude
 	}
 	parentStation := ""
+	zones := quay.TariffZoneRefs()
 	if stopPlace != nil {
-		parentStation = stopPlace.ID
+		parentStation = p.stationOf(stopPlace).ID
+		if len(zones) == 0 {
+			zones = stopPlace.TariffZoneRefs()
+		}
 	}
 	return &model.Stop{
 		StopID:        quay.ID,
@@ -210,27 +434,143 @@ func (p *DefaultStopProducer) ProduceStopFromQuay(quay *model.Quay) (*model.Stop
 		StopName:      name,
 		StopLat:       lat,
 		StopLon:       lon,
+		ZoneID:        p.zoneID(zones),
 		LocationType:  "0",
 		ParentStation: parentStation,
+		LevelID:       quay.LevelRef(),
 		PlatformCode:  quay.PublicCode,
 	}, nil
 }
 
+// stopPlaceOf returns the stop place of a quay
+func (p *DefaultStopProducer) stopPlaceOf(quayID string) *model.StopPlace {
+	if stopPlace := p.stopAreaRepository.GetStopPlaceByQuayId(quayID); stopPlace != nil {
+		return stopPlace
+	}
+	if p.netexRepository != nil {
+		return p.netexRepository.GetStopPlaceByQuayId(quayID)
+	}
+	return nil
+}
+
+// stopPlaceByID returns a stop place of the stop area or NeTEx repository
+func (p *DefaultStopProducer) stopPlaceByID(id string) *model.StopPlace {
+	if stopPlace := p.stopAreaRepository.GetStopPlaceById(id); stopPlace != nil {
+		return stopPlace
+	}
+	if p.netexRepository != nil {
+		return p.netexRepository.GetStopPlaceById(id)
+	}
+	return nil
+}
+
+// stationOf returns the stop place that is the GTFS station of a stop place:
+// itself, or its multimodal parent with the MultimodalParent strategy
+func (p *DefaultStopProducer) stationOf(stopPlace *model.StopPlace) *model.StopPlace {
+	if p.multimodalStrategy == MultimodalParent && stopPlace.ParentSiteRef != "" {
+		if parent := p.stopPlaceByID(stopPlace.ParentSiteRef); parent != nil {
+			return parent
+		}
+	}
+	return stopPlace
+}
+
+// zoneID chooses the zone_id of a stop in the given zones by the zone policy
+func (p *DefaultStopProducer) zoneID(zones []string) string {
+	var unique []string
+	for _, zone := range zones {
+		unique = appendUnique(unique, zone)
+	}
+	switch {
+	case len(unique) == 0:
+		return ""
+	case len(unique) == 1:
+		return unique[0]
+	}
+
+	switch p.zonePolicy {
+	case ZonePolicyEmpty:
+		return ""
+	case ZonePolicyCombined:
+		sort.Strings(unique)
+		return strings.Join(unique, "+")
+	default:
+		return unique[0]
+	}
+}
+
 func (p *DefaultStopProducer) ProduceStopFromStopPlace(stopPlace *model.StopPlace) (*model.Stop, error) {
-	var lat, lon float64
-	if stopPlace.Centroid != nil && stopPlace.Centroid.Location != nil {
-		lat = stopPlace.Centroid.Location.Latitude
-		lon = stopPlace.Centroid.Location.Longitude
+	lat, lon, _ := centroidLocation(stopPlace.Centroid)
+	if lat == 0 && lon == 0 && stopPlace.Quays != nil {
+		// A station without centroid lies in the middle of its quays
+		var count int
+		for i := range stopPlace.Quays.Quay {
+			if quayLat, quayLon, ok := centroidLocation(stopPlace.Quays.Quay[i].Centroid); ok {
+				lat += quayLat
+				lon += quayLon
+				count++
+			}
+		}
+		if count > 0 {
+			lat /= float64(count)
+			lon /= float64(count)
+		}
 	}
 	return &model.Stop{
 		StopID:       stopPlace.ID,
-		StopName:     stopPlace.Name,
+		StopName:     firstNonEmpty(stopPlace.Name, stopPlace.ShortName),
+		StopDesc:     stopPlace.Description,
 		StopLat:      lat,
 		StopLon:      lon,
 		LocationType: "1", // station
 	}, nil
 }
 
+// ProduceStopFromEntrance produces an entrance of the station of a stop place
+func (p *DefaultStopProducer) ProduceStopFromEntrance(entrance *model.StopPlaceEntrance, stopPlace *model.StopPlace) (*model.Stop, error) {
+	station := p.stationOf(stopPlace)
+	lat, lon, _ := centroidLocation(entrance.Centroid)
+	return &model.Stop{
+		StopID:        entrance.ID,
+		StopCode:      entrance.PublicCode,
+		StopName:      firstNonEmpty(entrance.Name, entrance.PublicCode, stopPlace.Name, station.Name),
+		StopDesc:      entrance.Description,
+		StopLat:       lat,
+		StopLon:       lon,
+		LocationType:  "2", // entrance/exit
+		ParentStation: station.ID,
+		LevelID:       entrance.LevelRef(),
+	}, nil
+}
+
+// ProduceStopFromBoardingPosition produces a boarding area of a quay; it lies
+// at the quay, and on its level, when it has no centroid or level of its own
+func (p *DefaultStopProducer) ProduceStopFromBoardingPosition(boardingPosition *model.BoardingPosition, quay *model.Quay) (*model.Stop, error) {
+	lat, lon, ok := centroidLocation(boardingPosition.Centroid)
+	if !ok {
+		lat, lon, _ = centroidLocation(quay.Centroid)
+	}
+	return &model.Stop{
+		StopID:        boardingPosition.ID,
+		StopCode:      boardingPosition.PublicCode,
+		StopName:      firstNonEmpty(boardingPosition.Name, boardingPosition.PublicCode, quay.Name, quay.PublicCode),
+		StopDesc:      boardingPosition.Description,
+		StopLat:       lat,
+		StopLon:       lon,
+		LocationType:  "4", // boarding area
+		ParentStation: quay.ID,
+		LevelID:       firstNonEmpty(boardingPosition.LevelRef(), quay.LevelRef()),
+	}, nil
+}
+
+// centroidLocation returns the coordinates of a centroid, if it has any
+func centroidLocation(centroid *model.Centroid) (float64, float64, bool) {
+	if centroid == nil || centroid.Location == nil {
+		return 0, 0, false
+	}
+	return centroid.Location.Latitude, centroid.Location.Longitude, true
+}
+
 // DefaultStopTimeProducer implements StopTimeProducer
 type DefaultStopTimeProducer struct {
 	netexRepository NetexRepository
@@ -250,31 +590,9 @@ func (p *DefaultStopTimeProducer) Produce(input StopTimeInput) (*model.StopTime,
 	}
 
 	// Handle times as strings, applying day offset if needed
-	if input.TimetabledPassingTime.ArrivalTime != "" {
-		arrivalTime := input.TimetabledPassingTime.ArrivalTime
-		// Handle day offset (add 24 hours for each day)
-		if input.TimetabledPassingTime.DayOffset > 0 {
-			if parsedTime, err := time.Parse("15:04:05", arrivalTime); err == nil {
-				arrivalTime = fmt.Sprintf("%02d:%s",
-					parsedTime.Hour()+(input.TimetabledPassingTime.DayOffset*24),
-					parsedTime.Format("04:05"))
-			}
-		}
-		st.ArrivalTime = arrivalTime
-	}
-
-	if input.TimetabledPassingTime.DepartureTime != "" {
-		departureTime := input.TimetabledPassingTime.DepartureTime
-		// Handle day offset
-		if input.TimetabledPassingTime.DayOffset > 0 {
-			if parsedTime, err := time.Parse("15:04:05", departureTime); err == nil {
-				departureTime = fmt.Sprintf("%02d:%s",
-					parsedTime.Hour()+(input.TimetabledPassingTime.DayOffset*24),
-					parsedTime.Format("04:05"))
-			}
-		}
-		st.DepartureTime = departureTime
-	}
+	passingTime := input.TimetabledPassingTime
+	st.ArrivalTime = gtfsTimeWithDayOffset(passingTime.ArrivalTime, passingTime.DayOffset)
+	st.DepartureTime = gtfsTimeWithDayOffset(passingTime.DepartureTime, passingTime.DayOffset)
 
 	// If only one time is available, use it for both
 	if st.ArrivalTime == "" && st.DepartureTime != "" {
@@ -289,19 +607,16 @@ func (p *DefaultStopTimeProducer) Produce(input StopTimeInput) (*model.StopTime,
 		pjpRef := input.TimetabledPassingTime.PointInJourneyPatternRef
 		sspRef := p.netexRepository.GetScheduledStopPointRefByPointInJourneyPatternRef(pjpRef)
 		if sspRef != "" {
-			// Look up ScheduledStopPoint to get QuayRef or StopPlaceRef
-			if ssp := p.netexRepository.GetScheduledStopPointById(sspRef); ssp != nil {
-				switch {
-				case ssp.QuayRef != "":
-					st.StopID = ssp.QuayRef
-				case ssp.StopPlaceRef != "":
-					st.StopID = ssp.StopPlaceRef
-				default:
-					st.StopID = sspRef
-				}
+			st.StopID = scheduledStopPointStopID(p.netexRepository, sspRef)
+		}
+		// Stops at a flexible stop place refer to its zone, or else to the
+		// location group of its member stops
+		if flexibleStopPlace := p.netexRepository.GetFlexibleStopPlaceByScheduledStopPointId(sspRef); flexibleStopPlace != nil {
+			st.StopID = ""
+			if len(flexibleStopPlacePolygons(flexibleStopPlace)) > 0 {
+				st.LocationID = flexibleStopPlace.ID
 			} else {
-				// Fallback: use reference directly
-				st.StopID = sspRef
+				st.LocationGroupID = flexibleStopPlace.ID
 			}
 		}
 	}
@@ -311,157 +626,348 @@ func (p *DefaultStopTimeProducer) Produce(input StopTimeInput) (*model.StopTime,
 		st.StopHeadsign = input.CurrentHeadSign
 	}
 
-	// Set pickup and drop-off types (default to regular)
-	st.PickupType = "0"  // Regular pickup
-	st.DropOffType = "0" // Regular drop-off
+	// Pickup and drop-off follow the stop point's boarding and alighting flags
+	st.PickupType, st.DropOffType = pickupDropOffTypes(p.stopPointFor(input))
 
-	// Shape distance is handled in Enhanced GTFS Exporter
+	// Flexible stops are served within a window and must be booked
+	if setPickupDropOffWindow(st, passingTime) {
+		if st.PickupType != "1" {
+			st.PickupType = "2"
+		}
+		if st.DropOffType != "1" {
+			st.DropOffType = "2"
+		}
+		st.Timepoint = "0"
+	}
+
+	// Shape distance needs the whole trip and is filled in by the exporter
 	st.ShapeDistTraveled = 0
 
 	return st, nil
 }
 
+// gtfsTimeWithDayOffset adds 24 hours per day of offset to a NeTEx time of
+// day; times that cannot be parsed are returned as given
+func gtfsTimeWithDayOffset(value string, dayOffset int) string {
+	if value == "" || dayOffset <= 0 {
+		return value
+	}
+	parsedTime, err := time.Parse("15:04:05", value)
+	if err != nil {
+		return value
+	}
+	return fmt.Sprintf("%02d:%s", parsedTime.Hour()+dayOffset*24, parsedTime.Format("04:05"))
+}
+
+// setPickupDropOffWindow sets the GTFS-Flex window of a stop time from the
+// earliest departure and latest arrival of its passing time, completed by
+// the arrival and departure times. Stop times at flexible locations use
+// their arrival and departure times as window when the passing time has
+// none. The window replaces the arrival and departure times; it reports
+// whether a window was set.
+func setPickupDropOffWindow(st *model.StopTime, passingTime *model.TimetabledPassingTime) bool {
+	start := gtfsTimeWithDayOffset(passingTime.EarliestDepartureTime, passingTime.EarliestDepartureDayOffset)
+	end := gtfsTimeWithDayOffset(passingTime.LatestArrivalTime, passingTime.LatestArrivalDayOffset)
+	if start == "" && end == "" && st.LocationID == "" && st.LocationGroupID == "" {
+		return false
+	}
+
+	if start == "" {
+		start = st.ArrivalTime
+	}
+	if end == "" {
+		end = st.DepartureTime
+	}
+	if start == "" || end == "" {
+		return false
+	}
+
+	st.StartPickupDropOffWindow, st.EndPickupDropOffWindow = start, end
+	st.ArrivalTime, st.DepartureTime = "", ""
+	return true
+}
+
+// scheduledStopPointStopID resolves a ScheduledStopPoint to the GTFS stop_id
+// it is served at: its quay, else its stop place, else the point itself
+func scheduledStopPointStopID(netexRepository NetexRepository, sspRef string) string {
+	ssp := netexRepository.GetScheduledStopPointById(sspRef)
+	switch {
+	case ssp == nil:
+		return sspRef
+	case ssp.QuayRef != "":
+		return ssp.QuayRef
+	case ssp.StopPlaceRef != "":
+		return ssp.StopPlaceRef
+	default:
+		return sspRef
+	}
+}
+
+// stopPointFor returns the stop point a passing time refers to, looked up in
+// the repository or else in the input's journey pattern
+func (p *DefaultStopTimeProducer) stopPointFor(input StopTimeInput) *model.StopPointInJourneyPattern {
+	ref := input.TimetabledPassingTime.PointInJourneyPatternRef
+	if ref == "" {
+		return nil
+	}
+	if stopPoint := p.netexRepository.GetStopPointInJourneyPatternById(ref); stopPoint != nil {
+		return stopPoint
+	}
+	if input.JourneyPattern == nil || input.JourneyPattern.PointsInSequence == nil {
+		return nil
+	}
+	for _, point := range input.JourneyPattern.PointsInSequence.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern {
+		if stopPoint, ok := point.(*model.StopPointInJourneyPattern); ok && stopPoint.ID == ref {
+			return stopPoint
+		}
+	}
+	return nil
+}
+
+// pickupDropOffTypes maps a stop point to GTFS pickup_type and drop_off_type.
+// ForBoarding/ForAlighting set to false give 1 (not available); request stops
+// and stops with booking arrangements give 3 (coordinate with driver).
+func pickupDropOffTypes(stopPoint *model.StopPointInJourneyPattern) (pickupType, dropOffType string) {
+	if stopPoint == nil {
+		return "0", "0"
+	}
+	available := "0"
+	if stopPoint.RequestStop || stopPoint.BookingArrangements != nil {
+		available = "3"
+	}
+	pickupType, dropOffType = available, available
+	if stopPoint.ForBoarding != nil && !*stopPoint.ForBoarding {
+		pickupType = "1"
+	}
+	if stopPoint.ForAlighting != nil && !*stopPoint.ForAlighting {
+		dropOffType = "1"
+	}
+	return pickupType, dropOffType
+}
+
 // DefaultServiceCalendarProducer implements ServiceCalendarProducer
 type DefaultServiceCalendarProducer struct {
-	gtfsRepository GtfsRepository
+	netexRepository NetexRepository
+	gtfsRepository  GtfsRepository
 }
 
-func NewDefaultServiceCalendarProducer(gtfsRepository GtfsRepository) *DefaultServiceCalendarProducer {
+func NewDefaultServiceCalendarProducer(netexRepository NetexRepository, gtfsRepository GtfsRepository) *DefaultServiceCalendarProducer {
 	return &DefaultServiceCalendarProducer{
-		gtfsRepository: gtfsRepository,
+		netexRepository: netexRepository,
+		gtfsRepository:  gtfsRepository,
 	}
 }
 
+// Produce resolves the service dates of the day types and returns the calendar
+// row of their compressed representation. DefaultServiceCalendarDateProducer
+// returns the matching exceptions; nil means the service is dates-only.
 func (p *DefaultServiceCalendarProducer) Produce(serviceID string, dayTypes []*model.DayType) (*model.Calendar, error) {
-	if len(dayTypes) == 0 {
+	if len(dayTypes) == 0 || p.netexRepository == nil {
 		return nil, nil
 	}
 
-	calendar := &model.Calendar{
-		ServiceID: serviceID,
-		Monday:    false,
-		Tuesday:   false,
-		Wednesday: false,
-		Thursday:  false,
-		Friday:    false,
-		Saturday:  false,
-		Sunday:    false,
-		StartDate: "20240101", // Default start date
-		EndDate:   "20251231", // Default end date
-	}
-
-	// Analyze DayType properties to determine service days
+	var assignments []*model.DayTypeAssignment
 	for _, dayType := range dayTypes {
-		if dayType != nil && dayType.Properties != nil {
-			for _, prop := range dayType.Properties.PropertyOfDay {
-				daysOfWeek := prop.DaysOfWeek
-				switch daysOfWeek {
-				case "Monday", "monday", "1":
-					calendar.Monday = true
-				case "Tuesday", "tuesday", "2":
-					calendar.Tuesday = true
-				case "Wednesday", "wednesday", "3":
-					calendar.Wednesday = true
-				case "Thursday", "thursday", "4":
-					calendar.Thursday = true
-				case "Friday", "friday", "5":
-					calendar.Friday = true
-				case "Saturday", "saturday", "6":
-					calendar.Saturday = true
-				case "Sunday", "sunday", "7":
-					calendar.Sunday = true
-				case "Weekdays", "weekdays":
-					calendar.Monday = true
-					calendar.Tuesday = true
-					calendar.Wednesday = true
-					calendar.Thursday = true
-					calendar.Friday = true
-				case "Weekend", "weekend":
-					calendar.Saturday = true
-					calendar.Sunday = true
-				case "Everyday", "everyday", "Daily", "daily":
-					calendar.Monday = true
-					calendar.Tuesday = true
-					calendar.Wednesday = true
-					calendar.Thursday = true
-					calendar.Friday = true
-					calendar.Saturday = true
-					calendar.Sunday = true
-				}
-			}
+		if dayType != nil {
+			assignments = append(assignments, p.netexRepository.GetDayTypeAssignmentsByDayType(dayType)...)
 		}
 	}
 
-	// If no specific days are set, assume it runs every day
-	if !calendar.Monday && !calendar.Tuesday && !calendar.Wednesday &&
-		!calendar.Thursday && !calendar.Friday && !calendar.Saturday && !calendar.Sunday {
-		calendar.Monday = true
-		calendar.Tuesday = true
-		calendar.Wednesday = true
-		calendar.Thursday = true
-		calendar.Friday = true
-		calendar.Saturday = true
-		calendar.Sunday = true
-	}
-
-	return calendar, nil
+	cal, _ := calendar.CompressServiceDates(serviceID, resolveServiceDates(p.netexRepository, assignments))
+	return cal, nil
 }
 
 // DefaultServiceCalendarDateProducer implements ServiceCalendarDateProducer
 type DefaultServiceCalendarDateProducer struct {
-	gtfsRepository GtfsRepository
+	netexRepository NetexRepository
+	gtfsRepository  GtfsRepository
 }
 
-func NewDefaultServiceCalendarDateProducer(gtfsRepository GtfsRepository) *DefaultServiceCalendarDateProducer {
+func NewDefaultServiceCalendarDateProducer(netexRepository NetexRepository, gtfsRepository GtfsRepository) *DefaultServiceCalendarDateProducer {
 	return &DefaultServiceCalendarDateProducer{
-		gtfsRepository: gtfsRepository,
+		netexRepository: netexRepository,
+		gtfsRepository:  gtfsRepository,
 	}
 }
 
+// Produce resolves the service dates of the assignments and returns the
+// calendar_dates exceptions of their compressed representation
 func (p *DefaultServiceCalendarDateProducer) Produce(serviceID string, dayTypeAssignments []*model.DayTypeAssignment) ([]*model.CalendarDate, error) {
-	result := make([]*model.CalendarDate, 0)
+	if p.netexRepository == nil {
+		return make([]*model.CalendarDate, 0), nil
+	}
+
+	_, exceptions := calendar.CompressServiceDates(serviceID, resolveServiceDates(p.netexRepository, dayTypeAssignments))
+	if exceptions == nil {
+		exceptions = make([]*model.CalendarDate, 0)
+	}
+	return exceptions, nil
+}
 
-	for _, assignment := range dayTypeAssignments {
+const gtfsDateLayout = "20060102"
+
+// resolveServiceDates returns the sorted dates made available by the assignments.
+// Unavailable assignments remove their dates, whatever the assignment order.
+func resolveServiceDates(netexRepository NetexRepository, assignments []*model.DayTypeAssignment) []time.Time {
+	available := make(map[string]time.Time)
+	removed := make(map[string]bool)
+	for _, assignment := range assignments {
 		if assignment == nil {
 			continue
 		}
+		for _, date := range assignmentDates(netexRepository, assignment) {
+			key := date.Format(gtfsDateLayout)
+			if assignment.IsAvailable {
+				available[key] = date
+			} else {
+				removed[key] = true
+			}
+		}
+	}
 
-		// Handle operating day references
-		if assignment.OperatingDayRef != "" {
-			// The operating day ref might need to be resolved to an actual date
-			// For now, assume it's in YYYYMMDD format or can be converted
-			dateStr := assignment.OperatingDayRef
+	dates := make([]time.Time, 0, len(available))
+	for key, date := range available {
+		if !removed[key] {
+			dates = append(dates, date)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	return dates
+}
 
-			// If it's not in YYYYMMDD format, try to parse and convert
-			if len(dateStr) == 10 && (dateStr[4] == '-' || dateStr[4] == '/') {
-				// Convert YYYY-MM-DD or YYYY/MM/DD to YYYYMMDD
-				dateStr = dateStr[0:4] + dateStr[5:7] + dateStr[8:10]
+// assignmentDates returns the dates covered by a single day type assignment:
+// its date or operating day, or the days of its (UIC) operating period that
+// match the days of week of the assigned DayType
+func assignmentDates(netexRepository NetexRepository, assignment *model.DayTypeAssignment) []time.Time {
+	switch {
+	case assignment.Date != "":
+		if date, ok := parseNetexDate(assignment.Date); ok {
+			return []time.Time{date}
+		}
+	case assignment.OperatingDayRef != "":
+		value := assignment.OperatingDayRef
+		if operatingDay := netexRepository.GetOperatingDayById(assignment.OperatingDayRef); operatingDay != nil {
+			value = operatingDay.CalendarDate
+		}
+		// Some feeds use the date itself as the operating day reference
+		if date, ok := parseNetexDate(value); ok {
+			return []time.Time{date}
+		}
+	case assignment.OperatingPeriodRef != "":
+		var periodDates []time.Time
+		if uicPeriod := netexRepository.GetUicOperatingPeriodById(assignment.OperatingPeriodRef); uicPeriod != nil {
+			from, ok := parseNetexDate(uicPeriod.FromDate)
+			if !ok {
+				return nil
 			}
-
-			calendarDate := &model.CalendarDate{
-				ServiceID:     serviceID,
-				Date:          dateStr,
-				ExceptionType: 1, // Service added
+			decoded, err := calendar.DecodeValidDayBits(from, uicPeriod.ValidDayBits)
+			if err != nil {
+				return nil
 			}
-
-			// If service is not available, it's an exception (service removed)
-			if !assignment.IsAvailable {
-				calendarDate.ExceptionType = 2
+			periodDates = decoded
+		} else if from, to, ok := operatingPeriodRange(netexRepository.GetOperatingPeriodById(assignment.OperatingPeriodRef)); ok {
+			for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+				periodDates = append(periodDates, day)
 			}
+		}
 
-			result = append(result, calendarDate)
+		weekdays := daysOfWeekMask(netexRepository.GetDayTypeById(assignment.DayTypeRef))
+		dates := make([]time.Time, 0, len(periodDates))
+		for _, day := range periodDates {
+			if weekdays[day.Weekday()] {
+				dates = append(dates, day)
+			}
 		}
+		return dates
+	}
+	return nil
+}
+
+// operatingPeriodRange returns the inclusive date range of an operating period
+func operatingPeriodRange(period *model.OperatingPeriod) (time.Time, time.Time, bool) {
+	if period == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	from, ok := parseNetexDate(period.FromDate)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	to, ok := parseNetexDate(period.ToDate)
+	if !ok || to.Before(from) {
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
 
-		// Handle operating period references
-		if assignment.OperatingPeriodRef != "" {
-			// Operating periods represent date ranges
-			// This would typically need to be expanded into individual dates
-			// For now, we skip this complex logic
-			_ = assignment.OperatingPeriodRef // Suppress unused variable warning
+// daysOfWeekMask returns the days of week of a DayType indexed by time.Weekday.
+// A DayType without recognizable days of week applies to every day.
+func daysOfWeekMask(dayType *model.DayType) [7]bool {
+	var mask [7]bool
+	found := false
+	if dayType != nil && dayType.Properties != nil {
+		for _, prop := range dayType.Properties.PropertyOfDay {
+			for _, token := range strings.Fields(prop.DaysOfWeek) {
+				days, ok := weekdaysByName[strings.ToLower(token)]
+				if !ok {
+					continue
+				}
+				for _, day := range days {
+					mask[day] = true
+				}
+				found = true
+			}
+		}
+	}
+	if !found {
+		for day := range mask {
+			mask[day] = true
 		}
 	}
+	return mask
+}
 
-	return result, nil
+// weekdaysByName maps NeTEx DaysOfWeek values (and ISO day numbers) to weekdays
+var weekdaysByName = map[string][]time.Weekday{
+	"monday":    {time.Monday},
+	"tuesday":   {time.Tuesday},
+	"wednesday": {time.Wednesday},
+	"thursday":  {time.Thursday},
+	"friday":    {time.Friday},
+	"saturday":  {time.Saturday},
+	"sunday":    {time.Sunday},
+	"1":         {time.Monday},
+	"2":         {time.Tuesday},
+	"3":         {time.Wednesday},
+	"4":         {time.Thursday},
+	"5":         {time.Friday},
+	"6":         {time.Saturday},
+	"7":         {time.Sunday},
+	"weekdays":  {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekend":   {time.Saturday, time.Sunday},
+	"everyday":  {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday},
+	"daily":     {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday},
+}
+
+// parseNetexDate parses the date part of a NeTEx date or datetime
+// (YYYY-MM-DD, YYYY-MM-DDThh:mm:ss with optional offset, YYYY/MM/DD or YYYYMMDD)
+func parseNetexDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	var layout string
+	switch {
+	case len(value) >= 10 && value[4] == '-':
+		value, layout = value[:10], "2006-01-02"
+	case len(value) >= 10 && value[4] == '/':
+		value, layout = value[:10], "2006/01/02"
+	case len(value) == 8:
+		layout = gtfsDateLayout
+	default:
+		return time.Time{}, false
+	}
+	date, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return date, true
 }
 
 // ternaryInt is a helper function for conditional integer selection
@@ -478,24 +984,30 @@ func ternaryInt(b bool, t, f int) int {
 type DefaultShapeProducer struct {
 	netexRepository NetexRepository
 	gtfsRepository  GtfsRepository
+	shapeGenerator  *geometry.ShapeGenerator
 }
 
 func NewDefaultShapeProducer(netexRepository NetexRepository, gtfsRepository GtfsRepository) *DefaultShapeProducer {
 	return &DefaultShapeProducer{
 		netexRepository: netexRepository,
 		gtfsRepository:  gtfsRepository,
+		shapeGenerator:  geometry.NewShapeGenerator(),
 	}
 }
 
-func (p *DefaultShapeProducer) Produce(journeyPattern *model.JourneyPattern) (*model.Shape, error) {
+// SetShapeGenerator replaces the generator used to build shape polylines
+func (p *DefaultShapeProducer) SetShapeGenerator(shapeGenerator *geometry.ShapeGenerator) {
+	p.shapeGenerator = shapeGenerator
+}
+
+func (p *DefaultShapeProducer) Produce(journeyPattern *model.JourneyPattern) ([]*model.Shape, error) {
 	if journeyPattern == nil {
 		return nil, nil
 	}
 
-	// Skip shape generation to avoid invalid (0,0) coordinates
-	// This prevents GTFS validation errors with placeholder coordinates
-	// In a full implementation, this would extract actual route geometry
-	return nil, nil
+	// The generator returns no points when any stop of the pattern lacks coordinates,
+	// so patterns without real geometry never produce (0,0) placeholder shapes
+	return p.shapeGenerator.GenerateShape(journeyPattern, p.netexRepository)
 }
 
 // DefaultTransferProducer implements TransferProducer
@@ -511,70 +1023,133 @@ func NewDefaultTransferProducer(netexRepository NetexRepository, gtfsRepository
 	}
 }
 
+// Produce converts an interchange to a transfer. Point refs are resolved to
+// the stop_ids used in stop_times.txt and journey refs are kept only when they
+// name produced trips. Interchanges that cannot be expressed in the feed, such
+// as those to stops outside it, produce no transfer.
 func (p *DefaultTransferProducer) Produce(interchange *model.ServiceJourneyInterchange) (*model.Transfer, error) {
 	if interchange == nil {
 		return nil, nil
 	}
 
+	fromStopID := p.resolveStopID(interchange.FromPointRef)
+	toStopID := p.resolveStopID(interchange.ToPointRef)
+	if fromStopID == "" || toStopID == "" {
+		return nil, nil
+	}
+
 	transfer := &model.Transfer{
-		FromStopID: interchange.FromPointRef,
-		ToStopID:   interchange.ToPointRef,
+		FromStopID: fromStopID,
+		ToStopID:   toStopID,
+		FromTripID: p.resolveTripID(interchange.FromJourneyRef),
+		ToTripID:   p.resolveTripID(interchange.ToJourneyRef),
+	}
+
+	// NeTEx uses ISO 8601 durations (PT5M, PT1M30S, PT1H)
+	if seconds, ok := parseISODurationSeconds(interchange.MinimumTransferTime); ok {
+		transfer.MinTransferTime = seconds
 	}
 
 	// Set transfer type based on NeTEx properties
 	switch {
 	case interchange.StaySeated:
-		transfer.TransferType = 0 // Recommended transfer point
+		// In-seat transfers are only defined between two trips
+		if transfer.FromTripID == "" || transfer.ToTripID == "" {
+			return nil, nil
+		}
+		transfer.TransferType = 4 // In-seat transfer
 	case interchange.Guaranteed:
 		transfer.TransferType = 1 // Timed transfer point
-	default:
+		if transfer.MinTransferTime == 0 {
+			transfer.MinTransferTime = 120 // 2 minutes default
+		}
+	case transfer.MinTransferTime > 0:
 		transfer.TransferType = 2 // Minimum transfer time required
+	default:
+		transfer.TransferType = 0 // Recommended transfer point
 	}
 
-	// Parse minimum transfer time if available
-	if interchange.MinimumTransferTime != "" {
-		// NeTEx uses ISO 8601 duration format (PT5M for 5 minutes)
-		// Simple parsing for minutes - in full implementation would use proper ISO 8601 parser
-		if len(interchange.MinimumTransferTime) > 3 &&
-			interchange.MinimumTransferTime[:2] == "PT" &&
-			interchange.MinimumTransferTime[len(interchange.MinimumTransferTime)-1:] == "M" {
+	return transfer, nil
+}
 
-			// Extract number of minutes
-			minutesStr := interchange.MinimumTransferTime[2 : len(interchange.MinimumTransferTime)-1]
-			if minutes := parseIntSafe(minutesStr); minutes > 0 {
-				transfer.MinTransferTime = minutes * 60 // Convert to seconds
-			}
-		}
+// resolveStopID maps a ScheduledStopPoint ref to a stop_id of the feed, or ""
+// when the stop was not produced
+func (p *DefaultTransferProducer) resolveStopID(pointRef string) string {
+	if pointRef == "" {
+		return ""
+	}
+	stopID := scheduledStopPointStopID(p.netexRepository, pointRef)
+	if p.gtfsRepository.GetStopById(stopID) == nil {
+		return ""
 	}
+	return stopID
+}
 
-	// Set default transfer time if none specified but guaranteed
-	if transfer.MinTransferTime == 0 && interchange.Guaranteed {
-		transfer.MinTransferTime = 120 // 2 minutes default
+// resolveTripID returns journeyRef when it names a produced trip, else ""
+func (p *DefaultTransferProducer) resolveTripID(journeyRef string) string {
+	if journeyRef == "" || p.gtfsRepository.GetTripById(journeyRef) == nil {
+		return ""
 	}
+	return journeyRef
+}
 
-	// Resolve from/to journey references to route/trip if needed
-	// This would require additional lookup logic in a full implementation
-	transfer.FromRouteID = ""
-	transfer.ToRouteID = ""
-	transfer.FromTripID = interchange.FromJourneyRef
-	transfer.ToTripID = interchange.ToJourneyRef
+// parseISODurationSeconds parses an ISO 8601 duration such as PT2M, PT1M30S,
+// PT1H or P1DT2H into whole seconds. Fractional seconds are truncated.
+func parseISODurationSeconds(value string) (int, bool) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 3 || value[0] != 'P' {
+		return 0, false
+	}
 
-	return transfer, nil
-}
+	total := 0.0
+	inTime := false
+	number := ""
+	for _, r := range value[1:] {
+		switch {
+		case r >= '0' && r <= '9' || r == '.':
+			number += string(r)
+			continue
+		case r == 'T':
+			if inTime || number != "" {
+				return 0, false
+			}
+			inTime = true
+			continue
+		}
 
-// Helper function to safely parse integers
-func parseIntSafe(s string) int {
-	result := 0
-	for _, r := range s {
-		if r >= '0' && r <= '9' {
-			result = result*10 + int(r-'0')
-		} else {
-			return 0 // Invalid number
+		amount, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return 0, false
+		}
+		number = ""
+		switch {
+		case r == 'D' && !inTime:
+			total += amount * 86400
+		case r == 'W' && !inTime:
+			total += amount * 7 * 86400
+		case r == 'H' && inTime:
+			total += amount * 3600
+		case r == 'M' && inTime:
+			total += amount * 60
+		case r == 'S' && inTime:
+			total += amount
+		default:
+			return 0, false
 		}
 	}
-	return result
+	if number != "" {
+		return 0, false
+	}
+	return int(total), true
 }
 
+// Feed info defaults used when no publisher or language is configured
+const (
+	defaultFeedPublisherName = "NeTEx to GTFS Converter"
+	defaultFeedPublisherURL  = "https://github.com/theoremus-urban-solutions/netex-gtfs-converter"
+	defaultFeedLang          = "en"
+)
+
 // DefaultFeedInfoProducer implements FeedInfoProducer
 type DefaultFeedInfoProducer struct{}
 
@@ -582,20 +1157,45 @@ func NewDefaultFeedInfoProducer() *DefaultFeedInfoProducer {
 	return &DefaultFeedInfoProducer{}
 }
 
-func (p *DefaultFeedInfoProducer) ProduceFeedInfo() (*model.FeedInfo, error) {
+// ProduceFeedInfo describes the feed: its publisher, its validity from the
+// first to the last service date, and a version identifying the NeTEx
+// publication it was converted from
+func (p *DefaultFeedInfoProducer) ProduceFeedInfo(input FeedInfoInput) (*model.FeedInfo, error) {
 	feedInfo := &model.FeedInfo{
-		FeedPublisherName: "NeTEx to GTFS Converter (Go)",
-		FeedPublisherURL:  "https://github.com/entur/netex-gtfs-converter-java",
-		FeedLang:          "en",
-		FeedVersion:       "1.0.0",
-		FeedContactEmail:  "",
-		FeedContactURL:    "",
+		FeedPublisherName: firstNonEmpty(input.PublisherName, defaultFeedPublisherName),
+		FeedPublisherURL:  firstNonEmpty(input.PublisherURL, defaultFeedPublisherURL),
+		FeedLang:          firstNonEmpty(input.Language, defaultFeedLang),
+		FeedVersion:       feedVersion(input.PublicationTimestamp, input.ContentHash),
+		FeedContactEmail:  input.ContactEmail,
+		FeedContactURL:    input.ContactURL,
 	}
 
-	// Set date range - would typically be calculated from actual service dates
-	// For now, use reasonable defaults
-	feedInfo.FeedStartDate = "20240101"
-	feedInfo.FeedEndDate = "20251231"
+	if first, last, ok := calendar.ServicePeriod(input.Calendars, input.CalendarDates); ok {
+		feedInfo.FeedStartDate = first.Format(gtfsDateLayout)
+		feedInfo.FeedEndDate = last.Format(gtfsDateLayout)
+	}
 
 	return feedInfo, nil
 }
+
+// feedVersion combines a publication timestamp and a content hash, e.g.
+// "20240501T100000-3f2a9c1b07de"; either part is left out when unknown
+func feedVersion(publicationTimestamp, contentHash string) string {
+	var parts []string
+	if timestamp := strings.TrimSpace(publicationTimestamp); timestamp != "" {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+			if parsed, err := time.Parse(layout, timestamp); err == nil {
+				timestamp = parsed.Format("20060102T150405")
+				break
+			}
+		}
+		parts = append(parts, timestamp)
+	}
+	if hash := strings.TrimSpace(contentHash); hash != "" {
+		if len(hash) > 12 {
+			hash = hash[:12]
+		}
+		parts = append(parts, hash)
+	}
+	return strings.Join(parts, "-")
+}
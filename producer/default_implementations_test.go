@@ -1,8 +1,13 @@
 package producer
 
 import (
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
 )
@@ -11,13 +16,18 @@ import (
 
 type mockNetexRepository struct {
 	timeZone string
+	language string
 }
 
 func (m *mockNetexRepository) SaveEntity(entity interface{}) error                 { return nil }
 func (m *mockNetexRepository) GetLines() []*model.Line                             { return nil }
+func (m *mockNetexRepository) GetLineById(id string) *model.Line                   { return nil }
 func (m *mockNetexRepository) GetServiceJourneys() []*model.ServiceJourney         { return nil }
 func (m *mockNetexRepository) GetAuthorityById(id string) *model.Authority         { return nil }
 func (m *mockNetexRepository) GetQuayById(id string) *model.Quay                   { return nil }
+func (m *mockNetexRepository) GetStopPlaceById(id string) *model.StopPlace         { return nil }
+func (m *mockNetexRepository) GetAllSitePathLinks() []*model.SitePathLink          { return nil }
+func (m *mockNetexRepository) GetVehicleTypeById(id string) *model.VehicleType     { return nil }
 func (m *mockNetexRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace { return nil }
 func (m *mockNetexRepository) GetTimeZone() string {
 	if m.timeZone != "" {
@@ -25,6 +35,8 @@ func (m *mockNetexRepository) GetTimeZone() string {
 	}
 	return "UTC"
 }
+func (m *mockNetexRepository) GetDefaultLanguage() string                            { return m.language }
+func (m *mockNetexRepository) GetPublicationTimestamp() string                       { return "" }
 func (m *mockNetexRepository) GetJourneyPatternById(id string) *model.JourneyPattern { return nil }
 func (m *mockNetexRepository) GetRouteById(id string) *model.Route                   { return nil }
 func (m *mockNetexRepository) GetRoutesByLine(line *model.Line) []*model.Route       { return nil }
@@ -45,6 +57,12 @@ func (m *mockNetexRepository) GetDatedServiceJourneysByServiceJourneyId(serviceJ
 }
 func (m *mockNetexRepository) GetDayTypeById(id string) *model.DayType           { return nil }
 func (m *mockNetexRepository) GetOperatingDayById(id string) *model.OperatingDay { return nil }
+func (m *mockNetexRepository) GetOperatingPeriodById(id string) *model.OperatingPeriod {
+	return nil
+}
+func (m *mockNetexRepository) GetUicOperatingPeriodById(id string) *model.UicOperatingPeriod {
+	return nil
+}
 func (m *mockNetexRepository) GetDayTypeAssignmentsByDayType(dayType *model.DayType) []*model.DayTypeAssignment {
 	return nil
 }
@@ -63,6 +81,24 @@ func (m *mockNetexRepository) GetHeadwayJourneyGroups() []*model.HeadwayJourneyG
 func (m *mockNetexRepository) GetHeadwayJourneyGroupById(id string) *model.HeadwayJourneyGroup {
 	return nil
 }
+func (m *mockNetexRepository) GetServiceLinkById(id string) *model.ServiceLink { return nil }
+func (m *mockNetexRepository) GetRouteLinkById(id string) *model.RouteLink     { return nil }
+func (m *mockNetexRepository) GetFlexibleStopPlaceById(id string) *model.FlexibleStopPlace {
+	return nil
+}
+func (m *mockNetexRepository) GetFlexibleStopPlaceByScheduledStopPointId(sspId string) *model.FlexibleStopPlace {
+	return nil
+}
+func (m *mockNetexRepository) GetAllFlexibleStopPlaces() []*model.FlexibleStopPlace { return nil }
+func (m *mockNetexRepository) GetDefaultCurrency() string                           { return "" }
+func (m *mockNetexRepository) GetAllTariffZones() []*model.TariffZone               { return nil }
+func (m *mockNetexRepository) GetAllFareZones() []*model.FareZone                   { return nil }
+func (m *mockNetexRepository) GetAllTariffs() []*model.Tariff                       { return nil }
+func (m *mockNetexRepository) GetAllPreassignedFareProducts() []*model.PreassignedFareProduct {
+	return nil
+}
+func (m *mockNetexRepository) GetAllSalesOfferPackages() []*model.SalesOfferPackage { return nil }
+func (m *mockNetexRepository) GetAllFareTables() []*model.FareTable                 { return nil }
 
 type mockGtfsRepository struct{}
 
@@ -76,6 +112,8 @@ func (m *mockGtfsRepository) WriteGtfs() (io.Reader, error)         { return nil
 type mockStopAreaRepository struct{}
 
 func (m *mockStopAreaRepository) GetQuayById(quayId string) *model.Quay               { return nil }
+func (m *mockStopAreaRepository) GetStopPlaceById(id string) *model.StopPlace         { return nil }
+func (m *mockStopAreaRepository) GetAllSitePathLinks() []*model.SitePathLink          { return nil }
 func (m *mockStopAreaRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace { return nil }
 func (m *mockStopAreaRepository) GetAllQuays() []*model.Quay                          { return nil }
 func (m *mockStopAreaRepository) LoadStopAreas(data []byte) error                     { return nil }
@@ -224,6 +262,60 @@ func TestDefaultStopProducer_ProduceStopFromQuay(t *testing.T) {
 	}
 }
 
+type mockStopPlaceNetexRepository struct {
+	mockNetexRepository
+	stopPlaceByQuay map[string]*model.StopPlace
+	stopPlaces      map[string]*model.StopPlace
+}
+
+func (m *mockStopPlaceNetexRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace {
+	return m.stopPlaceByQuay[quayId]
+}
+
+func (m *mockStopPlaceNetexRepository) GetStopPlaceById(id string) *model.StopPlace {
+	return m.stopPlaces[id]
+}
+
+func TestDefaultStopProducer_ZoneID(t *testing.T) {
+	stopPlace := &model.StopPlace{}
+	mustUnmarshalXML(t, `<StopPlace id="sp1"><tariffZones><TariffZoneRef ref="zone2"/></tariffZones>
+		<quays>
+			<Quay id="quay1"><tariffZones><TariffZoneRef ref="zone3"/><FareZoneRef>zone1</FareZoneRef><TariffZoneRef ref="zone3"/></tariffZones></Quay>
+			<Quay id="quay2"/>
+		</quays></StopPlace>`, stopPlace)
+	inZones, inherits := &stopPlace.Quays.Quay[0], &stopPlace.Quays.Quay[1]
+
+	producer := NewDefaultStopProducer(&mockStopAreaRepository{}, &mockGtfsRepository{})
+	producer.SetNetexRepository(&mockStopPlaceNetexRepository{
+		stopPlaceByQuay: map[string]*model.StopPlace{"quay1": stopPlace, "quay2": stopPlace},
+	})
+
+	testCases := []struct {
+		policy   ZonePolicy
+		quay     *model.Quay
+		expected string
+	}{
+		{ZonePolicyFirst, inZones, "zone3"},
+		{ZonePolicyEmpty, inZones, ""},
+		{ZonePolicyCombined, inZones, "zone1+zone3"},
+		// A quay without zones is in the zones of its stop place
+		{ZonePolicyEmpty, inherits, "zone2"},
+	}
+	for _, tc := range testCases {
+		producer.SetZonePolicy(tc.policy)
+		stop, err := producer.ProduceStopFromQuay(tc.quay)
+		if err != nil {
+			t.Fatalf("ProduceStopFromQuay() failed: %v", err)
+		}
+		if stop.ZoneID != tc.expected {
+			t.Errorf("%s policy, %s: expected zone_id %q, got %q", tc.policy, tc.quay.ID, tc.expected, stop.ZoneID)
+		}
+		if stop.ParentStation != "sp1" {
+			t.Errorf("Expected the stop place of the NeTEx repository as parent station, got %q", stop.ParentStation)
+		}
+	}
+}
+
 func TestDefaultStopProducer_ProduceStopFromStopPlace(t *testing.T) {
 	stopAreaRepo := &mockStopAreaRepository{}
 	gtfsRepo := &mockGtfsRepository{}
@@ -263,10 +355,741 @@ func TestDefaultStopProducer_ProduceStopFromStopPlace(t *testing.T) {
 	}
 }
 
+func TestDefaultStopProducer_StationHierarchy(t *testing.T) {
+	parent, child := &model.StopPlace{}, &model.StopPlace{}
+	mustUnmarshalXML(t, `<StopPlace id="mm1"><Name>Central</Name>
+		<Centroid><Location><Longitude>10.75</Longitude><Latitude>59.91</Latitude></Location></Centroid></StopPlace>`, parent)
+	mustUnmarshalXML(t, `<StopPlace id="sp1"><ShortName>Central bus</ShortName><ParentSiteRef ref="mm1"/>
+		<entrances>
+			<StopPlaceEntrance id="ent1"><PublicCode>A</PublicCode>
+				<Centroid><Location><Longitude>10.70</Longitude><Latitude>59.90</Latitude></Location></Centroid></StopPlaceEntrance>
+		</entrances>
+		<quays>
+			<Quay id="quay1"><PublicCode>1</PublicCode>
+				<Centroid><Location><Longitude>10.00</Longitude><Latitude>59.00</Latitude></Location></Centroid>
+				<boardingPositions><BoardingPosition id="bp1"/></boardingPositions></Quay>
+			<Quay id="quay2"><Centroid><Location><Longitude>11.00</Longitude><Latitude>60.00</Latitude></Location></Centroid></Quay>
+		</quays></StopPlace>`, child)
+	quay := &child.Quays.Quay[0]
+
+	producer := NewDefaultStopProducer(&mockStopAreaRepository{}, &mockGtfsRepository{})
+	producer.SetNetexRepository(&mockStopPlaceNetexRepository{
+		stopPlaceByQuay: map[string]*model.StopPlace{"quay1": child, "quay2": child},
+		stopPlaces:      map[string]*model.StopPlace{"mm1": parent, "sp1": child},
+	})
+
+	// A station without centroid lies in the middle of its quays
+	station, err := producer.ProduceStopFromStopPlace(child)
+	if err != nil {
+		t.Fatalf("ProduceStopFromStopPlace() failed: %v", err)
+	}
+	if station.StopName != "Central bus" || station.StopLat != 59.5 || station.StopLon != 10.5 {
+		t.Errorf("Expected station Central bus at 59.5,10.5, got %q at %v,%v", station.StopName, station.StopLat, station.StopLon)
+	}
+
+	boardingArea, err := producer.ProduceStopFromBoardingPosition(&quay.BoardingPositions[0], quay)
+	if err != nil {
+		t.Fatalf("ProduceStopFromBoardingPosition() failed: %v", err)
+	}
+	if boardingArea.LocationType != "4" || boardingArea.ParentStation != "quay1" || boardingArea.StopLat != 59.0 {
+		t.Errorf("Expected boarding area in quay1 at its location, got %+v", boardingArea)
+	}
+
+	for _, tc := range []struct {
+		strategy MultimodalStrategy
+		station  string
+	}{
+		{MultimodalChild, "sp1"},
+		{MultimodalParent, "mm1"},
+	} {
+		producer.SetMultimodalStrategy(tc.strategy)
+		stop, err := producer.ProduceStopFromQuay(quay)
+		if err != nil {
+			t.Fatalf("ProduceStopFromQuay() failed: %v", err)
+		}
+		if stop.ParentStation != tc.station {
+			t.Errorf("%s strategy: expected quay in station %q, got %q", tc.strategy, tc.station, stop.ParentStation)
+		}
+		entrance, err := producer.ProduceStopFromEntrance(&child.Entrances[0], child)
+		if err != nil {
+			t.Fatalf("ProduceStopFromEntrance() failed: %v", err)
+		}
+		if entrance.LocationType != "2" || entrance.ParentStation != tc.station || entrance.StopCode != "A" {
+			t.Errorf("%s strategy: expected entrance A of station %q, got %+v", tc.strategy, tc.station, entrance)
+		}
+	}
+}
+
+func TestDefaultTripProducer_ServiceID(t *testing.T) {
+	repo := newMockCalendarNetexRepository()
+	repo.operatingDays["od1"] = &model.OperatingDay{ID: "od1", CalendarDate: "2025-01-06"}
+	repo.datedJourneys["sjDated"] = []*model.DatedServiceJourney{
+		{ID: "dsj1", ServiceJourneyRef: "sjDated", OperatingDayRef: "od1"},
+		{ID: "dsj2", ServiceJourneyRef: "sjDated", OperatingDayRef: "2025-01-07"},
+		{ID: "dsj3", ServiceJourneyRef: "sjDated", OperatingDayRef: "2025-01-08", ServiceAlteration: "cancelled"},
+	}
+	producer := NewDefaultTripProducer(repo, &mockGtfsRepository{})
+	route := &model.GtfsRoute{RouteID: "route1"}
+
+	dates := []time.Time{
+		time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name     string
+		id       string
+		dayTypes *model.DayTypes
+		expected string
+	}{
+		{"single day type", "sj1", &model.DayTypes{DayTypeRef: []string{"NSB:DayType:1"}}, "NSB:DayType:1"},
+		{"order independent", "sj1", &model.DayTypes{DayTypeRef: []string{"dt2", "dt1", "dt2"}}, ServiceIDForDayTypes([]string{"dt1", "dt2"})},
+		{"dated journeys", "sjDated", nil, ServiceIDForDates(dates)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trip, err := producer.Produce(TripInput{
+				ServiceJourney: &model.ServiceJourney{ID: tt.id, DayTypes: tt.dayTypes},
+				GtfsRoute:      route,
+			})
+			if err != nil {
+				t.Fatalf("Produce() failed: %v", err)
+			}
+			if trip.ServiceID != tt.expected {
+				t.Errorf("Expected service_id %s, got %s", tt.expected, trip.ServiceID)
+			}
+		})
+	}
+
+	t.Run("no service days", func(t *testing.T) {
+		trip, err := producer.Produce(TripInput{
+			ServiceJourney: &model.ServiceJourney{ID: "sj1", DayTypes: &model.DayTypes{}},
+			GtfsRoute:      route,
+		})
+		if !errors.Is(err, ErrNoServiceDays) || trip != nil {
+			t.Errorf("Expected ErrNoServiceDays and no trip, got %+v (err %v)", trip, err)
+		}
+	})
+}
+
+func TestServiceIDForDayTypes(t *testing.T) {
+	many := make([]string, 50)
+	for i := range many {
+		many[i] = fmt.Sprintf("NSB:DayType:%d", i)
+	}
+	id := ServiceIDForDayTypes(many)
+	if len(id) > 32 || !strings.HasPrefix(id, "daytypes_") {
+		t.Errorf("Expected a short hashed service_id, got %q", id)
+	}
+	reversed := make([]string, len(many))
+	for i, ref := range many {
+		reversed[len(many)-1-i] = ref
+	}
+	if ServiceIDForDayTypes(reversed) != id {
+		t.Error("Expected the service_id not to depend on the order of the day types")
+	}
+	if ServiceIDForDayTypes(many[:49]) == id {
+		t.Error("Expected different day type sets to get different service_ids")
+	}
+	if got := ServiceIDForDayTypes(nil); got != "" {
+		t.Errorf("Expected no service_id without day types, got %q", got)
+	}
+}
+
+type mockDestinationDisplayNetexRepository struct {
+	mockNetexRepository
+	displays map[string]*model.DestinationDisplay
+}
+
+func (m *mockDestinationDisplayNetexRepository) GetDestinationDisplayById(id string) *model.DestinationDisplay {
+	return m.displays[id]
+}
+
+func TestHeadsignFormatter_Format(t *testing.T) {
+	repo := &mockDestinationDisplayNetexRepository{displays: map[string]*model.DestinationDisplay{
+		"dd:lysaker":  {ID: "dd:lysaker", FrontText: "Lysaker"},
+		"dd:sandvika": {ID: "dd:sandvika", SideText: "Sandvika"},
+	}}
+	display := &model.DestinationDisplay{
+		FrontText: "Oslo S",
+		Vias: &model.Vias{Via: []model.Via{
+			{DestinationDisplayRef: "dd:lysaker"},
+			{DestinationDisplayRef: "dd:missing"},
+			{Name: "Skøyen"},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		display   *model.DestinationDisplay
+		format    string
+		separator string
+		expected  string
+	}{
+		{"nil display", nil, DefaultViaFormat, "", ""},
+		{"front text only", &model.DestinationDisplay{FrontText: " Oslo S "}, DefaultViaFormat, "", "Oslo S"},
+		{"side text fallback", &model.DestinationDisplay{SideText: "Drammen"}, DefaultViaFormat, "", "Drammen"},
+		{"vias", display, DefaultViaFormat, "", "Oslo S via Lysaker, Skøyen"},
+		{"custom format and separator", display, "{destination} (via {vias})", " and ", "Oslo S (via Lysaker and Skøyen)"},
+		{"vias disabled", display, "", "", "Oslo S"},
+		{"via side text", &model.DestinationDisplay{FrontText: "Asker", Vias: &model.Vias{Via: []model.Via{{DestinationDisplayRef: "dd:sandvika"}}}}, DefaultViaFormat, "", "Asker via Sandvika"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter := NewHeadsignFormatter(repo)
+			formatter.SetViaFormat(tt.format)
+			if tt.separator != "" {
+				formatter.SetViaSeparator(tt.separator)
+			}
+			if got := formatter.Format(tt.display); got != tt.expected {
+				t.Errorf("Expected headsign %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDefaultTripProducer_Headsign(t *testing.T) {
+	repo := &mockDestinationDisplayNetexRepository{displays: map[string]*model.DestinationDisplay{
+		"dd:lysaker": {ID: "dd:lysaker", FrontText: "Lysaker"},
+	}}
+	producer := NewDefaultTripProducer(repo, &mockGtfsRepository{})
+	input := TripInput{
+		ServiceJourney: &model.ServiceJourney{ID: "sj1", DayTypes: &model.DayTypes{DayTypeRef: []string{"dt1"}}},
+		GtfsRoute:      &model.GtfsRoute{RouteID: "route1"},
+		DestinationDisplay: &model.DestinationDisplay{
+			FrontText: "Oslo S",
+			Vias:      &model.Vias{Via: []model.Via{{DestinationDisplayRef: "dd:lysaker"}}},
+		},
+	}
+
+	trip, err := producer.Produce(input)
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+	if trip.TripHeadsign != "Oslo S via Lysaker" {
+		t.Errorf("Expected trip_headsign 'Oslo S via Lysaker', got '%s'", trip.TripHeadsign)
+	}
+
+	formatter := NewHeadsignFormatter(repo)
+	formatter.SetViaFormat("")
+	producer.SetHeadsignFormatter(formatter)
+	trip, err = producer.Produce(input)
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+	if trip.TripHeadsign != "Oslo S" {
+		t.Errorf("Expected trip_headsign 'Oslo S', got '%s'", trip.TripHeadsign)
+	}
+}
+
+type mockDirectionNetexRepository struct {
+	mockNetexRepository
+	routes          map[string]*model.Route
+	journeyPatterns map[string]*model.JourneyPattern
+	stopPoints      map[string]*model.ScheduledStopPoint
+	serviceJourneys []*model.ServiceJourney
+	// journeyScans counts the calls to GetServiceJourneys
+	journeyScans int
+}
+
+func newMockDirectionNetexRepository() *mockDirectionNetexRepository {
+	return &mockDirectionNetexRepository{
+		routes:          make(map[string]*model.Route),
+		journeyPatterns: make(map[string]*model.JourneyPattern),
+		stopPoints:      make(map[string]*model.ScheduledStopPoint),
+	}
+}
+
+func (m *mockDirectionNetexRepository) GetRouteById(id string) *model.Route { return m.routes[id] }
+func (m *mockDirectionNetexRepository) GetJourneyPatternById(id string) *model.JourneyPattern {
+	return m.journeyPatterns[id]
+}
+func (m *mockDirectionNetexRepository) GetScheduledStopPointById(id string) *model.ScheduledStopPoint {
+	return m.stopPoints[id]
+}
+func (m *mockDirectionNetexRepository) GetServiceJourneys() []*model.ServiceJourney {
+	m.journeyScans++
+	return m.serviceJourneys
+}
+
+// addPattern registers a journey pattern over the given stop points, run by one journey on lineID
+func (m *mockDirectionNetexRepository) addPattern(lineID, id string, stops ...string) *model.JourneyPattern {
+	var points []interface{}
+	for i, stop := range stops {
+		points = append(points, &model.StopPointInJourneyPattern{ID: id + "_" + stop, Order: i + 1, ScheduledStopPointRef: stop})
+	}
+	jp := &model.JourneyPattern{ID: id, PointsInSequence: &model.PointsInSequence{PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: points}}
+	m.journeyPatterns[id] = jp
+	m.serviceJourneys = append(m.serviceJourneys, &model.ServiceJourney{
+		ID:                "sj_" + id,
+		LineRef:           model.ServiceJourneyLineRef{Ref: lineID},
+		JourneyPatternRef: model.ServiceJourneyPatternRef{Ref: id},
+	})
+	return jp
+}
+
+func TestDirectionIDForType(t *testing.T) {
+	tests := map[string]string{
+		"outbound":      DirectionOutbound,
+		"Inbound":       DirectionInbound,
+		" clockwise ":   DirectionOutbound,
+		"anticlockwise": DirectionInbound,
+		"":              "",
+		"unknown":       "",
+	}
+	for directionType, expected := range tests {
+		if got := DirectionIDForType(directionType); got != expected {
+			t.Errorf("DirectionIDForType(%q) = %q, expected %q", directionType, got, expected)
+		}
+	}
+}
+
+func TestDirectionResolver_DirectionID(t *testing.T) {
+	t.Run("explicit direction type", func(t *testing.T) {
+		repo := newMockDirectionNetexRepository()
+		repo.routes["route:in"] = &model.Route{ID: "route:in", DirectionType: "inbound"}
+		resolver := NewDirectionResolver(repo)
+
+		if got := resolver.DirectionID("line1", &model.JourneyPattern{ID: "jp1", DirectionType: "anticlockwise"}, nil); got != DirectionInbound {
+			t.Errorf("Expected pattern DirectionType to map to 1, got %q", got)
+		}
+		if got := resolver.DirectionID("line1", &model.JourneyPattern{ID: "jp2", RouteRef: "route:in"}, nil); got != DirectionInbound {
+			t.Errorf("Expected route DirectionType to map to 1, got %q", got)
+		}
+		if got := resolver.DirectionID("line1", nil, &model.Route{DirectionType: "outbound"}); got != DirectionOutbound {
+			t.Errorf("Expected route DirectionType to map to 0, got %q", got)
+		}
+	})
+
+	t.Run("terminal stops", func(t *testing.T) {
+		repo := newMockDirectionNetexRepository()
+		main := repo.addPattern("line1", "jp:a-main", "A", "B", "C", "D")
+		reverse := repo.addPattern("line1", "jp:b-reverse", "D", "C", "B", "A")
+		shortTurn := repo.addPattern("line1", "jp:c-short", "A", "B", "C")
+		shortReverse := repo.addPattern("line1", "jp:d-short-reverse", "C", "B", "A")
+		otherLine := repo.addPattern("line2", "jp:e-other", "D", "C")
+		resolver := NewDirectionResolver(repo)
+
+		// Resolve in a different order than the patterns sort to check consistency
+		expected := []struct {
+			jp        *model.JourneyPattern
+			line      string
+			direction string
+		}{
+			{shortReverse, "line1", DirectionInbound},
+			{main, "line1", DirectionOutbound},
+			{reverse, "line1", DirectionInbound},
+			{shortTurn, "line1", DirectionOutbound},
+			{otherLine, "line2", DirectionOutbound},
+		}
+		for _, e := range expected {
+			if got := resolver.DirectionID(e.line, e.jp, nil); got != e.direction {
+				t.Errorf("Pattern %s: expected direction_id %q, got %q", e.jp.ID, e.direction, got)
+			}
+		}
+	})
+
+	t.Run("explicit pattern seeds heuristic", func(t *testing.T) {
+		repo := newMockDirectionNetexRepository()
+		repo.routes["route:in"] = &model.Route{ID: "route:in", DirectionType: "inbound"}
+		inbound := repo.addPattern("line1", "jp1", "A", "B", "C")
+		inbound.RouteRef = "route:in"
+		// Different platforms of the same stop places compare equal
+		repo.stopPoints["C2"] = &model.ScheduledStopPoint{ID: "C2", StopPlaceRef: "C"}
+		repo.stopPoints["A2"] = &model.ScheduledStopPoint{ID: "A2", StopPlaceRef: "A"}
+		reverse := repo.addPattern("line1", "jp2", "C2", "B", "A2")
+		resolver := NewDirectionResolver(repo)
+
+		if got := resolver.DirectionID("line1", reverse, nil); got != DirectionOutbound {
+			t.Errorf("Expected reverse of an inbound pattern to be 0, got %q", got)
+		}
+	})
+
+	t.Run("shared stop order", func(t *testing.T) {
+		repo := newMockDirectionNetexRepository()
+		repo.addPattern("line1", "jp1", "A", "B", "C", "D", "E")
+		middle := repo.addPattern("line1", "jp2", "D", "C", "B")
+		resolver := NewDirectionResolver(repo)
+
+		if got := resolver.DirectionID("line1", middle, nil); got != DirectionInbound {
+			t.Errorf("Expected pattern visiting shared stops backwards to be 1, got %q", got)
+		}
+	})
+}
+
+func TestDirectionResolver_IndexesLinePatternsOnce(t *testing.T) {
+	repo := newMockDirectionNetexRepository()
+	a1 := repo.addPattern("lineA", "a1", "A", "B", "C")
+	a2 := repo.addPattern("lineA", "a2", "C", "B", "A")
+	b1 := repo.addPattern("lineB", "b1", "X", "Y")
+	b2 := repo.addPattern("lineB", "b2", "Y", "X")
+	resolver := NewDirectionResolver(repo)
+
+	expected := map[*model.JourneyPattern][2]string{
+		a1: {"lineA", DirectionOutbound},
+		a2: {"lineA", DirectionInbound},
+		b1: {"lineB", DirectionOutbound},
+		b2: {"lineB", DirectionInbound},
+	}
+	for jp, want := range expected {
+		if got := resolver.DirectionID(want[0], jp, nil); got != want[1] {
+			t.Errorf("Expected pattern %s to get direction %q, got %q", jp.ID, want[1], got)
+		}
+	}
+	if repo.journeyScans != 1 {
+		t.Errorf("Expected the service journeys to be scanned once, got %d scans", repo.journeyScans)
+	}
+}
+
+func TestDefaultTripProducer_DirectionID(t *testing.T) {
+	repo := newMockDirectionNetexRepository()
+	outbound := repo.addPattern("line1", "jp1", "A", "B", "C")
+	inbound := repo.addPattern("line1", "jp2", "C", "B", "A")
+	producer := NewDefaultTripProducer(repo, &mockGtfsRepository{})
+
+	tests := []struct {
+		name     string
+		input    TripInput
+		expected string
+	}{
+		{"route direction type", TripInput{NetexRoute: &model.Route{DirectionType: "inbound"}}, DirectionInbound},
+		{"heuristic outbound", TripInput{JourneyPattern: outbound}, DirectionOutbound},
+		{"heuristic inbound", TripInput{JourneyPattern: inbound}, DirectionInbound},
+		{"unknown", TripInput{}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.ServiceJourney = &model.ServiceJourney{ID: "sj1", LineRef: model.ServiceJourneyLineRef{Ref: "line1"}, DayTypes: &model.DayTypes{DayTypeRef: []string{"dt1"}}}
+			tt.input.GtfsRoute = &model.GtfsRoute{RouteID: "line1"}
+			trip, err := producer.Produce(tt.input)
+			if err != nil {
+				t.Fatalf("Produce() failed: %v", err)
+			}
+			if trip.DirectionID != tt.expected {
+				t.Errorf("Expected direction_id %q, got %q", tt.expected, trip.DirectionID)
+			}
+		})
+	}
+}
+
+// mockVehicleTypeNetexRepository resolves the lines and vehicle types of trips
+type mockVehicleTypeNetexRepository struct {
+	mockNetexRepository
+	lines        map[string]*model.Line
+	vehicleTypes map[string]*model.VehicleType
+}
+
+func (m *mockVehicleTypeNetexRepository) GetLineById(id string) *model.Line {
+	return m.lines[id]
+}
+
+func (m *mockVehicleTypeNetexRepository) GetVehicleTypeById(id string) *model.VehicleType {
+	return m.vehicleTypes[id]
+}
+
+func TestDefaultTripProducer_Accessibility(t *testing.T) {
+	repo := &mockVehicleTypeNetexRepository{
+		lines: map[string]*model.Line{
+			"line1": {ID: "line1", VehicleTypeRef: "lowFloorBus"},
+			"line2": {ID: "line2"},
+		},
+		vehicleTypes: map[string]*model.VehicleType{
+			"lowFloorBus": {ID: "lowFloorBus", LowFloor: "true", CyclesAllowed: "false"},
+			"tram": {ID: "tram", WheelchairAccessible: "true",
+				VehicleTypeCapacity: &model.VehicleTypeCapacity{BicyclePlaces: "4"}},
+			"coach": {ID: "coach", WheelchairAccessible: "false"},
+		},
+	}
+	producer := NewDefaultTripProducer(repo, &mockGtfsRepository{})
+
+	var journey model.ServiceJourney
+	mustUnmarshalXML(t, `<ServiceJourney id="sj1"><dayTypes><DayTypeRef ref="dt1"/></dayTypes><VehicleTypeRef ref="tram"/><LineRef ref="line1"/></ServiceJourney>`, &journey)
+
+	tests := []struct {
+		name               string
+		journey            *model.ServiceJourney
+		pattern            *model.JourneyPattern
+		expectedWheelchair string
+		expectedBikes      string
+	}{
+		{"journey vehicle type", &journey, &model.JourneyPattern{ID: "jp1", VehicleTypeRef: "coach"}, "1", "1"},
+		{"pattern default", &model.ServiceJourney{ID: "sj2", DayTypes: &model.DayTypes{DayTypeRef: []string{"dt1"}}, LineRef: model.ServiceJourneyLineRef{Ref: "line1"}},
+			&model.JourneyPattern{ID: "jp1", VehicleTypeRef: "coach"}, "2", "0"},
+		{"line default", &model.ServiceJourney{ID: "sj3", DayTypes: &model.DayTypes{DayTypeRef: []string{"dt1"}}, LineRef: model.ServiceJourneyLineRef{Ref: "line1"}},
+			&model.JourneyPattern{ID: "jp2"}, "1", "2"},
+		{"unknown vehicle type", &model.ServiceJourney{ID: "sj4", DayTypes: &model.DayTypes{DayTypeRef: []string{"dt1"}}, VehicleTypeRef: "ferry", LineRef: model.ServiceJourneyLineRef{Ref: "line2"}},
+			&model.JourneyPattern{ID: "jp2"}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trip, err := producer.Produce(TripInput{
+				ServiceJourney: tt.journey,
+				JourneyPattern: tt.pattern,
+				NetexRoute:     &model.Route{ID: "route1"},
+				GtfsRoute:      &model.GtfsRoute{RouteID: "route1"},
+			})
+			if err != nil {
+				t.Fatalf("Produce() failed: %v", err)
+			}
+			if trip.WheelchairAccessible != tt.expectedWheelchair || trip.BikesAllowed != tt.expectedBikes {
+				t.Errorf("Expected wheelchair_accessible %q and bikes_allowed %q, got %q and %q",
+					tt.expectedWheelchair, tt.expectedBikes, trip.WheelchairAccessible, trip.BikesAllowed)
+			}
+		})
+	}
+}
+
+func TestDefaultStopTimeProducer_PickupDropOffTypes(t *testing.T) {
+	producer := NewDefaultStopTimeProducer(&mockNetexRepository{}, &mockGtfsRepository{})
+	no := false
+	yes := true
+
+	tests := []struct {
+		name            string
+		stopPoint       *model.StopPointInJourneyPattern
+		expectedPickup  string
+		expectedDropOff string
+	}{
+		{"flags omitted", &model.StopPointInJourneyPattern{}, "0", "0"},
+		{"flags true", &model.StopPointInJourneyPattern{ForBoarding: &yes, ForAlighting: &yes}, "0", "0"},
+		{"set down only", &model.StopPointInJourneyPattern{ForBoarding: &no}, "1", "0"},
+		{"pick up only", &model.StopPointInJourneyPattern{ForAlighting: &no}, "0", "1"},
+		{"request stop", &model.StopPointInJourneyPattern{RequestStop: true}, "3", "3"},
+		{"request stop set down only", &model.StopPointInJourneyPattern{RequestStop: true, ForBoarding: &no}, "1", "3"},
+		{"booking arrangements", &model.StopPointInJourneyPattern{BookingArrangements: &model.BookingArrangements{}}, "3", "3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.stopPoint.ID = "spjp1"
+			st, err := producer.Produce(StopTimeInput{
+				TimetabledPassingTime: &model.TimetabledPassingTime{PointInJourneyPatternRef: "spjp1", DepartureTime: "08:00:00"},
+				JourneyPattern: &model.JourneyPattern{ID: "jp1", PointsInSequence: &model.PointsInSequence{
+					PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: []interface{}{tt.stopPoint},
+				}},
+				Trip: &model.Trip{TripID: "trip1"},
+			})
+			if err != nil {
+				t.Fatalf("Produce() failed: %v", err)
+			}
+			if st.PickupType != tt.expectedPickup || st.DropOffType != tt.expectedDropOff {
+				t.Errorf("Expected pickup_type %s and drop_off_type %s, got %s and %s",
+					tt.expectedPickup, tt.expectedDropOff, st.PickupType, st.DropOffType)
+			}
+		})
+	}
+}
+
+func TestDefaultShapeProducer_Produce(t *testing.T) {
+	producer := NewDefaultShapeProducer(&mockNetexRepository{}, &mockGtfsRepository{})
+
+	shape, err := producer.Produce(nil)
+	if err != nil || shape != nil {
+		t.Errorf("Expected no shape for nil pattern, got %v (err %v)", shape, err)
+	}
+
+	// The mock repository cannot resolve any stop location
+	jp := &model.JourneyPattern{
+		ID: "jp1",
+		PointsInSequence: &model.PointsInSequence{
+			PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern: []interface{}{
+				&model.StopPointInJourneyPattern{ID: "spjp1", ScheduledStopPointRef: "ssp1"},
+				&model.StopPointInJourneyPattern{ID: "spjp2", ScheduledStopPointRef: "ssp2"},
+			},
+		},
+	}
+	shape, err = producer.Produce(jp)
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+	if len(shape) != 0 {
+		t.Errorf("Expected no shape points for unresolvable stops, got %d", len(shape))
+	}
+}
+
+// mockCalendarNetexRepository resolves the calendar entities used by the service calendar producers
+type mockCalendarNetexRepository struct {
+	mockNetexRepository
+	dayTypes         map[string]*model.DayType
+	operatingDays    map[string]*model.OperatingDay
+	operatingPeriods map[string]*model.OperatingPeriod
+	assignments      map[string][]*model.DayTypeAssignment
+	datedJourneys    map[string][]*model.DatedServiceJourney
+}
+
+func newMockCalendarNetexRepository() *mockCalendarNetexRepository {
+	return &mockCalendarNetexRepository{
+		dayTypes:         make(map[string]*model.DayType),
+		operatingDays:    make(map[string]*model.OperatingDay),
+		operatingPeriods: make(map[string]*model.OperatingPeriod),
+		assignments:      make(map[string][]*model.DayTypeAssignment),
+		datedJourneys:    make(map[string][]*model.DatedServiceJourney),
+	}
+}
+
+func (m *mockCalendarNetexRepository) addAssignment(assignment *model.DayTypeAssignment) {
+	m.assignments[assignment.DayTypeRef] = append(m.assignments[assignment.DayTypeRef], assignment)
+}
+
+func (m *mockCalendarNetexRepository) GetDayTypeById(id string) *model.DayType {
+	return m.dayTypes[id]
+}
+func (m *mockCalendarNetexRepository) GetDatedServiceJourneysByServiceJourneyId(serviceJourneyId string) []*model.DatedServiceJourney {
+	return m.datedJourneys[serviceJourneyId]
+}
+func (m *mockCalendarNetexRepository) GetOperatingDayById(id string) *model.OperatingDay {
+	return m.operatingDays[id]
+}
+func (m *mockCalendarNetexRepository) GetOperatingPeriodById(id string) *model.OperatingPeriod {
+	return m.operatingPeriods[id]
+}
+func (m *mockCalendarNetexRepository) GetDayTypeAssignmentsByDayType(dayType *model.DayType) []*model.DayTypeAssignment {
+	return m.assignments[dayType.ID]
+}
+
+func TestDefaultServiceCalendarProducer_Produce(t *testing.T) {
+	netexRepo := newMockCalendarNetexRepository()
+	netexRepo.dayTypes["dt-weekdays"] = &model.DayType{
+		ID: "dt-weekdays",
+		Properties: &model.Properties{
+			PropertyOfDay: []model.PropertyOfDay{{DaysOfWeek: "Monday Tuesday Wednesday Thursday Friday"}},
+		},
+	}
+	netexRepo.operatingPeriods["op-winter"] = &model.OperatingPeriod{ID: "op-winter", FromDate: "2025-01-06T00:00:00", ToDate: "2025-03-30T00:00:00"}
+	netexRepo.addAssignment(&model.DayTypeAssignment{ID: "dta1", DayTypeRef: "dt-weekdays", OperatingPeriodRef: "op-winter", IsAvailable: true})
+
+	producer := NewDefaultServiceCalendarProducer(netexRepo, &mockGtfsRepository{})
+	calendar, err := producer.Produce("svc1", []*model.DayType{netexRepo.dayTypes["dt-weekdays"]})
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+	if calendar == nil {
+		t.Fatal("Expected a calendar")
+	}
+	// The period ends on a Sunday, so the row ends on the last Friday
+	if calendar.StartDate != "20250106" || calendar.EndDate != "20250328" {
+		t.Errorf("Expected period 20250106-20250328, got %s-%s", calendar.StartDate, calendar.EndDate)
+	}
+	if !calendar.Monday || !calendar.Friday || calendar.Saturday || calendar.Sunday {
+		t.Errorf("Expected weekday service only, got %+v", calendar)
+	}
+
+	// A day type without any operating period has no calendar row
+	netexRepo.dayTypes["dt-orphan"] = &model.DayType{ID: "dt-orphan"}
+	calendar, err = producer.Produce("svc2", []*model.DayType{netexRepo.dayTypes["dt-orphan"]})
+	if err != nil || calendar != nil {
+		t.Errorf("Expected no calendar without operating period, got %+v (err %v)", calendar, err)
+	}
+}
+
+func TestDefaultServiceCalendarDateProducer_Produce(t *testing.T) {
+	netexRepo := newMockCalendarNetexRepository()
+	netexRepo.dayTypes["dt-weekdays"] = &model.DayType{
+		ID: "dt-weekdays",
+		Properties: &model.Properties{
+			PropertyOfDay: []model.PropertyOfDay{{DaysOfWeek: "Weekdays"}},
+		},
+	}
+	netexRepo.operatingPeriods["op-jan"] = &model.OperatingPeriod{ID: "op-jan", FromDate: "2025-01-01", ToDate: "2025-01-10"}
+	netexRepo.operatingPeriods["op-late-jan"] = &model.OperatingPeriod{ID: "op-late-jan", FromDate: "2025-01-20", ToDate: "2025-01-24"}
+	netexRepo.operatingDays["od-holiday"] = &model.OperatingDay{ID: "od-holiday", CalendarDate: "2025-01-06"}
+	netexRepo.operatingDays["od-extra"] = &model.OperatingDay{ID: "od-extra", CalendarDate: "2025-01-11"}
+	netexRepo.addAssignment(&model.DayTypeAssignment{ID: "dta1", DayTypeRef: "dt-weekdays", OperatingPeriodRef: "op-jan", IsAvailable: true})
+	netexRepo.addAssignment(&model.DayTypeAssignment{ID: "dta2", DayTypeRef: "dt-weekdays", OperatingPeriodRef: "op-late-jan", IsAvailable: true})
+	netexRepo.addAssignment(&model.DayTypeAssignment{ID: "dta3", DayTypeRef: "dt-weekdays", OperatingDayRef: "od-holiday", IsAvailable: false})
+	netexRepo.addAssignment(&model.DayTypeAssignment{ID: "dta4", DayTypeRef: "dt-weekdays", OperatingDayRef: "od-extra", IsAvailable: true})
+
+	producer := NewDefaultServiceCalendarDateProducer(netexRepo, &mockGtfsRepository{})
+	dates, err := producer.Produce("svc1", netexRepo.assignments["dt-weekdays"])
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+
+	got := make(map[string]int)
+	for _, cd := range dates {
+		if cd.ServiceID != "svc1" {
+			t.Errorf("Expected service svc1, got %s", cd.ServiceID)
+		}
+		got[cd.Date] = cd.ExceptionType
+	}
+	// Mondays are mostly idle (holiday on the 6th, gap on the 13th), so the
+	// compressed row runs Tuesday to Friday with the Monday 20th added back
+	expected := map[string]int{
+		"20250111": 1, // extra Saturday
+		"20250120": 1,
+		// weekdays between the two periods
+		"20250114": 2, "20250115": 2, "20250116": 2, "20250117": 2,
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d calendar dates, got %v", len(expected), got)
+	}
+	for date, exceptionType := range expected {
+		if got[date] != exceptionType {
+			t.Errorf("Expected exception %d on %s, got %d", exceptionType, date, got[date])
+		}
+	}
+	for i := 1; i < len(dates); i++ {
+		if dates[i-1].Date > dates[i].Date {
+			t.Errorf("Expected calendar dates sorted by date, got %s before %s", dates[i-1].Date, dates[i].Date)
+		}
+	}
+}
+
+func TestDefaultServiceCalendarProducers_UicOperatingPeriod(t *testing.T) {
+	netexRepo := &mockUicCalendarNetexRepository{
+		mockCalendarNetexRepository: newMockCalendarNetexRepository(),
+		uicPeriods:                  make(map[string]*model.UicOperatingPeriod),
+	}
+	netexRepo.dayTypes["dt-uic"] = &model.DayType{ID: "dt-uic"}
+	// Two weeks from Monday 2025-03-03, running on weekdays except Wednesday 12th
+	netexRepo.uicPeriods["uic1"] = &model.UicOperatingPeriod{ID: "uic1", FromDate: "2025-03-03T00:00:00", ValidDayBits: "11111001101100"}
+	netexRepo.addAssignment(&model.DayTypeAssignment{ID: "dta1", DayTypeRef: "dt-uic", OperatingPeriodRef: "uic1", IsAvailable: true})
+
+	calendarProducer := NewDefaultServiceCalendarProducer(netexRepo, &mockGtfsRepository{})
+	calendar, err := calendarProducer.Produce("svc-uic", []*model.DayType{netexRepo.dayTypes["dt-uic"]})
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+	if calendar == nil {
+		t.Fatal("Expected a calendar row for a regular UIC pattern")
+	}
+	if !calendar.Monday || !calendar.Friday || calendar.Saturday || calendar.Sunday ||
+		calendar.StartDate != "20250303" || calendar.EndDate != "20250314" {
+		t.Errorf("Expected weekday calendar 20250303-20250314, got %+v", calendar)
+	}
+
+	dateProducer := NewDefaultServiceCalendarDateProducer(netexRepo, &mockGtfsRepository{})
+	dates, err := dateProducer.Produce("svc-uic", netexRepo.assignments["dt-uic"])
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+	if len(dates) != 1 || dates[0].Date != "20250312" || dates[0].ExceptionType != 2 {
+		t.Errorf("Expected only 20250312 removed, got %d exceptions", len(dates))
+	}
+}
+
+type mockUicCalendarNetexRepository struct {
+	*mockCalendarNetexRepository
+	uicPeriods map[string]*model.UicOperatingPeriod
+}
+
+func (m *mockUicCalendarNetexRepository) GetUicOperatingPeriodById(id string) *model.UicOperatingPeriod {
+	return m.uicPeriods[id]
+}
+
 func TestDefaultFeedInfoProducer_ProduceFeedInfo(t *testing.T) {
 	producer := NewDefaultFeedInfoProducer()
 
-	feedInfo, err := producer.ProduceFeedInfo()
+	feedInfo, err := producer.ProduceFeedInfo(FeedInfoInput{
+		PublicationTimestamp: "2024-05-01T10:00:00",
+		ContentHash:          "3f2a9c1b07de5a6b",
+		Calendars: []*model.Calendar{
+			// Weekdays from Monday 3 June, the first Monday removed below
+			{ServiceID: "s1", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true, StartDate: "20240601", EndDate: "20240630"},
+		},
+		CalendarDates: []*model.CalendarDate{
+			{ServiceID: "s1", Date: "20240603", ExceptionType: 2},
+			{ServiceID: "s2", Date: "20240707", ExceptionType: 1},
+		},
+	})
 	if err != nil {
 		t.Fatalf("ProduceFeedInfo() failed: %v", err)
 	}
@@ -279,19 +1102,252 @@ func TestDefaultFeedInfoProducer_ProduceFeedInfo(t *testing.T) {
 		t.Error("FeedPublisherName should not be empty")
 	}
 
-	if feedInfo.FeedVersion == "" {
-		t.Error("FeedVersion should not be empty")
+	if feedInfo.FeedVersion != "20240501T100000-3f2a9c1b07de" {
+		t.Errorf("Expected FeedVersion '20240501T100000-3f2a9c1b07de', got '%s'", feedInfo.FeedVersion)
 	}
 
 	if feedInfo.FeedLang == "" {
 		t.Error("FeedLang should not be empty")
 	}
 
-	if feedInfo.FeedStartDate == "" {
-		t.Error("FeedStartDate should not be empty")
+	if feedInfo.FeedStartDate != "20240604" {
+		t.Errorf("Expected FeedStartDate '20240604', got '%s'", feedInfo.FeedStartDate)
 	}
 
-	if feedInfo.FeedEndDate == "" {
-		t.Error("FeedEndDate should not be empty")
+	if feedInfo.FeedEndDate != "20240707" {
+		t.Errorf("Expected FeedEndDate '20240707', got '%s'", feedInfo.FeedEndDate)
+	}
+}
+
+func TestDefaultFeedInfoProducer_Publisher(t *testing.T) {
+	producer := NewDefaultFeedInfoProducer()
+
+	feedInfo, err := producer.ProduceFeedInfo(FeedInfoInput{
+		PublisherName: "Transit Authority",
+		PublisherURL:  "https://transit.example.com",
+		ContactEmail:  "gtfs@transit.example.com",
+		Language:      "fr",
+	})
+	if err != nil {
+		t.Fatalf("ProduceFeedInfo() failed: %v", err)
+	}
+
+	if feedInfo.FeedPublisherName != "Transit Authority" || feedInfo.FeedPublisherURL != "https://transit.example.com" {
+		t.Errorf("Unexpected publisher: %s, %s", feedInfo.FeedPublisherName, feedInfo.FeedPublisherURL)
+	}
+	if feedInfo.FeedContactEmail != "gtfs@transit.example.com" || feedInfo.FeedLang != "fr" {
+		t.Errorf("Unexpected contact email or language: %s, %s", feedInfo.FeedContactEmail, feedInfo.FeedLang)
+	}
+	// Without services or a publication there is no validity period or version
+	if feedInfo.FeedStartDate != "" || feedInfo.FeedEndDate != "" || feedInfo.FeedVersion != "" {
+		t.Errorf("Expected no dates or version, got %s-%s, %s", feedInfo.FeedStartDate, feedInfo.FeedEndDate, feedInfo.FeedVersion)
+	}
+}
+
+type mockTransferGtfsRepository struct {
+	mockGtfsRepository
+	stops map[string]*model.Stop
+	trips map[string]*model.Trip
+}
+
+func newMockTransferGtfsRepository(stopIDs, tripIDs []string) *mockTransferGtfsRepository {
+	m := &mockTransferGtfsRepository{stops: make(map[string]*model.Stop), trips: make(map[string]*model.Trip)}
+	for _, id := range stopIDs {
+		m.stops[id] = &model.Stop{StopID: id}
+	}
+	for _, id := range tripIDs {
+		m.trips[id] = &model.Trip{TripID: id}
+	}
+	return m
+}
+
+func (m *mockTransferGtfsRepository) GetStopById(id string) *model.Stop { return m.stops[id] }
+func (m *mockTransferGtfsRepository) GetTripById(id string) *model.Trip { return m.trips[id] }
+
+func TestDefaultTransferProducer_Produce(t *testing.T) {
+	netexRepo := newMockDirectionNetexRepository()
+	netexRepo.stopPoints["ssp1"] = &model.ScheduledStopPoint{ID: "ssp1", QuayRef: "quay1"}
+	netexRepo.stopPoints["ssp2"] = &model.ScheduledStopPoint{ID: "ssp2", StopPlaceRef: "sp2"}
+	gtfsRepo := newMockTransferGtfsRepository([]string{"quay1", "sp2"}, []string{"sj1", "sj2"})
+	producer := NewDefaultTransferProducer(netexRepo, gtfsRepo)
+
+	transfer, err := producer.Produce(&model.ServiceJourneyInterchange{
+		ID:                  "ic1",
+		FromPointRef:        "ssp1",
+		ToPointRef:          "ssp2",
+		FromJourneyRef:      "sj1",
+		ToJourneyRef:        "sj-unknown",
+		MinimumTransferTime: "PT1M30S",
+	})
+	if err != nil {
+		t.Fatalf("Produce() failed: %v", err)
+	}
+	if transfer == nil {
+		t.Fatal("Expected a transfer")
+	}
+	if transfer.FromStopID != "quay1" || transfer.ToStopID != "sp2" {
+		t.Errorf("Expected stops quay1 -> sp2, got %s -> %s", transfer.FromStopID, transfer.ToStopID)
+	}
+	if transfer.FromTripID != "sj1" || transfer.ToTripID != "" {
+		t.Errorf("Expected trips sj1 -> \"\", got %q -> %q", transfer.FromTripID, transfer.ToTripID)
+	}
+	if transfer.TransferType != 2 || transfer.MinTransferTime != 90 {
+		t.Errorf("Expected type 2 with 90 seconds, got type %d with %d seconds", transfer.TransferType, transfer.MinTransferTime)
+	}
+
+	// A stay-seated interchange needs both trips
+	transfer, err = producer.Produce(&model.ServiceJourneyInterchange{
+		ID: "ic2", FromPointRef: "ssp1", ToPointRef: "ssp1", FromJourneyRef: "sj1", ToJourneyRef: "sj-unknown", StaySeated: true,
+	})
+	if err != nil || transfer != nil {
+		t.Errorf("Expected no transfer for a stay-seated interchange to an unknown trip, got %+v, %v", transfer, err)
+	}
+
+	// Points whose stops are not in the feed produce no transfer
+	transfer, err = producer.Produce(&model.ServiceJourneyInterchange{ID: "ic3", FromPointRef: "ssp1", ToPointRef: "ssp-other"})
+	if err != nil || transfer != nil {
+		t.Errorf("Expected no transfer to a stop outside the feed, got %+v, %v", transfer, err)
+	}
+
+	// Without a guarantee or a minimum time the transfer is only recommended
+	transfer, _ = producer.Produce(&model.ServiceJourneyInterchange{ID: "ic4", FromPointRef: "ssp1", ToPointRef: "ssp2"})
+	if transfer == nil || transfer.TransferType != 0 || transfer.MinTransferTime != 0 {
+		t.Errorf("Expected a recommended transfer without minimum time, got %+v", transfer)
+	}
+}
+
+func TestParseISODurationSeconds(t *testing.T) {
+	tests := []struct {
+		value   string
+		seconds int
+		ok      bool
+	}{
+		{"PT5M", 300, true},
+		{"PT1M30S", 90, true},
+		{"PT1H", 3600, true},
+		{"PT1H2M3S", 3723, true},
+		{"PT45S", 45, true},
+		{"PT0.5M", 30, true},
+		{"P1DT2H", 93600, true},
+		{"pt2m", 120, true},
+		{"", 0, false},
+		{"5M", 0, false},
+		{"PT5", 0, false},
+		{"PT5X", 0, false},
+		{"P5M", 0, false},
+	}
+	for _, tt := range tests {
+		seconds, ok := parseISODurationSeconds(tt.value)
+		if seconds != tt.seconds || ok != tt.ok {
+			t.Errorf("parseISODurationSeconds(%q) = %d, %v; want %d, %v", tt.value, seconds, ok, tt.seconds, tt.ok)
+		}
+	}
+}
+
+type mockFareNetexRepository struct {
+	mockNetexRepository
+	fareZones          []*model.FareZone
+	tariffs            []*model.Tariff
+	products           []*model.PreassignedFareProduct
+	salesOfferPackages []*model.SalesOfferPackage
+	stopPlace          *model.StopPlace
+}
+
+func (m *mockFareNetexRepository) GetAllFareZones() []*model.FareZone { return m.fareZones }
+func (m *mockFareNetexRepository) GetAllTariffs() []*model.Tariff     { return m.tariffs }
+func (m *mockFareNetexRepository) GetAllPreassignedFareProducts() []*model.PreassignedFareProduct {
+	return m.products
+}
+func (m *mockFareNetexRepository) GetAllSalesOfferPackages() []*model.SalesOfferPackage {
+	return m.salesOfferPackages
+}
+func (m *mockFareNetexRepository) GetAllQuays() []*model.Quay {
+	var quays []*model.Quay
+	for i := range m.stopPlace.Quays.Quay {
+		quays = append(quays, &m.stopPlace.Quays.Quay[i])
+	}
+	return quays
+}
+func (m *mockFareNetexRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace {
+	return m.stopPlace
+}
+
+func TestDefaultFareProducer_Produce(t *testing.T) {
+	zone := &model.FareZone{}
+	mustUnmarshalXML(t, `<FareZone id="zone1"><Name>Centre</Name><members>
+		<ScheduledStopPointRef ref="ssp1"/><ScheduledStopPointRef ref="ssp2"/><ScheduledStopPointRef ref="ssp1"/>
+	</members></FareZone>`, zone)
+	buses, night := &model.Tariff{}, &model.Tariff{}
+	mustUnmarshalXML(t, `<Tariff id="tariff1"><Name>Buses</Name><lines><LineRef ref="line1"/></lines></Tariff>`, buses)
+	mustUnmarshalXML(t, `<Tariff id="tariff2"><Name>Night</Name><lines><LineRef ref="line1"/><LineRef ref="line2"/></lines></Tariff>`, night)
+
+	// Quays in tariff zones are stops of the zones' areas
+	stopPlace := &model.StopPlace{}
+	mustUnmarshalXML(t, `<StopPlace id="sp1"><tariffZones><TariffZoneRef ref="zone1"/></tariffZones><quays>
+		<Quay id="quay1"/><Quay id="quay2"><tariffZones><TariffZoneRef ref="zone-unknown"/></tariffZones></Quay>
+	</quays></StopPlace>`, stopPlace)
+
+	netexRepo := &mockFareNetexRepository{
+		fareZones: []*model.FareZone{zone},
+		tariffs:   []*model.Tariff{night, buses},
+		stopPlace: stopPlace,
+		products: []*model.PreassignedFareProduct{
+			{ID: "single", Name: "Single", Prices: []model.FarePrice{{Amount: "200", Currency: "jpy"}}},
+			{ID: "day", Name: "Day pass"},
+			{ID: "week", Name: "Week pass", Prices: []model.FarePrice{{Amount: "20"}}},
+		},
+		salesOfferPackages: []*model.SalesOfferPackage{{
+			ID: "paper", Name: "Paper ticket",
+			Elements: []model.SalesOfferPackageElement{
+				{TypeOfTravelDocumentRef: "paperTicket", PreassignedFareProductRef: "single"},
+				{TypeOfTravelDocumentRef: "paperTicket", PreassignedFareProductRef: "day"},
+			},
+			Prices: []model.FarePrice{{ID: "paper-price", Amount: "5", Currency: "EUR"}},
+		}},
+	}
+
+	fares := NewDefaultFareProducer(netexRepo).Produce()
+
+	if len(fares.Areas) != 1 || fares.Areas[0].AreaName != "Centre" {
+		t.Errorf("Expected the fare zone as area, got %v", fares.Areas)
+	}
+	if len(fares.AreaStops) != 3 || fares.AreaStops[0].StopID != "ssp1" || fares.AreaStops[1].StopID != "ssp2" ||
+		fares.AreaStops[2].StopID != "quay1" {
+		t.Errorf("Expected the two zone stops once each and the quay inheriting the zone, got %v", fares.AreaStops)
+	}
+	// A line stays in its first tariff
+	if len(fares.RouteNetworks) != 2 || fares.RouteNetworks[0].NetworkID != "tariff1" || fares.RouteNetworks[1].RouteID != "line2" {
+		t.Errorf("Unexpected route networks %v", fares.RouteNetworks)
+	}
+	// Yen have no decimals; the product is sold on paper
+	if len(fares.FareProducts) != 1 || fares.FareProducts[0].Amount != "200" || fares.FareProducts[0].Currency != "JPY" ||
+		fares.FareProducts[0].FareMediaID != "paper" {
+		t.Errorf("Unexpected fare products %v", fares.FareProducts)
+	}
+	if len(fares.FareMedia) != 1 || fares.FareMedia[0].FareMediaType != 1 {
+		t.Errorf("Expected a paper fare media, got %v", fares.FareMedia)
+	}
+	if len(fares.FareLegRules) != 1 || fares.FareLegRules[0].NetworkID != "" || fares.FareLegRules[0].FromAreaID != "" {
+		t.Errorf("Expected one leg rule without network or areas, got %v", fares.FareLegRules)
+	}
+
+	// The shared line, the package price for two products, the week pass
+	// without currency and the two products without price are reported
+	issues := make(map[string]int)
+	for _, issue := range fares.Issues {
+		issues[issue.EntityID]++
+	}
+	expected := map[string]int{"tariff2": 1, "paper": 1, "week": 2, "day": 1}
+	for id, count := range expected {
+		if issues[id] != count {
+			t.Errorf("Expected %d issues for %s, got %d in %v", count, id, issues[id], fares.Issues)
+		}
+	}
+}
+
+func mustUnmarshalXML(t *testing.T, data string, v interface{}) {
+	t.Helper()
+	if err := xml.Unmarshal([]byte(data), v); err != nil {
+		t.Fatal(err)
 	}
 }
@@ -0,0 +1,314 @@
+package producer
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+)
+
+// GTFS direction_id values
+const (
+	DirectionOutbound = "0"
+	DirectionInbound  = "1"
+)
+
+// DirectionIDForType maps a NeTEx DirectionType to a GTFS direction_id, or ""
+// when the type is empty or unknown
+func DirectionIDForType(directionType string) string {
+	switch strings.ToLower(strings.TrimSpace(directionType)) {
+	case "outbound", "outward", "clockwise":
+		return DirectionOutbound
+	case "inbound", "return", "anticlockwise":
+		return DirectionInbound
+	default:
+		return ""
+	}
+}
+
+// DirectionResolver assigns GTFS direction_ids to journey patterns. An
+// explicit DirectionType on the pattern or its route wins; otherwise the
+// patterns of a line are compared by their terminal stops, so that patterns
+// running the same way always share a direction_id.
+type DirectionResolver struct {
+	netexRepository NetexRepository
+	// lineDirections caches the resolved direction of each pattern per line
+	lineDirections map[string]map[string]string
+	// linePatternIndex lists the journey patterns run on each line; it is
+	// built from the service journeys on first use
+	linePatternIndex map[string][]*model.JourneyPattern
+}
+
+// NewDirectionResolver creates a direction resolver backed by a NeTEx repository
+func NewDirectionResolver(netexRepository NetexRepository) *DirectionResolver {
+	return &DirectionResolver{
+		netexRepository: netexRepository,
+		lineDirections:  make(map[string]map[string]string),
+	}
+}
+
+// DirectionID returns the direction_id of a journey pattern on a line. route
+// is used when given, otherwise the pattern's RouteRef is looked up; an empty
+// lineID falls back to the route's line.
+func (r *DirectionResolver) DirectionID(lineID string, jp *model.JourneyPattern, route *model.Route) string {
+	if jp == nil {
+		if route != nil {
+			return DirectionIDForType(route.DirectionType)
+		}
+		return ""
+	}
+	if direction := DirectionIDForType(jp.DirectionType); direction != "" {
+		return direction
+	}
+	if route == nil {
+		route = r.routeOf(jp)
+	}
+	if route != nil {
+		if direction := DirectionIDForType(route.DirectionType); direction != "" {
+			return direction
+		}
+		if lineID == "" {
+			lineID = route.LineRef.Ref
+		}
+	}
+	if r.netexRepository == nil {
+		return ""
+	}
+
+	directions, ok := r.lineDirections[lineID]
+	if !ok || directions[jp.ID] == "" {
+		directions = r.resolveLine(lineID, jp)
+		r.lineDirections[lineID] = directions
+	}
+	return directions[jp.ID]
+}
+
+// explicitDirection returns the direction declared by a pattern or its route
+func (r *DirectionResolver) explicitDirection(jp *model.JourneyPattern) string {
+	if direction := DirectionIDForType(jp.DirectionType); direction != "" {
+		return direction
+	}
+	if route := r.routeOf(jp); route != nil {
+		return DirectionIDForType(route.DirectionType)
+	}
+	return ""
+}
+
+// routeOf looks up the route of a journey pattern
+func (r *DirectionResolver) routeOf(jp *model.JourneyPattern) *model.Route {
+	if jp.RouteRef == "" || r.netexRepository == nil {
+		return nil
+	}
+	return r.netexRepository.GetRouteById(jp.RouteRef)
+}
+
+// journeyLine returns the line of a service journey, directly or through its
+// pattern's route
+func (r *DirectionResolver) journeyLine(sj *model.ServiceJourney, jp *model.JourneyPattern) string {
+	if sj.LineRef.Ref != "" {
+		return sj.LineRef.Ref
+	}
+	if route := r.routeOf(jp); route != nil {
+		return route.LineRef.Ref
+	}
+	return ""
+}
+
+// patternStops is a journey pattern with the stop keys it serves in order
+type patternStops struct {
+	id        string
+	stops     []string
+	direction string
+}
+
+// resolveLine assigns a direction to every pattern used on a line. Patterns
+// with an explicit direction seed the comparison; when there are none, the
+// longest pattern is taken as outbound.
+func (r *DirectionResolver) resolveLine(lineID string, jp *model.JourneyPattern) map[string]string {
+	patterns := r.linePatterns(lineID, jp)
+
+	var resolved, pending []*patternStops
+	for _, p := range patterns {
+		if p.direction != "" {
+			resolved = append(resolved, p)
+		} else {
+			pending = append(pending, p)
+		}
+	}
+
+	for len(pending) > 0 {
+		if len(resolved) == 0 {
+			// Nothing to compare with: the longest remaining pattern is outbound
+			longest := 0
+			for i, p := range pending {
+				if len(p.stops) > len(pending[longest].stops) {
+					longest = i
+				}
+			}
+			pending[longest].direction = DirectionOutbound
+			resolved = append(resolved, pending[longest])
+			pending = append(pending[:longest], pending[longest+1:]...)
+			continue
+		}
+
+		var remaining []*patternStops
+		for _, p := range pending {
+			for _, reference := range resolved {
+				if same, ok := sameDirection(p.stops, reference.stops); ok {
+					p.direction = reference.direction
+					if !same {
+						p.direction = oppositeDirection(reference.direction)
+					}
+					break
+				}
+			}
+			if p.direction != "" {
+				resolved = append(resolved, p)
+			} else {
+				remaining = append(remaining, p)
+			}
+		}
+		if len(remaining) == len(pending) {
+			// No pattern relates to the resolved ones; start a new group
+			resolved = nil
+		}
+		pending = remaining
+	}
+
+	directions := make(map[string]string, len(patterns))
+	for _, p := range patterns {
+		directions[p.id] = p.direction
+	}
+	return directions
+}
+
+// linePatterns collects the journey patterns run on a line, sorted by ID so
+// that the heuristic does not depend on load order
+func (r *DirectionResolver) linePatterns(lineID string, jp *model.JourneyPattern) []*patternStops {
+	byID := map[string]*model.JourneyPattern{jp.ID: jp}
+	for _, pattern := range r.patternsOfLine(lineID) {
+		if byID[pattern.ID] == nil {
+			byID[pattern.ID] = pattern
+		}
+	}
+
+	patterns := make([]*patternStops, 0, len(byID))
+	for id, pattern := range byID {
+		patterns = append(patterns, &patternStops{
+			id:        id,
+			stops:     r.stopKeys(pattern),
+			direction: r.explicitDirection(pattern),
+		})
+	}
+	sort.Slice(patterns, func(i, j int) bool { return patterns[i].id < patterns[j].id })
+	return patterns
+}
+
+// patternsOfLine returns the journey patterns run on a line, indexing the
+// patterns of all lines in one pass over the service journeys
+func (r *DirectionResolver) patternsOfLine(lineID string) []*model.JourneyPattern {
+	if r.linePatternIndex == nil {
+		r.linePatternIndex = make(map[string][]*model.JourneyPattern)
+		indexed := make(map[string]map[string]bool)
+		for _, sj := range r.netexRepository.GetServiceJourneys() {
+			if sj == nil {
+				continue
+			}
+			pattern := r.netexRepository.GetJourneyPatternById(sj.JourneyPatternRef.Ref)
+			if pattern == nil {
+				continue
+			}
+			line := r.journeyLine(sj, pattern)
+			if indexed[line] == nil {
+				indexed[line] = make(map[string]bool)
+			}
+			if !indexed[line][pattern.ID] {
+				indexed[line][pattern.ID] = true
+				r.linePatternIndex[line] = append(r.linePatternIndex[line], pattern)
+			}
+		}
+	}
+	return r.linePatternIndex[lineID]
+}
+
+// stopKeys identifies the stops of a pattern by stop place where known, so
+// that opposite platforms of the same stop compare equal
+func (r *DirectionResolver) stopKeys(jp *model.JourneyPattern) []string {
+	if jp.PointsInSequence == nil {
+		return nil
+	}
+	var keys []string
+	for _, point := range jp.PointsInSequence.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern {
+		stopPoint, ok := point.(*model.StopPointInJourneyPattern)
+		if !ok || stopPoint.ScheduledStopPointRef == "" {
+			continue
+		}
+		key := stopPoint.ScheduledStopPointRef
+		if ssp := r.netexRepository.GetScheduledStopPointById(key); ssp != nil {
+			switch {
+			case ssp.StopPlaceRef != "":
+				key = ssp.StopPlaceRef
+			case ssp.QuayRef != "":
+				key = ssp.QuayRef
+				if stopPlace := r.netexRepository.GetStopPlaceByQuayId(ssp.QuayRef); stopPlace != nil {
+					key = stopPlace.ID
+				}
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// sameDirection reports whether two stop sequences run the same way. Shared
+// terminals decide first; otherwise the order in which shared stops are
+// visited is compared. ok is false when the sequences cannot be related.
+func sameDirection(stops, reference []string) (same bool, ok bool) {
+	if len(stops) < 2 || len(reference) < 2 {
+		return false, false
+	}
+	first, last := stops[0], stops[len(stops)-1]
+	refFirst, refLast := reference[0], reference[len(reference)-1]
+	if first != last && refFirst != refLast {
+		switch {
+		case first == refFirst || last == refLast:
+			return true, true
+		case first == refLast || last == refFirst:
+			return false, true
+		}
+	}
+
+	positions := make(map[string]int, len(reference))
+	for i, stop := range reference {
+		if _, seen := positions[stop]; !seen {
+			positions[stop] = i
+		}
+	}
+	forward, backward := 0, 0
+	previous := -1
+	for _, stop := range stops {
+		position, shared := positions[stop]
+		if !shared {
+			continue
+		}
+		if previous >= 0 {
+			if position > previous {
+				forward++
+			} else if position < previous {
+				backward++
+			}
+		}
+		previous = position
+	}
+	if forward == backward {
+		return false, false
+	}
+	return forward > backward, true
+}
+
+func oppositeDirection(direction string) string {
+	if direction == DirectionInbound {
+		return DirectionOutbound
+	}
+	return DirectionInbound
+}
@@ -244,7 +244,12 @@ func TestStopTimeProducerEdgeCases(t *testing.T) {
 // TestServiceCalendarProducerEdgeCases tests service calendar edge cases
 func TestServiceCalendarProducerEdgeCases(t *testing.T) {
 	gtfsRepo := &mockGtfsRepository{}
-	producer := NewDefaultServiceCalendarProducer(gtfsRepo)
+	netexRepo := newMockCalendarNetexRepository()
+	netexRepo.operatingPeriods["2024"] = &model.OperatingPeriod{ID: "2024", FromDate: "2024-01-01T00:00:00", ToDate: "2024-12-31T00:00:00"}
+	for _, id := range []string{"weekdays", "weekend", "default"} {
+		netexRepo.addAssignment(&model.DayTypeAssignment{ID: "dta-" + id, DayTypeRef: id, OperatingPeriodRef: "2024", IsAvailable: true})
+	}
+	producer := NewDefaultServiceCalendarProducer(netexRepo, gtfsRepo)
 
 	t.Run("Service calendar with no day types", func(t *testing.T) {
 		serviceID := "test-service"
@@ -270,6 +275,7 @@ func TestServiceCalendarProducerEdgeCases(t *testing.T) {
 				},
 			},
 		}
+		netexRepo.dayTypes[dayType.ID] = dayType
 		dayTypes := []*model.DayType{dayType}
 
 		calendar, err := producer.Produce(serviceID, dayTypes)
@@ -306,6 +312,7 @@ func TestServiceCalendarProducerEdgeCases(t *testing.T) {
 				},
 			},
 		}
+		netexRepo.dayTypes[dayType.ID] = dayType
 		dayTypes := []*model.DayType{dayType}
 
 		calendar, err := producer.Produce(serviceID, dayTypes)
@@ -338,6 +345,7 @@ func TestServiceCalendarProducerEdgeCases(t *testing.T) {
 				},
 			},
 		}
+		netexRepo.dayTypes[dayType.ID] = dayType
 		dayTypes := []*model.DayType{dayType}
 
 		calendar, err := producer.Produce(serviceID, dayTypes)
@@ -359,7 +367,7 @@ func TestServiceCalendarProducerEdgeCases(t *testing.T) {
 // TestTransferProducerEdgeCases tests transfer/interchange edge cases
 func TestTransferProducerEdgeCases(t *testing.T) {
 	netexRepo := &mockNetexRepository{}
-	gtfsRepo := &mockGtfsRepository{}
+	gtfsRepo := newMockTransferGtfsRepository([]string{"stop1", "stop2"}, []string{"trip1", "trip2"})
 	producer := NewDefaultTransferProducer(netexRepo, gtfsRepo)
 
 	t.Run("Transfer with stay seated", func(t *testing.T) {
@@ -381,9 +389,9 @@ func TestTransferProducerEdgeCases(t *testing.T) {
 			t.Fatal("Expected non-nil transfer")
 		}
 
-		// Stay seated should result in recommended transfer (type 0)
-		if transfer.TransferType != 0 {
-			t.Errorf("Expected TransferType 0 for stay seated, got %d", transfer.TransferType)
+		// Stay seated should result in an in-seat transfer (type 4)
+		if transfer.TransferType != 4 {
+			t.Errorf("Expected TransferType 4 for stay seated, got %d", transfer.TransferType)
 		}
 	})
 
@@ -3,7 +3,9 @@ package producer
 import (
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/geometry"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
 )
 
@@ -321,21 +323,11 @@ func (p *EuropeanFlexibleServiceProducer) ProduceBookingRules(flexibleService *m
 
 	// Create GTFS booking rule
 	bookingRule := &model.BookingRule{
-		BookingRuleID:          flexibleService.ID + "_booking",
-		BookingType:            p.mapBookingType(flexibleService.FlexibleServiceType),
-		PriorNoticeDurationMin: p.convertDurationToMinutes(arrangements.MinimumBookingPeriod),
-		PriorNoticeDurationMax: p.convertDurationToMinutes(arrangements.LatestBookingTime),
-		PriorNoticeLastDay:     0,  // Default
-		PriorNoticeLastTime:    "", // Not specified
-		PriorNoticeStartDay:    0,  // Default
-		PriorNoticeStartTime:   "", // Not specified
-		PriorNoticeServiceID:   "", // Not applicable
-		Message:                arrangements.BookingNote,
-		PickupMessage:          "",
-		DropOffMessage:         "",
-		PhoneNumber:            "",
-		InfoURL:                "",
+		BookingRuleID: flexibleService.ID + "_booking",
+		Message:       arrangements.BookingNote,
+		BookingURL:    arrangements.BookingUrl,
 	}
+	p.setPriorNotice(bookingRule, arrangements)
 
 	// Add contact information
 	if arrangements.BookingContact != nil {
@@ -354,53 +346,57 @@ func (p *EuropeanFlexibleServiceProducer) ProduceBookingRules(flexibleService *m
 	return bookingRule, nil
 }
 
-// mapBookingType maps NeTEx flexible service type to GTFS booking type
-func (p *EuropeanFlexibleServiceProducer) mapBookingType(flexibleServiceType string) int {
-	switch strings.ToLower(flexibleServiceType) {
-	case "dynamicpassingtimes":
-		return 2 // Phone agency, at least X min before
-	case "fixedheadwayfrequency":
-		return 1 // Real time
-	case "hailandride":
-		return 0 // Real time
-	default:
-		return 2 // Default to advance booking
-	}
-}
-
-// convertDurationToMinutes converts ISO duration to minutes
-func (p *EuropeanFlexibleServiceProducer) convertDurationToMinutes(isoDuration string) int {
-	if isoDuration == "" {
-		return 0
-	}
-
-	// Simple conversion - in practice would use proper ISO 8601 duration parsing
-	// Example: "PT30M" = 30 minutes, "PT2H" = 120 minutes
-	duration := strings.ToUpper(isoDuration)
-
-	if strings.Contains(duration, "PT") && strings.Contains(duration, "M") {
-		// Extract minutes
-		start := strings.Index(duration, "PT") + 2
-		end := strings.Index(duration, "M")
-		if end > start {
-			if minutes, err := strconv.Atoi(duration[start:end]); err == nil {
-				return minutes
+// setPriorNotice sets the booking type and prior notice of a rule from
+// BookWhen: booking until a previous day gives type 2, with the minimum
+// booking period in days and the latest booking time as deadline; booking on
+// the day of travel gives type 1 with the minimum booking period as notice,
+// or type 0 without one. Without BookWhen, a minimum booking period gives
+// type 1 and a latest booking time alone the previous day as deadline.
+func (p *EuropeanFlexibleServiceProducer) setPriorNotice(rule *model.BookingRule, arrangements *model.BookingArrangements) {
+	minutes := p.convertDurationToMinutes(arrangements.MinimumBookingPeriod)
+	latestTime := strings.TrimSpace(arrangements.LatestBookingTime)
+
+	priorDays := func(days int) {
+		rule.BookingType = 2
+		rule.PriorNoticeLastDay = max(days, 1)
+		rule.PriorNoticeLastTime = "23:59:59"
+		if len(latestTime) > 8 {
+			latestTime = latestTime[:8] // drop fractions and time zones
+		}
+		for _, layout := range []string{"15:04:05", "15:04"} {
+			if t, err := time.Parse(layout, latestTime); err == nil {
+				rule.PriorNoticeLastTime = t.Format("15:04:05")
+				break
 			}
 		}
 	}
 
-	if strings.Contains(duration, "PT") && strings.Contains(duration, "H") {
-		// Extract hours and convert to minutes
-		start := strings.Index(duration, "PT") + 2
-		end := strings.Index(duration, "H")
-		if end > start {
-			if hours, err := strconv.Atoi(duration[start:end]); err == nil {
-				return hours * 60
-			}
+	switch strings.ToLower(arrangements.BookWhen) {
+	case "untilpreviousday", "advanceonly":
+		priorDays(minutes / (24 * 60))
+	case "dayoftravelonly", "advanceanddayoftravel", "":
+		switch {
+		case minutes > 0:
+			rule.BookingType = 1
+			rule.PriorNoticeDurationMin = minutes
+		case arrangements.BookWhen == "" && latestTime != "":
+			priorDays(1)
+		default:
+			rule.BookingType = 0
 		}
+	default:
+		rule.BookingType = 0
 	}
+}
 
-	return 0
+// convertDurationToMinutes converts an ISO 8601 duration to minutes, rounding
+// up; invalid durations give 0
+func (p *EuropeanFlexibleServiceProducer) convertDurationToMinutes(isoDuration string) int {
+	seconds, ok := parseISODurationSeconds(isoDuration)
+	if !ok {
+		return 0
+	}
+	return (seconds + 59) / 60
 }
 
 // ProduceLocationGroups creates GTFS location groups from flexible areas
@@ -424,6 +420,92 @@ func (p *EuropeanFlexibleServiceProducer) ProduceLocationGroups(flexibleService
 	return locationGroups, nil
 }
 
+// ProduceLocation creates the GTFS-Flex zone of a flexible stop place from
+// the polygons of its areas, or nil when it has none
+func (p *EuropeanFlexibleServiceProducer) ProduceLocation(stopPlace *model.FlexibleStopPlace) *model.FlexLocation {
+	polygons := flexibleStopPlacePolygons(stopPlace)
+	if len(polygons) == 0 {
+		return nil
+	}
+
+	location := &model.FlexLocation{
+		LocationID: stopPlace.ID,
+		StopName:   stopPlace.Name,
+		StopDesc:   stopPlace.Description,
+		Polygons:   polygons,
+	}
+	if location.StopName == "" && len(stopPlace.Areas) == 1 {
+		location.StopName = stopPlace.Areas[0].Name
+	}
+	return location
+}
+
+// ProduceLocationGroup creates a GTFS location group of the member stops of
+// a flexible stop place's areas. Members resolve like scheduled stop points
+// of stop times, and only stops already produced are included.
+func (p *EuropeanFlexibleServiceProducer) ProduceLocationGroup(stopPlace *model.FlexibleStopPlace) (*model.LocationGroup, []*model.LocationGroupStop) {
+	if stopPlace == nil {
+		return nil, nil
+	}
+
+	var groupStops []*model.LocationGroupStop
+	seen := make(map[string]bool)
+	add := func(stopID string) {
+		if stopID == "" || seen[stopID] || p.gtfsRepo.GetStopById(stopID) == nil {
+			return
+		}
+		seen[stopID] = true
+		groupStops = append(groupStops, &model.LocationGroupStop{LocationGroupID: stopPlace.ID, StopID: stopID})
+	}
+	for _, area := range stopPlace.Areas {
+		if area.Members == nil {
+			continue
+		}
+		for _, quayRef := range area.Members.QuayRefs() {
+			add(quayRef)
+		}
+		for _, stopPlaceRef := range area.Members.StopPlaceRefs() {
+			add(stopPlaceRef)
+		}
+		for _, sspRef := range area.Members.ScheduledStopPointRefs() {
+			add(scheduledStopPointStopID(p.netexRepo, sspRef))
+		}
+	}
+	if len(groupStops) == 0 {
+		return nil, nil
+	}
+
+	return &model.LocationGroup{
+		LocationGroupID:   stopPlace.ID,
+		LocationGroupName: stopPlace.Name,
+	}, groupStops
+}
+
+// flexibleStopPlacePolygons returns the polygons of the areas of a flexible
+// stop place as GeoJSON [lon, lat] rings
+func flexibleStopPlacePolygons(stopPlace *model.FlexibleStopPlace) [][][][2]float64 {
+	if stopPlace == nil {
+		return nil
+	}
+
+	var polygons [][][][2]float64
+	for _, area := range stopPlace.Areas {
+		rings := geometry.PolygonRings(area.Polygon)
+		if rings == nil {
+			continue
+		}
+		polygon := make([][][2]float64, len(rings))
+		for i, ring := range rings {
+			polygon[i] = make([][2]float64, len(ring))
+			for j, point := range ring {
+				polygon[i][j] = [2]float64{point.Lon, point.Lat}
+			}
+		}
+		polygons = append(polygons, polygon)
+	}
+	return polygons
+}
+
 // ProduceStopAreas creates GTFS stop areas from flexible quays
 func (p *EuropeanFlexibleServiceProducer) ProduceStopAreas(flexibleService *model.FlexibleService) ([]*model.StopArea, error) {
 	if flexibleService == nil || flexibleService.FlexibleArea == nil {
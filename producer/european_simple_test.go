@@ -245,7 +245,9 @@ func TestEuropeanFlexibleServiceProducer_ConvertDuration(t *testing.T) {
 		{"", 0},
 		{"PT30M", 30},
 		{"PT2H", 120},
-		{"PT1H30M", 60}, // Simple implementation takes first match (1H)
+		{"PT1H30M", 90},
+		{"P1D", 1440},
+		{"PT90S", 2},
 		{"invalid", 0},
 	}
 
@@ -0,0 +1,614 @@
+package producer
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+)
+
+// DefaultFareProducer converts the fare zones, tariffs, preassigned fare
+// products, sales offer packages and fare tables of a NeTEx dataset to GTFS
+// Fares v2.
+//
+// Zones become areas, tariffs with lines become networks, sales offer
+// packages become fare media and each priced product becomes a fare product
+// with leg rules for the zones of its fare structure elements; distance
+// matrix prices become one fare product per zone pair. Products share their
+// ID as leg group so that transferability and usage validity limits become
+// transfer rules between legs of the same product.
+type DefaultFareProducer struct {
+	netexRepository NetexRepository
+}
+
+// NewDefaultFareProducer creates a new fare producer
+func NewDefaultFareProducer(netexRepository NetexRepository) *DefaultFareProducer {
+	return &DefaultFareProducer{netexRepository: netexRepository}
+}
+
+// fareElement is a fare structure element with its tariff
+type fareElement struct {
+	tariff  *model.Tariff
+	element *model.FareStructureElement
+}
+
+// matrixElement is a distance matrix element with its fare structure element
+type matrixElement struct {
+	fareElement
+	matrix *model.DistanceMatrixElement
+}
+
+// fareKey identifies a price: a product, optionally between the zones of a
+// distance matrix element and on the media of a sales offer package
+type fareKey struct {
+	product           string
+	matrix            string
+	salesOfferPackage string
+}
+
+// fareConversion holds the indexes of one Produce call
+type fareConversion struct {
+	netexRepository NetexRepository
+	fares           *Fares
+
+	areas             map[string]bool
+	fareElements      map[string]fareElement
+	matrixElements    map[string]matrixElement
+	networkByTariff   map[string]string
+	products          map[string]*model.PreassignedFareProduct
+	packages          map[string]*model.SalesOfferPackage
+	packagesByProduct map[string][]string
+	prices            map[fareKey]*model.FareProduct
+}
+
+// Produce converts the fare elements of the repository
+func (p *DefaultFareProducer) Produce() *Fares {
+	c := &fareConversion{
+		netexRepository:   p.netexRepository,
+		fares:             &Fares{},
+		areas:             make(map[string]bool),
+		fareElements:      make(map[string]fareElement),
+		matrixElements:    make(map[string]matrixElement),
+		networkByTariff:   make(map[string]string),
+		products:          make(map[string]*model.PreassignedFareProduct),
+		packages:          make(map[string]*model.SalesOfferPackage),
+		packagesByProduct: make(map[string][]string),
+		prices:            make(map[fareKey]*model.FareProduct),
+	}
+
+	c.convertZones()
+	c.convertTariffs()
+	c.indexProducts()
+	c.collectPrices()
+	c.convertProducts()
+	return c.fares
+}
+
+// issue records a fare element GTFS Fares v2 cannot express
+func (c *fareConversion) issue(entityType, entityID, format string, args ...interface{}) {
+	c.fares.Issues = append(c.fares.Issues, FareIssue{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Message:    fmt.Sprintf(format, args...),
+	})
+}
+
+// convertZones produces an area per tariff and fare zone, and stop areas for
+// the scheduled stop points of fare zones and the quays in tariff zones
+func (c *fareConversion) convertZones() {
+	names := make(map[string]string)
+	for _, zone := range c.netexRepository.GetAllTariffZones() {
+		names[zone.ID] = zone.Name
+	}
+	fareZones := c.netexRepository.GetAllFareZones()
+	sort.Slice(fareZones, func(i, j int) bool { return fareZones[i].ID < fareZones[j].ID })
+	for _, zone := range fareZones {
+		if zone.Name != "" || names[zone.ID] == "" {
+			names[zone.ID] = zone.Name
+		}
+	}
+
+	ids := make([]string, 0, len(names))
+	for id := range names {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c.areas[id] = true
+		c.fares.Areas = append(c.fares.Areas, &model.Area{AreaID: id, AreaName: names[id]})
+	}
+
+	seen := make(map[model.AreaStop]bool)
+	for _, zone := range fareZones {
+		for _, sspRef := range zone.ScheduledStopPointRefs() {
+			stopID := scheduledStopPointStopID(c.netexRepository, sspRef)
+			if stopID == "" {
+				continue
+			}
+			areaStop := model.AreaStop{AreaID: zone.ID, StopID: stopID}
+			if !seen[areaStop] {
+				seen[areaStop] = true
+				c.fares.AreaStops = append(c.fares.AreaStops, &areaStop)
+			}
+		}
+	}
+
+	// Quays without zones of their own are in the zones of their stop place
+	quays := c.netexRepository.GetAllQuays()
+	sort.Slice(quays, func(i, j int) bool { return quays[i].ID < quays[j].ID })
+	for _, quay := range quays {
+		zones := quay.TariffZoneRefs()
+		if len(zones) == 0 {
+			if stopPlace := c.netexRepository.GetStopPlaceByQuayId(quay.ID); stopPlace != nil {
+				zones = stopPlace.TariffZoneRefs()
+			}
+		}
+		for _, zone := range zones {
+			areaStop := model.AreaStop{AreaID: zone, StopID: quay.ID}
+			if c.areas[zone] && !seen[areaStop] {
+				seen[areaStop] = true
+				c.fares.AreaStops = append(c.fares.AreaStops, &areaStop)
+			}
+		}
+	}
+}
+
+// convertTariffs indexes the fare structure of the tariffs and produces a
+// network per tariff with lines
+func (c *fareConversion) convertTariffs() {
+	tariffs := c.netexRepository.GetAllTariffs()
+	sort.Slice(tariffs, func(i, j int) bool { return tariffs[i].ID < tariffs[j].ID })
+
+	networkByLine := make(map[string]string)
+	for _, tariff := range tariffs {
+		for i := range tariff.FareStructureElements {
+			element := fareElement{tariff: tariff, element: &tariff.FareStructureElements[i]}
+			c.fareElements[element.element.ID] = element
+			for j := range element.element.DistanceMatrixElements {
+				matrix := &element.element.DistanceMatrixElements[j]
+				c.matrixElements[matrix.ID] = matrixElement{fareElement: element, matrix: matrix}
+			}
+		}
+
+		lines := tariff.LineRefs()
+		if len(lines) == 0 {
+			continue
+		}
+		c.networkByTariff[tariff.ID] = tariff.ID
+		c.fares.Networks = append(c.fares.Networks, &model.GtfsNetwork{NetworkID: tariff.ID, NetworkName: tariff.Name})
+		for _, lineID := range lines {
+			if network, exists := networkByLine[lineID]; exists {
+				if network != tariff.ID {
+					c.issue("Tariff", tariff.ID, "Line %s also belongs to tariff %s; a GTFS route belongs to one network, so it stays in %s",
+						lineID, network, network)
+				}
+				continue
+			}
+			networkByLine[lineID] = tariff.ID
+			c.fares.RouteNetworks = append(c.fares.RouteNetworks, &model.RouteNetwork{NetworkID: tariff.ID, RouteID: lineID})
+		}
+	}
+}
+
+// indexProducts indexes the products and the packages selling them
+func (c *fareConversion) indexProducts() {
+	for _, product := range c.netexRepository.GetAllPreassignedFareProducts() {
+		c.products[product.ID] = product
+	}
+	for _, salesOfferPackage := range c.netexRepository.GetAllSalesOfferPackages() {
+		c.packages[salesOfferPackage.ID] = salesOfferPackage
+		for _, productID := range salesOfferPackage.ProductRefs() {
+			c.packagesByProduct[productID] = appendUnique(c.packagesByProduct[productID], salesOfferPackage.ID)
+		}
+	}
+}
+
+// collectPrices gathers the prices of products, packages, distance matrix
+// elements and fare tables by the product, zone pair and package they are for
+func (c *fareConversion) collectPrices() {
+	products := c.netexRepository.GetAllPreassignedFareProducts()
+	sort.Slice(products, func(i, j int) bool { return products[i].ID < products[j].ID })
+	for _, product := range products {
+		for _, price := range product.Prices {
+			if price.PreassignedFareProductRef == "" {
+				price.PreassignedFareProductRef = product.ID
+			}
+			c.addPrice("PreassignedFareProduct", product.ID, price)
+		}
+	}
+
+	packages := c.netexRepository.GetAllSalesOfferPackages()
+	sort.Slice(packages, func(i, j int) bool { return packages[i].ID < packages[j].ID })
+	for _, salesOfferPackage := range packages {
+		for _, price := range salesOfferPackage.Prices {
+			if price.SalesOfferPackageRef == "" {
+				price.SalesOfferPackageRef = salesOfferPackage.ID
+			}
+			c.addPrice("SalesOfferPackage", salesOfferPackage.ID, price)
+		}
+	}
+
+	matrixIDs := make([]string, 0, len(c.matrixElements))
+	for id := range c.matrixElements {
+		matrixIDs = append(matrixIDs, id)
+	}
+	sort.Strings(matrixIDs)
+	for _, id := range matrixIDs {
+		for _, price := range c.matrixElements[id].matrix.Prices {
+			if price.DistanceMatrixElementRef == "" {
+				price.DistanceMatrixElementRef = id
+			}
+			c.addPrice("DistanceMatrixElement", id, price)
+		}
+	}
+
+	tables := c.netexRepository.GetAllFareTables()
+	sort.Slice(tables, func(i, j int) bool { return tables[i].ID < tables[j].ID })
+	for _, table := range tables {
+		if len(table.Cells) > 0 {
+			c.issue("FareTable", table.ID, "Prices arranged in %d cells are not supported and were not converted", len(table.Cells))
+		}
+		for _, price := range table.Prices() {
+			c.addPrice("FareTable", table.ID, price)
+		}
+	}
+}
+
+// addPrice records a price under the single product it applies to
+func (c *fareConversion) addPrice(ownerType, ownerID string, price model.FarePrice) {
+	priceID := price.ID
+	if priceID == "" {
+		priceID = ownerID
+	}
+
+	key := fareKey{
+		product:           price.PreassignedFareProductRef,
+		matrix:            price.DistanceMatrixElementRef,
+		salesOfferPackage: price.SalesOfferPackageRef,
+	}
+	if key.product == "" {
+		candidates := c.priceProducts(key)
+		if len(candidates) != 1 {
+			c.issue(ownerType, ownerID, "Price %s applies to %d fare products; GTFS fare products need a price of their own", priceID, len(candidates))
+			return
+		}
+		key.product = candidates[0]
+	}
+	if c.products[key.product] == nil {
+		c.issue(ownerType, ownerID, "Price %s is for unknown fare product %s", priceID, key.product)
+		return
+	}
+	if key.matrix != "" {
+		if _, exists := c.matrixElements[key.matrix]; !exists {
+			c.issue(ownerType, ownerID, "Price %s is for unknown distance matrix element %s", priceID, key.matrix)
+			return
+		}
+	}
+
+	currency := strings.TrimSpace(price.Currency)
+	if currency == "" {
+		currency = c.netexRepository.GetDefaultCurrency()
+	}
+	if currency == "" {
+		c.issue(ownerType, ownerID, "Price %s has no currency and the dataset sets no DefaultCurrency", priceID)
+		return
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(price.Amount), 64)
+	if err != nil {
+		c.issue(ownerType, ownerID, "Price %s has no valid amount: %q", priceID, price.Amount)
+		return
+	}
+
+	fareProduct := &model.FareProduct{Amount: formatFareAmount(amount, currency), Currency: strings.ToUpper(currency)}
+	if existing := c.prices[key]; existing != nil {
+		if *existing != *fareProduct {
+			c.issue(ownerType, ownerID, "Price %s conflicts with an earlier price of %s %s; the earlier price is kept",
+				priceID, existing.Amount, existing.Currency)
+		}
+		return
+	}
+	c.prices[key] = fareProduct
+}
+
+// priceProducts returns the products a price without product reference can
+// be for: the products of its package, else those giving access to its
+// distance matrix element
+func (c *fareConversion) priceProducts(key fareKey) []string {
+	if key.salesOfferPackage != "" {
+		if salesOfferPackage := c.packages[key.salesOfferPackage]; salesOfferPackage != nil {
+			return salesOfferPackage.ProductRefs()
+		}
+		return nil
+	}
+	matrix, exists := c.matrixElements[key.matrix]
+	if !exists {
+		return nil
+	}
+	var products []string
+	for id, product := range c.products {
+		for _, ref := range product.FareStructureElementRefs() {
+			if ref == matrix.element.ID {
+				products = append(products, id)
+				break
+			}
+		}
+	}
+	sort.Strings(products)
+	return products
+}
+
+// convertProducts produces the fare media, fare products, leg rules and
+// transfer rules of the priced products
+func (c *fareConversion) convertProducts() {
+	matricesByProduct := make(map[string][]string)
+	packagesByProduct := make(map[string][]string)
+	for key := range c.prices {
+		matricesByProduct[key.product] = appendUnique(matricesByProduct[key.product], key.matrix)
+		if key.salesOfferPackage != "" {
+			packagesByProduct[key.product] = appendUnique(packagesByProduct[key.product], key.salesOfferPackage)
+		}
+	}
+
+	productIDs := make([]string, 0, len(c.products))
+	for id := range c.products {
+		productIDs = append(productIDs, id)
+	}
+	sort.Strings(productIDs)
+
+	usedPackages := make(map[string]bool)
+	for _, productID := range productIDs {
+		product := c.products[productID]
+		matrices := matricesByProduct[productID]
+		if len(matrices) == 0 {
+			c.issue("PreassignedFareProduct", productID, "Fare product has no price and was not converted")
+			continue
+		}
+		sort.Strings(matrices)
+
+		packages := append([]string(nil), c.packagesByProduct[productID]...)
+		for _, id := range packagesByProduct[productID] {
+			packages = appendUnique(packages, id)
+		}
+		sort.Strings(packages)
+
+		for _, matrix := range matrices {
+			fareProductID, fareProductName := productID, product.Name
+			if matrix != "" {
+				fareProductID = productID + "_" + matrix
+				if name := c.matrixElements[matrix].matrix.Name; name != "" {
+					fareProductName = strings.TrimSpace(product.Name + " " + name)
+				}
+			}
+
+			produced := false
+			for _, packageID := range packages {
+				price := c.prices[fareKey{productID, matrix, packageID}]
+				if price == nil {
+					price = c.prices[fareKey{productID, matrix, ""}]
+				}
+				if price == nil {
+					continue
+				}
+				usedPackages[packageID] = true
+				c.fares.FareProducts = append(c.fares.FareProducts, &model.FareProduct{
+					FareProductID: fareProductID, FareProductName: fareProductName, FareMediaID: packageID,
+					Amount: price.Amount, Currency: price.Currency,
+				})
+				produced = true
+			}
+			if price := c.prices[fareKey{productID, matrix, ""}]; price != nil && len(packages) == 0 {
+				c.fares.FareProducts = append(c.fares.FareProducts, &model.FareProduct{
+					FareProductID: fareProductID, FareProductName: fareProductName,
+					Amount: price.Amount, Currency: price.Currency,
+				})
+				produced = true
+			}
+			if produced {
+				c.legRules(product, fareProductID, matrix)
+			}
+		}
+		c.transferRule(product)
+	}
+
+	c.fareMedia(usedPackages)
+}
+
+// legRules produces the leg rules of a fare product: between the zones of its
+// distance matrix element, or within and between the zones of the product's
+// fare structure elements
+func (c *fareConversion) legRules(product *model.PreassignedFareProduct, fareProductID, matrix string) {
+	seen := make(map[model.FareLegRule]bool)
+	add := func(networkID, fromAreaID, toAreaID string) {
+		rule := model.FareLegRule{LegGroupID: product.ID, NetworkID: networkID, FromAreaID: fromAreaID, ToAreaID: toAreaID, FareProductID: fareProductID}
+		if !seen[rule] {
+			seen[rule] = true
+			c.fares.FareLegRules = append(c.fares.FareLegRules, &rule)
+		}
+	}
+
+	if matrix != "" {
+		element := c.matrixElements[matrix]
+		from, to := element.matrix.StartTariffZoneRef, element.matrix.EndTariffZoneRef
+		if !c.areas[from] || !c.areas[to] {
+			c.issue("DistanceMatrixElement", matrix, "Zones %q and %q are not both known tariff zones; fare product %s has no leg rule",
+				from, to, fareProductID)
+			return
+		}
+		add(c.networkByTariff[element.tariff.ID], from, to)
+		return
+	}
+
+	refs := product.FareStructureElementRefs()
+	if len(refs) == 0 {
+		add("", "", "")
+		return
+	}
+	for _, ref := range refs {
+		element, exists := c.fareElements[ref]
+		if !exists {
+			c.issue("PreassignedFareProduct", product.ID, "Fare structure element %s was not found", ref)
+			continue
+		}
+		networkID := c.networkByTariff[element.tariff.ID]
+
+		var zones []string
+		if assignment := element.element.GenericParameterAssignment; assignment != nil && assignment.ValidityParameters != nil {
+			for _, zone := range assignment.ValidityParameters.ZoneRefs() {
+				if !c.areas[zone] {
+					c.issue("FareStructureElement", ref, "Zone %s is not a known tariff zone", zone)
+					continue
+				}
+				zones = appendUnique(zones, zone)
+			}
+		}
+		if len(zones) == 0 {
+			add(networkID, "", "")
+			continue
+		}
+		for _, from := range zones {
+			for _, to := range zones {
+				add(networkID, from, to)
+			}
+		}
+	}
+}
+
+// transferRule produces the transfer rule between legs of a product from
+// the transferability or the usage validity period of its fare structure
+func (c *fareConversion) transferRule(product *model.PreassignedFareProduct) {
+	var transferability *model.Transferability
+	var validity *model.UsageValidityPeriod
+	for _, ref := range product.FareStructureElementRefs() {
+		element, exists := c.fareElements[ref]
+		if !exists || element.element.GenericParameterAssignment == nil {
+			continue
+		}
+		limitations := element.element.GenericParameterAssignment.Limitations
+		if limitations == nil {
+			continue
+		}
+		if profiles := limitations.UserProfileRefs(); len(profiles) > 0 {
+			c.issue("FareStructureElement", ref, "Limited to user profiles %s; rider categories are not converted, so the fare applies to every rider",
+				strings.Join(profiles, ", "))
+		}
+		if transferability == nil {
+			transferability = limitations.Transferability
+		}
+		if validity == nil {
+			validity = limitations.UsageValidityPeriod
+		}
+	}
+	if transferability == nil && validity == nil {
+		return
+	}
+	if transferability != nil && strings.EqualFold(strings.TrimSpace(transferability.CanTransfer), "false") {
+		return
+	}
+
+	rule := &model.FareTransferRule{FromLegGroupID: product.ID, ToLegGroupID: product.ID, TransferCount: "-1"}
+	duration := ""
+	if transferability != nil {
+		if count, err := strconv.Atoi(strings.TrimSpace(transferability.MaximumNumberOfTransfers)); err == nil && count >= 0 {
+			rule.TransferCount = strconv.Itoa(count)
+		}
+		duration = transferability.TransferDuration
+	}
+	if duration == "" && validity != nil {
+		duration = validity.StandardDuration
+	}
+	if duration != "" {
+		seconds, ok := parseISODurationSeconds(duration)
+		if !ok {
+			c.issue("PreassignedFareProduct", product.ID, "Transfer duration %q is not an ISO 8601 duration", duration)
+		} else {
+			rule.DurationLimit = strconv.Itoa(seconds)
+			// From the departure of the first leg to the departure of the next
+			rule.DurationLimitType = "1"
+		}
+	}
+	c.fares.FareTransferRules = append(c.fares.FareTransferRules, rule)
+}
+
+// fareMedia produces the media of the packages products are sold in
+func (c *fareConversion) fareMedia(used map[string]bool) {
+	ids := make([]string, 0, len(used))
+	for id := range used {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		media := &model.FareMedia{FareMediaID: id}
+		if salesOfferPackage := c.packages[id]; salesOfferPackage != nil {
+			media.FareMediaName = salesOfferPackage.Name
+			mediaType, known := fareMediaType(salesOfferPackage)
+			if !known {
+				c.issue("SalesOfferPackage", id, "Travel document and distribution channels do not tell the fare media; written as fare_media_type 0")
+			}
+			media.FareMediaType = mediaType
+		} else {
+			c.issue("SalesOfferPackage", id, "Sales offer package was not found; written as fare_media_type 0")
+		}
+		c.fares.FareMedia = append(c.fares.FareMedia, media)
+	}
+}
+
+// fareMediaType derives the GTFS fare media type of a package from its types
+// of travel document and distribution channels
+func fareMediaType(salesOfferPackage *model.SalesOfferPackage) (int, bool) {
+	var hints []string
+	for _, element := range salesOfferPackage.Elements {
+		hints = append(hints, element.TypeOfTravelDocumentRef)
+	}
+	for _, assignment := range salesOfferPackage.DistributionAssignments {
+		hints = append(hints, assignment.DistributionChannelType)
+	}
+	text := strings.ToLower(strings.Join(hints, " "))
+
+	containsAny := func(words ...string) bool {
+		for _, word := range words {
+			if strings.Contains(text, word) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case containsAny("mobile", "app"):
+		return 4, true
+	case containsAny("emv", "contactless", "bankcard", "creditcard", "debitcard"):
+		return 3, true
+	case containsAny("card"):
+		return 2, true
+	case containsAny("paper", "print"):
+		return 1, true
+	}
+	return 0, false
+}
+
+// currencyDecimals are the decimal places of currencies without two
+var currencyDecimals = map[string]int{
+	"BHD": 3, "CLP": 0, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "OMR": 3, "TND": 3, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+// formatFareAmount writes an amount with the decimal places of its currency
+func formatFareAmount(amount float64, currency string) string {
+	decimals, exists := currencyDecimals[strings.ToUpper(currency)]
+	if !exists {
+		decimals = 2
+	}
+	return strconv.FormatFloat(amount, 'f', decimals, 64)
+}
+
+// appendUnique appends value to values unless it is already there
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
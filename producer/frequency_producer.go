@@ -10,15 +10,17 @@ import (
 
 // DefaultFrequencyProducer implements frequency conversion
 type DefaultFrequencyProducer struct {
-	netexRepo NetexRepository
-	gtfsRepo  GtfsRepository
+	netexRepo         NetexRepository
+	gtfsRepo          GtfsRepository
+	directionResolver *DirectionResolver
 }
 
 // NewDefaultFrequencyProducer creates a new frequency producer
 func NewDefaultFrequencyProducer(netexRepo NetexRepository, gtfsRepo GtfsRepository) *DefaultFrequencyProducer {
 	return &DefaultFrequencyProducer{
-		netexRepo: netexRepo,
-		gtfsRepo:  gtfsRepo,
+		netexRepo:         netexRepo,
+		gtfsRepo:          gtfsRepo,
+		directionResolver: NewDirectionResolver(netexRepo),
 	}
 }
 
@@ -131,9 +133,13 @@ func (p *DefaultFrequencyProducer) ProduceFrequencyTrip(group *model.HeadwayJour
 		ServiceID:    serviceID,
 		TripID:       tripID,
 		TripHeadsign: group.Name, // Use group name as headsign
-		// DirectionID will be determined from journey pattern
 		// ShapeID will be determined from journey pattern
 	}
+	if group.JourneyPatternRef != "" {
+		if jp := p.netexRepo.GetJourneyPatternById(group.JourneyPatternRef); jp != nil {
+			trip.DirectionID = p.directionResolver.DirectionID(route.RouteID, jp, nil)
+		}
+	}
 
 	return trip, nil
 }
@@ -279,13 +285,17 @@ func (p *DefaultFrequencyProducer) formatTimeForGTFS(seconds int) string {
 
 // determineServiceID determines the service ID from day types
 func (p *DefaultFrequencyProducer) determineServiceID(dayTypes *model.DayTypeRefs) (string, error) {
-	if dayTypes == nil || len(dayTypes.DayTypeRef) == 0 {
-		return "default_service", nil
+	var refs []string
+	if dayTypes != nil {
+		refs = dayTypes.DayTypeRef
 	}
 
-	// Use the first day type reference as the service ID
-	// In practice, this might need more sophisticated logic
-	return dayTypes.DayTypeRef[0], nil
+	// Same service ID as the trips of the journeys running on these day types
+	serviceID := ServiceIDForDayTypes(refs)
+	if serviceID == "" {
+		return "", fmt.Errorf("no day types to derive a service from")
+	}
+	return serviceID, nil
 }
 
 // resolveStopID resolves stop ID from stop point in journey pattern
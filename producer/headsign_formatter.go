@@ -0,0 +1,78 @@
+package producer
+
+import (
+	"strings"
+
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+)
+
+// DefaultViaFormat renders a destination with its vias, e.g. "Oslo S via Lysaker"
+const DefaultViaFormat = "{destination} via {vias}"
+
+// HeadsignFormatter renders NeTEx DestinationDisplays as GTFS headsigns
+type HeadsignFormatter struct {
+	netexRepository NetexRepository
+	viaFormat       string
+	viaSeparator    string
+}
+
+// NewHeadsignFormatter creates a formatter using DefaultViaFormat
+func NewHeadsignFormatter(netexRepository NetexRepository) *HeadsignFormatter {
+	return &HeadsignFormatter{
+		netexRepository: netexRepository,
+		viaFormat:       DefaultViaFormat,
+		viaSeparator:    ", ",
+	}
+}
+
+// SetViaFormat sets the template used when a display has vias. The
+// {destination} and {vias} placeholders are replaced; an empty format leaves
+// vias out of headsigns.
+func (f *HeadsignFormatter) SetViaFormat(format string) {
+	f.viaFormat = format
+}
+
+// SetViaSeparator sets the separator placed between several vias
+func (f *HeadsignFormatter) SetViaSeparator(separator string) {
+	f.viaSeparator = separator
+}
+
+// Format returns the headsign of a destination display, or "" when it has no text
+func (f *HeadsignFormatter) Format(display *model.DestinationDisplay) string {
+	if display == nil {
+		return ""
+	}
+	destination := firstNonEmpty(strings.TrimSpace(display.FrontText), strings.TrimSpace(display.SideText))
+	if destination == "" || f.viaFormat == "" {
+		return destination
+	}
+
+	vias := f.viaNames(display)
+	if len(vias) == 0 {
+		return destination
+	}
+	return strings.NewReplacer(
+		"{destination}", destination,
+		"{vias}", strings.Join(vias, f.viaSeparator),
+	).Replace(f.viaFormat)
+}
+
+// viaNames resolves the text of each via, skipping vias without any
+func (f *HeadsignFormatter) viaNames(display *model.DestinationDisplay) []string {
+	if display.Vias == nil {
+		return nil
+	}
+	names := make([]string, 0, len(display.Vias.Via))
+	for _, via := range display.Vias.Via {
+		name := strings.TrimSpace(via.Name)
+		if via.DestinationDisplayRef != "" && f.netexRepository != nil {
+			if viaDisplay := f.netexRepository.GetDestinationDisplayById(via.DestinationDisplayRef); viaDisplay != nil {
+				name = firstNonEmpty(strings.TrimSpace(viaDisplay.FrontText), strings.TrimSpace(viaDisplay.SideText), name)
+			}
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
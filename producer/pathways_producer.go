@@ -3,6 +3,8 @@ package producer
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
@@ -19,6 +21,10 @@ type DefaultPathwaysProducer struct {
 	defaultTraversalTime int     // seconds
 	defaultStairHeight   float64 // meters per stair
 	maxSlopePercent      float64 // maximum slope percentage
+
+	// heuristicFallback infers levels and pathways of stop places without
+	// NeTEx levels and path links from quay names and locations
+	heuristicFallback bool
 }
 
 // NewDefaultPathwaysProducer creates a new pathways producer
@@ -32,12 +38,72 @@ func NewDefaultPathwaysProducer(netexRepo NetexRepository, gtfsRepo GtfsReposito
 	}
 }
 
-// ProducePathwaysFromStopPlace generates pathways within a stop place
+// SetHeuristicFallback sets whether levels and pathways of stop places
+// without NeTEx levels and path links are inferred from their quays
+func (p *DefaultPathwaysProducer) SetHeuristicFallback(enabled bool) {
+	p.heuristicFallback = enabled
+}
+
+// PathLinkError reports the path links of a stop place that could not be
+// converted to pathways. It is returned along with the pathways of the
+// stop place's other path links.
+type PathLinkError struct {
+	StopPlaceID string
+	// Links holds the error of each skipped path link by link ID
+	Links map[string]error
+}
+
+func (e *PathLinkError) Error() string {
+	problems := make([]string, 0, len(e.Links))
+	for _, linkID := range e.LinkIDs() {
+		problems = append(problems, e.Links[linkID].Error())
+	}
+	return fmt.Sprintf("stop place %s: skipped %d path link(s): %s", e.StopPlaceID, len(e.Links), strings.Join(problems, "; "))
+}
+
+// LinkIDs returns the IDs of the skipped path links, sorted
+func (e *PathLinkError) LinkIDs() []string {
+	linkIDs := make([]string, 0, len(e.Links))
+	for linkID := range e.Links {
+		linkIDs = append(linkIDs, linkID)
+	}
+	sort.Strings(linkIDs)
+	return linkIDs
+}
+
+// ProducePathwaysFromStopPlace converts the path links of a stop place to
+// pathways; a stop place without path links gets pathways inferred between
+// its quays when the heuristic fallback is enabled. Path links that cannot
+// be converted are skipped and reported in a *PathLinkError.
 func (p *DefaultPathwaysProducer) ProducePathwaysFromStopPlace(stopPlace *model.StopPlace) ([]*model.Pathway, error) {
 	if stopPlace == nil {
 		return nil, nil
 	}
 
+	if len(stopPlace.PathLinks) > 0 {
+		pathways := make([]*model.Pathway, 0, len(stopPlace.PathLinks))
+		var linkErr *PathLinkError
+		for i := range stopPlace.PathLinks {
+			link := &stopPlace.PathLinks[i]
+			pathway, err := p.ProducePathwayFromLink(link)
+			if err != nil {
+				if linkErr == nil {
+					linkErr = &PathLinkError{StopPlaceID: stopPlace.ID, Links: make(map[string]error)}
+				}
+				linkErr.Links[link.ID] = err
+				continue
+			}
+			pathways = append(pathways, pathway)
+		}
+		if linkErr != nil {
+			return pathways, linkErr
+		}
+		return pathways, nil
+	}
+	if !p.heuristicFallback {
+		return nil, nil
+	}
+
 	var pathways []*model.Pathway
 
 	// Get all quays in the stop place
@@ -71,12 +137,90 @@ func (p *DefaultPathwaysProducer) ProducePathwaysFromStopPlace(stopPlace *model.
 	return pathways, nil
 }
 
-// ProduceLevelsFromStopPlace generates levels for a multi-level stop place
+// pathwayModes maps NeTEx access feature types to GTFS pathway modes
+var pathwayModes = map[string]int{
+	"stairs":         2,
+	"seriesOfStairs": 2,
+	"travelator":     3,
+	"escalator":      4,
+	"lift":           5,
+	"validator":      6,
+}
+
+// ProducePathwayFromLink converts a NeTEx path link to a pathway between the
+// places at its ends
+func (p *DefaultPathwaysProducer) ProducePathwayFromLink(link *model.SitePathLink) (*model.Pathway, error) {
+	if link == nil {
+		return nil, fmt.Errorf("path link is required")
+	}
+	from, to := link.From.PlaceRef(), link.To.PlaceRef()
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("path link %s needs a From and a To place", link.ID)
+	}
+
+	pathway := &model.Pathway{
+		PathwayID:       link.ID,
+		FromStopID:      from,
+		ToStopID:        to,
+		PathwayMode:     1, // Walkway
+		IsBidirectional: 1,
+		Length:          link.Distance,
+		StairCount:      link.NumberOfSteps,
+		MinWidth:        link.MinimumWidth,
+		SignpostedAs:    link.Label,
+	}
+	if mode, ok := pathwayModes[link.AccessFeatureType]; ok {
+		pathway.PathwayMode = mode
+	} else if link.NumberOfSteps > 0 {
+		pathway.PathwayMode = 2 // Stairs
+	}
+	// stair_count is negative when the steps go down from the From place
+	if link.Transition == "down" {
+		pathway.StairCount = -pathway.StairCount
+	}
+	if link.TransferDuration != nil {
+		if seconds, ok := parseISODurationSeconds(link.TransferDuration.DefaultDuration); ok {
+			pathway.TraversalTime = seconds
+		}
+	}
+
+	switch link.AllowedUse {
+	case "oneWay", "forwards":
+		pathway.IsBidirectional = 0
+	case "backwards":
+		pathway.IsBidirectional = 0
+		pathway.FromStopID, pathway.ToStopID = to, from
+		pathway.StairCount = -pathway.StairCount
+	}
+	return pathway, nil
+}
+
+// ProduceLevelsFromStopPlace converts the levels of a stop place, taking the
+// level index from a numeric PublicCode; a stop place without levels gets
+// levels inferred from its quay names when the heuristic fallback is enabled
 func (p *DefaultPathwaysProducer) ProduceLevelsFromStopPlace(stopPlace *model.StopPlace) ([]*model.Level, error) {
 	if stopPlace == nil {
 		return nil, nil
 	}
 
+	if len(stopPlace.Levels) > 0 {
+		levels := make([]*model.Level, 0, len(stopPlace.Levels))
+		for _, netexLevel := range stopPlace.Levels {
+			level := &model.Level{
+				LevelID:   netexLevel.ID,
+				LevelName: firstNonEmpty(netexLevel.Name, netexLevel.ShortName, netexLevel.PublicCode),
+			}
+			if index, err := strconv.ParseFloat(strings.TrimSpace(netexLevel.PublicCode), 64); err == nil {
+				level.LevelIndex = index
+			}
+			levels = append(levels, level)
+		}
+		return levels, nil
+	}
+	if !p.heuristicFallback {
+		return nil, nil
+	}
+
 	var levels []*model.Level
 	levelMap := make(map[string]*model.Level)
 
@@ -420,9 +564,9 @@ type LevelInfo struct {
 	Name  string
 }
 
+// extractLevelInfo guesses the level of a quay from its name, for the
+// heuristic fallback
 func (p *DefaultPathwaysProducer) extractLevelInfo(quay *model.Quay) *LevelInfo {
-	// Extract level information from quay properties
-	// This is simplified - real implementation would parse NeTEx level references
 
 	// Check quay name for level indicators
 	name := strings.ToLower(quay.Name)
@@ -1,6 +1,7 @@
 package producer
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -9,6 +10,7 @@ import (
 
 func TestDefaultPathwaysProducer_ProducePathwaysFromStopPlace(t *testing.T) {
 	producer := NewDefaultPathwaysProducer(nil, nil)
+	producer.SetHeuristicFallback(true)
 
 	// Create test stop place with multiple quays
 	stopPlace := &model.StopPlace{
@@ -103,6 +105,7 @@ func TestDefaultPathwaysProducer_ProducePathwaysFromStopPlace(t *testing.T) {
 
 func TestDefaultPathwaysProducer_ProduceLevelsFromStopPlace(t *testing.T) {
 	producer := NewDefaultPathwaysProducer(nil, nil)
+	producer.SetHeuristicFallback(true)
 
 	// Create multi-level stop place
 	stopPlace := &model.StopPlace{
@@ -434,3 +437,98 @@ func TestDefaultPathwaysProducer_NilHandling(t *testing.T) {
 		t.Error("Expected nil levels for nil stop place")
 	}
 }
+
+func TestDefaultPathwaysProducer_LevelsAndPathLinks(t *testing.T) {
+	producer := NewDefaultPathwaysProducer(nil, nil)
+
+	stopPlace := &model.StopPlace{}
+	mustUnmarshalXML(t, `<StopPlace id="sp1">
+		<levels>
+			<Level id="lvl0"><Name>Street</Name><PublicCode>0</PublicCode></Level>
+			<Level id="lvlM"><ShortName>Mezzanine</ShortName><PublicCode>M</PublicCode></Level>
+			<Level id="lvl-1"><PublicCode>-1</PublicCode></Level>
+		</levels>
+		<pathLinks>
+			<SitePathLink id="stairs">
+				<From><PlaceRef ref="ent1"/></From><To><PlaceRef ref="quay1"/></To>
+				<Distance>25.5</Distance><AllowedUse>twoWay</AllowedUse><Transition>down</Transition>
+				<NumberOfSteps>20</NumberOfSteps><TransferDuration><DefaultDuration>PT1M30S</DefaultDuration></TransferDuration>
+			</SitePathLink>
+			<SitePathLink id="escalator">
+				<From><PlaceRef ref="quay1"/></From><To><PlaceRef ref="ent1"/></To>
+				<AllowedUse>oneWay</AllowedUse><AccessFeatureType>escalator</AccessFeatureType><Label>Exit</Label>
+			</SitePathLink>
+			<SitePathLink id="lift">
+				<From><PlaceRef ref="quay1"/></From><To><PlaceRef ref="ent1"/></To>
+				<AllowedUse>backwards</AllowedUse><AccessFeatureType>lift</AccessFeatureType>
+			</SitePathLink>
+		</pathLinks>
+		<quays><Quay id="quay1"><Name>Upper platform</Name></Quay><Quay id="quay2"/></quays>
+	</StopPlace>`, stopPlace)
+
+	levels, err := producer.ProduceLevelsFromStopPlace(stopPlace)
+	if err != nil {
+		t.Fatalf("ProduceLevelsFromStopPlace failed: %v", err)
+	}
+	expectedLevels := []model.Level{
+		{LevelID: "lvl0", LevelIndex: 0, LevelName: "Street"},
+		{LevelID: "lvlM", LevelIndex: 0, LevelName: "Mezzanine"},
+		{LevelID: "lvl-1", LevelIndex: -1, LevelName: "-1"},
+	}
+	if len(levels) != len(expectedLevels) {
+		t.Fatalf("Expected %d levels, got %d", len(expectedLevels), len(levels))
+	}
+	for i, expected := range expectedLevels {
+		if *levels[i] != expected {
+			t.Errorf("Expected level %+v, got %+v", expected, *levels[i])
+		}
+	}
+
+	pathways, err := producer.ProducePathwaysFromStopPlace(stopPlace)
+	if err != nil {
+		t.Fatalf("ProducePathwaysFromStopPlace failed: %v", err)
+	}
+	expectedPathways := []model.Pathway{
+		{PathwayID: "stairs", FromStopID: "ent1", ToStopID: "quay1", PathwayMode: 2, IsBidirectional: 1, Length: 25.5, TraversalTime: 90, StairCount: -20},
+		{PathwayID: "escalator", FromStopID: "quay1", ToStopID: "ent1", PathwayMode: 4, IsBidirectional: 0, SignpostedAs: "Exit"},
+		{PathwayID: "lift", FromStopID: "ent1", ToStopID: "quay1", PathwayMode: 5, IsBidirectional: 0},
+	}
+	if len(pathways) != len(expectedPathways) {
+		t.Fatalf("Expected %d pathways, got %d", len(expectedPathways), len(pathways))
+	}
+	for i, expected := range expectedPathways {
+		if *pathways[i] != expected {
+			t.Errorf("Expected pathway %+v, got %+v", expected, *pathways[i])
+		}
+	}
+
+	if _, err := producer.ProducePathwayFromLink(&model.SitePathLink{ID: "dangling"}); err == nil {
+		t.Error("Expected error for a path link without ends")
+	}
+
+	// A path link without ends is skipped and reported, the others are kept
+	stopPlace.PathLinks = append(stopPlace.PathLinks, model.SitePathLink{ID: "dangling"})
+	pathways, err = producer.ProducePathwaysFromStopPlace(stopPlace)
+	var linkErr *PathLinkError
+	if !errors.As(err, &linkErr) || strings.Join(linkErr.LinkIDs(), ",") != "dangling" {
+		t.Errorf("Expected a path link error for the dangling link, got %v", err)
+	}
+	if len(pathways) != len(expectedPathways) {
+		t.Errorf("Expected the %d other pathways, got %d", len(expectedPathways), len(pathways))
+	}
+
+	// Without levels and path links nothing is inferred unless the heuristic
+	// fallback is enabled
+	stopPlace.Levels, stopPlace.PathLinks = nil, nil
+	levels, _ = producer.ProduceLevelsFromStopPlace(stopPlace)
+	pathways, _ = producer.ProducePathwaysFromStopPlace(stopPlace)
+	if len(levels) != 0 || len(pathways) != 0 {
+		t.Errorf("Expected no heuristic levels and pathways by default, got %d and %d", len(levels), len(pathways))
+	}
+	producer.SetHeuristicFallback(true)
+	levels, _ = producer.ProduceLevelsFromStopPlace(stopPlace)
+	pathways, _ = producer.ProducePathwaysFromStopPlace(stopPlace)
+	if len(levels) == 0 || len(pathways) == 0 {
+		t.Error("Expected heuristic levels and pathways with the fallback enabled")
+	}
+}
@@ -27,15 +27,19 @@ type TripProducer interface {
 type TripInput struct {
 	ServiceJourney     *model.ServiceJourney
 	NetexRoute         *model.Route
+	JourneyPattern     *model.JourneyPattern
 	GtfsRoute          *model.GtfsRoute
 	ShapeID            string
 	DestinationDisplay *model.DestinationDisplay
 }
 
-// StopProducer converts NeTEx Quay to GTFS Stop
+// StopProducer converts NeTEx quays, stop places, entrances and boarding
+// positions to GTFS stops, stations, entrances and boarding areas
 type StopProducer interface {
 	ProduceStopFromQuay(quay *model.Quay) (*model.Stop, error)
 	ProduceStopFromStopPlace(stopPlace *model.StopPlace) (*model.Stop, error)
+	ProduceStopFromEntrance(entrance *model.StopPlaceEntrance, stopPlace *model.StopPlace) (*model.Stop, error)
+	ProduceStopFromBoardingPosition(boardingPosition *model.BoardingPosition, quay *model.Quay) (*model.Stop, error)
 }
 
 // StopTimeProducer converts NeTEx TimetabledPassingTime to GTFS StopTime
@@ -62,9 +66,9 @@ type ServiceCalendarDateProducer interface {
 	Produce(serviceID string, dayTypeAssignments []*model.DayTypeAssignment) ([]*model.CalendarDate, error)
 }
 
-// ShapeProducer converts NeTEx route geometry to GTFS Shape
+// ShapeProducer converts NeTEx route geometry to the ordered points of a GTFS Shape
 type ShapeProducer interface {
-	Produce(journeyPattern *model.JourneyPattern) (*model.Shape, error)
+	Produce(journeyPattern *model.JourneyPattern) ([]*model.Shape, error)
 }
 
 // TransferProducer converts NeTEx ServiceJourneyInterchange to GTFS Transfer
@@ -74,12 +78,29 @@ type TransferProducer interface {
 
 // FeedInfoProducer creates GTFS FeedInfo
 type FeedInfoProducer interface {
-	ProduceFeedInfo() (*model.FeedInfo, error)
-}
-
-// PathwaysProducer converts NeTEx accessibility data to GTFS pathways and levels
+	ProduceFeedInfo(input FeedInfoInput) (*model.FeedInfo, error)
+}
+
+// FeedInfoInput contains the data needed to produce GTFS feed info
+type FeedInfoInput struct {
+	PublisherName string
+	PublisherURL  string
+	ContactEmail  string
+	ContactURL    string
+	Language      string
+	// PublicationTimestamp is the latest NeTEx PublicationTimestamp of the dataset
+	PublicationTimestamp string
+	// ContentHash identifies the converted NeTEx content, e.g. a hex digest
+	ContentHash string
+	// Calendars and CalendarDates are the produced services, which set the feed's validity
+	Calendars     []*model.Calendar
+	CalendarDates []*model.CalendarDate
+}
+
+// PathwaysProducer converts NeTEx levels and path links to GTFS levels and pathways
 type PathwaysProducer interface {
 	ProducePathwaysFromStopPlace(stopPlace *model.StopPlace) ([]*model.Pathway, error)
+	ProducePathwayFromLink(link *model.SitePathLink) (*model.Pathway, error)
 	ProduceLevelsFromStopPlace(stopPlace *model.StopPlace) ([]*model.Level, error)
 	ProduceAccessibilityPathways(from, to *model.Quay) (*model.Pathway, error)
 }
@@ -92,6 +113,34 @@ type FrequencyProducer interface {
 	CreateFrequencyBasedService(group *model.HeadwayJourneyGroup, line *model.Line) (*FrequencyService, error)
 }
 
+// FareProducer converts NeTEx fare elements to GTFS Fares v2
+type FareProducer interface {
+	Produce() *Fares
+}
+
+// Fares holds the GTFS Fares v2 entities of a dataset's fare elements and the
+// elements that could not be expressed in them. Route networks refer to the
+// NeTEx line IDs and stop areas to the stop IDs the lines' stops resolve to.
+type Fares struct {
+	Areas             []*model.Area
+	AreaStops         []*model.AreaStop
+	Networks          []*model.GtfsNetwork
+	RouteNetworks     []*model.RouteNetwork
+	FareMedia         []*model.FareMedia
+	FareProducts      []*model.FareProduct
+	FareLegRules      []*model.FareLegRule
+	FareTransferRules []*model.FareTransferRule
+	Issues            []FareIssue
+}
+
+// FareIssue is a NeTEx fare element, or a part of one, that GTFS Fares v2
+// cannot express
+type FareIssue struct {
+	EntityType string
+	EntityID   string
+	Message    string
+}
+
 // Repository interfaces for data access
 
 // NetexRepository provides access to NeTEx data
@@ -101,11 +150,15 @@ type NetexRepository interface {
 
 	// Data retrieval
 	GetLines() []*model.Line
+	GetLineById(id string) *model.Line
 	GetServiceJourneys() []*model.ServiceJourney
 	GetAuthorityById(id string) *model.Authority
 	GetQuayById(id string) *model.Quay
+	GetStopPlaceById(id string) *model.StopPlace
 	GetStopPlaceByQuayId(quayId string) *model.StopPlace
 	GetTimeZone() string
+	GetDefaultLanguage() string
+	GetPublicationTimestamp() string
 	GetJourneyPatternById(id string) *model.JourneyPattern
 	GetRouteById(id string) *model.Route
 	GetRoutesByLine(line *model.Line) []*model.Route
@@ -116,6 +169,8 @@ type NetexRepository interface {
 	GetDatedServiceJourneysByServiceJourneyId(serviceJourneyId string) []*model.DatedServiceJourney
 	GetDayTypeById(id string) *model.DayType
 	GetOperatingDayById(id string) *model.OperatingDay
+	GetOperatingPeriodById(id string) *model.OperatingPeriod
+	GetUicOperatingPeriodById(id string) *model.UicOperatingPeriod
 	GetDayTypeAssignmentsByDayType(dayType *model.DayType) []*model.DayTypeAssignment
 	// Mapping from PointInJourneyPatternRef to ScheduledStopPointRef
 	GetScheduledStopPointRefByPointInJourneyPatternRef(pjpRef string) string
@@ -126,9 +181,28 @@ type NetexRepository interface {
 	// Access all stop places and quays (for exporting when stop-area repo is empty)
 	GetAllStopPlaces() []*model.StopPlace
 	GetAllQuays() []*model.Quay
+	// Path links kept outside their stop places
+	GetAllSitePathLinks() []*model.SitePathLink
+	// Vehicle types of journeys, patterns and lines
+	GetVehicleTypeById(id string) *model.VehicleType
 	// Frequency-based services
 	GetHeadwayJourneyGroups() []*model.HeadwayJourneyGroup
 	GetHeadwayJourneyGroupById(id string) *model.HeadwayJourneyGroup
+	// Link geometry for shapes
+	GetServiceLinkById(id string) *model.ServiceLink
+	GetRouteLinkById(id string) *model.RouteLink
+	// Flexible stop places of demand-responsive services
+	GetFlexibleStopPlaceById(id string) *model.FlexibleStopPlace
+	GetFlexibleStopPlaceByScheduledStopPointId(sspId string) *model.FlexibleStopPlace
+	GetAllFlexibleStopPlaces() []*model.FlexibleStopPlace
+	// Fare zones, tariffs, products and prices
+	GetDefaultCurrency() string
+	GetAllTariffZones() []*model.TariffZone
+	GetAllFareZones() []*model.FareZone
+	GetAllTariffs() []*model.Tariff
+	GetAllPreassignedFareProducts() []*model.PreassignedFareProduct
+	GetAllSalesOfferPackages() []*model.SalesOfferPackage
+	GetAllFareTables() []*model.FareTable
 }
 
 // GtfsRepository provides access to GTFS data
@@ -144,8 +218,10 @@ type GtfsRepository interface {
 // StopAreaRepository provides access to stop area data
 type StopAreaRepository interface {
 	GetQuayById(quayId string) *model.Quay
+	GetStopPlaceById(id string) *model.StopPlace
 	GetStopPlaceByQuayId(quayId string) *model.StopPlace
 	GetAllQuays() []*model.Quay
+	GetAllSitePathLinks() []*model.SitePathLink
 	LoadStopAreas(data []byte) error
 }
 
@@ -1,6 +1,7 @@
 package producer
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -411,8 +412,10 @@ func (p *SophisticatedInterchangeProducer) optimizeTransferTimes(transfers []*mo
 		return transfers
 	}
 
+	// Skipped path links still leave the pathways of the others
 	pathways, err := p.pathwaysProducer.ProducePathwaysFromStopPlace(stopPlace)
-	if err != nil || len(pathways) == 0 {
+	var linkErr *PathLinkError
+	if (err != nil && !errors.As(err, &linkErr)) || len(pathways) == 0 {
 		return transfers
 	}
 
@@ -534,6 +534,10 @@ func (m *mockPathwaysProducer) ProducePathwaysFromStopPlace(stopPlace *model.Sto
 	}, nil
 }
 
+func (m *mockPathwaysProducer) ProducePathwayFromLink(link *model.SitePathLink) (*model.Pathway, error) {
+	return &model.Pathway{PathwayID: link.ID, PathwayMode: 1, IsBidirectional: 1}, nil
+}
+
 func (m *mockPathwaysProducer) ProduceLevelsFromStopPlace(stopPlace *model.StopPlace) ([]*model.Level, error) {
 	return []*model.Level{}, nil
 }
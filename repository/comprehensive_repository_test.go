@@ -1,10 +1,19 @@
 package repository
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/output"
 )
 
 // Comprehensive tests for DefaultNetexRepository
@@ -503,6 +512,148 @@ func TestDefaultGtfsRepository_WriteGtfs(t *testing.T) {
 	}
 }
 
+func TestDefaultGtfsRepository_WriteGtfsTo(t *testing.T) {
+	repo := NewDefaultGtfsRepository().(*DefaultGtfsRepository)
+	if err := repo.SaveEntity(&model.Agency{AgencyID: "agency1", AgencyName: "Test Agency"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveEntity(&model.Stop{StopID: "stop1", StopName: "Stop 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var streamed bytes.Buffer
+	if err := repo.WriteGtfsTo(&streamed); err != nil {
+		t.Fatalf("WriteGtfsTo() failed: %v", err)
+	}
+	reader, err := repo.WriteGtfs()
+	if err != nil {
+		t.Fatalf("WriteGtfs() failed: %v", err)
+	}
+	var buffered bytes.Buffer
+	if _, err := buffered.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(streamed.Bytes(), buffered.Bytes()) {
+		t.Error("Expected the streamed archive to match the buffered one")
+	}
+}
+
+func TestDefaultGtfsRepository_WriteGtfsContextCancelled(t *testing.T) {
+	repo := NewDefaultGtfsRepository().(*DefaultGtfsRepository)
+	if err := repo.SaveEntity(&model.Agency{AgencyID: "agency1", AgencyName: "Test Agency"}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err := repo.WriteGtfsContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if result != nil {
+		t.Error("A cancelled write should not return an archive")
+	}
+}
+
+func TestDefaultGtfsRepository_WriteGtfsToSink(t *testing.T) {
+	repo := NewDefaultGtfsRepository().(*DefaultGtfsRepository)
+	if err := repo.SaveEntity(&model.Agency{AgencyID: "agency1", AgencyName: "Test Agency"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveEntity(&model.Stop{StopID: "stop1", StopName: "Stop 1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	sink, err := output.NewDirectorySink(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteGtfsToSink(context.Background(), sink); err != nil {
+		t.Fatalf("WriteGtfsToSink() failed: %v", err)
+	}
+
+	stops, err := os.ReadFile(filepath.Join(dir, "stops.txt"))
+	if err != nil {
+		t.Fatalf("Expected stops.txt in the directory: %v", err)
+	}
+	if !strings.HasPrefix(string(stops), "stop_id,") || !strings.Contains(string(stops), "stop1,") {
+		t.Errorf("Unexpected stops.txt:\n%s", stops)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "agency.txt")); err != nil {
+		t.Errorf("Expected agency.txt in the directory: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cancelledDir := t.TempDir()
+	sink, err = output.NewDirectorySink(cancelledDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.WriteGtfsToSink(ctx, sink); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if entries, _ := os.ReadDir(cancelledDir); len(entries) != 0 {
+		t.Errorf("Expected an aborted write to leave no files, got %d", len(entries))
+	}
+}
+
+func TestReadGtfsArchive_RoundTrip(t *testing.T) {
+	repo := NewDefaultGtfsRepository()
+	stop := &model.Stop{
+		StopID:        "NSR:Quay:1",
+		StopName:      "Oslo S, 1",
+		StopLat:       59.91,
+		StopLon:       10.75,
+		LocationType:  "1",
+		ParentStation: "NSR:StopPlace:1",
+	}
+	if err := repo.SaveEntity(stop); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := repo.WriteGtfs()
+	if err != nil {
+		t.Fatalf("WriteGtfs() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := ReadGtfsArchive(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadGtfsArchive() failed: %v", err)
+	}
+	table, ok := tables["stops.txt"]
+	if !ok || len(table.Rows) != 1 {
+		t.Fatalf("Expected one row in stops.txt, got %v", tables)
+	}
+	if got := table.Value(table.Rows[0], "stop_name"); got != "Oslo S, 1" {
+		t.Errorf("Expected stop_name %q, got %q", "Oslo S, 1", got)
+	}
+	if got := table.Value(table.Rows[0], "no_such_column"); got != "" {
+		t.Errorf("Expected empty value for a missing column, got %q", got)
+	}
+
+	var stops []*model.Stop
+	if err := DecodeGtfsTable(table, &stops); err != nil {
+		t.Fatalf("DecodeGtfsTable() failed: %v", err)
+	}
+	if len(stops) != 1 || *stops[0] != *stop {
+		t.Errorf("Expected decoded stop %+v, got %+v", stop, stops)
+	}
+}
+
+func TestReadGtfsArchive_Invalid(t *testing.T) {
+	if _, err := ReadGtfsArchive([]byte("not a zip")); err == nil {
+		t.Error("Expected an error for data that is not a ZIP archive")
+	}
+}
+
 func TestDefaultGtfsRepository_UnsupportedEntity(t *testing.T) {
 	repo := NewDefaultGtfsRepository()
 
@@ -591,6 +742,92 @@ func TestDefaultStopAreaRepository_LoadStopAreasXML(t *testing.T) {
 	t.Logf("XML loading result: %v", err)
 }
 
+func TestDefaultStopAreaRepository_LoadStopAreasArchive(t *testing.T) {
+	// Real stops exports use lowercase containers and may skip the CompositeFrame
+	stops := `<?xml version="1.0" encoding="UTF-8"?>
+<PublicationDelivery xmlns="http://www.netex.org.uk/netex">
+	<dataObjects>
+		<SiteFrame id="NSR:SiteFrame:1" version="1">
+			<stopPlaces>
+				<StopPlace id="NSR:StopPlace:1" version="1">
+					<Name>Oslo S</Name>
+					<quays>
+						<Quay id="NSR:Quay:1" version="1"><Name>Oslo S 1</Name></Quay>
+						<Quay id="NSR:Quay:2" version="1"><Name>Oslo S 2</Name></Quay>
+					</quays>
+				</StopPlace>
+				<StopPlace id="NSR:StopPlace:2" version="1">
+					<Name>Lysaker</Name>
+					<Quays>
+						<Quay id="NSR:Quay:3" version="1"><Name>Lysaker 1</Name></Quay>
+					</Quays>
+				</StopPlace>
+			</stopPlaces>
+			<pathLinks>
+				<SitePathLink id="NSR:SitePathLink:1" version="1">
+					<From><PlaceRef ref="NSR:Quay:1"/></From>
+					<To><PlaceRef ref="NSR:Quay:2"/></To>
+				</SitePathLink>
+			</pathLinks>
+		</SiteFrame>
+	</dataObjects>
+</PublicationDelivery>`
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"stops.xml": stops, "readme.txt": "not xml"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("Create(%s) failed: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("Write(%s) failed: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	repo := NewDefaultStopAreaRepository()
+	if err := repo.LoadStopAreas(buf.Bytes()); err != nil {
+		t.Fatalf("LoadStopAreas() failed: %v", err)
+	}
+
+	if got := len(repo.GetAllQuays()); got != 3 {
+		t.Errorf("Expected 3 quays, got %d", got)
+	}
+	expectedParents := map[string]string{
+		"NSR:Quay:1": "NSR:StopPlace:1",
+		"NSR:Quay:2": "NSR:StopPlace:1",
+		"NSR:Quay:3": "NSR:StopPlace:2",
+	}
+	for quayID, stopPlaceID := range expectedParents {
+		if repo.GetQuayById(quayID) == nil {
+			t.Errorf("Expected quay %s to be loaded", quayID)
+		}
+		sp := repo.GetStopPlaceByQuayId(quayID)
+		if sp == nil || sp.ID != stopPlaceID {
+			t.Errorf("Expected quay %s to belong to %s, got %v", quayID, stopPlaceID, sp)
+		}
+	}
+	if links := repo.GetAllSitePathLinks(); len(links) != 1 || links[0].From.PlaceRef() != "NSR:Quay:1" {
+		t.Errorf("Expected the path link between the Oslo S quays, got %v", links)
+	}
+
+	// An archive without any stop place is most likely the wrong file
+	var empty bytes.Buffer
+	zw = zip.NewWriter(&empty)
+	if _, err := zw.Create("empty.xml"); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := NewDefaultStopAreaRepository().LoadStopAreas(empty.Bytes()); err == nil {
+		t.Error("Expected error for an archive without stop places")
+	}
+}
+
 // Test error cases and edge conditions
 
 func TestRepositories_ConcurrentAccess(t *testing.T) {
@@ -688,3 +925,227 @@ func TestDefaultNetexRepository_EntityRelationships(t *testing.T) {
 		t.Errorf("Expected authority ID 'auth1', got '%s'", authorityID)
 	}
 }
+
+func TestDefaultGtfsRepository_WriteFaresTranslationsAttributions(t *testing.T) {
+	repo := NewDefaultGtfsRepository()
+	entities := []interface{}{
+		&model.FareAttribute{FareID: "adult", Price: 3.5, CurrencyType: "EUR", PaymentMethod: 1, AgencyID: "agency1", TransferDuration: "5400"},
+		&model.FareRule{FareID: "adult", RouteID: "route1"},
+		&model.Translation{TableName: "stops", FieldName: "stop_name", Language: "en", Translation: "Central Station", RecordID: "stop1"},
+		&model.Attribution{AttributionID: "attr1", OrganizationName: "Transit Data Co", IsProducer: 1},
+	}
+	for _, entity := range entities {
+		if err := repo.SaveEntity(entity); err != nil {
+			t.Fatalf("SaveEntity(%T) failed: %v", entity, err)
+		}
+	}
+
+	reader, err := repo.WriteGtfs()
+	if err != nil {
+		t.Fatalf("WriteGtfs() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := ReadGtfsArchive(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadGtfsArchive() failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"fare_attributes.txt": "fare_id,price,currency_type,payment_method,transfers,agency_id,transfer_duration",
+		"fare_rules.txt":      "fare_id,route_id,origin_id,destination_id,contains_id",
+		"translations.txt":    "table_name,field_name,language,translation,record_id,record_sub_id,field_value",
+		"attributions.txt":    "attribution_id,agency_id,route_id,trip_id,organization_name,is_producer,is_operator,is_authority,attribution_url,attribution_email,attribution_phone",
+	}
+	if len(tables) != len(expected) {
+		t.Errorf("Expected only the files with rows, got %d files", len(tables))
+	}
+	for name, header := range expected {
+		table, ok := tables[name]
+		if !ok {
+			t.Errorf("Expected %s in the archive", name)
+			continue
+		}
+		if got := strings.Join(table.Header, ","); got != header {
+			t.Errorf("Expected %s header\n  %s\ngot\n  %s", name, header, got)
+		}
+		if len(table.Rows) != 1 {
+			t.Errorf("Expected one row in %s, got %d", name, len(table.Rows))
+		}
+	}
+
+	fares := tables["fare_attributes.txt"]
+	if fares != nil && len(fares.Rows) == 1 {
+		row := fares.Rows[0]
+		if fares.Value(row, "price") != "3.5" || fares.Value(row, "transfers") != "" || fares.Value(row, "transfer_duration") != "5400" {
+			t.Errorf("Unexpected fare attribute row %v", row)
+		}
+	}
+}
+
+func TestDefaultGtfsRepository_WriteFlex(t *testing.T) {
+	repo := NewDefaultGtfsRepository()
+	square := func(offset float64) [][][2]float64 {
+		return [][][2]float64{{{offset, 60}, {offset + 1, 60}, {offset + 1, 61}, {offset, 61}, {offset, 60}}}
+	}
+	entities := []interface{}{
+		&model.LocationGroup{LocationGroupID: "group1", LocationGroupName: "Village stops"},
+		&model.LocationGroupStop{LocationGroupID: "group1", StopID: "stop1"},
+		&model.FlexLocation{LocationID: "zone1", StopName: "Village zone", Polygons: [][][][2]float64{square(10)}},
+		&model.FlexLocation{LocationID: "zone2", Polygons: [][][][2]float64{square(10), square(12)}},
+		&model.BookingRule{BookingRuleID: "rule1", BookingType: 1, PriorNoticeDurationMin: 60, PhoneNumber: "+47 12345678"},
+	}
+	for _, entity := range entities {
+		if err := repo.SaveEntity(entity); err != nil {
+			t.Fatalf("SaveEntity(%T) failed: %v", entity, err)
+		}
+	}
+
+	reader, err := repo.WriteGtfs()
+	if err != nil {
+		t.Fatalf("WriteGtfs() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := ReadGtfsArchive(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadGtfsArchive() failed: %v", err)
+	}
+
+	for _, name := range []string{"location_groups.txt", "location_group_stops.txt", "booking_rules.txt"} {
+		if table := tables[name]; table == nil || len(table.Rows) != 1 {
+			t.Errorf("Expected one row in %s, got %+v", name, table)
+		}
+	}
+	if rules := tables["booking_rules.txt"]; rules != nil && len(rules.Rows) == 1 {
+		row := rules.Rows[0]
+		if rules.Value(row, "booking_type") != "1" || rules.Value(row, "prior_notice_duration_min") != "60" ||
+			rules.Value(row, "prior_notice_last_day") != "" || rules.Value(row, "phone_number") != "+47 12345678" {
+			t.Errorf("Unexpected booking rule row %v", row)
+		}
+	}
+
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := zipReader.Open("locations.geojson")
+	if err != nil {
+		t.Fatalf("Expected locations.geojson in the archive: %v", err)
+	}
+	defer file.Close()
+	var locations struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID         string            `json:"id"`
+			Properties map[string]string `json:"properties"`
+			Geometry   struct {
+				Type string `json:"type"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(file).Decode(&locations); err != nil {
+		t.Fatalf("locations.geojson is not valid JSON: %v", err)
+	}
+	if locations.Type != "FeatureCollection" || len(locations.Features) != 2 {
+		t.Fatalf("Expected a collection of 2 features, got %+v", locations)
+	}
+	if feature := locations.Features[0]; feature.ID != "zone1" || feature.Geometry.Type != "Polygon" || feature.Properties["stop_name"] != "Village zone" {
+		t.Errorf("Unexpected first feature %+v", feature)
+	}
+	if feature := locations.Features[1]; feature.ID != "zone2" || feature.Geometry.Type != "MultiPolygon" || len(feature.Properties) != 0 {
+		t.Errorf("Unexpected second feature %+v", feature)
+	}
+}
+
+func TestDefaultGtfsRepository_WriteFlexWithoutPolygons(t *testing.T) {
+	repo := NewDefaultGtfsRepository()
+	if err := repo.SaveEntity(&model.FlexLocation{LocationID: "zone1", StopName: "Village zone"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := repo.WriteGtfs()
+	if err != nil {
+		t.Fatalf("WriteGtfs() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zipReader.Open("locations.geojson"); err == nil {
+		t.Error("Expected no locations.geojson without a zone polygon")
+	}
+}
+
+func TestDefaultGtfsRepository_WriteFaresV2(t *testing.T) {
+	repo := NewDefaultGtfsRepository()
+	entities := []interface{}{
+		&model.Area{AreaID: "zone1", AreaName: "Centre"},
+		&model.AreaStop{AreaID: "zone1", StopID: "stop1"},
+		&model.GtfsNetwork{NetworkID: "tariff1", NetworkName: "City"},
+		&model.RouteNetwork{NetworkID: "tariff1", RouteID: "route1"},
+		&model.FareMedia{FareMediaID: "app", FareMediaName: "App", FareMediaType: 4},
+		&model.FareProduct{FareProductID: "single", FareProductName: "Single", FareMediaID: "app", Amount: "2.50", Currency: "EUR"},
+		&model.FareLegRule{LegGroupID: "single", NetworkID: "tariff1", FromAreaID: "zone1", ToAreaID: "zone1", FareProductID: "single"},
+		&model.FareTransferRule{FromLegGroupID: "single", ToLegGroupID: "single", TransferCount: "-1", DurationLimit: "5400", DurationLimitType: "1"},
+	}
+	for _, entity := range entities {
+		if err := repo.SaveEntity(entity); err != nil {
+			t.Fatalf("SaveEntity(%T) failed: %v", entity, err)
+		}
+	}
+
+	reader, err := repo.WriteGtfs()
+	if err != nil {
+		t.Fatalf("WriteGtfs() failed: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(reader); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := ReadGtfsArchive(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadGtfsArchive() failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"areas.txt":               "area_id,area_name",
+		"stop_areas.txt":          "area_id,stop_id",
+		"networks.txt":            "network_id,network_name",
+		"route_networks.txt":      "network_id,route_id",
+		"fare_media.txt":          "fare_media_id,fare_media_name,fare_media_type",
+		"fare_products.txt":       "fare_product_id,fare_product_name,fare_media_id,amount,currency",
+		"fare_leg_rules.txt":      "leg_group_id,network_id,from_area_id,to_area_id,from_timeframe_group_id,to_timeframe_group_id,fare_product_id,rule_priority",
+		"fare_transfer_rules.txt": "from_leg_group_id,to_leg_group_id,transfer_count,duration_limit,duration_limit_type,fare_transfer_type,fare_product_id",
+	}
+	if len(tables) != len(expected) {
+		t.Errorf("Expected only the files with rows, got %d files", len(tables))
+	}
+	for name, header := range expected {
+		table, ok := tables[name]
+		if !ok {
+			t.Errorf("Expected %s in the archive", name)
+			continue
+		}
+		if got := strings.Join(table.Header, ","); got != header {
+			t.Errorf("Expected %s header\n  %s\ngot\n  %s", name, header, got)
+		}
+		if len(table.Rows) != 1 {
+			t.Errorf("Expected one row in %s, got %d", name, len(table.Rows))
+		}
+	}
+
+	if products := tables["fare_products.txt"]; products != nil && len(products.Rows) == 1 {
+		if amount := products.Value(products.Rows[0], "amount"); amount != "2.50" {
+			t.Errorf("Expected the amount as given, got %q", amount)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// GtfsTable holds the rows of one GTFS file
+type GtfsTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+// Column returns the index of a column, or -1 when the file does not have it
+func (t *GtfsTable) Column(name string) int {
+	for i, column := range t.Header {
+		if column == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Value returns the value of a column in a row, or "" when the column is missing
+func (t *GtfsTable) Value(row []string, name string) string {
+	if i := t.Column(name); i >= 0 && i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// ReadGtfsArchive reads every .txt file of a GTFS ZIP archive, keyed by file name
+func ReadGtfsArchive(data []byte) (map[string]*GtfsTable, error) {
+	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP archive: %w", err)
+	}
+
+	tables := make(map[string]*GtfsTable)
+	for _, file := range zipReader.File {
+		name := path.Base(file.Name)
+		if file.FileInfo().IsDir() || !strings.HasSuffix(name, ".txt") {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s: %w", file.Name, err)
+		}
+		table, err := readGtfsTable(rc)
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", file.Name, err)
+		}
+		tables[name] = table
+	}
+
+	return tables, nil
+}
+
+// readGtfsTable reads one CSV file; the header loses a UTF-8 byte order mark
+// and surrounding spaces
+func readGtfsTable(r io.Reader) (*GtfsTable, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	table := &GtfsTable{}
+	if len(records) == 0 {
+		return table, nil
+	}
+	for i, column := range records[0] {
+		if i == 0 {
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		table.Header = append(table.Header, strings.TrimSpace(column))
+	}
+	table.Rows = records[1:]
+	return table, nil
+}
+
+// DecodeGtfsTable decodes the rows of a table into entities, a pointer to a
+// slice of model pointers such as *[]*model.Stop. Columns are matched to
+// fields by the names WriteGtfs uses; unknown columns are ignored.
+func DecodeGtfsTable(table *GtfsTable, entities interface{}) error {
+	slicePtr := reflect.ValueOf(entities)
+	if slicePtr.Kind() != reflect.Ptr || slicePtr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("entities must be a pointer to a slice")
+	}
+	sliceValue := slicePtr.Elem()
+	elemType := sliceValue.Type().Elem()
+	if elemType.Kind() != reflect.Ptr || elemType.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("entities must be a slice of struct pointers")
+	}
+	structType := elemType.Elem()
+
+	// Map each field to its column once
+	columns := make([]int, structType.NumField())
+	names := make([]string, structType.NumField())
+	for i := range columns {
+		names[i], _ = csvColumn(structType.Field(i))
+		columns[i] = table.Column(names[i])
+	}
+
+	for rowIndex, row := range table.Rows {
+		entity := reflect.New(structType)
+		for i, column := range columns {
+			if column < 0 || column >= len(row) {
+				continue
+			}
+			if err := setCSVFieldValue(entity.Elem().Field(i), strings.TrimSpace(row[column])); err != nil {
+				return fmt.Errorf("row %d, %s: %w", rowIndex+2, names[i], err)
+			}
+		}
+		sliceValue.Set(reflect.Append(sliceValue, entity))
+	}
+
+	return nil
+}
+
+// setCSVFieldValue parses a CSV value into a field; empty values leave the
+// zero value
+func setCSVFieldValue(field reflect.Value, value string) error {
+	if value == "" {
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		field.SetBool(value == "1")
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Kind())
+	}
+	return nil
+}
@@ -1,19 +1,26 @@
 package repository
 
 import (
-	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/output"
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
 )
 
+// cancelCheckInterval is the number of rows written between checks of the
+// context
+const cancelCheckInterval = 1000
+
 // DefaultGtfsRepository implements GtfsRepository
 type DefaultGtfsRepository struct {
 	// Entity storage
@@ -32,9 +39,31 @@ type DefaultGtfsRepository struct {
 	fareRules      []*model.FareRule
 	pathways       []*model.Pathway
 	levels         []*model.Level
+	translations   []*model.Translation
+	attributions   []*model.Attribution
+
+	// GTFS-Flex entities
+	locationGroups     []*model.LocationGroup
+	locationGroupStops []*model.LocationGroupStop
+	flexLocations      []*model.FlexLocation
+	bookingRules       []*model.BookingRule
+
+	// GTFS Fares v2 entities
+	areas             []*model.Area
+	areaStops         []*model.AreaStop
+	networks          []*model.GtfsNetwork
+	routeNetworks     []*model.RouteNetwork
+	fareMedia         []*model.FareMedia
+	fareProducts      []*model.FareProduct
+	fareLegRules      []*model.FareLegRule
+	fareTransferRules []*model.FareTransferRule
 
 	// Default agency
 	defaultAgency *model.Agency
+
+	// stopTimeBatches passes the stop times to write in batches; nil writes
+	// them at once
+	stopTimeBatches func(write func([]*model.StopTime) error) error
 }
 
 // NewDefaultGtfsRepository creates a new DefaultGtfsRepository
@@ -54,6 +83,8 @@ func NewDefaultGtfsRepository() producer.GtfsRepository {
 		fareRules:      make([]*model.FareRule, 0),
 		pathways:       make([]*model.Pathway, 0),
 		levels:         make([]*model.Level, 0),
+		translations:   make([]*model.Translation, 0),
+		attributions:   make([]*model.Attribution, 0),
 	}
 }
 
@@ -93,6 +124,34 @@ func (r *DefaultGtfsRepository) SaveEntity(entity interface{}) error {
 		r.pathways = append(r.pathways, e)
 	case *model.Level:
 		r.levels = append(r.levels, e)
+	case *model.Translation:
+		r.translations = append(r.translations, e)
+	case *model.Attribution:
+		r.attributions = append(r.attributions, e)
+	case *model.LocationGroup:
+		r.locationGroups = append(r.locationGroups, e)
+	case *model.LocationGroupStop:
+		r.locationGroupStops = append(r.locationGroupStops, e)
+	case *model.FlexLocation:
+		r.flexLocations = append(r.flexLocations, e)
+	case *model.BookingRule:
+		r.bookingRules = append(r.bookingRules, e)
+	case *model.Area:
+		r.areas = append(r.areas, e)
+	case *model.AreaStop:
+		r.areaStops = append(r.areaStops, e)
+	case *model.GtfsNetwork:
+		r.networks = append(r.networks, e)
+	case *model.RouteNetwork:
+		r.routeNetworks = append(r.routeNetworks, e)
+	case *model.FareMedia:
+		r.fareMedia = append(r.fareMedia, e)
+	case *model.FareProduct:
+		r.fareProducts = append(r.fareProducts, e)
+	case *model.FareLegRule:
+		r.fareLegRules = append(r.fareLegRules, e)
+	case *model.FareTransferRule:
+		r.fareTransferRules = append(r.fareTransferRules, e)
 	default:
 		return fmt.Errorf("unknown GTFS entity type: %T", entity)
 	}
@@ -119,74 +178,144 @@ func (r *DefaultGtfsRepository) GetDefaultAgency() *model.Agency {
 	return r.defaultAgency
 }
 
-// WriteGtfs generates a GTFS ZIP archive
+// WriteGtfs generates a GTFS ZIP archive in memory; WriteGtfsTo streams it
+// instead
 func (r *DefaultGtfsRepository) WriteGtfs() (io.Reader, error) {
+	return r.WriteGtfsContext(context.Background())
+}
+
+// WriteGtfsContext generates a GTFS ZIP archive in memory, stopping with the
+// context's error when it is cancelled
+func (r *DefaultGtfsRepository) WriteGtfsContext(ctx context.Context) (io.Reader, error) {
 	var buf bytes.Buffer
-	zipWriter := zip.NewWriter(&buf)
+	if err := r.WriteGtfsToContext(ctx, &buf); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
+
+// WriteGtfsTo streams a GTFS ZIP archive to w, writing each file as it is
+// serialized rather than building the archive in memory
+func (r *DefaultGtfsRepository) WriteGtfsTo(w io.Writer) error {
+	return r.WriteGtfsToContext(context.Background(), w)
+}
+
+// WriteGtfsToContext streams a GTFS ZIP archive to w, stopping with the
+// context's error when it is cancelled. After an error w holds a truncated
+// archive.
+func (r *DefaultGtfsRepository) WriteGtfsToContext(ctx context.Context, w io.Writer) error {
+	return r.WriteGtfsToSink(ctx, output.NewZipSink(w))
+}
+
+// WriteGtfsToSink writes the GTFS files to sink and closes it, stopping with
+// the context's error when it is cancelled. After an error the sink is
+// aborted.
+func (r *DefaultGtfsRepository) WriteGtfsToSink(ctx context.Context, sink output.Sink) error {
+	if err := r.writeFiles(ctx, sink); err != nil {
+		_ = sink.Abort()
+		return err
+	}
+
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
 
+	return nil
+}
+
+// writeFiles writes each GTFS file of the feed to sink
+func (r *DefaultGtfsRepository) writeFiles(ctx context.Context, sink output.Sink) error {
 	// Write each GTFS file
-	if err := r.writeAgencies(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write agencies: %w", err)
+	if err := r.writeAgencies(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write agencies: %w", err)
 	}
 
-	if err := r.writeStops(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write stops: %w", err)
+	if err := r.writeStops(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write stops: %w", err)
 	}
 
-	if err := r.writeRoutes(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write routes: %w", err)
+	if err := r.writeRoutes(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write routes: %w", err)
 	}
 
-	if err := r.writeTrips(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write trips: %w", err)
+	if err := r.writeTrips(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write trips: %w", err)
 	}
 
-	if err := r.writeStopTimes(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write stop times: %w", err)
+	if err := r.writeStopTimes(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write stop times: %w", err)
 	}
 
-	if err := r.writeCalendar(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write calendar: %w", err)
+	if err := r.writeCalendar(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write calendar: %w", err)
 	}
 
-	if err := r.writeCalendarDates(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write calendar dates: %w", err)
+	if err := r.writeCalendarDates(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write calendar dates: %w", err)
 	}
 
-	if err := r.writeTransfers(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write transfers: %w", err)
+	if err := r.writeFareAttributes(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write fare attributes: %w", err)
 	}
 
-	if err := r.writeShapes(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write shapes: %w", err)
+	if err := r.writeFareRules(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write fare rules: %w", err)
 	}
 
-	if err := r.writeFrequencies(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write frequencies: %w", err)
+	if err := r.writeFaresV2(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write fares v2: %w", err)
 	}
 
-	if err := r.writePathways(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write pathways: %w", err)
+	if err := r.writeTransfers(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write transfers: %w", err)
 	}
 
-	if err := r.writeLevels(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write levels: %w", err)
+	if err := r.writeShapes(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write shapes: %w", err)
 	}
 
-	if err := r.writeFeedInfo(zipWriter); err != nil {
-		return nil, fmt.Errorf("failed to write feed info: %w", err)
+	if err := r.writeFrequencies(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write frequencies: %w", err)
 	}
 
-	if err := zipWriter.Close(); err != nil {
-		return nil, fmt.Errorf("failed to close ZIP writer: %w", err)
+	if err := r.writePathways(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write pathways: %w", err)
 	}
 
-	return bytes.NewReader(buf.Bytes()), nil
+	if err := r.writeLevels(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write levels: %w", err)
+	}
+
+	if err := r.writeLocationGroups(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write location groups: %w", err)
+	}
+
+	if err := r.writeLocations(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write locations: %w", err)
+	}
+
+	if err := r.writeBookingRules(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write booking rules: %w", err)
+	}
+
+	if err := r.writeTranslations(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write translations: %w", err)
+	}
+
+	if err := r.writeFeedInfo(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write feed info: %w", err)
+	}
+
+	if err := r.writeAttributions(ctx, sink); err != nil {
+		return fmt.Errorf("failed to write attributions: %w", err)
+	}
+
+	return nil
 }
 
 // Helper methods for writing CSV files
 
-func (r *DefaultGtfsRepository) writeAgencies(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeAgencies(ctx context.Context, sink output.Sink) error {
 	if len(r.agencies) == 0 {
 		return nil
 	}
@@ -196,10 +325,10 @@ func (r *DefaultGtfsRepository) writeAgencies(zipWriter *zip.Writer) error {
 		agencies = append(agencies, agency)
 	}
 
-	return r.writeCSV(zipWriter, "agency.txt", agencies)
+	return r.writeCSV(ctx, sink, "agency.txt", agencies)
 }
 
-func (r *DefaultGtfsRepository) writeStops(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeStops(ctx context.Context, sink output.Sink) error {
 	if len(r.stops) == 0 {
 		return nil
 	}
@@ -209,10 +338,10 @@ func (r *DefaultGtfsRepository) writeStops(zipWriter *zip.Writer) error {
 		stops = append(stops, stop)
 	}
 
-	return r.writeCSV(zipWriter, "stops.txt", stops)
+	return r.writeCSV(ctx, sink, "stops.txt", stops)
 }
 
-func (r *DefaultGtfsRepository) writeRoutes(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeRoutes(ctx context.Context, sink output.Sink) error {
 	if len(r.routes) == 0 {
 		return nil
 	}
@@ -222,10 +351,10 @@ func (r *DefaultGtfsRepository) writeRoutes(zipWriter *zip.Writer) error {
 		routes = append(routes, route)
 	}
 
-	return r.writeCSV(zipWriter, "routes.txt", routes)
+	return r.writeCSV(ctx, sink, "routes.txt", routes)
 }
 
-func (r *DefaultGtfsRepository) writeTrips(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeTrips(ctx context.Context, sink output.Sink) error {
 	if len(r.trips) == 0 {
 		return nil
 	}
@@ -235,18 +364,25 @@ func (r *DefaultGtfsRepository) writeTrips(zipWriter *zip.Writer) error {
 		trips = append(trips, trip)
 	}
 
-	return r.writeCSV(zipWriter, "trips.txt", trips)
+	return r.writeCSV(ctx, sink, "trips.txt", trips)
 }
 
-func (r *DefaultGtfsRepository) writeStopTimes(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeStopTimes(ctx context.Context, sink output.Sink) error {
 	if len(r.stopTimes) == 0 {
 		return nil
 	}
+	if r.stopTimeBatches == nil {
+		return r.writeCSV(ctx, sink, "stop_times.txt", r.stopTimes)
+	}
 
-	return r.writeCSV(zipWriter, "stop_times.txt", r.stopTimes)
+	return r.writeCSVBatches(ctx, sink, "stop_times.txt", func(write func(entities interface{}) error) error {
+		return r.stopTimeBatches(func(batch []*model.StopTime) error {
+			return write(batch)
+		})
+	})
 }
 
-func (r *DefaultGtfsRepository) writeCalendar(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeCalendar(ctx context.Context, sink output.Sink) error {
 	if len(r.calendars) == 0 {
 		return nil
 	}
@@ -256,119 +392,312 @@ func (r *DefaultGtfsRepository) writeCalendar(zipWriter *zip.Writer) error {
 		calendars = append(calendars, calendar)
 	}
 
-	return r.writeCSV(zipWriter, "calendar.txt", calendars)
+	return r.writeCSV(ctx, sink, "calendar.txt", calendars)
 }
 
-func (r *DefaultGtfsRepository) writeCalendarDates(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeCalendarDates(ctx context.Context, sink output.Sink) error {
 	if len(r.calendarDates) == 0 {
 		return nil
 	}
 
-	return r.writeCSV(zipWriter, "calendar_dates.txt", r.calendarDates)
+	return r.writeCSV(ctx, sink, "calendar_dates.txt", r.calendarDates)
+}
+
+func (r *DefaultGtfsRepository) writeFareAttributes(ctx context.Context, sink output.Sink) error {
+	if len(r.fareAttributes) == 0 {
+		return nil
+	}
+
+	fareAttributes := make([]*model.FareAttribute, 0, len(r.fareAttributes))
+	for _, fareAttribute := range r.fareAttributes {
+		fareAttributes = append(fareAttributes, fareAttribute)
+	}
+	sort.Slice(fareAttributes, func(i, j int) bool { return fareAttributes[i].FareID < fareAttributes[j].FareID })
+
+	return r.writeCSV(ctx, sink, "fare_attributes.txt", fareAttributes)
 }
 
-func (r *DefaultGtfsRepository) writeTransfers(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeFareRules(ctx context.Context, sink output.Sink) error {
+	if len(r.fareRules) == 0 {
+		return nil
+	}
+
+	return r.writeCSV(ctx, sink, "fare_rules.txt", r.fareRules)
+}
+
+// writeFaresV2 writes the GTFS Fares v2 files that have rows
+func (r *DefaultGtfsRepository) writeFaresV2(ctx context.Context, sink output.Sink) error {
+	files := []struct {
+		name     string
+		rows     int
+		entities interface{}
+	}{
+		{"areas.txt", len(r.areas), r.areas},
+		{"stop_areas.txt", len(r.areaStops), r.areaStops},
+		{"networks.txt", len(r.networks), r.networks},
+		{"route_networks.txt", len(r.routeNetworks), r.routeNetworks},
+		{"fare_media.txt", len(r.fareMedia), r.fareMedia},
+		{"fare_products.txt", len(r.fareProducts), r.fareProducts},
+		{"fare_leg_rules.txt", len(r.fareLegRules), r.fareLegRules},
+		{"fare_transfer_rules.txt", len(r.fareTransferRules), r.fareTransferRules},
+	}
+	for _, file := range files {
+		if file.rows == 0 {
+			continue
+		}
+		if err := r.writeCSV(ctx, sink, file.name, file.entities); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *DefaultGtfsRepository) writeTransfers(ctx context.Context, sink output.Sink) error {
 	if len(r.transfers) == 0 {
 		return nil
 	}
 
-	return r.writeCSV(zipWriter, "transfers.txt", r.transfers)
+	return r.writeCSV(ctx, sink, "transfers.txt", r.transfers)
 }
 
-func (r *DefaultGtfsRepository) writeShapes(zipWriter *zip.Writer) error {
+func (r *DefaultGtfsRepository) writeShapes(ctx context.Context, sink output.Sink) error {
 	if len(r.shapes) == 0 {
 		return nil
 	}
 
-	return r.writeCSV(zipWriter, "shapes.txt", r.shapes)
+	return r.writeCSV(ctx, sink, "shapes.txt", r.shapes)
 }
 
-func (r *DefaultGtfsRepository) writeFeedInfo(zipWriter *zip.Writer) error {
-	if r.feedInfo == nil {
+func (r *DefaultGtfsRepository) writeTranslations(ctx context.Context, sink output.Sink) error {
+	if len(r.translations) == 0 {
 		return nil
 	}
 
-	return r.writeCSV(zipWriter, "feed_info.txt", []*model.FeedInfo{r.feedInfo})
+	return r.writeCSV(ctx, sink, "translations.txt", r.translations)
 }
 
-func (r *DefaultGtfsRepository) writeFrequencies(zipWriter *zip.Writer) error {
-	if len(r.frequencies) == 0 {
+func (r *DefaultGtfsRepository) writeAttributions(ctx context.Context, sink output.Sink) error {
+	if len(r.attributions) == 0 {
 		return nil
 	}
 
-	return r.writeCSV(zipWriter, "frequencies.txt", r.frequencies)
+	return r.writeCSV(ctx, sink, "attributions.txt", r.attributions)
 }
 
-func (r *DefaultGtfsRepository) writePathways(zipWriter *zip.Writer) error {
-	if len(r.pathways) == 0 {
+func (r *DefaultGtfsRepository) writeLocationGroups(ctx context.Context, sink output.Sink) error {
+	if len(r.locationGroups) == 0 {
 		return nil
 	}
 
-	return r.writeCSV(zipWriter, "pathways.txt", r.pathways)
+	if err := r.writeCSV(ctx, sink, "location_groups.txt", r.locationGroups); err != nil {
+		return err
+	}
+	if len(r.locationGroupStops) == 0 {
+		return nil
+	}
+	return r.writeCSV(ctx, sink, "location_group_stops.txt", r.locationGroupStops)
 }
 
-func (r *DefaultGtfsRepository) writeLevels(zipWriter *zip.Writer) error {
-	if len(r.levels) == 0 {
+// geoJSONFeature is a feature of locations.geojson
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	ID         string          `json:"id"`
+	Properties geoJSONProps    `json:"properties"`
+	Geometry   geoJSONGeometry `json:"geometry"`
+}
+
+type geoJSONProps struct {
+	StopName string `json:"stop_name,omitempty"`
+	StopDesc string `json:"stop_desc,omitempty"`
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// writeLocations writes the flexible zones as a GeoJSON feature collection,
+// with a Polygon geometry for zones of one polygon and a MultiPolygon one
+// otherwise
+func (r *DefaultGtfsRepository) writeLocations(ctx context.Context, sink output.Sink) error {
+	if len(r.flexLocations) == 0 {
 		return nil
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	return r.writeCSV(zipWriter, "levels.txt", r.levels)
-}
+	features := make([]geoJSONFeature, 0, len(r.flexLocations))
+	for _, location := range r.flexLocations {
+		if len(location.Polygons) == 0 {
+			continue
+		}
+		geometry := geoJSONGeometry{Type: "MultiPolygon", Coordinates: location.Polygons}
+		if len(location.Polygons) == 1 {
+			geometry = geoJSONGeometry{Type: "Polygon", Coordinates: location.Polygons[0]}
+		}
+		features = append(features, geoJSONFeature{
+			Type:       "Feature",
+			ID:         location.LocationID,
+			Properties: geoJSONProps{StopName: location.StopName, StopDesc: location.StopDesc},
+			Geometry:   geometry,
+		})
+	}
+	// Without a polygon there is no zone to write
+	if len(features) == 0 {
+		return nil
+	}
 
-// writeCSV writes entities to a CSV file in the ZIP archive
-func (r *DefaultGtfsRepository) writeCSV(zipWriter *zip.Writer, filename string, entities interface{}) error {
-	writer, err := zipWriter.Create(filename)
+	writer, err := sink.Create("locations.geojson")
 	if err != nil {
 		return err
 	}
+	defer func() { _ = writer.Close() }()
 
-	csvWriter := csv.NewWriter(writer)
-	defer csvWriter.Flush()
+	collection := struct {
+		Type     string           `json:"type"`
+		Features []geoJSONFeature `json:"features"`
+	}{Type: "FeatureCollection", Features: features}
+	if err := json.NewEncoder(writer).Encode(collection); err != nil {
+		return err
+	}
+	return writer.Close()
+}
 
-	// Use reflection to get entity data
-	entitiesValue := reflect.ValueOf(entities)
-	if entitiesValue.Kind() != reflect.Slice {
-		return fmt.Errorf("entities must be a slice")
+func (r *DefaultGtfsRepository) writeBookingRules(ctx context.Context, sink output.Sink) error {
+	if len(r.bookingRules) == 0 {
+		return nil
 	}
 
-	if entitiesValue.Len() == 0 {
+	return r.writeCSV(ctx, sink, "booking_rules.txt", r.bookingRules)
+}
+
+func (r *DefaultGtfsRepository) writeFeedInfo(ctx context.Context, sink output.Sink) error {
+	if r.feedInfo == nil {
 		return nil
 	}
 
-	// Get the first entity to determine structure
-	firstEntity := entitiesValue.Index(0).Elem()
-	entityType := firstEntity.Type()
+	return r.writeCSV(ctx, sink, "feed_info.txt", []*model.FeedInfo{r.feedInfo})
+}
 
-	// Write header
-	header := make([]string, entityType.NumField())
-	for i := 0; i < entityType.NumField(); i++ {
-		field := entityType.Field(i)
-		header[i] = r.getCSVFieldName(field.Name)
+func (r *DefaultGtfsRepository) writeFrequencies(ctx context.Context, sink output.Sink) error {
+	if len(r.frequencies) == 0 {
+		return nil
+	}
+
+	return r.writeCSV(ctx, sink, "frequencies.txt", r.frequencies)
+}
+
+func (r *DefaultGtfsRepository) writePathways(ctx context.Context, sink output.Sink) error {
+	if len(r.pathways) == 0 {
+		return nil
 	}
-	if err := csvWriter.Write(header); err != nil {
+
+	return r.writeCSV(ctx, sink, "pathways.txt", r.pathways)
+}
+
+func (r *DefaultGtfsRepository) writeLevels(ctx context.Context, sink output.Sink) error {
+	if len(r.levels) == 0 {
+		return nil
+	}
+
+	return r.writeCSV(ctx, sink, "levels.txt", r.levels)
+}
+
+// writeCSV writes entities to a CSV file of the feed
+func (r *DefaultGtfsRepository) writeCSV(ctx context.Context, sink output.Sink, filename string, entities interface{}) error {
+	return r.writeCSVBatches(ctx, sink, filename, func(write func(entities interface{}) error) error {
+		return write(entities)
+	})
+}
+
+// writeCSVBatches writes a CSV file of the feed from the slices of entities
+// batches passes to write. Each batch is flushed to the sink before the next
+// one; the header follows the first entity.
+func (r *DefaultGtfsRepository) writeCSVBatches(ctx context.Context, sink output.Sink, filename string, batches func(write func(entities interface{}) error) error) error {
+	writer, err := sink.Create(filename)
+	if err != nil {
 		return err
 	}
+	defer func() { _ = writer.Close() }()
 
-	// Write data rows
-	for i := 0; i < entitiesValue.Len(); i++ {
-		entity := entitiesValue.Index(i).Elem()
-		row := make([]string, entity.NumField())
+	csvWriter := csv.NewWriter(writer)
+	written := 0
 
-		for j := 0; j < entity.NumField(); j++ {
-			field := entity.Field(j)
-			row[j] = r.getCSVFieldValue(field)
+	err = batches(func(entities interface{}) error {
+		// Use reflection to get entity data
+		entitiesValue := reflect.ValueOf(entities)
+		if entitiesValue.Kind() != reflect.Slice {
+			return fmt.Errorf("entities must be a slice")
 		}
 
-		if err := csvWriter.Write(row); err != nil {
-			return err
+		// Write data rows, checking for cancellation every cancelCheckInterval rows
+		for i := 0; i < entitiesValue.Len(); i++ {
+			if written%cancelCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			entity := entitiesValue.Index(i).Elem()
+
+			// Write header
+			if written == 0 {
+				entityType := entity.Type()
+				header := make([]string, entityType.NumField())
+				for j := 0; j < entityType.NumField(); j++ {
+					header[j], _ = csvColumn(entityType.Field(j))
+				}
+				if err := csvWriter.Write(header); err != nil {
+					return err
+				}
+			}
+
+			row := make([]string, entity.NumField())
+			for j := 0; j < entity.NumField(); j++ {
+				field := entity.Field(j)
+				if _, omitEmpty := csvColumn(entity.Type().Field(j)); omitEmpty && field.IsZero() {
+					continue
+				}
+				row[j] = r.getCSVFieldValue(field)
+			}
+
+			if err := csvWriter.Write(row); err != nil {
+				return err
+			}
+			written++
 		}
+
+		csvWriter.Flush()
+		return csvWriter.Error()
+	})
+	if err != nil {
+		return err
 	}
 
-	return nil
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// csvColumn returns the column name of a field, taken from its csv tag or
+// else derived from the field name, and whether the tag has the omitempty
+// option, which writes zero values as empty
+func csvColumn(field reflect.StructField) (name string, omitEmpty bool) {
+	tag, ok := field.Tag.Lookup("csv")
+	if !ok {
+		return csvFieldName(field.Name), false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = csvFieldName(field.Name)
+	}
+	return name, options == "omitempty"
 }
 
-// getCSVFieldName converts Go field name to GTFS CSV field name
-func (r *DefaultGtfsRepository) getCSVFieldName(fieldName string) string {
+// csvFieldName converts Go field name to GTFS CSV field name
+func csvFieldName(fieldName string) string {
 	// Convert CamelCase to snake_case, handling consecutive capitals properly
 	var result strings.Builder
 	runes := []rune(fieldName)
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
@@ -26,10 +27,22 @@ type DefaultNetexRepository struct {
 	dayTypes                   map[string]*model.DayType
 	operatingDays              map[string]*model.OperatingDay
 	operatingPeriods           map[string]*model.OperatingPeriod
+	uicOperatingPeriods        map[string]*model.UicOperatingPeriod
 	dayTypeAssignments         map[string]*model.DayTypeAssignment
 	stopPlaces                 map[string]*model.StopPlace
 	quays                      map[string]*model.Quay
+	sitePathLinks              map[string]*model.SitePathLink
+	vehicleTypes               map[string]*model.VehicleType
 	headwayJourneyGroups       map[string]*model.HeadwayJourneyGroup
+	serviceLinks               map[string]*model.ServiceLink
+	routeLinks                 map[string]*model.RouteLink
+	flexibleStopPlaces         map[string]*model.FlexibleStopPlace
+	tariffZones                map[string]*model.TariffZone
+	fareZones                  map[string]*model.FareZone
+	tariffs                    map[string]*model.Tariff
+	fareProducts               map[string]*model.PreassignedFareProduct
+	salesOfferPackages         map[string]*model.SalesOfferPackage
+	fareTables                 map[string]*model.FareTable
 
 	// Lookup maps for efficient querying
 	routesByLineId                            map[string][]*model.Route
@@ -40,9 +53,14 @@ type DefaultNetexRepository struct {
 	stopPlaceByQuayId                         map[string]*model.StopPlace
 	pointInJourneyPatternToScheduledStopPoint map[string]string
 	lineIdToNetworkId                         map[string]string
+	flexibleStopPlaceIdByScheduledStopPoint   map[string]string
 
-	// Default timezone
-	timeZone string
+	// Default timezone, language and currency, from the dataset's FrameDefaults
+	timeZone        string
+	defaultLanguage string
+	defaultCurrency string
+	// Latest PublicationTimestamp of the loaded documents
+	publicationTimestamp string
 }
 
 // NewDefaultNetexRepository creates a new DefaultNetexRepository
@@ -62,10 +80,22 @@ func NewDefaultNetexRepository() producer.NetexRepository {
 		dayTypes:                   make(map[string]*model.DayType),
 		operatingDays:              make(map[string]*model.OperatingDay),
 		operatingPeriods:           make(map[string]*model.OperatingPeriod),
+		uicOperatingPeriods:        make(map[string]*model.UicOperatingPeriod),
 		dayTypeAssignments:         make(map[string]*model.DayTypeAssignment),
 		stopPlaces:                 make(map[string]*model.StopPlace),
 		quays:                      make(map[string]*model.Quay),
 		headwayJourneyGroups:       make(map[string]*model.HeadwayJourneyGroup),
+		serviceLinks:               make(map[string]*model.ServiceLink),
+		routeLinks:                 make(map[string]*model.RouteLink),
+		flexibleStopPlaces:         make(map[string]*model.FlexibleStopPlace),
+		sitePathLinks:              make(map[string]*model.SitePathLink),
+		vehicleTypes:               make(map[string]*model.VehicleType),
+		tariffZones:                make(map[string]*model.TariffZone),
+		fareZones:                  make(map[string]*model.FareZone),
+		tariffs:                    make(map[string]*model.Tariff),
+		fareProducts:               make(map[string]*model.PreassignedFareProduct),
+		salesOfferPackages:         make(map[string]*model.SalesOfferPackage),
+		fareTables:                 make(map[string]*model.FareTable),
 
 		routesByLineId:                            make(map[string][]*model.Route),
 		serviceJourneysByPattern:                  make(map[string][]*model.ServiceJourney),
@@ -75,6 +105,7 @@ func NewDefaultNetexRepository() producer.NetexRepository {
 		stopPlaceByQuayId:                         make(map[string]*model.StopPlace),
 		pointInJourneyPatternToScheduledStopPoint: make(map[string]string),
 		lineIdToNetworkId:                         make(map[string]string),
+		flexibleStopPlaceIdByScheduledStopPoint:   make(map[string]string),
 
 		timeZone: "Europe/Oslo", // Default timezone
 	}
@@ -118,6 +149,8 @@ func (r *DefaultNetexRepository) SaveEntity(entity interface{}) error {
 		r.operatingDays[e.ID] = e
 	case *model.OperatingPeriod:
 		r.operatingPeriods[e.ID] = e
+	case *model.UicOperatingPeriod:
+		r.uicOperatingPeriods[e.ID] = e
 	case *model.DayTypeAssignment:
 		r.dayTypeAssignments[e.ID] = e
 		r.addToDayTypeAssignmentsByDayType(e)
@@ -126,8 +159,39 @@ func (r *DefaultNetexRepository) SaveEntity(entity interface{}) error {
 	case *model.Quay:
 		r.quays[e.ID] = e
 		r.addQuayToStopPlace(e)
+	case *model.SitePathLink:
+		r.sitePathLinks[e.ID] = e
+	case *model.VehicleType:
+		r.vehicleTypes[e.ID] = e
 	case *model.HeadwayJourneyGroup:
 		r.headwayJourneyGroups[e.ID] = e
+	case *model.ServiceLink:
+		r.serviceLinks[e.ID] = e
+	case *model.RouteLink:
+		r.routeLinks[e.ID] = e
+	case *model.FlexibleStopPlace:
+		r.flexibleStopPlaces[e.ID] = e
+	case *model.FlexibleStopAssignment:
+		r.flexibleStopPlaceIdByScheduledStopPoint[e.ScheduledStopPointRef] = e.FlexibleStopPlaceRef
+	case *model.TariffZone:
+		r.tariffZones[e.ID] = e
+	case *model.FareZone:
+		r.fareZones[e.ID] = e
+	case *model.Tariff:
+		r.tariffs[e.ID] = e
+	case *model.PreassignedFareProduct:
+		r.fareProducts[e.ID] = e
+	case *model.SalesOfferPackage:
+		r.salesOfferPackages[e.ID] = e
+	case *model.FareTable:
+		r.fareTables[e.ID] = e
+	case *model.FrameDefaults:
+		r.applyFrameDefaults(e)
+	case *model.PublicationDelivery:
+		// Only the delivery header is kept; its frames are saved entity by entity
+		if e.PublicationTimestamp > r.publicationTimestamp {
+			r.publicationTimestamp = e.PublicationTimestamp
+		}
 	default:
 		return fmt.Errorf("unknown entity type: %T", entity)
 	}
@@ -145,6 +209,13 @@ func (r *DefaultNetexRepository) GetLines() []*model.Line {
 	return lines
 }
 
+// GetLineById returns a line by ID
+func (r *DefaultNetexRepository) GetLineById(id string) *model.Line {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.lines[id]
+}
+
 // GetServiceJourneys returns all service journeys
 func (r *DefaultNetexRepository) GetServiceJourneys() []*model.ServiceJourney {
 	r.mu.RLock()
@@ -170,6 +241,13 @@ func (r *DefaultNetexRepository) GetQuayById(id string) *model.Quay {
 	return r.quays[id]
 }
 
+// GetStopPlaceById returns a stop place by ID
+func (r *DefaultNetexRepository) GetStopPlaceById(id string) *model.StopPlace {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.stopPlaces[id]
+}
+
 // GetStopPlaceByQuayId returns the stop place for a given quay ID
 func (r *DefaultNetexRepository) GetStopPlaceByQuayId(quayId string) *model.StopPlace {
 	r.mu.RLock()
@@ -184,6 +262,46 @@ func (r *DefaultNetexRepository) GetTimeZone() string {
 	return r.timeZone
 }
 
+// GetDefaultLanguage returns the default language of the dataset, or "" when
+// no frame declares one
+func (r *DefaultNetexRepository) GetDefaultLanguage() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.defaultLanguage
+}
+
+// GetDefaultCurrency returns the currency of prices without one, "" when
+// the dataset sets none
+func (r *DefaultNetexRepository) GetDefaultCurrency() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.defaultCurrency
+}
+
+// GetPublicationTimestamp returns the latest PublicationTimestamp of the
+// loaded documents, or "" when none declares one
+func (r *DefaultNetexRepository) GetPublicationTimestamp() string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.publicationTimestamp
+}
+
+// applyFrameDefaults takes the timezone and language declared by a frame
+func (r *DefaultNetexRepository) applyFrameDefaults(defaults *model.FrameDefaults) {
+	if currency := strings.TrimSpace(defaults.DefaultCurrency); currency != "" {
+		r.defaultCurrency = currency
+	}
+	if defaults.DefaultLocale == nil {
+		return
+	}
+	if tz := strings.TrimSpace(defaults.DefaultLocale.TimeZone); tz != "" {
+		r.timeZone = tz
+	}
+	if lang := strings.TrimSpace(defaults.DefaultLocale.DefaultLanguage); lang != "" {
+		r.defaultLanguage = lang
+	}
+}
+
 // GetJourneyPatternById returns a journey pattern by ID
 func (r *DefaultNetexRepository) GetJourneyPatternById(id string) *model.JourneyPattern {
 	r.mu.RLock()
@@ -287,6 +405,20 @@ func (r *DefaultNetexRepository) GetOperatingDayById(id string) *model.Operating
 	return r.operatingDays[id]
 }
 
+// GetOperatingPeriodById returns an operating period by ID
+func (r *DefaultNetexRepository) GetOperatingPeriodById(id string) *model.OperatingPeriod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.operatingPeriods[id]
+}
+
+// GetUicOperatingPeriodById returns a UIC operating period by ID
+func (r *DefaultNetexRepository) GetUicOperatingPeriodById(id string) *model.UicOperatingPeriod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.uicOperatingPeriods[id]
+}
+
 // GetDayTypeAssignmentsByDayType returns all day type assignments for a day type
 func (r *DefaultNetexRepository) GetDayTypeAssignmentsByDayType(dayType *model.DayType) []*model.DayTypeAssignment {
 	r.mu.RLock()
@@ -340,6 +472,116 @@ func (r *DefaultNetexRepository) GetAllQuays() []*model.Quay {
 	return quays
 }
 
+// GetAllSitePathLinks returns the path links kept outside their stop places
+func (r *DefaultNetexRepository) GetAllSitePathLinks() []*model.SitePathLink {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	links := make([]*model.SitePathLink, 0, len(r.sitePathLinks))
+	for _, link := range r.sitePathLinks {
+		links = append(links, link)
+	}
+	return links
+}
+
+// GetVehicleTypeById returns a vehicle type by ID
+func (r *DefaultNetexRepository) GetVehicleTypeById(id string) *model.VehicleType {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.vehicleTypes[id]
+}
+
+// GetFlexibleStopPlaceById returns a flexible stop place by ID
+func (r *DefaultNetexRepository) GetFlexibleStopPlaceById(id string) *model.FlexibleStopPlace {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.flexibleStopPlaces[id]
+}
+
+// GetFlexibleStopPlaceByScheduledStopPointId returns the flexible stop place
+// a scheduled stop point is assigned to, or nil
+func (r *DefaultNetexRepository) GetFlexibleStopPlaceByScheduledStopPointId(sspId string) *model.FlexibleStopPlace {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.flexibleStopPlaces[r.flexibleStopPlaceIdByScheduledStopPoint[sspId]]
+}
+
+// GetAllFlexibleStopPlaces returns all flexible stop places
+func (r *DefaultNetexRepository) GetAllFlexibleStopPlaces() []*model.FlexibleStopPlace {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	stopPlaces := make([]*model.FlexibleStopPlace, 0, len(r.flexibleStopPlaces))
+	for _, stopPlace := range r.flexibleStopPlaces {
+		stopPlaces = append(stopPlaces, stopPlace)
+	}
+	return stopPlaces
+}
+
+// GetAllTariffZones returns all tariff zones
+func (r *DefaultNetexRepository) GetAllTariffZones() []*model.TariffZone {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	zones := make([]*model.TariffZone, 0, len(r.tariffZones))
+	for _, zone := range r.tariffZones {
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
+// GetAllFareZones returns all fare zones
+func (r *DefaultNetexRepository) GetAllFareZones() []*model.FareZone {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	zones := make([]*model.FareZone, 0, len(r.fareZones))
+	for _, zone := range r.fareZones {
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
+// GetAllTariffs returns all tariffs
+func (r *DefaultNetexRepository) GetAllTariffs() []*model.Tariff {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tariffs := make([]*model.Tariff, 0, len(r.tariffs))
+	for _, tariff := range r.tariffs {
+		tariffs = append(tariffs, tariff)
+	}
+	return tariffs
+}
+
+// GetAllPreassignedFareProducts returns all preassigned fare products
+func (r *DefaultNetexRepository) GetAllPreassignedFareProducts() []*model.PreassignedFareProduct {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	products := make([]*model.PreassignedFareProduct, 0, len(r.fareProducts))
+	for _, product := range r.fareProducts {
+		products = append(products, product)
+	}
+	return products
+}
+
+// GetAllSalesOfferPackages returns all sales offer packages
+func (r *DefaultNetexRepository) GetAllSalesOfferPackages() []*model.SalesOfferPackage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	packages := make([]*model.SalesOfferPackage, 0, len(r.salesOfferPackages))
+	for _, salesOfferPackage := range r.salesOfferPackages {
+		packages = append(packages, salesOfferPackage)
+	}
+	return packages
+}
+
+// GetAllFareTables returns all fare tables
+func (r *DefaultNetexRepository) GetAllFareTables() []*model.FareTable {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tables := make([]*model.FareTable, 0, len(r.fareTables))
+	for _, table := range r.fareTables {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
 // Helper methods for building lookup maps
 
 func (r *DefaultNetexRepository) addToRoutesByLine(route *model.Route) {
@@ -394,6 +636,7 @@ func (r *DefaultNetexRepository) buildPointInJourneyPatternMappings(journeyPatte
 	for _, pointInterface := range journeyPattern.PointsInSequence.PointInJourneyPatternOrStopPointInJourneyPatternOrTimingPointInJourneyPattern {
 		if stopPoint, ok := pointInterface.(*model.StopPointInJourneyPattern); ok {
 			r.pointInJourneyPatternToScheduledStopPoint[stopPoint.ID] = stopPoint.ScheduledStopPointRef
+			r.stopPointInJourneyPatterns[stopPoint.ID] = stopPoint
 		}
 	}
 }
@@ -424,3 +667,17 @@ func (r *DefaultNetexRepository) GetHeadwayJourneyGroups() []*model.HeadwayJourn
 func (r *DefaultNetexRepository) GetHeadwayJourneyGroupById(id string) *model.HeadwayJourneyGroup {
 	return r.headwayJourneyGroups[id]
 }
+
+// GetServiceLinkById returns a service link by ID
+func (r *DefaultNetexRepository) GetServiceLinkById(id string) *model.ServiceLink {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.serviceLinks[id]
+}
+
+// GetRouteLinkById returns a route link by ID
+func (r *DefaultNetexRepository) GetRouteLinkById(id string) *model.RouteLink {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.routeLinks[id]
+}
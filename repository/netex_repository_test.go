@@ -101,3 +101,56 @@ func TestDefaultNetexRepository_GetTimeZone(t *testing.T) {
 		t.Errorf("Expected default timezone 'Europe/Oslo', got '%s'", tz)
 	}
 }
+
+func TestDefaultNetexRepository_FrameDefaults(t *testing.T) {
+	repo := NewDefaultNetexRepository()
+	if lang := repo.GetDefaultLanguage(); lang != "" {
+		t.Errorf("Expected no default language, got '%s'", lang)
+	}
+
+	defaults := &model.FrameDefaults{DefaultLocale: &model.DefaultLocale{TimeZone: "Europe/Paris", DefaultLanguage: "fr"}}
+	if err := repo.SaveEntity(defaults); err != nil {
+		t.Fatal(err)
+	}
+	// Frames without a locale keep the values already loaded
+	if err := repo.SaveEntity(&model.FrameDefaults{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if tz := repo.GetTimeZone(); tz != "Europe/Paris" {
+		t.Errorf("Expected timezone 'Europe/Paris', got '%s'", tz)
+	}
+	if lang := repo.GetDefaultLanguage(); lang != "fr" {
+		t.Errorf("Expected language 'fr', got '%s'", lang)
+	}
+}
+
+func TestDefaultNetexRepository_Links(t *testing.T) {
+	for name, repo := range map[string]interface {
+		SaveEntity(entity interface{}) error
+		GetServiceLinkById(id string) *model.ServiceLink
+		GetRouteLinkById(id string) *model.RouteLink
+	}{
+		"default":   NewDefaultNetexRepository(),
+		"optimized": NewOptimizedNetexRepository(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := repo.SaveEntity(&model.ServiceLink{ID: "sl1"}); err != nil {
+				t.Fatalf("SaveEntity() failed: %v", err)
+			}
+			if err := repo.SaveEntity(&model.RouteLink{ID: "rl1"}); err != nil {
+				t.Fatalf("SaveEntity() failed: %v", err)
+			}
+
+			if link := repo.GetServiceLinkById("sl1"); link == nil || link.ID != "sl1" {
+				t.Errorf("Expected service link sl1, got %+v", link)
+			}
+			if link := repo.GetRouteLinkById("rl1"); link == nil || link.ID != "rl1" {
+				t.Errorf("Expected route link rl1, got %+v", link)
+			}
+			if repo.GetServiceLinkById("missing") != nil {
+				t.Error("Expected nil for unknown service link")
+			}
+		})
+	}
+}
@@ -38,6 +38,8 @@ func NewOptimizedGtfsRepository() producer.GtfsRepository {
 			fareRules:      make([]*model.FareRule, 0),
 			pathways:       make([]*model.Pathway, 0),
 			levels:         make([]*model.Level, 0),
+			translations:   make([]*model.Translation, 0),
+			attributions:   make([]*model.Attribution, 0),
 		},
 		memoryManager:   memManager,
 		streamProcessor: memory.NewStreamProcessor(memManager),
@@ -45,6 +47,9 @@ func NewOptimizedGtfsRepository() producer.GtfsRepository {
 	}
 
 	repo.initializePools()
+	repo.stopTimeBatches = func(write func([]*model.StopTime) error) error {
+		return repo.StreamWriteStopTimes(write, 0)
+	}
 	return repo
 }
 
@@ -251,6 +256,13 @@ func (r *OptimizedGtfsRepository) GetEntityCount() map[string]int {
 		"fareRules":      len(r.fareRules),
 		"pathways":       len(r.pathways),
 		"levels":         len(r.levels),
+		"translations":   len(r.translations),
+		"attributions":   len(r.attributions),
+		"locationGroups": len(r.locationGroups),
+		"flexLocations":  len(r.flexLocations),
+		"bookingRules":   len(r.bookingRules),
+		"fareProducts":   len(r.fareProducts),
+		"fareLegRules":   len(r.fareLegRules),
 	}
 }
 
@@ -287,27 +299,23 @@ func (r *OptimizedGtfsRepository) GetStopTimesCount() int {
 	return len(r.stopTimes)
 }
 
-// StreamWriteStopTimes writes stop times in a memory-efficient streaming manner
+// StreamWriteStopTimes passes the stop times to writer in batches of
+// batchSize, or of the configured batch size when batchSize is 0, collecting
+// garbage between batches under memory pressure
 func (r *OptimizedGtfsRepository) StreamWriteStopTimes(writer func([]*model.StopTime) error, batchSize int) error {
-	if len(r.stopTimes) == 0 {
-		return nil
+	if batchSize <= 0 {
+		batchSize = r.memoryManager.GetBatchSize()
 	}
 
-	batchProcessor := memory.NewBatchProcessor(r.memoryManager)
-	if err := batchProcessor.ProcessInBatches(make([]interface{}, len(r.stopTimes)), func(batch []interface{}) error {
-		start := len(batch) * (batchSize)
-		end := start + len(batch)
+	for start := 0; start < len(r.stopTimes); start += batchSize {
+		end := start + batchSize
 		if end > len(r.stopTimes) {
 			end = len(r.stopTimes)
 		}
-
-		if start < len(r.stopTimes) {
-			batchStopTimes := r.stopTimes[start:end]
-			return writer(batchStopTimes)
+		if err := writer(r.stopTimes[start:end]); err != nil {
+			return err
 		}
-		return nil
-	}); err != nil {
-		return err
+		r.memoryManager.ForceGC()
 	}
 
 	return nil
@@ -39,10 +39,22 @@ func NewOptimizedNetexRepository() producer.NetexRepository {
 			dayTypes:                             make(map[string]*model.DayType),
 			operatingDays:                        make(map[string]*model.OperatingDay),
 			operatingPeriods:                     make(map[string]*model.OperatingPeriod),
+			uicOperatingPeriods:                  make(map[string]*model.UicOperatingPeriod),
 			dayTypeAssignments:                   make(map[string]*model.DayTypeAssignment),
 			stopPlaces:                           make(map[string]*model.StopPlace),
 			quays:                                make(map[string]*model.Quay),
 			headwayJourneyGroups:                 make(map[string]*model.HeadwayJourneyGroup),
+			serviceLinks:                         make(map[string]*model.ServiceLink),
+			routeLinks:                           make(map[string]*model.RouteLink),
+			flexibleStopPlaces:                   make(map[string]*model.FlexibleStopPlace),
+			sitePathLinks:                        make(map[string]*model.SitePathLink),
+			vehicleTypes:                         make(map[string]*model.VehicleType),
+			tariffZones:                          make(map[string]*model.TariffZone),
+			fareZones:                            make(map[string]*model.FareZone),
+			tariffs:                              make(map[string]*model.Tariff),
+			fareProducts:                         make(map[string]*model.PreassignedFareProduct),
+			salesOfferPackages:                   make(map[string]*model.SalesOfferPackage),
+			fareTables:                           make(map[string]*model.FareTable),
 			routesByLineId:                       make(map[string][]*model.Route),
 			serviceJourneysByPattern:             make(map[string][]*model.ServiceJourney),
 			datedServiceJourneysByServiceJourney: make(map[string][]*model.DatedServiceJourney),
@@ -51,6 +63,7 @@ func NewOptimizedNetexRepository() producer.NetexRepository {
 			stopPlaceByQuayId:                    make(map[string]*model.StopPlace),
 			pointInJourneyPatternToScheduledStopPoint: make(map[string]string),
 			lineIdToNetworkId:                         make(map[string]string),
+			flexibleStopPlaceIdByScheduledStopPoint:   make(map[string]string),
 			timeZone:                                  "Europe/Oslo",
 		},
 		memoryManager:   memManager,
@@ -273,10 +286,20 @@ func (r *OptimizedNetexRepository) GetEntityCount() map[string]int {
 		"dayTypes":                   len(r.dayTypes),
 		"operatingDays":              len(r.operatingDays),
 		"operatingPeriods":           len(r.operatingPeriods),
+		"uicOperatingPeriods":        len(r.uicOperatingPeriods),
 		"dayTypeAssignments":         len(r.dayTypeAssignments),
 		"stopPlaces":                 len(r.stopPlaces),
 		"quays":                      len(r.quays),
 		"headwayJourneyGroups":       len(r.headwayJourneyGroups),
+		"serviceLinks":               len(r.serviceLinks),
+		"routeLinks":                 len(r.routeLinks),
+		"flexibleStopPlaces":         len(r.flexibleStopPlaces),
+		"tariffZones":                len(r.tariffZones),
+		"fareZones":                  len(r.fareZones),
+		"tariffs":                    len(r.tariffs),
+		"fareProducts":               len(r.fareProducts),
+		"salesOfferPackages":         len(r.salesOfferPackages),
+		"fareTables":                 len(r.fareTables),
 	}
 	r.DefaultNetexRepository.mu.RUnlock()
 	return counts
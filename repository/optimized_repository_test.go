@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
@@ -166,6 +167,46 @@ func TestOptimizedGtfsRepository_BasicOperations(t *testing.T) {
 	}
 }
 
+func TestOptimizedGtfsRepository_StreamWriteStopTimes(t *testing.T) {
+	repo := NewOptimizedGtfsRepository().(*OptimizedGtfsRepository)
+	repo.SetBatchSize(2)
+	for i := 1; i <= 5; i++ {
+		if err := repo.SaveEntity(&model.StopTime{TripID: "trip-1", StopID: fmt.Sprintf("stop-%d", i), StopSequence: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sizes []int
+	if err := repo.StreamWriteStopTimes(func(batch []*model.StopTime) error {
+		sizes = append(sizes, len(batch))
+		return nil
+	}, 0); err != nil {
+		t.Fatalf("StreamWriteStopTimes() failed: %v", err)
+	}
+	if fmt.Sprint(sizes) != "[2 2 1]" {
+		t.Errorf("Expected batches of the configured size [2 2 1], got %v", sizes)
+	}
+
+	// The archive streams stop_times.txt batch by batch with a single header
+	var buf bytes.Buffer
+	if err := repo.WriteGtfsTo(&buf); err != nil {
+		t.Fatalf("WriteGtfsTo() failed: %v", err)
+	}
+	tables, err := ReadGtfsArchive(buf.Bytes())
+	if err != nil {
+		t.Fatalf("ReadGtfsArchive() failed: %v", err)
+	}
+	table := tables["stop_times.txt"]
+	if table == nil || len(table.Rows) != 5 {
+		t.Fatalf("Expected 5 stop times, got %v", table)
+	}
+	for i, row := range table.Rows {
+		if got := table.Value(row, "stop_id"); got != fmt.Sprintf("stop-%d", i+1) {
+			t.Errorf("Expected stop-%d in row %d, got %q", i+1, i, got)
+		}
+	}
+}
+
 func TestOptimizedGtfsRepository_BulkOperations(t *testing.T) {
 	repo := NewOptimizedGtfsRepository()
 
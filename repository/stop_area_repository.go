@@ -17,6 +17,7 @@ type DefaultStopAreaRepository struct {
 	stopPlaces        map[string]*model.StopPlace
 	quays             map[string]*model.Quay
 	stopPlaceByQuayId map[string]*model.StopPlace
+	sitePathLinks     []*model.SitePathLink
 }
 
 // NewDefaultStopAreaRepository creates a new DefaultStopAreaRepository
@@ -80,72 +81,69 @@ func (r *DefaultStopAreaRepository) LoadStopAreas(data []byte) error {
 		}
 	}
 
+	if len(r.stopPlaces) == 0 {
+		return fmt.Errorf("no stop places found in archive")
+	}
+
 	return nil
 }
 
-// parseStopAreaXML parses stop area XML data and loads it into the repository
+// parseStopAreaXML loads every StopPlace and SitePathLink of a stops
+// document, wherever its frame and container sit in the delivery
 func (r *DefaultStopAreaRepository) parseStopAreaXML(xmlData []byte) error {
-	// Parse the root PublicationDelivery structure
-	var pubDelivery model.PublicationDelivery
-	if err := xml.Unmarshal(xmlData, &pubDelivery); err != nil {
-		return fmt.Errorf("failed to unmarshal XML: %w", err)
-	}
-
-	// Extract frames from the structure
-	var compositeFrame *model.CompositeFrame
-	if pubDelivery.DataObjects != nil && pubDelivery.DataObjects.CompositeFrame != nil {
-		compositeFrame = pubDelivery.DataObjects.CompositeFrame
-	} else if pubDelivery.CompositeFrame != nil {
-		compositeFrame = pubDelivery.CompositeFrame
-	}
-
-	if compositeFrame == nil {
-		return fmt.Errorf("no CompositeFrame found in XML")
-	}
-
-	if compositeFrame.Frames == nil {
-		return fmt.Errorf("no Frames found in CompositeFrame")
-	}
+	decoder := xml.NewDecoder(bytes.NewReader(xmlData))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read XML: %w", err)
+		}
 
-	frames := compositeFrame.Frames
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
 
-	// Load stop areas from SiteFrame
-	if frames.SiteFrame != nil {
-		if err := r.loadStopAreasFromSiteFrame(frames.SiteFrame); err != nil {
-			return fmt.Errorf("failed to load stop areas from site frame: %w", err)
+		switch start.Name.Local {
+		case "StopPlace":
+			stopPlace := &model.StopPlace{}
+			if err := decoder.DecodeElement(stopPlace, &start); err != nil {
+				return fmt.Errorf("failed to decode stop place: %w", err)
+			}
+			r.addStopPlace(stopPlace)
+		case "SitePathLink":
+			link := &model.SitePathLink{}
+			if err := decoder.DecodeElement(link, &start); err != nil {
+				return fmt.Errorf("failed to decode path link: %w", err)
+			}
+			r.sitePathLinks = append(r.sitePathLinks, link)
 		}
 	}
 
 	return nil
 }
 
-// loadStopAreasFromSiteFrame loads stop places and quays from a SiteFrame
-func (r *DefaultStopAreaRepository) loadStopAreasFromSiteFrame(siteFrame *model.SiteFrame) error {
-	if siteFrame.StopPlaces == nil {
-		return nil
+// addStopPlace stores a stop place and indexes its quays
+func (r *DefaultStopAreaRepository) addStopPlace(stopPlace *model.StopPlace) {
+	r.stopPlaces[stopPlace.ID] = stopPlace
+	if stopPlace.Quays == nil {
+		return
 	}
-
-	// Load stop places and their quays
-	for _, stopPlace := range siteFrame.StopPlaces.StopPlace {
-		// Store the stop place
-		r.stopPlaces[stopPlace.ID] = &stopPlace
-
-		// Load quays within the stop place
-		if stopPlace.Quays != nil {
-			for _, quay := range stopPlace.Quays.Quay {
-				// Store the quay
-				r.quays[quay.ID] = &quay
-
-				// Map quay to its stop place
-				r.stopPlaceByQuayId[quay.ID] = &stopPlace
-			}
-		}
+	for i := range stopPlace.Quays.Quay {
+		quay := &stopPlace.Quays.Quay[i]
+		r.quays[quay.ID] = quay
+		r.stopPlaceByQuayId[quay.ID] = stopPlace
 	}
+}
 
-	return nil
+// GetAllSitePathLinks returns the path links kept outside their stop places
+func (r *DefaultStopAreaRepository) GetAllSitePathLinks() []*model.SitePathLink {
+	return r.sitePathLinks
 }
 
-// GetStopPlaceById returns a stop place by ID (helper method)
+// GetStopPlaceById returns a stop place by ID
 func (r *DefaultStopAreaRepository) GetStopPlaceById(id string) *model.StopPlace {
 	return r.stopPlaces[id]
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/theoremus-urban-solutions/netex-gtfs-converter/model"
+	"github.com/theoremus-urban-solutions/netex-gtfs-converter/producer"
 )
 
 // Comprehensive tests for Validator - Additional Coverage
@@ -535,6 +536,23 @@ func TestValidationService_ConversionManagement(t *testing.T) {
 		}
 	})
 
+	t.Run("Record fare issues", func(t *testing.T) {
+		ctx := service.StartConversion()
+		service.RecordFareIssues(ctx, []producer.FareIssue{
+			{EntityType: "FareTable", EntityID: "ft1", Message: "Prices arranged in cells are not supported"},
+		})
+
+		found := false
+		for _, issue := range service.GetCurrentReport().Issues {
+			if issue.Code == "FARE_NOT_EXPRESSIBLE" && issue.EntityID == "ft1" && issue.Severity == SeverityWarning {
+				found = true
+			}
+		}
+		if !found {
+			t.Error("Expected a FARE_NOT_EXPRESSIBLE warning for the fare table")
+		}
+	})
+
 	t.Run("Performance monitoring", func(t *testing.T) {
 		ctx := service.StartConversion()
 
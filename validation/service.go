@@ -219,6 +219,21 @@ func (vs *ValidationService) RecordConversionError(ctx *ValidationContext, stage
 	})
 }
 
+// RecordFareIssues records the fare elements GTFS Fares v2 cannot express
+func (vs *ValidationService) RecordFareIssues(ctx *ValidationContext, issues []producer.FareIssue) {
+	for _, issue := range issues {
+		vs.validator.AddIssue(ValidationIssue{
+			Severity:   SeverityWarning,
+			Code:       "FARE_NOT_EXPRESSIBLE",
+			Message:    issue.Message,
+			EntityType: issue.EntityType,
+			EntityID:   issue.EntityID,
+			Location:   "fares",
+			Suggestion: "Check the fare files, which leave out or approximate this fare element",
+		})
+	}
+}
+
 // RecordProcessingTime records processing time for a conversion stage
 func (vs *ValidationService) RecordProcessingTime(ctx *ValidationContext, stage string, duration time.Duration) {
 	ctx.ConversionStats.ProcessingTimes[stage] = duration